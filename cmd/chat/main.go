@@ -3,10 +3,22 @@ package main
 import (
 	_ "app/docs/api/chat" // Import generated docs
 	"app/pkg/chat/config"
+	"app/pkg/chat/repository/local"
 	repository "app/pkg/chat/repository/mongodb"
+	"app/pkg/chat/service/analytics"
+	"app/pkg/chat/service/attachment"
 	"app/pkg/chat/service/chat"
 	"app/pkg/chat/service/chatroom"
 	"app/pkg/chat/service/client"
+	"app/pkg/chat/service/gdpr"
+	"app/pkg/chat/service/moderation"
+	"app/pkg/chat/service/notification"
+	"app/pkg/chat/service/presence"
+	"app/pkg/chat/service/privacy"
+	"app/pkg/chat/service/ratelimit"
+	"app/pkg/chat/service/receipt"
+	"app/pkg/chat/service/search"
+	"app/pkg/chat/service/squad"
 	"app/pkg/chat/service/user"
 	"app/pkg/chat/transport/http/handler"
 	"app/pkg/chat/transport/http/middleware"
@@ -64,6 +76,10 @@ func main() {
 		}
 		log.Fatalf("error reading config file: %v", err)
 	}
+	// Invite codes must never be signed with an empty key, nor with the admin API key
+	if cfg.App.InviteSecret == "" {
+		log.Println("APP_INVITE_SECRET is not set, squad invites are disabled")
+	}
 
 	// Initialize MongoDB connection
 	mongoClient := mongodb.NewClient(&cfg.MongoDB)
@@ -90,6 +106,10 @@ func main() {
 	if err != nil {
 		log.Fatalf("Failed to create client repository: %v", err)
 	}
+	clientKeyRepo, err := repository.NewClientKeyRepository(db)
+	if err != nil {
+		log.Fatalf("Failed to create client key repository: %v", err)
+	}
 	chatRepo, err := repository.NewChatRepository(db)
 	if err != nil {
 		log.Fatalf("Failed to create chat repository: %v", err)
@@ -98,12 +118,84 @@ func main() {
 	if err != nil {
 		log.Fatalf("Failed to create chatroom repository: %v", err)
 	}
+	readStateRepo, err := repository.NewReadStateRepository(db)
+	if err != nil {
+		log.Fatalf("Failed to create read state repository: %v", err)
+	}
+	attachmentRepo, err := repository.NewAttachmentRepository(db)
+	if err != nil {
+		log.Fatalf("Failed to create attachment repository: %v", err)
+	}
+	messageSearch, err := repository.NewMessageSearch(db)
+	if err != nil {
+		log.Fatalf("Failed to create message search: %v", err)
+	}
+	chatAnalytics, err := repository.NewChatAnalytics(db)
+	if err != nil {
+		log.Fatalf("Failed to create chat analytics: %v", err)
+	}
+	inviteRepo, err := repository.NewInviteRepository(db)
+	if err != nil {
+		log.Fatalf("Failed to create invite repository: %v", err)
+	}
+	joinRequestRepo, err := repository.NewJoinRequestRepository(db)
+	if err != nil {
+		log.Fatalf("Failed to create join request repository: %v", err)
+	}
+	notificationPreferenceRepo, err := repository.NewNotificationPreferenceRepository(db)
+	if err != nil {
+		log.Fatalf("Failed to create notification preference repository: %v", err)
+	}
+	moderationRepo, err := repository.NewModerationRepository(db)
+	if err != nil {
+		log.Fatalf("Failed to create moderation repository: %v", err)
+	}
+	userPrivacyRepo, err := repository.NewUserPrivacyRepository(db)
+	if err != nil {
+		log.Fatalf("Failed to create user privacy repository: %v", err)
+	}
+	dataJobRepo, err := repository.NewDataJobRepository(db)
+	if err != nil {
+		log.Fatalf("Failed to create data job repository: %v", err)
+	}
+	fileStorage, err := local.NewFileStorage(cfg.Storage.Path)
+	if err != nil {
+		log.Fatalf("Failed to create file storage: %v", err)
+	}
 
 	// Create services
 	userService := user.NewUserService(userRepo)
-	clientService := client.NewClientService(clientRepo, userRepo, redisClient)
-	chatroomService := chatroom.NewChatroomService(chatroomRepo)
-	chatService := chat.NewChatService(chatRepo, chatroomService)
+	clientService := client.NewClientService(clientRepo, clientKeyRepo, userRepo, redisClient)
+	publisher := ws.NewPublisher(redisClient)
+	chatroomService := chatroom.NewChatroomService(chatroomRepo, userRepo, publisher)
+	privacyService := privacy.NewPrivacyService(userPrivacyRepo, chatroomRepo, publisher)
+	presenceService := presence.NewPresenceService(redisClient, chatroomRepo, publisher)
+	notifiers := []notification.Notifier{notification.NewWebhookNotifier(clientRepo)}
+	telegramBotID := ""
+	if cfg.Notification.TelegramURL != "" && cfg.Notification.TelegramAPIKey != "" && cfg.Notification.TelegramBotID != "" {
+		telegramBotID = cfg.Notification.TelegramBotID
+		notifiers = append(notifiers, notification.NewTelegramNotifier(cfg.Notification.TelegramURL, cfg.Notification.TelegramAPIKey, cfg.Notification.TelegramBotID))
+	}
+	outbox := notification.NewOutbox(redisClient, notificationPreferenceRepo, notifiers...)
+	notificationService := notification.NewNotificationService(notificationPreferenceRepo, chatroomRepo, userRepo, userPrivacyRepo, presenceService, outbox, redisClient, telegramBotID)
+	messageLimiter := ratelimit.NewMessageLimiter(ratelimit.NewLimiter(redisClient), ratelimit.MessageLimits{
+		User:     ratelimit.Limit(cfg.RateLimit.User),
+		Chatroom: ratelimit.Limit(cfg.RateLimit.Chatroom),
+		Client:   ratelimit.Limit(cfg.RateLimit.Client),
+	})
+	moderationService := moderation.NewModerationService(moderationRepo, chatroomService,
+		moderation.NewWordFilter(cfg.Moderation.BlockedWords),
+		moderation.NewLinkFilter(cfg.Moderation.BlockedDomains),
+		moderation.NewRepeatFilter(redisClient, cfg.Moderation.RepeatLimit, cfg.Moderation.RepeatWindow),
+	)
+	attachmentService := attachment.NewAttachmentService(attachmentRepo, fileStorage, chatroomService, int64(cfg.Server.UploadLimit)*1024*1024) // Upload limit is configured in MB
+	chatService := chat.NewChatService(chatRepo, chatroomRepo, attachmentRepo, chatroomService, publisher, notificationService, messageLimiter, moderationService, privacyService, attachmentService)
+	receiptService := receipt.NewReceiptService(readStateRepo, chatRepo, chatroomService)
+	squadService := squad.NewSquadService(inviteRepo, joinRequestRepo, chatroomService, cfg.App.InviteSecret)
+	searchService := search.NewSearchService(messageSearch, chatroomService)
+	gdprService := gdpr.NewGDPRService(dataJobRepo, userRepo, fileStorage)
+	gdprWorker := gdpr.NewWorker(dataJobRepo, userRepo, clientRepo, chatRepo, chatroomRepo, attachmentRepo, userPrivacyRepo, notificationPreferenceRepo, fileStorage, clientService, publisher, cfg.GDPR.ExportRetention)
+	analyticsService := analytics.NewAnalyticsService(chatAnalytics, redisClient)
 
 	// Create middleware
 	clientMiddleware := middleware.NewClientMiddleware(clientService)
@@ -112,15 +204,36 @@ func main() {
 	errorHandler := sharedMiddleware.NewErrorMiddleware()
 
 	// Create WebSocket hub
-	hub := ws.NewHub(redisClient, chatService, chatroomService)
+	hub := ws.NewHub(redisClient, chatService, chatroomService, privacyService, presenceService, ws.Options{
+		PingInterval:       cfg.WebSocket.PingInterval,
+		PongTimeout:        cfg.WebSocket.PongTimeout,
+		WriteTimeout:       cfg.WebSocket.WriteTimeout,
+		MaxMessageSize:     cfg.WebSocket.MaxMessageSize,
+		SendQueueSize:      cfg.WebSocket.SendQueueSize,
+		SlowConsumerPolicy: ws.SlowConsumerPolicy(cfg.WebSocket.SlowConsumerPolicy),
+	})
 	go hub.Run()
 
+	// Deliver notifications for offline users
+	outboxCtx, stopOutbox := context.WithCancel(context.Background())
+	defer stopOutbox()
+	go outbox.Run(outboxCtx)
+
+	// Run user data exports and erasures
+	workerCtx, stopWorker := context.WithCancel(context.Background())
+	defer stopWorker()
+	go gdprWorker.Run(workerCtx)
+
 	// Create handlers
-	userHandler := handler.NewUserHandler(userService, clientMiddleware, authMiddleware)
-	clientHandler := handler.NewClientHandler(clientService, adminMiddleware)
-	chatHandler := handler.NewChatHandler(chatService, clientMiddleware, authMiddleware)
-	chatroomHandler := handler.NewChatroomHandler(chatroomService, clientMiddleware, authMiddleware)
-	wsHandler := ws.NewHandler(redisClient, clientService, chatService, chatroomService)
+	userHandler := handler.NewUserHandler(userService, privacyService, presenceService, clientMiddleware, authMiddleware)
+	clientHandler := handler.NewClientHandler(clientService, adminMiddleware, clientMiddleware)
+	chatHandler := handler.NewChatHandler(chatService, attachmentService, searchService, clientMiddleware, authMiddleware)
+	chatroomHandler := handler.NewChatroomHandler(chatroomService, receiptService, squadService, clientMiddleware, authMiddleware)
+	notificationHandler := handler.NewNotificationHandler(notificationService, adminMiddleware, clientMiddleware, authMiddleware)
+	moderationHandler := handler.NewModerationHandler(moderationService, adminMiddleware)
+	gdprHandler := handler.NewGDPRHandler(gdprService, adminMiddleware, clientMiddleware, authMiddleware)
+	analyticsHandler := handler.NewAnalyticsHandler(analyticsService, adminMiddleware)
+	wsHandler := ws.NewHandler(hub, clientService, chatService, chatroomService, receiptService)
 
 	// API Custom error handler
 	cfg.Server.ErrorHandler = errorHandler.Handler()
@@ -134,6 +247,10 @@ func main() {
 	clientHandler.RegisterRoutes(api)
 	chatHandler.RegisterRoutes(api)
 	chatroomHandler.RegisterRoutes(api)
+	notificationHandler.RegisterRoutes(api)
+	moderationHandler.RegisterRoutes(api)
+	gdprHandler.RegisterRoutes(api)
+	analyticsHandler.RegisterRoutes(api)
 	wsHandler.RegisterRoutes(api)
 
 	// Swagger documentation route
@@ -166,6 +283,8 @@ func main() {
 	<-quit
 
 	log.Println("Shutting down server...")
+	stopOutbox()
+	stopWorker()
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
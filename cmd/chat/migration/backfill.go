@@ -0,0 +1,165 @@
+package main
+
+import (
+	"app/pkg/chat/domain/entity"
+	domain "app/pkg/chat/domain/repository"
+	repository "app/pkg/chat/repository/mongodb"
+	"app/pkg/types/pagination"
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// runBackfills fills in fields that existing documents are missing, it's safe to run repeatedly
+// Users and chatrooms without a client are assigned to clientID, which can be left empty when only one client exists
+func runBackfills(ctx context.Context, db *mongo.Database, clientID string) error {
+	log.Println("Starting backfills...")
+	start := time.Now()
+
+	userRepo, err := repository.NewUserRepository(db)
+	if err != nil {
+		return fmt.Errorf("failed to create user repository: %v", err)
+	}
+
+	clientRepo, err := repository.NewClientRepository(db)
+	if err != nil {
+		return fmt.Errorf("failed to create client repository: %v", err)
+	}
+
+	chatRepo, err := repository.NewChatRepository(db)
+	if err != nil {
+		return fmt.Errorf("failed to create chat repository: %v", err)
+	}
+
+	chatroomRepo, err := repository.NewChatroomRepository(db)
+	if err != nil {
+		return fmt.Errorf("failed to create chatroom repository: %v", err)
+	}
+
+	clientKeyRepo, err := repository.NewClientKeyRepository(db)
+	if err != nil {
+		return fmt.Errorf("failed to create client key repository: %v", err)
+	}
+
+	log.Println("Hashing plaintext client keys...")
+	moved, err := backfillClientKeys(ctx, clientRepo, clientKeyRepo)
+	if err != nil {
+		return err
+	}
+	log.Printf("Hashed the keys of %d clients\n", moved)
+
+	client, err := resolveBackfillClient(ctx, clientRepo, clientID)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Assigning users and chatrooms without a client to %s (%s)...\n", client.Name, client.ID)
+	updated, err := userRepo.BackfillClientID(ctx, client.ID)
+	if err != nil {
+		return fmt.Errorf("failed to backfill user clients: %v", err)
+	}
+	log.Printf("Set the client of %d users\n", updated)
+
+	updated, err = chatroomRepo.BackfillClientID(ctx, client.ID)
+	if err != nil {
+		return fmt.Errorf("failed to backfill chatroom clients: %v", err)
+	}
+	log.Printf("Set the client of %d chatrooms\n", updated)
+
+	log.Println("Copying chatroom clients to their messages...")
+	updated, err = chatRepo.BackfillClientID(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to backfill chat message clients: %v", err)
+	}
+	log.Printf("Set the client of %d chat messages\n", updated)
+
+	log.Println("Backfilling direct chatroom keys...")
+	updated, duplicates, err := chatroomRepo.BackfillDirectKeys(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to backfill direct chatroom keys: %v", err)
+	}
+	log.Printf("Set the direct key of %d chatrooms\n", updated)
+	if duplicates > 0 {
+		log.Printf("Skipped %d duplicate direct chatrooms, new messages go to the oldest chatroom of each pair\n", duplicates)
+	}
+
+	log.Printf("Backfills completed in %v\n", time.Since(start))
+	return nil
+}
+
+// backfillClientKeys stores the plaintext keys of clients as hashed client keys with every scope and removes them
+// Webhooks were signed with the plaintext key, so clients without a webhook secret get one before it's removed
+// A key already stored by an earlier, interrupted run is reused
+func backfillClientKeys(ctx context.Context, clientRepo domain.ClientRepository, clientKeyRepo domain.ClientKeyRepository) (int, error) {
+	clients, err := clientRepo.GetWithLegacyKey(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to fetch clients with plaintext keys: %v", err)
+	}
+
+	for _, client := range clients {
+		hash := entity.HashClientKey(client.LegacyKey)
+
+		existing, err := clientKeyRepo.GetByHash(ctx, hash)
+		if err != nil {
+			return 0, fmt.Errorf("failed to fetch client key: %v", err)
+		}
+		if existing == nil {
+			key := &entity.ClientKey{
+				ClientID: client.ID,
+				Name:     "Migrated",
+				Hash:     hash,
+				Prefix:   entity.ClientKeyPrefix(client.LegacyKey),
+				Scopes:   entity.ClientKeyScopes,
+			}
+			if err := clientKeyRepo.Create(ctx, key); err != nil {
+				return 0, fmt.Errorf("failed to store the key of client %s: %v", client.ID, err)
+			}
+		}
+
+		if client.WebhookSecret == "" {
+			secret := entity.NewWebhookSecret()
+			if err := clientRepo.SetWebhookSecret(ctx, client.ID, secret); err != nil {
+				return 0, fmt.Errorf("failed to store the webhook secret of client %s: %v", client.ID, err)
+			}
+			// The secret is never returned by the API, this is the only time it's shown
+			log.Printf("Client %s (%s) now signs webhooks with %s\n", client.Name, client.ID, secret)
+		}
+
+		if err := clientRepo.ClearLegacyKey(ctx, client.ID); err != nil {
+			return 0, fmt.Errorf("failed to remove the plaintext key of client %s: %v", client.ID, err)
+		}
+	}
+
+	return len(clients), nil
+}
+
+// resolveBackfillClient retrieves the client existing data is assigned to
+// Without an ID the only stored client is used, there's no safe default when there are several
+func resolveBackfillClient(ctx context.Context, clientRepo domain.ClientRepository, clientID string) (*entity.Client, error) {
+	if clientID != "" {
+		client, err := clientRepo.Get(ctx, clientID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch client %s: %v", clientID, err)
+		}
+		if client == nil {
+			return nil, fmt.Errorf("client %s not found", clientID)
+		}
+		return client, nil
+	}
+
+	clients, total, err := clientRepo.GetAll(ctx, pagination.Pagination{Page: 1, Limit: 1})
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch clients: %v", err)
+	}
+	switch {
+	case total == 0:
+		return nil, fmt.Errorf("no client exists to assign existing data to, create one first")
+	case total > 1:
+		return nil, fmt.Errorf("%d clients exist, pass the one existing data belongs to with -client", total)
+	}
+
+	return clients[0], nil
+}
@@ -20,6 +20,8 @@ import (
 func main() {
 	var cfg config.ChatConfig
 	var configPath = flag.String("config", filepath.Join("cmd", "chat", "config", "config.yml"), "path to config file")
+	var backfill = flag.Bool("backfill", false, "backfill fields added to existing data instead of creating sample data")
+	var backfillClient = flag.String("client", "", "client that backfilled users and chatrooms belong to, required when several clients exist")
 
 	flag.Parse()
 
@@ -43,6 +45,14 @@ func main() {
 	db := mongoClient.Database(cfg.MongoDB.Database)
 	ctx := context.Background()
 
+	if *backfill {
+		if err := runBackfills(ctx, db, *backfillClient); err != nil {
+			log.Fatalf("Failed to run backfills: %v", err)
+		}
+		log.Println("Backfills completed successfully")
+		return
+	}
+
 	// Run migrations
 	if err := runMigrations(ctx, db); err != nil {
 		log.Fatalf("Failed to run migrations: %v", err)
@@ -66,6 +76,11 @@ func runMigrations(ctx context.Context, db *mongo.Database) error {
 		return fmt.Errorf("failed to create client repository: %v", err)
 	}
 
+	clientKeyRepo, err := repository.NewClientKeyRepository(db)
+	if err != nil {
+		return fmt.Errorf("failed to create client key repository: %v", err)
+	}
+
 	chatRepo, err := repository.NewChatRepository(db)
 	if err != nil {
 		return fmt.Errorf("failed to create chat repository: %v", err)
@@ -76,9 +91,36 @@ func runMigrations(ctx context.Context, db *mongo.Database) error {
 		return fmt.Errorf("failed to create chatroom repository: %v", err)
 	}
 
-	// Create sample users
+	// Create sample client
+	client := &entity.Client{
+		Name:          "Test Client",
+		Description:   "Test client for development",
+		Status:        "active",
+		AuthEndpoint:  "http://localhost:8080/auth",
+		WebhookSecret: "test_webhook_secret",
+	}
+
+	log.Println("Creating sample client...")
+	if err := clientRepo.Create(ctx, client); err != nil {
+		return fmt.Errorf("failed to create client: %v", err)
+	}
+
+	// Keys are stored hashed, the sample key is test_client_key
+	clientKey := &entity.ClientKey{
+		ClientID: client.ID,
+		Name:     "Default",
+		Hash:     entity.HashClientKey("test_client_key"),
+		Prefix:   entity.ClientKeyPrefix("test_client_key"),
+		Scopes:   entity.ClientKeyScopes,
+	}
+	if err := clientKeyRepo.Create(ctx, clientKey); err != nil {
+		return fmt.Errorf("failed to create client key: %v", err)
+	}
+
+	// Create sample users, they all belong to the sample client
 	users := []*entity.User{
 		{
+			ClientID: client.ID,
 			UserID:   "user1",
 			Name:     "John Doe",
 			Username: "johndoe",
@@ -86,6 +128,7 @@ func runMigrations(ctx context.Context, db *mongo.Database) error {
 			Level:    1,
 		},
 		{
+			ClientID: client.ID,
 			UserID:   "user2",
 			Name:     "Jane Smith",
 			Username: "janesmith",
@@ -93,6 +136,7 @@ func runMigrations(ctx context.Context, db *mongo.Database) error {
 			Level:    2,
 		},
 		{
+			ClientID: client.ID,
 			UserID:   "user3",
 			Name:     "Admin User",
 			Username: "admin",
@@ -108,23 +152,10 @@ func runMigrations(ctx context.Context, db *mongo.Database) error {
 		}
 	}
 
-	// Create sample client
-	client := &entity.Client{
-		Name:         "Test Client",
-		Description:  "Test client for development",
-		ClientKey:    "test_client_key",
-		Status:       "active",
-		AuthEndpoint: "http://localhost:8080/auth",
-	}
-
-	log.Println("Creating sample client...")
-	if err := clientRepo.Create(ctx, client); err != nil {
-		return fmt.Errorf("failed to create client: %v", err)
-	}
-
 	// Create sample chatroom
 	now := time.Now().UnixMilli()
 	chatroom := &entity.Chatroom{
+		ClientID:         client.ID,
 		Name:             "General Chat",
 		IsGroup:          true,
 		Type:             entity.ChatroomTypePublic,
@@ -161,9 +192,11 @@ func runMigrations(ctx context.Context, db *mongo.Database) error {
 		"Welcome to the general chat!",
 	}
 
+	// Creating messages also updates the chatroom's last message and message count
 	log.Println("Creating sample chat messages...")
 	for i, msg := range messages {
 		chat := &entity.Chat{
+			ClientID:         client.ID,
 			Message:          msg,
 			Sender:           users[i].ID,
 			Chatroom:         chatroom.ID,
@@ -174,16 +207,6 @@ func runMigrations(ctx context.Context, db *mongo.Database) error {
 		}
 	}
 
-	// Update chatroom with last message
-	chatroom.LastMessage = &messages[len(messages)-1]
-	chatroom.LastSender = &users[len(messages)-1].ID
-	chatroom.LastMessageTimestamp = &now
-	chatroom.MessagesCount = len(messages)
-
-	if err := chatroomRepo.Update(ctx, chatroom); err != nil {
-		return fmt.Errorf("failed to update chatroom: %v", err)
-	}
-
 	log.Printf("Migration completed in %v\n", time.Since(start))
 	return nil
 }
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"app/pkg/chat/config"
+	repository "app/pkg/chat/repository/mongodb"
+	"app/pkg/database/mongodb"
+	"context"
+	"flag"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/ilyakaznacheev/cleanenv"
+)
+
+// Recomputes the denormalized chatroom stats (last message, last sender, last message
+// timestamp and message count) from the chats collection
+func main() {
+	var cfg config.ChatConfig
+	var configPath = flag.String("config", filepath.Join("cmd", "chat", "config", "config.yml"), "path to config file")
+	var chatroomID = flag.String("room", "", "only repair the chatroom with this ID")
+
+	flag.Parse()
+
+	// Load configuration
+	if err := cleanenv.ReadConfig(*configPath, &cfg); err != nil {
+		if os.IsNotExist(err) {
+			if err := cleanenv.ReadEnv(&cfg); err != nil {
+				log.Fatalf("error reading environment variables: %v", err)
+			}
+		}
+		log.Fatalf("error reading config file: %v", err)
+	}
+
+	// Initialize MongoDB connection
+	mongoClient := mongodb.NewClient(&cfg.MongoDB)
+	if err := mongoClient.Connect(); err != nil {
+		log.Fatalf("Failed to connect to MongoDB: %v", err)
+	}
+	defer mongoClient.Disconnect()
+
+	db := mongoClient.Database(cfg.MongoDB.Database)
+	ctx := context.Background()
+
+	chatroomRepo, err := repository.NewChatroomRepository(db)
+	if err != nil {
+		log.Fatalf("Failed to create chatroom repository: %v", err)
+	}
+
+	if *chatroomID != "" {
+		log.Printf("Recomputing stats of chatroom %s...\n", *chatroomID)
+	} else {
+		log.Println("Recomputing stats of all chatrooms...")
+	}
+	start := time.Now()
+
+	if err := chatroomRepo.RecomputeStats(ctx, *chatroomID); err != nil {
+		log.Fatalf("Failed to recompute chatroom stats: %v", err)
+	}
+
+	log.Printf("Chatroom stats repaired in %v\n", time.Since(start))
+}
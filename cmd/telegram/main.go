@@ -9,7 +9,10 @@ import (
 	repository "app/pkg/telegram/repository/mongodb"
 	"app/pkg/telegram/service/bot"
 	"app/pkg/telegram/service/client"
+	"app/pkg/telegram/service/link"
 	"app/pkg/telegram/service/payment"
+	"app/pkg/telegram/service/user"
+	botTransport "app/pkg/telegram/transport/bot"
 	httpHandler "app/pkg/telegram/transport/http/handler"
 	httpMiddleware "app/pkg/telegram/transport/http/middleware"
 	"app/pkg/types/pagination"
@@ -57,11 +60,22 @@ func main() {
 	if err != nil {
 		log.Fatalf("Failed to create client repository: %v", err)
 	}
+	userRepo, err := repository.NewUserRepository(db)
+	if err != nil {
+		log.Fatalf("Failed to create user repository: %v", err)
+	}
 
 	// Create services
 	clientService := client.NewClientService(clientRepo)
+	userService := user.NewUserService(userRepo, clientRepo)
 	botService := bot.NewBotService()
 
+	// Link Telegram chats to chat service users who send their link code to a bot
+	if cfg.Chat.URL != "" && cfg.Chat.APIKey != "" {
+		linkHandler := botTransport.NewLinkHandler(link.NewLinkService(cfg.Chat.URL, cfg.Chat.APIKey, userService))
+		botService.OnStart(linkHandler.HandleStart)
+	}
+
 	// Create and start the payment worker
 	var paymentWorker *payment.PaymentWorker
 	ctx := context.Background()
@@ -72,7 +86,7 @@ func main() {
 	errorHandler := sharedMiddleware.NewErrorMiddleware()
 
 	// Create handlers
-	botHandler := httpHandler.NewBotHandler(botService, clientService)
+	botHandler := httpHandler.NewBotHandler(botService, clientService, userService, keyMiddleware)
 	webhookHandler := httpHandler.NewWebhookHandler(botService, clientService)
 	clientHandler := httpHandler.NewClientHandler(clientService, keyMiddleware, clientMiddleware)
 
@@ -4,19 +4,79 @@ import (
 	"app/pkg/config"
 	"app/pkg/database"
 	"app/pkg/fiber"
+	"time"
 )
 
 // AppConfig holds application specific configuration
 type AppConfig struct {
-	APIKey string `yaml:"api_key" env:"API_KEY"`
+	APIKey       string `yaml:"api_key" env:"API_KEY"`
+	InviteSecret string `yaml:"invite_secret" env:"INVITE_SECRET"` // Key used to sign squad invite codes, invites are disabled without it
+}
+
+// StorageConfig holds attachment storage configuration
+// Attachments are served through authorized endpoints, so the path shouldn't be the server's public upload path
+type StorageConfig struct {
+	Path string `yaml:"path" env:"PATH" env-default:"storage/chat"`
+}
+
+// NotificationConfig holds offline notification configuration
+// Telegram notifications are disabled unless the telegram service URL, API key and bot are set
+type NotificationConfig struct {
+	TelegramURL    string `yaml:"telegram_url" env:"TELEGRAM_URL"`         // Telegram service API root, e.g. http://telegram:8080/api
+	TelegramAPIKey string `yaml:"telegram_api_key" env:"TELEGRAM_API_KEY"` // Admin API key of the telegram service
+	TelegramBotID  string `yaml:"telegram_bot_id" env:"TELEGRAM_BOT_ID"`   // Client ID of the bot sending notifications
+}
+
+// WebSocketConfig holds WebSocket heartbeat and backpressure configuration
+type WebSocketConfig struct {
+	PingInterval       time.Duration `yaml:"ping_interval" env:"PING_INTERVAL" env-default:"30s"`
+	PongTimeout        time.Duration `yaml:"pong_timeout" env:"PONG_TIMEOUT" env-default:"60s"` // Must exceed the ping interval
+	WriteTimeout       time.Duration `yaml:"write_timeout" env:"WRITE_TIMEOUT" env-default:"10s"`
+	MaxMessageSize     int64         `yaml:"max_message_size" env:"MAX_MESSAGE_SIZE" env-default:"65536"` // In bytes
+	SendQueueSize      int           `yaml:"send_queue_size" env:"SEND_QUEUE_SIZE" env-default:"256"`
+	SlowConsumerPolicy string        `yaml:"slow_consumer_policy" env:"SLOW_CONSUMER_POLICY" env-default:"disconnect"` // drop or disconnect
+}
+
+// LimitConfig holds a token bucket limit, a zero rate or period disables it
+type LimitConfig struct {
+	Rate   int           `yaml:"rate" env:"RATE"`
+	Period time.Duration `yaml:"period" env:"PERIOD"`
+	Burst  int           `yaml:"burst" env:"BURST"` // Defaults to the rate
+}
+
+// RateLimitConfig holds message rate limit configuration
+type RateLimitConfig struct {
+	User     LimitConfig `yaml:"user" env-prefix:"USER_"`         // Per sender across all chatrooms
+	Chatroom LimitConfig `yaml:"chatroom" env-prefix:"CHATROOM_"` // Per chatroom across all senders
+	Client   LimitConfig `yaml:"client" env-prefix:"CLIENT_"`     // Per client across all its users
+}
+
+// ModerationConfig holds message moderation configuration applied to every chatroom
+// Chatrooms can block more words and domains and override the repeat limit through their moderation settings
+type ModerationConfig struct {
+	BlockedWords   []string      `yaml:"blocked_words" env:"BLOCKED_WORDS" env-separator:","`
+	BlockedDomains []string      `yaml:"blocked_domains" env:"BLOCKED_DOMAINS" env-separator:","`
+	RepeatLimit    int           `yaml:"repeat_limit" env:"REPEAT_LIMIT" env-default:"5"` // Identical messages a user can send per window, 0 disables the check
+	RepeatWindow   time.Duration `yaml:"repeat_window" env:"REPEAT_WINDOW" env-default:"1m"`
+}
+
+// GDPRConfig holds user data export and erasure configuration
+type GDPRConfig struct {
+	ExportRetention time.Duration `yaml:"export_retention" env:"EXPORT_RETENTION" env-default:"168h"` // How long export files can be downloaded
 }
 
 // ChatConfig holds chat service specific configuration
 type ChatConfig struct {
-	Server  fiber.ServerConfig      `yaml:"server" env-prefix:"SERVER_"`
-	MongoDB database.DatabaseConfig `yaml:"mongodb" env-prefix:"MONGODB_"`
-	Redis   database.DatabaseConfig `yaml:"redis" env-prefix:"REDIS_"`
-	App     AppConfig               `yaml:"app" env-prefix:"APP_"`
+	Server       fiber.ServerConfig      `yaml:"server" env-prefix:"SERVER_"`
+	MongoDB      database.DatabaseConfig `yaml:"mongodb" env-prefix:"MONGODB_"`
+	Redis        database.DatabaseConfig `yaml:"redis" env-prefix:"REDIS_"`
+	App          AppConfig               `yaml:"app" env-prefix:"APP_"`
+	Storage      StorageConfig           `yaml:"storage" env-prefix:"STORAGE_"`
+	Notification NotificationConfig      `yaml:"notification" env-prefix:"NOTIFICATION_"`
+	WebSocket    WebSocketConfig         `yaml:"websocket" env-prefix:"WS_"`
+	RateLimit    RateLimitConfig         `yaml:"rate_limit" env-prefix:"RATE_LIMIT_"`
+	Moderation   ModerationConfig        `yaml:"moderation" env-prefix:"MODERATION_"`
+	GDPR         GDPRConfig              `yaml:"gdpr" env-prefix:"GDPR_"`
 }
 
 // Load loads chat service configuration
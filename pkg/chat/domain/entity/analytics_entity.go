@@ -0,0 +1,39 @@
+package entity
+
+// ActivitySummary represents the chat activity over a time range
+type ActivitySummary struct {
+	Messages  int64 `bson:"messages" json:"messages"`
+	Senders   int64 `bson:"senders" json:"senders"`     // Distinct users who sent a message
+	Chatrooms int64 `bson:"chatrooms" json:"chatrooms"` // Distinct chatrooms a message was sent to
+}
+
+// DailyActivity represents the chat activity of a day
+type DailyActivity struct {
+	Date     string `bson:"_id" json:"date"` // YYYY-MM-DD
+	Messages int64  `bson:"messages" json:"messages"`
+	Senders  int64  `bson:"senders" json:"senders"` // Daily active users
+}
+
+// MonthlyActivity represents the chat activity of a month
+type MonthlyActivity struct {
+	Month    string `bson:"_id" json:"month"` // YYYY-MM
+	Messages int64  `bson:"messages" json:"messages"`
+	Senders  int64  `bson:"senders" json:"senders"` // Monthly active users
+}
+
+// ChatroomActivity represents the chat activity of a chatroom
+type ChatroomActivity struct {
+	Chatroom string       `bson:"_id" json:"chatroom"` // Reference to Chatrooms collection
+	Name     string       `bson:"name" json:"name"`
+	Type     ChatroomType `bson:"type" json:"type"`
+	IsGroup  bool         `bson:"isGroup" json:"isGroup"`
+	Messages int64        `bson:"messages" json:"messages"`
+	Senders  int64        `bson:"senders" json:"senders"`
+}
+
+// ResponseTime represents how long users take to answer direct messages
+// A response is a direct message following a message of the other participant
+type ResponseTime struct {
+	Responses     int64 `bson:"responses" json:"responses"`
+	AverageMillis int64 `bson:"average" json:"averageMillis"`
+}
@@ -0,0 +1,23 @@
+package entity
+
+// AttachmentKind represents how an attachment should be displayed
+type AttachmentKind string
+
+const (
+	AttachmentKindImage AttachmentKind = "image"
+	AttachmentKindFile  AttachmentKind = "file"
+)
+
+// Attachment represents a file uploaded to a chatroom and attached to a chat message
+type Attachment struct {
+	ID               string         `bson:"_id,omitempty" json:"id,omitempty"`
+	Name             string         `bson:"name" json:"name"`
+	Key              string         `bson:"key" json:"-"` // Storage key of the file content
+	ContentType      string         `bson:"contentType" json:"contentType"`
+	Kind             AttachmentKind `bson:"kind" json:"kind"`
+	Size             int64          `bson:"size" json:"size"`
+	Chatroom         string         `bson:"chatroom" json:"chatroom"`             // Reference to Chatrooms collection
+	Uploader         string         `bson:"uploader" json:"uploader"`             // Reference to Users collection
+	Chat             *string        `bson:"chat,omitempty" json:"chat,omitempty"` // Reference to Chats collection, null until sent
+	CreatedTimestamp int64          `bson:"createdTimestamp" json:"createdTimestamp"`
+}
@@ -2,22 +2,60 @@ package entity
 
 // Chat represents a chat message
 type Chat struct {
-	ID               string  `bson:"_id,omitempty" json:"id,omitempty"`
-	Message          string  `bson:"message" json:"message"`
-	Sender           string  `bson:"sender" json:"sender"`     // Reference to Users collection
-	Receiver         *string `bson:"receiver" json:"receiver"` // Null for group chats
-	Chatroom         string  `bson:"chatroom" json:"chatroom"` // Can be string ID or Chatroom object
-	CreatedTimestamp int64   `bson:"createdTimestamp" json:"createdTimestamp"`
-	Premium          *bool   `bson:"premium,omitempty" json:"premium,omitempty"`
+	ID               string                 `bson:"_id,omitempty" json:"id,omitempty"`
+	ClientID         string                 `bson:"clientId" json:"clientId"` // Client of the message's chatroom
+	Message          string                 `bson:"message" json:"message"`
+	Sender           string                 `bson:"sender" json:"sender"`     // Reference to Users collection
+	Receiver         *string                `bson:"receiver" json:"receiver"` // Null for group chats
+	Chatroom         string                 `bson:"chatroom" json:"chatroom"` // Can be string ID or Chatroom object
+	CreatedTimestamp int64                  `bson:"createdTimestamp" json:"createdTimestamp"`
+	Premium          *bool                  `bson:"premium,omitempty" json:"premium,omitempty"`
+	ReplyTo          *string                `bson:"replyTo,omitempty" json:"replyTo,omitempty"` // Reference to the parent Chat of a thread
+	Metadata         map[string]interface{} `bson:"metadata,omitempty" json:"metadata,omitempty"`
+	Attachments      []Attachment           `bson:"attachments,omitempty" json:"attachments,omitempty"`
+	Reactions        []ChatReaction         `bson:"reactions,omitempty" json:"reactions,omitempty"`
+	EditedTimestamp  *int64                 `bson:"editedTimestamp,omitempty" json:"editedTimestamp,omitempty"`
+	EditHistory      []ChatEdit             `bson:"editHistory,omitempty" json:"editHistory,omitempty"`           // Previous versions, oldest first
+	DeletedTimestamp *int64                 `bson:"deletedTimestamp,omitempty" json:"deletedTimestamp,omitempty"` // Set when the message is a tombstone
+	DeletedBy        *string                `bson:"deletedBy,omitempty" json:"deletedBy,omitempty"`               // Reference to Users collection
+	Sequence         int64                  `bson:"sequence" json:"sequence"`                                     // Position of the message in its chatroom
+	UpdatedSequence  int64                  `bson:"updatedSequence" json:"updatedSequence"`                       // Chatroom sequence of the latest change to the message
+	ClientMessageID  string                 `bson:"clientMessageId,omitempty" json:"clientMessageId,omitempty"`   // Sender generated ID making retried sends idempotent
+}
+
+// ChatEdit represents a previous version of an edited chat message
+type ChatEdit struct {
+	Message         string `bson:"message" json:"message"`
+	EditedBy        string `bson:"editedBy" json:"editedBy"` // Reference to Users collection
+	EditedTimestamp int64  `bson:"editedTimestamp" json:"editedTimestamp"`
+}
+
+// ChatReaction represents an emoji reaction of a user to a chat message
+type ChatReaction struct {
+	Emoji            string `bson:"emoji" json:"emoji"`
+	User             string `bson:"user" json:"user"` // Reference to Users collection
+	CreatedTimestamp int64  `bson:"createdTimestamp" json:"createdTimestamp"`
 }
 
 // ChatPopulated represents a chat message with populated user references
 type ChatPopulated struct {
-	ID               string `bson:"_id,omitempty" json:"id,omitempty"`
-	Message          string `bson:"message" json:"message"`
-	Sender           User   `bson:"sender" json:"sender"`     // Populated User object
-	Receiver         *User  `bson:"receiver" json:"receiver"` // Populated User object, null for group chats
-	Chatroom         string `bson:"chatroom" json:"chatroom"` // Can be string ID or Chatroom object
-	CreatedTimestamp int64  `bson:"createdTimestamp" json:"createdTimestamp"`
-	Premium          *bool  `bson:"premium,omitempty" json:"premium,omitempty"`
+	ID               string                 `bson:"_id,omitempty" json:"id,omitempty"`
+	ClientID         string                 `bson:"clientId" json:"clientId"`
+	Message          string                 `bson:"message" json:"message"`
+	Sender           User                   `bson:"sender" json:"sender"`     // Populated User object
+	Receiver         *User                  `bson:"receiver" json:"receiver"` // Populated User object, null for group chats
+	Chatroom         string                 `bson:"chatroom" json:"chatroom"` // Can be string ID or Chatroom object
+	CreatedTimestamp int64                  `bson:"createdTimestamp" json:"createdTimestamp"`
+	Premium          *bool                  `bson:"premium,omitempty" json:"premium,omitempty"`
+	ReplyTo          *string                `bson:"replyTo,omitempty" json:"replyTo,omitempty"`
+	Metadata         map[string]interface{} `bson:"metadata,omitempty" json:"metadata,omitempty"`
+	Attachments      []Attachment           `bson:"attachments,omitempty" json:"attachments,omitempty"`
+	Reactions        []ChatReaction         `bson:"reactions,omitempty" json:"reactions,omitempty"`
+	EditedTimestamp  *int64                 `bson:"editedTimestamp,omitempty" json:"editedTimestamp,omitempty"`
+	EditHistory      []ChatEdit             `bson:"editHistory,omitempty" json:"editHistory,omitempty"`
+	DeletedTimestamp *int64                 `bson:"deletedTimestamp,omitempty" json:"deletedTimestamp,omitempty"`
+	DeletedBy        *string                `bson:"deletedBy,omitempty" json:"deletedBy,omitempty"`
+	Sequence         int64                  `bson:"sequence" json:"sequence"`
+	UpdatedSequence  int64                  `bson:"updatedSequence" json:"updatedSequence"`
+	ClientMessageID  string                 `bson:"clientMessageId,omitempty" json:"clientMessageId,omitempty"`
 }
@@ -1,5 +1,10 @@
 package entity
 
+import (
+	"sort"
+	"strings"
+)
+
 // ChatroomType represents the type of chatroom
 type ChatroomType string
 
@@ -9,6 +14,14 @@ const (
 	ChatroomTypeSquad   ChatroomType = "squad"
 )
 
+// JoinPolicy represents how users join a squad chatroom through an invite
+type JoinPolicy string
+
+const (
+	JoinPolicyOpen     JoinPolicy = "open"     // Invited users join immediately
+	JoinPolicyApproval JoinPolicy = "approval" // Invited users join once an admin approves their request
+)
+
 // ParticipantRole represents the role of a participant in a chatroom
 type ParticipantRole string
 
@@ -21,6 +34,7 @@ const (
 // Chatroom represents a chatroom for group or direct conversations
 type Chatroom struct {
 	ID                   string                `bson:"_id,omitempty" json:"id,omitempty"`
+	ClientID             string                `bson:"clientId" json:"clientId"` // Client the chatroom belongs to
 	Name                 string                `bson:"name" json:"name"`
 	IsGroup              bool                  `bson:"isGroup" json:"isGroup"`
 	Type                 ChatroomType          `bson:"type" json:"type"`
@@ -30,6 +44,29 @@ type Chatroom struct {
 	LastMessageTimestamp *int64                `bson:"lastMessageTimestamp" json:"lastMessageTimestamp"`
 	MessagesCount        int                   `bson:"messagesCount" json:"messagesCount"`
 	Participants         []ChatroomParticipant `bson:"participants" json:"participants"`
+	JoinPolicy           JoinPolicy            `bson:"joinPolicy,omitempty" json:"joinPolicy,omitempty"`           // Squads only, defaults to open
+	MaxParticipants      int                   `bson:"maxParticipants,omitempty" json:"maxParticipants,omitempty"` // 0 means unlimited
+	DirectKey            string                `bson:"directKey,omitempty" json:"directKey,omitempty"`             // Direct chatrooms only, see DirectChatroomKey
+}
+
+// DirectChatroomKey returns the key identifying the direct chatroom between two users, whichever of them opens it
+func DirectChatroomKey(userID string, otherUserID string) string {
+	users := []string{userID, otherUserID}
+	sort.Strings(users)
+	return strings.Join(users, ":")
+}
+
+// ChatroomListing represents a chatroom as shown in public discovery, without its participant list
+type ChatroomListing struct {
+	ID                   string       `bson:"_id,omitempty" json:"id,omitempty"`
+	Name                 string       `bson:"name" json:"name"`
+	Type                 ChatroomType `bson:"type" json:"type"`
+	CreatedTimestamp     int64        `bson:"createdTimestamp" json:"createdTimestamp"`
+	LastMessageTimestamp *int64       `bson:"lastMessageTimestamp" json:"lastMessageTimestamp"`
+	MessagesCount        int          `bson:"messagesCount" json:"messagesCount"`
+	ParticipantsCount    int          `bson:"participantsCount" json:"participantsCount"`
+	MaxParticipants      int          `bson:"maxParticipants,omitempty" json:"maxParticipants,omitempty"`
+	Joined               bool         `bson:"joined" json:"joined"` // Whether the requesting user is a participant
 }
 
 // ChatroomParticipant represents a participant in a chatroom
@@ -53,6 +90,7 @@ type ChatroomParticipantPopulated struct {
 // ChatroomPopulated represents a chatroom with populated user references
 type ChatroomPopulated struct {
 	ID                   string                         `bson:"_id,omitempty" json:"id,omitempty"`
+	ClientID             string                         `bson:"clientId" json:"clientId"`
 	Name                 string                         `bson:"name" json:"name"`
 	IsGroup              bool                           `bson:"isGroup" json:"isGroup"`
 	Type                 ChatroomType                   `bson:"type" json:"type"`
@@ -62,4 +100,8 @@ type ChatroomPopulated struct {
 	LastMessageTimestamp *int64                         `bson:"lastMessageTimestamp" json:"lastMessageTimestamp"`
 	MessagesCount        int                            `bson:"messagesCount" json:"messagesCount"`
 	Participants         []ChatroomParticipantPopulated `bson:"participants" json:"participants"` // Array of populated participants
+	JoinPolicy           JoinPolicy                     `bson:"joinPolicy,omitempty" json:"joinPolicy,omitempty"`
+	MaxParticipants      int                            `bson:"maxParticipants,omitempty" json:"maxParticipants,omitempty"`
+	DirectKey            string                         `bson:"directKey,omitempty" json:"directKey,omitempty"`
+	UnreadCount          int64                          `bson:"-" json:"unreadCount"` // Unread messages for the requesting user
 }
@@ -0,0 +1,37 @@
+package entity
+
+import "testing"
+
+func TestDirectChatroomKeyIgnoresWhoOpensTheChatroom(t *testing.T) {
+	tests := []struct {
+		userID      string
+		otherUserID string
+		want        string
+	}{
+		{"alice", "bob", "alice:bob"},
+		{"bob", "alice", "alice:bob"},
+		{"6650a1b2c3d4e5f607182930", "6650a1b2c3d4e5f607182931", "6650a1b2c3d4e5f607182930:6650a1b2c3d4e5f607182931"},
+		{"6650a1b2c3d4e5f607182931", "6650a1b2c3d4e5f607182930", "6650a1b2c3d4e5f607182930:6650a1b2c3d4e5f607182931"},
+		{"B", "a", "B:a"}, // Ordered bytewise, IDs are case sensitive
+		{"alice", "alice", "alice:alice"},
+	}
+
+	for _, tt := range tests {
+		if got := DirectChatroomKey(tt.userID, tt.otherUserID); got != tt.want {
+			t.Errorf("DirectChatroomKey(%q, %q) = %q, want %q", tt.userID, tt.otherUserID, got, tt.want)
+		}
+	}
+}
+
+func TestDirectChatroomKeyDiffersPerPair(t *testing.T) {
+	pairs := [][2]string{{"alice", "bob"}, {"alice", "carol"}, {"bob", "carol"}, {"alice", "alice"}}
+
+	keys := make(map[string][2]string)
+	for _, pair := range pairs {
+		key := DirectChatroomKey(pair[0], pair[1])
+		if other, ok := keys[key]; ok {
+			t.Errorf("DirectChatroomKey gives %v and %v the same key %q", pair, other, key)
+		}
+		keys[key] = pair
+	}
+}
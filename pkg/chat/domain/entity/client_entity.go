@@ -1,13 +1,114 @@
 package entity
 
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"slices"
+)
+
 // Client represents an application client in the system
 type Client struct {
-	ID               string `bson:"_id,omitempty" json:"id,omitempty"`
-	Name             string `bson:"name" json:"name"`
-	Description      string `bson:"description" json:"description"`
-	ClientKey        string `bson:"clientKey" json:"clientKey"`
-	Status           string `bson:"status" json:"status"`
-	AuthEndpoint     string `bson:"authEndpoint" json:"authEndpoint"`
-	CreatedTimestamp int64  `bson:"createdTimestamp" json:"createdTimestamp"`
-	UpdatedTimestamp int64  `bson:"updatedTimestamp" json:"updatedTimestamp"`
+	ID               string        `bson:"_id,omitempty" json:"id,omitempty"`
+	Name             string        `bson:"name" json:"name"`
+	Description      string        `bson:"description" json:"description"`
+	LegacyKey        string        `bson:"clientKey,omitempty" json:"-"` // Plaintext key of clients created before keys were hashed, moved to ClientKey by the backfill
+	Status           string        `bson:"status" json:"status"`
+	AuthEndpoint     string        `bson:"authEndpoint" json:"authEndpoint"`
+	Auth             ClientAuth    `bson:"auth" json:"auth"`                                           // How user tokens are verified, defaults to calling AuthEndpoint
+	WebhookEndpoint  string        `bson:"webhookEndpoint,omitempty" json:"webhookEndpoint,omitempty"` // Receives notifications for offline users
+	WebhookSecret    string        `bson:"webhookSecret,omitempty" json:"-"`                           // Signs webhook requests, never returned
+	ErasurePolicy    ErasurePolicy `bson:"erasurePolicy,omitempty" json:"erasurePolicy,omitempty"`     // What happens to an erased user's messages, defaults to ErasurePolicyTombstone
+	CreatedTimestamp int64         `bson:"createdTimestamp" json:"createdTimestamp"`
+	UpdatedTimestamp int64         `bson:"updatedTimestamp" json:"updatedTimestamp"`
+}
+
+// ErasurePolicy represents what happens to the chat messages of an erased user
+type ErasurePolicy string
+
+const (
+	ErasurePolicyTombstone ErasurePolicy = "tombstone" // Messages become tombstones, so replies and threads keep their place
+	ErasurePolicyDelete    ErasurePolicy = "delete"    // Messages are removed
+)
+
+// AuthStrategy represents how a client's user tokens are verified
+type AuthStrategy string
+
+const (
+	AuthStrategyEndpoint      AuthStrategy = "endpoint"      // The token is sent to the client's auth endpoint, which returns the user
+	AuthStrategyHMAC          AuthStrategy = "hmac"          // The token is a JWT signed with a secret shared with the client
+	AuthStrategyPublicKey     AuthStrategy = "public_key"    // The token is a JWT verified with the client's PEM encoded public key
+	AuthStrategyJWKS          AuthStrategy = "jwks"          // The token is a JWT verified with the keys published at the client's JWKS URL
+	AuthStrategyIntrospection AuthStrategy = "introspection" // The token is checked with the client's OAuth 2.0 introspection endpoint
+)
+
+// ClientAuth represents how a client's user tokens are verified
+// JWT strategies map the sub claim to the user ID and the name, username, picture and level claims to the user's profile
+type ClientAuth struct {
+	Strategy              AuthStrategy `bson:"strategy,omitempty" json:"strategy,omitempty"` // Empty means AuthStrategyEndpoint
+	Secret                string       `bson:"secret,omitempty" json:"-"`                    // HMAC secret or introspection client secret, never returned
+	PublicKey             string       `bson:"publicKey,omitempty" json:"publicKey,omitempty"`
+	JWKSURL               string       `bson:"jwksUrl,omitempty" json:"jwksUrl,omitempty"`
+	IntrospectionEndpoint string       `bson:"introspectionEndpoint,omitempty" json:"introspectionEndpoint,omitempty"`
+	IntrospectionClientID string       `bson:"introspectionClientId,omitempty" json:"introspectionClientId,omitempty"` // Sent with Secret as basic auth
+	Issuer                string       `bson:"issuer,omitempty" json:"issuer,omitempty"`                               // Expected iss claim, checked when set
+	Audience              string       `bson:"audience,omitempty" json:"audience,omitempty"`                           // Expected aud claim, checked when set
+}
+
+// ClientKeyScope represents what requests a client key can be used for
+type ClientKeyScope string
+
+const (
+	ClientKeyScopeRead      ClientKeyScope = "read"      // HTTP requests that don't change anything
+	ClientKeyScopeWrite     ClientKeyScope = "write"     // HTTP requests that change something
+	ClientKeyScopeWebSocket ClientKeyScope = "websocket" // WebSocket connections
+)
+
+// ClientKeyScopes lists every scope, keys created without scopes get all of them
+var ClientKeyScopes = []ClientKeyScope{ClientKeyScopeRead, ClientKeyScopeWrite, ClientKeyScopeWebSocket}
+
+// ClientKey represents one of the keys a client authenticates with
+// Only the SHA-256 of the key is stored, the key itself is returned once when it's created
+type ClientKey struct {
+	ID               string           `bson:"_id,omitempty" json:"id,omitempty"`
+	ClientID         string           `bson:"clientId" json:"clientId"`
+	Name             string           `bson:"name" json:"name"`
+	Hash             string           `bson:"hash" json:"-"`
+	Prefix           string           `bson:"prefix" json:"prefix"` // Start of the key, to tell keys apart
+	Scopes           []ClientKeyScope `bson:"scopes" json:"scopes"`
+	ExpiresTimestamp *int64           `bson:"expiresTimestamp,omitempty" json:"expiresTimestamp,omitempty"` // Nil when the key doesn't expire
+	RevokedTimestamp *int64           `bson:"revokedTimestamp,omitempty" json:"revokedTimestamp,omitempty"`
+	CreatedTimestamp int64            `bson:"createdTimestamp" json:"createdTimestamp"`
+}
+
+// HashClientKey returns the hex encoded SHA-256 of a client key, keys are random so a plain hash is enough
+func HashClientKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// NewWebhookSecret generates a random secret with 256 bits of entropy to sign a client's webhook requests with
+func NewWebhookSecret() string {
+	b := make([]byte, 32)
+	rand.Read(b)
+	return "whsec_" + base64.RawURLEncoding.EncodeToString(b)
+}
+
+// ClientKeyPrefix returns the start of a key stored to tell keys apart, never more than half of it
+func ClientKeyPrefix(key string) string {
+	return key[:min(len(key)/2, 10)]
+}
+
+// Active checks whether the key can be used at the time, in milliseconds
+func (k *ClientKey) Active(now int64) bool {
+	if k.RevokedTimestamp != nil {
+		return false
+	}
+	return k.ExpiresTimestamp == nil || *k.ExpiresTimestamp > now
+}
+
+// Allows checks whether the key has the scope, an empty scope is allowed by every key
+func (k *ClientKey) Allows(scope ClientKeyScope) bool {
+	return scope == "" || slices.Contains(k.Scopes, scope)
 }
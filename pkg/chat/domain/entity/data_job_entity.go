@@ -0,0 +1,41 @@
+package entity
+
+// DataJobType represents what a data job does with a user's data
+type DataJobType string
+
+const (
+	DataJobTypeExport  DataJobType = "export"  // Writes the user's chat messages and chatroom memberships to a JSON Lines file
+	DataJobTypeErasure DataJobType = "erasure" // Anonymizes the user and removes their chat messages following the client's erasure policy
+)
+
+// DataJobStatus represents the progress of a data job
+type DataJobStatus string
+
+const (
+	DataJobStatusPending   DataJobStatus = "pending"
+	DataJobStatusRunning   DataJobStatus = "running"
+	DataJobStatusCompleted DataJobStatus = "completed"
+	DataJobStatusFailed    DataJobStatus = "failed"
+	DataJobStatusExpired   DataJobStatus = "expired" // Exports whose file was removed
+)
+
+// DataJob represents a background export or erasure of a user's data
+type DataJob struct {
+	ID                 string        `bson:"_id,omitempty" json:"id,omitempty"`
+	ClientID           string        `bson:"clientId" json:"clientId"` // Client of the user
+	UserID             string        `bson:"userId" json:"userId"`     // Reference to Users collection
+	Type               DataJobType   `bson:"type" json:"type"`
+	Status             DataJobStatus `bson:"status" json:"status"`
+	RequestedBy        string        `bson:"requestedBy" json:"requestedBy"` // The user's ID, or admin
+	Attempts           int           `bson:"attempts" json:"attempts"`
+	Error              string        `bson:"error,omitempty" json:"error,omitempty"`
+	ErasurePolicy      ErasurePolicy `bson:"erasurePolicy,omitempty" json:"erasurePolicy,omitempty"` // Erasures only, the client's policy when the job ran
+	FileKey            string        `bson:"fileKey,omitempty" json:"-"`                             // Exports only, storage key of the file
+	Size               int64         `bson:"size,omitempty" json:"size,omitempty"`                   // Exports only, size of the file in bytes
+	Chatrooms          int64         `bson:"chatrooms" json:"chatrooms"`                             // Chatroom memberships exported
+	Messages           int64         `bson:"messages" json:"messages"`                               // Chat messages exported or erased
+	CreatedTimestamp   int64         `bson:"createdTimestamp" json:"createdTimestamp"`
+	StartedTimestamp   *int64        `bson:"startedTimestamp,omitempty" json:"startedTimestamp,omitempty"`
+	CompletedTimestamp *int64        `bson:"completedTimestamp,omitempty" json:"completedTimestamp,omitempty"`
+	ExpiresTimestamp   *int64        `bson:"expiresTimestamp,omitempty" json:"expiresTimestamp,omitempty"` // Completed exports only, when the file is removed
+}
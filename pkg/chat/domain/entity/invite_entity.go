@@ -0,0 +1,14 @@
+package entity
+
+// ChatroomInvite represents an invite link to a squad chatroom
+type ChatroomInvite struct {
+	ID               string `bson:"_id,omitempty" json:"id,omitempty"`
+	Chatroom         string `bson:"chatroom" json:"chatroom"` // Reference to Chatrooms collection
+	Code             string `bson:"code" json:"code"`         // Signed code shared with invitees
+	CreatedBy        string `bson:"createdBy" json:"createdBy"`
+	MaxUses          int    `bson:"maxUses" json:"maxUses"` // 0 means unlimited
+	Uses             int    `bson:"uses" json:"uses"`
+	ExpiresTimestamp int64  `bson:"expiresTimestamp" json:"expiresTimestamp"`
+	RevokedTimestamp *int64 `bson:"revokedTimestamp,omitempty" json:"revokedTimestamp,omitempty"`
+	CreatedTimestamp int64  `bson:"createdTimestamp" json:"createdTimestamp"`
+}
@@ -0,0 +1,23 @@
+package entity
+
+// JoinRequestStatus represents the review state of a join request
+type JoinRequestStatus string
+
+const (
+	JoinRequestStatusPending  JoinRequestStatus = "pending"
+	JoinRequestStatusApproved JoinRequestStatus = "approved"
+	JoinRequestStatusRejected JoinRequestStatus = "rejected"
+)
+
+// JoinRequest represents a pending request of a user to join a squad chatroom
+type JoinRequest struct {
+	ID                string            `bson:"_id,omitempty" json:"id,omitempty"`
+	Chatroom          string            `bson:"chatroom" json:"chatroom"` // Reference to Chatrooms collection
+	User              string            `bson:"user" json:"user"`         // Reference to Users collection
+	Invite            string            `bson:"invite" json:"invite"`     // Reference to the redeemed ChatroomInvite
+	Message           string            `bson:"message,omitempty" json:"message,omitempty"`
+	Status            JoinRequestStatus `bson:"status" json:"status"`
+	ReviewedBy        *string           `bson:"reviewedBy,omitempty" json:"reviewedBy,omitempty"`
+	ReviewedTimestamp *int64            `bson:"reviewedTimestamp,omitempty" json:"reviewedTimestamp,omitempty"`
+	CreatedTimestamp  int64             `bson:"createdTimestamp" json:"createdTimestamp"`
+}
@@ -0,0 +1,39 @@
+package entity
+
+// ModerationAction represents what moderation does with a message
+type ModerationAction string
+
+const (
+	ModerationActionAllow  ModerationAction = "allow"  // The message is stored as sent
+	ModerationActionMask   ModerationAction = "mask"   // The offending parts are hidden before the message is stored
+	ModerationActionReject ModerationAction = "reject" // The message isn't stored
+)
+
+// ModerationSettings represents the moderation configuration of a chatroom
+// They add to the service wide word and domain lists, which always apply
+type ModerationSettings struct {
+	ID               string           `bson:"_id" json:"chatroomId"` // Chatroom ID
+	Disabled         bool             `bson:"disabled" json:"disabled"`
+	BlockedWords     []string         `bson:"blockedWords" json:"blockedWords"`
+	WordAction       ModerationAction `bson:"wordAction" json:"wordAction"`         // mask or reject, defaults to mask
+	AllowedDomains   []string         `bson:"allowedDomains" json:"allowedDomains"` // When set, links to any other domain are filtered
+	BlockedDomains   []string         `bson:"blockedDomains" json:"blockedDomains"`
+	LinkAction       ModerationAction `bson:"linkAction" json:"linkAction"`     // mask or reject, defaults to reject
+	RepeatLimit      int              `bson:"repeatLimit" json:"repeatLimit"`   // Identical messages a user can send per window, 0 uses the service default
+	RepeatWindow     int64            `bson:"repeatWindow" json:"repeatWindow"` // In seconds, 0 uses the service default
+	UpdatedTimestamp int64            `bson:"updatedTimestamp" json:"updatedTimestamp"`
+}
+
+// ModerationDecision represents a message that was masked or rejected by moderation
+type ModerationDecision struct {
+	ID               string           `bson:"_id,omitempty" json:"id,omitempty"`
+	ClientID         string           `bson:"clientId,omitempty" json:"clientId,omitempty"` // Client the message was sent through
+	Chatroom         string           `bson:"chatroom" json:"chatroom"`                     // Reference to Chatrooms collection
+	Sender           string           `bson:"sender" json:"sender"`                         // Reference to Users collection
+	Stage            string           `bson:"stage" json:"stage"`                           // Stage that made the decision
+	Action           ModerationAction `bson:"action" json:"action"`
+	Reason           string           `bson:"reason" json:"reason"`
+	Message          string           `bson:"message" json:"message"`                                       // Message as sent
+	ModeratedMessage string           `bson:"moderatedMessage,omitempty" json:"moderatedMessage,omitempty"` // Message as stored when masked
+	CreatedTimestamp int64            `bson:"createdTimestamp" json:"createdTimestamp"`
+}
@@ -0,0 +1,42 @@
+package entity
+
+// NotificationChannel represents a way of delivering notifications to offline users
+type NotificationChannel string
+
+const (
+	NotificationChannelWebhook  NotificationChannel = "webhook"  // HTTP request to the user's client
+	NotificationChannelTelegram NotificationChannel = "telegram" // Telegram bot message
+)
+
+// NotificationPreference represents a user's choices about offline notifications
+// A user without stored preferences receives notifications on every channel
+type NotificationPreference struct {
+	ID               string                `bson:"_id" json:"id"` // Same as the user ID
+	Muted            bool                  `bson:"muted" json:"muted"`
+	MutedChatrooms   []string              `bson:"mutedChatrooms" json:"mutedChatrooms"`
+	DisabledChannels []NotificationChannel `bson:"disabledChannels" json:"disabledChannels"`
+	TelegramChatID   int64                 `bson:"telegramChatId,omitempty" json:"telegramChatId,omitempty"`
+	UpdatedTimestamp int64                 `bson:"updatedTimestamp" json:"updatedTimestamp"`
+}
+
+// TelegramLink represents a one-time code linking a Telegram chat to a user's notifications
+// The user sends it to the notification bot as /start <code>, the bot's chat is linked once the bot reports it
+type TelegramLink struct {
+	Code             string `json:"code"`
+	ExpiresTimestamp int64  `json:"expiresTimestamp"`
+}
+
+// Notification represents a message sent to a user while they weren't connected
+type Notification struct {
+	ID               string `json:"id"`
+	ClientID         string `json:"clientId"`
+	Recipient        string `json:"recipient"`
+	Chatroom         string `json:"chatroom"`
+	ChatroomName     string `json:"chatroomName"`
+	IsGroup          bool   `json:"isGroup"`
+	Chat             string `json:"chat"`
+	Sender           string `json:"sender"`
+	SenderName       string `json:"senderName"`
+	Preview          string `json:"preview"` // Shortened message content
+	CreatedTimestamp int64  `json:"createdTimestamp"`
+}
@@ -0,0 +1,20 @@
+package entity
+
+// PresenceStatus represents whether a user is around
+type PresenceStatus string
+
+const (
+	PresenceStatusOnline  PresenceStatus = "online"
+	PresenceStatusAway    PresenceStatus = "away"
+	PresenceStatusBusy    PresenceStatus = "busy"
+	PresenceStatusOffline PresenceStatus = "offline" // Set by the server when the user's last session ends, users can't pick it
+)
+
+// Presence represents a user's connection status across their devices, it's kept in Redis
+type Presence struct {
+	UserID            string         `json:"userId"`
+	Status            PresenceStatus `json:"status"`                      // Offline without sessions, otherwise the user's custom status or online
+	StatusText        string         `json:"statusText,omitempty"`        // Only shown while the user is online
+	Sessions          int            `json:"sessions"`                    // Live WebSocket connections of the user, one per device or tab
+	LastSeenTimestamp *int64         `json:"lastSeenTimestamp,omitempty"` // When the user's last session ended, nil if none did yet
+}
@@ -0,0 +1,12 @@
+package entity
+
+// ReadState represents the read position of a participant in a chatroom
+type ReadState struct {
+	ID                string `bson:"_id,omitempty" json:"id,omitempty"`
+	Chatroom          string `bson:"chatroom" json:"chatroom"`                   // Reference to Chatrooms collection
+	User              string `bson:"user" json:"user"`                           // Reference to Users collection
+	LastReadMessage   string `bson:"lastReadMessage" json:"lastReadMessage"`     // Reference to Chats collection
+	LastReadTimestamp int64  `bson:"lastReadTimestamp" json:"lastReadTimestamp"` // Created timestamp of the last read message
+	LastReadSequence  int64  `bson:"lastReadSequence" json:"lastReadSequence"`   // Chatroom sequence of the last read message, everything after it is unread
+	UpdatedTimestamp  int64  `bson:"updatedTimestamp" json:"updatedTimestamp"`
+}
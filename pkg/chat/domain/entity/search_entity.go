@@ -0,0 +1,13 @@
+package entity
+
+// ChatSearchResult represents a chat message matching a search query
+type ChatSearchResult struct {
+	Chat       *Chat              `json:"chat"`
+	Highlights []HighlightSegment `json:"highlights"` // The message split into matching and non-matching parts
+}
+
+// HighlightSegment represents a part of a message, flagged when it matches the search query
+type HighlightSegment struct {
+	Text  string `json:"text"`
+	Match bool   `json:"match"`
+}
@@ -2,10 +2,31 @@ package entity
 
 // User represents a user in the chat system
 type User struct {
-	ID       string `bson:"_id,omitempty" json:"id,omitempty"`
-	UserID   string `bson:"userId" json:"userId"`
-	Name     string `bson:"name" json:"name"`
-	Username string `bson:"username" json:"username"`
-	Picture  string `bson:"picture" json:"picture"`
-	Level    int    `bson:"level" json:"level"`
+	ID              string `bson:"_id,omitempty" json:"id,omitempty"`
+	ClientID        string `bson:"clientId" json:"clientId"`                         // Client the user authenticated through
+	ExternalID      string `bson:"externalId,omitempty" json:"externalId,omitempty"` // ID the client's auth strategy returns for the user, unique per client
+	UserID          string `bson:"userId" json:"userId"`
+	Name            string `bson:"name" json:"name"`
+	Username        string `bson:"username" json:"username"`
+	Picture         string `bson:"picture" json:"picture"`
+	Level           int    `bson:"level" json:"level"`
+	ErasedTimestamp *int64 `bson:"erasedTimestamp,omitempty" json:"erasedTimestamp,omitempty"` // Set once the user is anonymized, erased users can't authenticate again
+}
+
+// DMPrivacy represents who can send direct messages to a user
+type DMPrivacy string
+
+const (
+	DMPrivacyEveryone   DMPrivacy = "everyone"
+	DMPrivacySharedRoom DMPrivacy = "shared_room" // Only users sharing a group chatroom with the user
+	DMPrivacyNobody     DMPrivacy = "nobody"
+)
+
+// UserPrivacy represents a user's block list and direct message privacy
+// It's kept apart from User since users are replaced with the client's copy on every authentication
+type UserPrivacy struct {
+	ID               string    `bson:"_id" json:"userId"` // User ID
+	DMPrivacy        DMPrivacy `bson:"dmPrivacy" json:"dmPrivacy"`
+	BlockedUsers     []string  `bson:"blockedUsers" json:"blockedUsers"` // Users whose messages aren't delivered to the user
+	UpdatedTimestamp int64     `bson:"updatedTimestamp" json:"updatedTimestamp"`
 }
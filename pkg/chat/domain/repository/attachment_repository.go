@@ -0,0 +1,27 @@
+package repository
+
+import (
+	"app/pkg/chat/domain/entity"
+	"context"
+)
+
+type AttachmentRepository interface {
+	// Get retrieves a single attachment by ID
+	Get(ctx context.Context, id string) (*entity.Attachment, error)
+
+	// GetByUploader retrieves every attachment the user uploaded
+	GetByUploader(ctx context.Context, uploaderID string) ([]*entity.Attachment, error)
+
+	// Create stores a new attachment
+	Create(ctx context.Context, attachment *entity.Attachment) error
+
+	// Attach links an attachment that isn't attached yet to a chat message
+	// Returns false if the attachment was already attached
+	Attach(ctx context.Context, id string, chatID string) (bool, error)
+
+	// Detach unlinks an attachment from the chat message it was attached to, if it's still attached to it
+	Detach(ctx context.Context, id string, chatID string) error
+
+	// Delete removes an attachment
+	Delete(ctx context.Context, id string) error
+}
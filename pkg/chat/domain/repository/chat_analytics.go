@@ -0,0 +1,33 @@
+package repository
+
+import (
+	"app/pkg/chat/domain/entity"
+	"context"
+)
+
+// AnalyticsFilter represents the chat messages analytics are computed over
+type AnalyticsFilter struct {
+	ClientID  string // Empty covers every client
+	StartTime int64  // Inclusive, in seconds like the chats' createdTimestamp
+	EndTime   int64  // Exclusive, in seconds
+	Timezone  string // IANA time zone days and months are bucketed in, empty means UTC
+}
+
+// ChatAnalytics defines the interface for aggregating chat activity
+type ChatAnalytics interface {
+	// GetSummary counts the messages, active senders and active chatrooms
+	GetSummary(ctx context.Context, filter AnalyticsFilter) (*entity.ActivitySummary, error)
+
+	// GetDailyActivity counts the messages and active senders of every day with messages, oldest first
+	GetDailyActivity(ctx context.Context, filter AnalyticsFilter) ([]*entity.DailyActivity, error)
+
+	// GetMonthlyActivity counts the messages and active senders of every month with messages, oldest first
+	GetMonthlyActivity(ctx context.Context, filter AnalyticsFilter) ([]*entity.MonthlyActivity, error)
+
+	// GetTopChatrooms retrieves the chatrooms with the most messages, most first
+	GetTopChatrooms(ctx context.Context, filter AnalyticsFilter, limit int) ([]*entity.ChatroomActivity, error)
+
+	// GetDirectResponseTime averages how long users take to answer direct messages
+	// Responses sent more than maxGap seconds after the message they follow start a new conversation and are left out
+	GetDirectResponseTime(ctx context.Context, filter AnalyticsFilter, maxGap int64) (*entity.ResponseTime, error)
+}
@@ -8,13 +8,21 @@ import (
 
 // ChatFilter represents filtering options for chat queries
 type ChatFilter struct {
+	ClientID   string
 	ChatroomID string
 	SenderID   string
 	ReceiverID string
+	ReplyTo    string // Only messages replying to this chat message
 	StartTime  *int64
 	EndTime    *int64
 }
 
+// ChatCursor represents a keyset position in a chat message listing
+type ChatCursor struct {
+	Timestamp int64
+	ID        string
+}
+
 type ChatRepository interface {
 	// Get retrieves a single chat message by ID
 	Get(ctx context.Context, id string) (*entity.Chat, error)
@@ -25,15 +33,73 @@ type ChatRepository interface {
 	// GetAll retrieves multiple chat messages with filtering and pagination
 	GetAll(ctx context.Context, filter ChatFilter, pagination pagination.Pagination) ([]*entity.Chat, int64, error)
 
+	// GetAllByCursor retrieves chat messages newest first using keyset pagination on (createdTimestamp, _id)
+	// Only messages older than before and newer than after are returned, when given
+	// Returns whether more messages exist beyond the page in the requested direction
+	GetAllByCursor(ctx context.Context, filter ChatFilter, before *ChatCursor, after *ChatCursor, limit int) ([]*entity.Chat, bool, error)
+
 	// GetAllPopulated retrieves multiple chat messages with populated user references
 	GetAllPopulated(ctx context.Context, filter ChatFilter, pagination pagination.Pagination) ([]*entity.ChatPopulated, int64, error)
 
-	// Create stores a new chat message
+	// CountUnread counts, per chatroom, the messages not sent by the user that come
+	// after the given sequence and haven't been deleted
+	CountUnread(ctx context.Context, userID string, after map[string]int64) (map[string]int64, error)
+
+	// GetByClientMessageID retrieves a sender's chat message by the ID their client generated for it
+	GetByClientMessageID(ctx context.Context, senderID string, clientMessageID string) (*entity.Chat, error)
+
+	// GetAllAfterSequence retrieves a chatroom's chat messages created or changed after the given sequence,
+	// oldest change first, and whether more changes exist beyond the limit
+	// Messages removed by DeleteBySender are included as tombstones holding only their IDs, timestamps and sequences
+	GetAllAfterSequence(ctx context.Context, chatroomID string, sequence int64, limit int) ([]*entity.Chat, bool, error)
+
+	// Create stores a new chat message with the chatroom's next sequence and updates its chatroom's last message and message count
+	// The writes run in a transaction when the deployment supports it, otherwise the message is written first
+	// and the stats are updated right after; RecomputeStats on the chatroom repository repairs any drift
+	// A sender reusing a client message ID fails with a duplicate key error
 	Create(ctx context.Context, chat *entity.Chat) error
 
+	// Touch assigns the chatroom's next sequence to a chat message after it changed, so resuming clients replay the change
+	// Returns the assigned sequence
+	Touch(ctx context.Context, id string, chatroomID string) (int64, error)
+
 	// Update modifies an existing chat message
 	Update(ctx context.Context, chat *entity.Chat) error
 
+	// Edit replaces the content of a chat message and appends the previous content to its edit history
+	// Returns mongo.ErrNoDocuments if the message doesn't exist or was deleted
+	Edit(ctx context.Context, id string, message string, edit entity.ChatEdit) error
+
+	// ToggleReaction adds the user's reaction if it doesn't exist yet, or removes it otherwise
+	// Returns true if the reaction was added
+	ToggleReaction(ctx context.Context, id string, reaction entity.ChatReaction) (bool, error)
+
+	// SoftDelete turns a chat message into a tombstone, clearing its content, edit history, attachments and metadata
+	// Returns mongo.ErrNoDocuments if the message doesn't exist
+	SoftDelete(ctx context.Context, id string, deletedBy string, timestamp int64) error
+
 	// Delete removes a chat message
 	Delete(ctx context.Context, id string) error
+
+	// ForEachByUser calls fn with every chat message the user sent or received directly, oldest first
+	// It stops at the first error fn returns
+	ForEachByUser(ctx context.Context, userID string, fn func(chat *entity.Chat) error) error
+
+	// GetChatroomIDsBySender retrieves the IDs of every chatroom the user sent chat messages to
+	GetChatroomIDsBySender(ctx context.Context, senderID string) ([]string, error)
+
+	// SoftDeleteBySender turns the sender's chat messages in a chatroom into tombstones, also clearing their attachments and metadata
+	// Each changed message gets the chatroom's next sequence, tombstones deleted earlier keep who deleted them and when
+	// Returns the changed messages as tombstones
+	SoftDeleteBySender(ctx context.Context, chatroomID string, senderID string, timestamp int64) ([]*entity.Chat, error)
+
+	// DeleteBySender removes the sender's chat messages in a chatroom
+	// Each removed message gets the chatroom's next sequence and leaves a tombstone without its sender for GetAllAfterSequence
+	// Returns the removed messages as those tombstones
+	DeleteBySender(ctx context.Context, chatroomID string, senderID string, timestamp int64) ([]*entity.Chat, error)
+
+	// BackfillClientID copies the client of each chatroom to its messages stored before messages belonged to a client
+	// Chatrooms need a client first, see ChatroomRepository.BackfillClientID
+	// Returns the number of updated messages
+	BackfillClientID(ctx context.Context) (int64, error)
 }
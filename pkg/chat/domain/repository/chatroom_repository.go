@@ -8,6 +8,7 @@ import (
 
 // ChatroomFilter represents filtering options for chatroom queries
 type ChatroomFilter struct {
+	ClientID      string
 	ParticipantID string
 	Type          *entity.ChatroomType
 	IsGroup       *bool
@@ -15,6 +16,20 @@ type ChatroomFilter struct {
 	EndTime       *int64
 }
 
+// ChatroomDiscoveryFilter represents filtering options for public chatroom discovery
+type ChatroomDiscoveryFilter struct {
+	ClientID string // Client whose chatrooms are listed
+	Query    string // Optional text search on the chatroom name
+	UserID   string // Requesting user, used to flag the chatrooms they already joined
+}
+
+// ChatroomUpdate represents the chatroom settings to change, nil fields are left as they are
+type ChatroomUpdate struct {
+	Name            *string
+	JoinPolicy      *entity.JoinPolicy
+	MaxParticipants *int // 0 removes the cap
+}
+
 type ChatroomRepository interface {
 	// Get retrieves a single chatroom by ID
 	Get(ctx context.Context, id string) (*entity.Chatroom, error)
@@ -25,20 +40,52 @@ type ChatroomRepository interface {
 	// GetAll retrieves multiple chatrooms with filtering and pagination
 	GetAll(ctx context.Context, filter ChatroomFilter, pagination pagination.Pagination) ([]*entity.Chatroom, int64, error)
 
+	// GetIDsByParticipant retrieves the IDs of every chatroom the user participates in
+	GetIDsByParticipant(ctx context.Context, userID string) ([]string, error)
+
+	// FindOrCreateDirect atomically retrieves the chatroom with the given chatroom's direct key, or stores the given chatroom
+	// Returns the stored chatroom and whether it was created
+	FindOrCreateDirect(ctx context.Context, chatroom *entity.Chatroom) (*entity.Chatroom, bool, error)
+
+	// BackfillDirectKeys sets the direct key of direct chatrooms created before direct keys existed
+	// When a pair of users has several direct chatrooms only the oldest gets the key, the others are counted as duplicates
+	// Returns the number of updated and duplicate chatrooms
+	BackfillDirectKeys(ctx context.Context) (int64, int64, error)
+
+	// BackfillClientID assigns chatrooms created before chatrooms belonged to a client to the given client
+	// Returns the number of updated chatrooms
+	BackfillClientID(ctx context.Context, clientID string) (int64, error)
+
+	// SharesGroup checks if both users participate in at least one group chatroom
+	SharesGroup(ctx context.Context, userID string, otherUserID string) (bool, error)
+
+	// GetSharedParticipants retrieves which of the given users participate in at least one chatroom with the user
+	GetSharedParticipants(ctx context.Context, userID string, userIDs []string) ([]string, error)
+
 	// GetAllPopulated retrieves multiple chatrooms with populated user references
 	GetAllPopulated(ctx context.Context, filter ChatroomFilter, pagination pagination.Pagination) ([]*entity.ChatroomPopulated, int64, error)
 
+	// Discover retrieves public group chatrooms ranked by activity, most messages and most recent activity first
+	Discover(ctx context.Context, filter ChatroomDiscoveryFilter, pagination pagination.Pagination) ([]*entity.ChatroomListing, int64, error)
+
 	// Create stores a new chatroom
 	Create(ctx context.Context, chatroom *entity.Chatroom) error
 
-	// Update modifies an existing chatroom
-	Update(ctx context.Context, chatroom *entity.Chatroom) error
+	// UpdateSettings changes the given settings of a chatroom, leaving the rest of the chatroom as it is
+	// Returns the updated chatroom, or nil if it doesn't exist
+	UpdateSettings(ctx context.Context, id string, update ChatroomUpdate) (*entity.Chatroom, error)
+
+	// RecomputeStats recomputes the last message and message count of chatrooms from their chat messages
+	// Deleted messages are left out, an empty chatroomID recomputes every chatroom
+	RecomputeStats(ctx context.Context, chatroomID string) error
 
 	// Delete removes a chatroom
 	Delete(ctx context.Context, id string) error
 
-	// AddParticipant adds a participant to a chatroom
-	AddParticipant(ctx context.Context, chatroomID string, participant entity.ChatroomParticipant) error
+	// AddParticipant atomically adds a participant to a chatroom
+	// maxParticipants caps the number of participants, 0 means unlimited
+	// Returns false if the user is already a participant or the chatroom is full
+	AddParticipant(ctx context.Context, chatroomID string, participant entity.ChatroomParticipant, maxParticipants int) (bool, error)
 
 	// RemoveParticipant removes a participant from a chatroom
 	RemoveParticipant(ctx context.Context, chatroomID string, userID string) error
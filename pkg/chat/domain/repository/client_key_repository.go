@@ -0,0 +1,30 @@
+package repository
+
+import (
+	"app/pkg/chat/domain/entity"
+	"context"
+)
+
+// ClientKeyRepository defines the interface for client key data access
+type ClientKeyRepository interface {
+	// Get retrieves a single client key by ID
+	Get(ctx context.Context, id string) (*entity.ClientKey, error)
+
+	// GetByHash retrieves a single client key by the hash of the key
+	GetByHash(ctx context.Context, hash string) (*entity.ClientKey, error)
+
+	// GetByClient retrieves all keys of a client, including expired and revoked ones, newest first
+	GetByClient(ctx context.Context, clientID string) ([]*entity.ClientKey, error)
+
+	// Create stores a new client key
+	Create(ctx context.Context, key *entity.ClientKey) error
+
+	// SetExpiry sets when a client key expires, in milliseconds
+	SetExpiry(ctx context.Context, id string, expiresTimestamp int64) error
+
+	// Revoke marks a client key as revoked, revoking a revoked key keeps its original timestamp
+	Revoke(ctx context.Context, id string) error
+
+	// DeleteByClient removes all keys of a client
+	DeleteByClient(ctx context.Context, clientID string) error
+}
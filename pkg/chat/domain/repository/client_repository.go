@@ -11,8 +11,11 @@ type ClientRepository interface {
 	// Get retrieves a single client by ID
 	Get(ctx context.Context, id string) (*entity.Client, error)
 
-	// GetByKey retrieves a single client by client key
-	GetByKey(ctx context.Context, clientKey string) (*entity.Client, error)
+	// GetByLegacyKey retrieves a single client by the plaintext key of clients created before keys were hashed
+	GetByLegacyKey(ctx context.Context, clientKey string) (*entity.Client, error)
+
+	// GetWithLegacyKey retrieves the clients that still have a plaintext key
+	GetWithLegacyKey(ctx context.Context) ([]*entity.Client, error)
 
 	// GetAll retrieves multiple clients with pagination
 	GetAll(ctx context.Context, pagination pagination.Pagination) ([]*entity.Client, int64, error)
@@ -25,4 +28,11 @@ type ClientRepository interface {
 
 	// Delete removes a client
 	Delete(ctx context.Context, id string) error
+
+	// SetWebhookSecret replaces the secret webhook requests to the client are signed with
+	SetWebhookSecret(ctx context.Context, id string, secret string) error
+
+	// ClearLegacyKey removes the client's plaintext key once it's stored as a ClientKey
+	// Clients without a webhook secret keep signing webhooks with it, so receivers don't have to change
+	ClearLegacyKey(ctx context.Context, id string) error
 }
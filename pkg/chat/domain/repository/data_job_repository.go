@@ -0,0 +1,42 @@
+package repository
+
+import (
+	"app/pkg/chat/domain/entity"
+	"app/pkg/types/pagination"
+	"context"
+)
+
+// DataJobFilter represents filtering options for data job queries
+type DataJobFilter struct {
+	ClientID string
+	UserID   string
+	Type     *entity.DataJobType
+	Status   *entity.DataJobStatus
+}
+
+type DataJobRepository interface {
+	// Get retrieves a single data job by ID
+	Get(ctx context.Context, id string) (*entity.DataJob, error)
+
+	// GetAll retrieves multiple data jobs with filtering and pagination, newest first
+	GetAll(ctx context.Context, filter DataJobFilter, pagination pagination.Pagination) ([]*entity.DataJob, int64, error)
+
+	// GetUnfinished retrieves the user's pending or running job of the given type, returning nil if there is none
+	GetUnfinished(ctx context.Context, userID string, jobType entity.DataJobType) (*entity.DataJob, error)
+
+	// GetExports retrieves the user's completed exports whose file is still stored
+	GetExports(ctx context.Context, userID string) ([]*entity.DataJob, error)
+
+	// GetExpiredExports retrieves completed exports whose file expired before the given timestamp
+	GetExpiredExports(ctx context.Context, timestamp int64, limit int) ([]*entity.DataJob, error)
+
+	// Create stores a new data job
+	Create(ctx context.Context, job *entity.DataJob) error
+
+	// Claim atomically marks the oldest pending job as running and returns it, returning nil if there is none
+	// Running jobs started before staleTimestamp were abandoned by a stopped worker and are claimed again
+	Claim(ctx context.Context, staleTimestamp int64) (*entity.DataJob, error)
+
+	// Update modifies an existing data job
+	Update(ctx context.Context, job *entity.DataJob) error
+}
@@ -0,0 +1,18 @@
+package repository
+
+import (
+	"context"
+	"io"
+)
+
+// FileStorage defines the interface for storing file contents
+type FileStorage interface {
+	// Store writes the content of reader under the given key and returns the number of bytes written
+	Store(ctx context.Context, key string, reader io.Reader) (int64, error)
+
+	// Open returns a reader for the content stored under the given key
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+
+	// Delete removes the content stored under the given key
+	Delete(ctx context.Context, key string) error
+}
@@ -0,0 +1,27 @@
+package repository
+
+import (
+	"app/pkg/chat/domain/entity"
+	"context"
+)
+
+type InviteRepository interface {
+	// Get retrieves a single invite by ID
+	Get(ctx context.Context, id string) (*entity.ChatroomInvite, error)
+
+	// GetAllByChatroom retrieves the invites of a chatroom that are neither revoked nor expired, newest first
+	GetAllByChatroom(ctx context.Context, chatroomID string, now int64) ([]*entity.ChatroomInvite, error)
+
+	// Create stores a new invite
+	Create(ctx context.Context, invite *entity.ChatroomInvite) error
+
+	// Redeem atomically counts a use of an invite that is neither revoked, expired nor used up
+	// Returns false if the invite can't be redeemed
+	Redeem(ctx context.Context, id string, now int64) (bool, error)
+
+	// Release takes back a use of an invite counted by Redeem
+	Release(ctx context.Context, id string) error
+
+	// Revoke marks an invite as revoked
+	Revoke(ctx context.Context, id string, timestamp int64) error
+}
@@ -0,0 +1,30 @@
+package repository
+
+import (
+	"app/pkg/chat/domain/entity"
+	"app/pkg/types/pagination"
+	"context"
+)
+
+// JoinRequestFilter represents filtering options for join request queries
+type JoinRequestFilter struct {
+	ChatroomID string
+	UserID     string
+	Status     *entity.JoinRequestStatus
+}
+
+type JoinRequestRepository interface {
+	// Get retrieves a single join request by ID
+	Get(ctx context.Context, id string) (*entity.JoinRequest, error)
+
+	// GetAll retrieves join requests with filtering and pagination, oldest first
+	GetAll(ctx context.Context, filter JoinRequestFilter, pagination pagination.Pagination) ([]*entity.JoinRequest, int64, error)
+
+	// Create stores a new join request
+	// Fails with a duplicate key error if the user already has a pending request for the chatroom
+	Create(ctx context.Context, request *entity.JoinRequest) error
+
+	// Review moves a pending join request to the given status
+	// Returns false if the request is no longer pending
+	Review(ctx context.Context, id string, status entity.JoinRequestStatus, reviewedBy string, timestamp int64) (bool, error)
+}
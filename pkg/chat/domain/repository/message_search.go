@@ -0,0 +1,22 @@
+package repository
+
+import (
+	"app/pkg/chat/domain/entity"
+	"context"
+)
+
+// MessageSearchQuery represents a full-text search over chat messages
+type MessageSearchQuery struct {
+	Text        string      // Keywords, with "quoted phrases" and -excluded words
+	ChatroomIDs []string    // Only messages of these chatrooms are searched
+	Before      *ChatCursor // Only messages older than this position are returned
+	Limit       int
+}
+
+// MessageSearch defines the interface for full-text search over chat messages
+// The default implementation uses MongoDB text indexes, but it can be backed by a dedicated search engine
+type MessageSearch interface {
+	// Search returns matching messages newest first with their highlights
+	// Also returns whether more results exist
+	Search(ctx context.Context, query MessageSearchQuery) ([]*entity.ChatSearchResult, bool, error)
+}
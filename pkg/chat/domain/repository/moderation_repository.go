@@ -0,0 +1,29 @@
+package repository
+
+import (
+	"app/pkg/chat/domain/entity"
+	"app/pkg/types/pagination"
+	"context"
+)
+
+// ModerationDecisionFilter represents filtering options for moderation decision queries
+type ModerationDecisionFilter struct {
+	ChatroomID string
+	SenderID   string
+	ClientID   string
+	Action     *entity.ModerationAction
+}
+
+type ModerationRepository interface {
+	// GetSettings retrieves a chatroom's moderation settings, returning nil if the chatroom has none stored
+	GetSettings(ctx context.Context, chatroomID string) (*entity.ModerationSettings, error)
+
+	// UpsertSettings stores a chatroom's moderation settings, replacing any existing ones
+	UpsertSettings(ctx context.Context, settings *entity.ModerationSettings) error
+
+	// CreateDecision stores a new moderation decision
+	CreateDecision(ctx context.Context, decision *entity.ModerationDecision) error
+
+	// GetDecisions retrieves moderation decisions with filtering and pagination, newest first
+	GetDecisions(ctx context.Context, filter ModerationDecisionFilter, pagination pagination.Pagination) ([]*entity.ModerationDecision, int64, error)
+}
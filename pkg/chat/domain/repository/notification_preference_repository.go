@@ -0,0 +1,21 @@
+package repository
+
+import (
+	"app/pkg/chat/domain/entity"
+	"context"
+)
+
+type NotificationPreferenceRepository interface {
+	// Get retrieves a user's notification preferences, returning nil if the user has none stored
+	Get(ctx context.Context, userID string) (*entity.NotificationPreference, error)
+
+	// Upsert stores a user's notification preferences, replacing any existing ones but the linked Telegram chat
+	// The preference is updated to the stored one
+	Upsert(ctx context.Context, preference *entity.NotificationPreference) error
+
+	// SetTelegramChatID links a Telegram chat to a user's notification preferences, 0 unlinks it
+	SetTelegramChatID(ctx context.Context, userID string, chatID int64) error
+
+	// Delete removes a user's notification preferences
+	Delete(ctx context.Context, userID string) error
+}
@@ -0,0 +1,17 @@
+package repository
+
+import (
+	"app/pkg/chat/domain/entity"
+	"context"
+)
+
+type ReadStateRepository interface {
+	// Get retrieves the read state of a user in a chatroom
+	Get(ctx context.Context, chatroomID string, userID string) (*entity.ReadState, error)
+
+	// GetAllByUser retrieves the read states of a user in the given chatrooms
+	GetAllByUser(ctx context.Context, userID string, chatroomIDs []string) ([]*entity.ReadState, error)
+
+	// Upsert stores a read state, only moving the read position forward
+	Upsert(ctx context.Context, state *entity.ReadState) error
+}
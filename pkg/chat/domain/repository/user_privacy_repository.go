@@ -0,0 +1,23 @@
+package repository
+
+import (
+	"app/pkg/chat/domain/entity"
+	"context"
+)
+
+type UserPrivacyRepository interface {
+	// Get retrieves a user's privacy settings, returning nil if the user has none stored
+	Get(ctx context.Context, userID string) (*entity.UserPrivacy, error)
+
+	// SetDMPrivacy sets who can send direct messages to the user
+	SetDMPrivacy(ctx context.Context, userID string, privacy entity.DMPrivacy) error
+
+	// Block adds a user to the user's block list, blocking an already blocked user does nothing
+	Block(ctx context.Context, userID string, blockedUserID string) error
+
+	// Unblock removes a user from the user's block list
+	Unblock(ctx context.Context, userID string, blockedUserID string) error
+
+	// Delete removes a user's privacy settings
+	Delete(ctx context.Context, userID string) error
+}
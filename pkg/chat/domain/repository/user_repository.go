@@ -6,19 +6,38 @@ import (
 	"context"
 )
 
+// UserFilter represents filtering options for user queries
+type UserFilter struct {
+	ClientID string
+}
+
 type UserRepository interface {
 	// Get retrieves a single user message by ID
 	Get(ctx context.Context, id string) (*entity.User, error)
 
+	// GetByExternalID retrieves a client's user by the ID the client's auth strategy returns for them
+	GetByExternalID(ctx context.Context, clientID string, externalID string) (*entity.User, error)
+
 	// GetAll retrieves multiple user messages with pagination
-	GetAll(ctx context.Context, pagination pagination.Pagination) ([]*entity.User, int64, error)
+	GetAll(ctx context.Context, filter UserFilter, pagination pagination.Pagination) ([]*entity.User, int64, error)
+
+	// CountByClient counts how many of the given users belong to the client
+	CountByClient(ctx context.Context, clientID string, userIDs []string) (int64, error)
+
+	// BackfillClientID assigns users stored before users belonged to a client to the given client
+	// Returns the number of updated users
+	BackfillClientID(ctx context.Context, clientID string) (int64, error)
 
 	// Create stores a new user message
 	Create(ctx context.Context, user *entity.User) error
 
-	// Update modifies an existing user message
+	// Update modifies an existing user that hasn't been erased
+	// Returns mongo.ErrNoDocuments if the user doesn't exist or was erased
 	Update(ctx context.Context, user *entity.User) error
 
+	// Erase anonymizes a user, replacing their profile with placeholders derived from their ID and marking them erased
+	Erase(ctx context.Context, id string, timestamp int64) error
+
 	// Delete removes a user message
 	Delete(ctx context.Context, id string) error
 }
@@ -0,0 +1,97 @@
+package local
+
+import (
+	"app/pkg/chat/domain/repository"
+	"app/pkg/exception"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// fileStorage implements the FileStorage interface on the local disk
+type fileStorage struct {
+	basePath string
+}
+
+// NewFileStorage creates a new instance of the local file storage
+func NewFileStorage(basePath string) (repository.FileStorage, error) {
+	// Create storage directory if it doesn't exist
+	if err := os.MkdirAll(basePath, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create storage directory: %v", err)
+	}
+
+	return &fileStorage{
+		basePath: basePath,
+	}, nil
+}
+
+// Store writes the content of reader under the given key and returns the number of bytes written
+func (s *fileStorage) Store(ctx context.Context, key string, reader io.Reader) (int64, error) {
+	filePath, err := s.resolve(key)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+		return 0, exception.InternalError(fmt.Sprintf("Failed to create directory: %v", err))
+	}
+
+	file, err := os.Create(filePath)
+	if err != nil {
+		return 0, exception.InternalError(fmt.Sprintf("Failed to create file: %v", err))
+	}
+	defer file.Close()
+
+	written, err := io.Copy(file, reader)
+	if err != nil {
+		os.Remove(filePath) // Clean up on error
+		return 0, exception.InternalError(fmt.Sprintf("Failed to write file: %v", err))
+	}
+
+	return written, nil
+}
+
+// Open returns a reader for the content stored under the given key
+func (s *fileStorage) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	filePath, err := s.resolve(key)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(filePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, exception.NotFound("File")
+		}
+		return nil, exception.InternalError(fmt.Sprintf("Failed to open file: %v", err))
+	}
+
+	return file, nil
+}
+
+// Delete removes the content stored under the given key
+func (s *fileStorage) Delete(ctx context.Context, key string) error {
+	filePath, err := s.resolve(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(filePath); err != nil && !os.IsNotExist(err) {
+		return exception.InternalError(fmt.Sprintf("Failed to delete file: %v", err))
+	}
+
+	return nil
+}
+
+// resolve maps a storage key to a path, rejecting keys that escape the base path
+func (s *fileStorage) resolve(key string) (string, error) {
+	filePath := filepath.Join(s.basePath, filepath.FromSlash(key))
+	if !strings.HasPrefix(filePath, filepath.Clean(s.basePath)+string(os.PathSeparator)) {
+		return "", exception.BadRequest("Invalid file key")
+	}
+
+	return filePath, nil
+}
@@ -0,0 +1,124 @@
+package mongodb
+
+import (
+	"app/pkg/chat/domain/entity"
+	"app/pkg/chat/domain/repository"
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type AttachmentRepository struct {
+	collection *mongo.Collection
+}
+
+func NewAttachmentRepository(db *mongo.Database) (repository.AttachmentRepository, error) {
+	repo := &AttachmentRepository{
+		collection: db.Collection("attachments"),
+	}
+
+	if err := repo.ensureIndexes(context.Background()); err != nil {
+		return nil, err
+	}
+
+	return repo, nil
+}
+
+// ensureIndexes creates all necessary indexes for the attachment collection
+func (r *AttachmentRepository) ensureIndexes(ctx context.Context) error {
+	indexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				{Key: "chatroom", Value: 1},
+				{Key: "createdTimestamp", Value: -1},
+			},
+			Options: options.Index().SetName("chatroom_timestamp"),
+		},
+		{
+			Keys: bson.D{
+				{Key: "uploader", Value: 1},
+				{Key: "createdTimestamp", Value: -1},
+			},
+			Options: options.Index().SetName("uploader_timestamp"),
+		},
+	}
+
+	opts := options.CreateIndexes().SetMaxTime(10 * time.Second)
+
+	_, err := r.collection.Indexes().CreateMany(ctx, indexes, opts)
+	return err
+}
+
+// Get retrieves a single attachment by ID
+func (r *AttachmentRepository) Get(ctx context.Context, id string) (*entity.Attachment, error) {
+	var attachment entity.Attachment
+	err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&attachment)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &attachment, nil
+}
+
+// Create stores a new attachment
+func (r *AttachmentRepository) Create(ctx context.Context, attachment *entity.Attachment) error {
+	if attachment.ID == "" {
+		attachment.ID = primitive.NewObjectID().Hex()
+	}
+
+	_, err := r.collection.InsertOne(ctx, attachment)
+	return err
+}
+
+// Attach links an attachment that isn't attached yet to a chat message
+func (r *AttachmentRepository) Attach(ctx context.Context, id string, chatID string) (bool, error) {
+	result, err := r.collection.UpdateOne(
+		ctx,
+		bson.M{"_id": id, "chat": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"chat": chatID}},
+	)
+	if err != nil {
+		return false, err
+	}
+
+	return result.ModifiedCount > 0, nil
+}
+
+// Detach unlinks an attachment from the chat message it was attached to, if it's still attached to it
+func (r *AttachmentRepository) Detach(ctx context.Context, id string, chatID string) error {
+	_, err := r.collection.UpdateOne(
+		ctx,
+		bson.M{"_id": id, "chat": chatID},
+		bson.M{"$unset": bson.M{"chat": ""}},
+	)
+	return err
+}
+
+// GetByUploader retrieves every attachment the user uploaded
+func (r *AttachmentRepository) GetByUploader(ctx context.Context, uploaderID string) ([]*entity.Attachment, error) {
+	cursor, err := r.collection.Find(ctx, bson.M{"uploader": uploaderID})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	attachments := []*entity.Attachment{}
+	if err = cursor.All(ctx, &attachments); err != nil {
+		return nil, err
+	}
+
+	return attachments, nil
+}
+
+// Delete removes an attachment
+func (r *AttachmentRepository) Delete(ctx context.Context, id string) error {
+	_, err := r.collection.DeleteOne(ctx, bson.M{"_id": id})
+	return err
+}
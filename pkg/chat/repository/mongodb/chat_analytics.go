@@ -0,0 +1,209 @@
+package mongodb
+
+import (
+	"app/pkg/chat/domain/entity"
+	"app/pkg/chat/domain/repository"
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ChatAnalytics aggregates chat activity from the chats collection
+// Messages are matched on the clientId_timestamp index, counting distinct senders groups by sender first
+// so no group has to hold every sender of a busy range at once
+type ChatAnalytics struct {
+	collection *mongo.Collection
+}
+
+func NewChatAnalytics(db *mongo.Database) (repository.ChatAnalytics, error) {
+	return &ChatAnalytics{
+		collection: db.Collection("chats"),
+	}, nil
+}
+
+// match returns the stage selecting the messages of the filter
+func (a *ChatAnalytics) match(filter repository.AnalyticsFilter) bson.D {
+	query := bson.M{
+		"createdTimestamp": bson.M{"$gte": filter.StartTime, "$lt": filter.EndTime},
+	}
+	if filter.ClientID != "" {
+		query["clientId"] = filter.ClientID
+	}
+
+	return bson.D{{Key: "$match", Value: query}}
+}
+
+// dateString returns the expression formatting a message's creation time in the filter's time zone
+// Chats are timestamped in seconds while $toDate reads milliseconds
+func (a *ChatAnalytics) dateString(filter repository.AnalyticsFilter, format string) bson.M {
+	timezone := filter.Timezone
+	if timezone == "" {
+		timezone = "UTC"
+	}
+
+	return bson.M{"$dateToString": bson.M{
+		"format":   format,
+		"date":     bson.M{"$toDate": bson.M{"$multiply": bson.A{"$createdTimestamp", 1000}}},
+		"timezone": timezone,
+	}}
+}
+
+// aggregate runs a pipeline and decodes every result
+func (a *ChatAnalytics) aggregate(ctx context.Context, pipeline mongo.Pipeline, results interface{}) error {
+	cursor, err := a.collection.Aggregate(ctx, pipeline, options.Aggregate().SetAllowDiskUse(true))
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	return cursor.All(ctx, results)
+}
+
+// GetSummary counts the messages, active senders and active chatrooms
+func (a *ChatAnalytics) GetSummary(ctx context.Context, filter repository.AnalyticsFilter) (*entity.ActivitySummary, error) {
+	pipeline := mongo.Pipeline{
+		a.match(filter),
+		{{Key: "$facet", Value: bson.M{
+			"messages":  bson.A{bson.M{"$count": "count"}},
+			"senders":   bson.A{bson.M{"$group": bson.M{"_id": "$sender"}}, bson.M{"$count": "count"}},
+			"chatrooms": bson.A{bson.M{"$group": bson.M{"_id": "$chatroom"}}, bson.M{"$count": "count"}},
+		}}},
+		// An empty facet has no count document at all
+		{{Key: "$project", Value: bson.M{
+			"messages":  bson.M{"$ifNull": bson.A{bson.M{"$first": "$messages.count"}, 0}},
+			"senders":   bson.M{"$ifNull": bson.A{bson.M{"$first": "$senders.count"}, 0}},
+			"chatrooms": bson.M{"$ifNull": bson.A{bson.M{"$first": "$chatrooms.count"}, 0}},
+		}}},
+	}
+
+	var results []*entity.ActivitySummary
+	if err := a.aggregate(ctx, pipeline, &results); err != nil {
+		return nil, err
+	}
+	if len(results) == 0 {
+		return &entity.ActivitySummary{}, nil
+	}
+
+	return results[0], nil
+}
+
+// GetDailyActivity counts the messages and active senders of every day with messages, oldest first
+func (a *ChatAnalytics) GetDailyActivity(ctx context.Context, filter repository.AnalyticsFilter) ([]*entity.DailyActivity, error) {
+	activity := []*entity.DailyActivity{}
+	if err := a.aggregate(ctx, a.activityPipeline(filter, "%Y-%m-%d"), &activity); err != nil {
+		return nil, err
+	}
+
+	return activity, nil
+}
+
+// GetMonthlyActivity counts the messages and active senders of every month with messages, oldest first
+func (a *ChatAnalytics) GetMonthlyActivity(ctx context.Context, filter repository.AnalyticsFilter) ([]*entity.MonthlyActivity, error) {
+	activity := []*entity.MonthlyActivity{}
+	if err := a.aggregate(ctx, a.activityPipeline(filter, "%Y-%m"), &activity); err != nil {
+		return nil, err
+	}
+
+	return activity, nil
+}
+
+// activityPipeline counts the messages and distinct senders per period, the period being the formatted creation time
+func (a *ChatAnalytics) activityPipeline(filter repository.AnalyticsFilter, format string) mongo.Pipeline {
+	return mongo.Pipeline{
+		a.match(filter),
+		{{Key: "$group", Value: bson.M{
+			"_id":      bson.M{"period": a.dateString(filter, format), "sender": "$sender"},
+			"messages": bson.M{"$sum": 1},
+		}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":      "$_id.period",
+			"messages": bson.M{"$sum": "$messages"},
+			"senders":  bson.M{"$sum": 1},
+		}}},
+		{{Key: "$sort", Value: bson.D{{Key: "_id", Value: 1}}}},
+	}
+}
+
+// GetTopChatrooms retrieves the chatrooms with the most messages, most first
+func (a *ChatAnalytics) GetTopChatrooms(ctx context.Context, filter repository.AnalyticsFilter, limit int) ([]*entity.ChatroomActivity, error) {
+	pipeline := mongo.Pipeline{
+		a.match(filter),
+		{{Key: "$group", Value: bson.M{
+			"_id":      bson.M{"chatroom": "$chatroom", "sender": "$sender"},
+			"messages": bson.M{"$sum": 1},
+		}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":      "$_id.chatroom",
+			"messages": bson.M{"$sum": "$messages"},
+			"senders":  bson.M{"$sum": 1},
+		}}},
+		{{Key: "$sort", Value: bson.D{{Key: "messages", Value: -1}, {Key: "_id", Value: 1}}}},
+		{{Key: "$limit", Value: limit}},
+		{{Key: "$lookup", Value: bson.M{
+			"from":         "chatrooms",
+			"localField":   "_id",
+			"foreignField": "_id",
+			"as":           "chatroom",
+		}}},
+		{{Key: "$set", Value: bson.M{"chatroom": bson.M{"$arrayElemAt": bson.A{"$chatroom", 0}}}}},
+		{{Key: "$project", Value: bson.M{
+			"messages": 1,
+			"senders":  1,
+			"name":     "$chatroom.name",
+			"type":     "$chatroom.type",
+			"isGroup":  "$chatroom.isGroup",
+		}}},
+	}
+
+	chatrooms := []*entity.ChatroomActivity{}
+	if err := a.aggregate(ctx, pipeline, &chatrooms); err != nil {
+		return nil, err
+	}
+
+	return chatrooms, nil
+}
+
+// GetDirectResponseTime averages how long users take to answer direct messages
+// Direct messages are the ones with a receiver, every message is compared with the one before it in its chatroom (requires MongoDB 5.0 or newer)
+func (a *ChatAnalytics) GetDirectResponseTime(ctx context.Context, filter repository.AnalyticsFilter, maxGap int64) (*entity.ResponseTime, error) {
+	match := a.match(filter)
+	match[0].Value.(bson.M)["receiver"] = bson.M{"$type": "string"}
+
+	pipeline := mongo.Pipeline{
+		match,
+		{{Key: "$setWindowFields", Value: bson.M{
+			"partitionBy": "$chatroom",
+			"sortBy":      bson.D{{Key: "createdTimestamp", Value: 1}},
+			"output": bson.M{
+				"previousSender":    bson.M{"$shift": bson.M{"output": "$sender", "by": -1}},
+				"previousTimestamp": bson.M{"$shift": bson.M{"output": "$createdTimestamp", "by": -1}},
+			},
+		}}},
+		{{Key: "$match", Value: bson.M{"$expr": bson.M{"$and": bson.A{
+			bson.M{"$ne": bson.A{"$previousSender", nil}},
+			bson.M{"$ne": bson.A{"$sender", "$previousSender"}},
+			bson.M{"$lte": bson.A{bson.M{"$subtract": bson.A{"$createdTimestamp", "$previousTimestamp"}}, maxGap}},
+		}}}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":       nil,
+			"responses": bson.M{"$sum": 1},
+			"average":   bson.M{"$avg": bson.M{"$subtract": bson.A{"$createdTimestamp", "$previousTimestamp"}}},
+		}}},
+		{{Key: "$project", Value: bson.M{
+			"responses": 1,
+			"average":   bson.M{"$toLong": bson.M{"$round": bson.A{bson.M{"$multiply": bson.A{"$average", 1000}}, 0}}}, // Seconds to milliseconds
+		}}},
+	}
+
+	var results []*entity.ResponseTime
+	if err := a.aggregate(ctx, pipeline, &results); err != nil {
+		return nil, err
+	}
+	if len(results) == 0 {
+		return &entity.ResponseTime{}, nil
+	}
+
+	return results[0], nil
+}
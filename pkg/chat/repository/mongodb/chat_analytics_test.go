@@ -0,0 +1,90 @@
+package mongodb
+
+import (
+	"app/pkg/chat/domain/repository"
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// testDatabase connects to the MongoDB of MONGODB_TEST_URI and returns a database dropped once the test ends
+func testDatabase(t *testing.T) *mongo.Database {
+	t.Helper()
+
+	uri := os.Getenv("MONGODB_TEST_URI")
+	if uri == "" {
+		t.Skip("MONGODB_TEST_URI is not set")
+	}
+
+	ctx := context.Background()
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+	if err != nil {
+		t.Fatalf("Failed to connect to MongoDB: %v", err)
+	}
+
+	db := client.Database(fmt.Sprintf("chat_test_%d", time.Now().UnixNano()))
+	t.Cleanup(func() {
+		db.Drop(ctx)
+		client.Disconnect(ctx)
+	})
+
+	return db
+}
+
+func TestChatAnalyticsAggregatesStoredChats(t *testing.T) {
+	ctx := context.Background()
+	db := testDatabase(t)
+
+	// Chats are stored the way the chat service stores them, timestamped in seconds
+	day := time.Date(2026, time.March, 10, 12, 0, 0, 0, time.UTC)
+	receiverA, receiverB := "user-a", "user-b"
+	chats := []interface{}{
+		bson.M{"_id": "chat-1", "clientId": "client-1", "chatroom": "room-1", "sender": "user-b", "receiver": &receiverA, "message": "hi", "createdTimestamp": day.Unix()},
+		bson.M{"_id": "chat-2", "clientId": "client-1", "chatroom": "room-1", "sender": "user-a", "receiver": &receiverB, "message": "hello", "createdTimestamp": day.Add(90 * time.Second).Unix()},
+		bson.M{"_id": "chat-3", "clientId": "client-2", "chatroom": "room-2", "sender": "user-c", "receiver": nil, "message": "other client", "createdTimestamp": day.Unix()},
+	}
+	if _, err := db.Collection("chats").InsertMany(ctx, chats); err != nil {
+		t.Fatalf("Failed to store chats: %v", err)
+	}
+
+	analytics, err := NewChatAnalytics(db)
+	if err != nil {
+		t.Fatalf("Failed to create chat analytics: %v", err)
+	}
+
+	filter := repository.AnalyticsFilter{
+		ClientID:  "client-1",
+		StartTime: day.Add(-time.Hour).Unix(),
+		EndTime:   day.Add(time.Hour).Unix(),
+	}
+
+	summary, err := analytics.GetSummary(ctx, filter)
+	if err != nil {
+		t.Fatalf("GetSummary: %v", err)
+	}
+	if summary.Messages != 2 || summary.Senders != 2 || summary.Chatrooms != 1 {
+		t.Errorf("GetSummary = %+v, want 2 messages, 2 senders and 1 chatroom", summary)
+	}
+
+	daily, err := analytics.GetDailyActivity(ctx, filter)
+	if err != nil {
+		t.Fatalf("GetDailyActivity: %v", err)
+	}
+	if len(daily) != 1 || daily[0].Date != "2026-03-10" || daily[0].Messages != 2 || daily[0].Senders != 2 {
+		t.Errorf("GetDailyActivity = %+v, want 2 messages from 2 senders on 2026-03-10", daily)
+	}
+
+	responseTime, err := analytics.GetDirectResponseTime(ctx, filter, int64((24 * time.Hour).Seconds()))
+	if err != nil {
+		t.Fatalf("GetDirectResponseTime: %v", err)
+	}
+	if responseTime.Responses != 1 || responseTime.AverageMillis != 90000 {
+		t.Errorf("GetDirectResponseTime = %+v, want 1 response after 90000ms", responseTime)
+	}
+}
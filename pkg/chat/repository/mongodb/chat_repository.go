@@ -5,6 +5,7 @@ import (
 	"app/pkg/chat/domain/repository"
 	"app/pkg/types/pagination"
 	"context"
+	"errors"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
@@ -13,13 +14,24 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+// Chat messages changed per batch when erasing a sender's messages
+const erasureBatchSize = 500
+
+// ChatRepository stores chat messages in the chats collection
+// Create stores IDs as hex strings, so every query matches _id on the string
 type ChatRepository struct {
 	collection *mongo.Collection
+	chatrooms  *mongo.Collection // Holds the denormalized stats updated on every new message
+	sequences  *mongo.Collection // Holds the sequence counter of every chatroom
+	removals   *mongo.Collection // Holds what's left of removed chat messages, so resuming clients replay their removal
 }
 
 func NewChatRepository(db *mongo.Database) (repository.ChatRepository, error) {
 	repo := &ChatRepository{
 		collection: db.Collection("chats"),
+		chatrooms:  db.Collection("chatrooms"),
+		sequences:  db.Collection("chatroom_sequences"),
+		removals:   db.Collection("chat_removals"),
 	}
 
 	if err := repo.ensureIndexes(context.Background()); err != nil {
@@ -39,6 +51,14 @@ func (r *ChatRepository) ensureIndexes(ctx context.Context) error {
 			},
 			Options: options.Index().SetName("chatroom_timestamp"),
 		},
+		{
+			Keys: bson.D{
+				{Key: "chatroom", Value: 1},
+				{Key: "createdTimestamp", Value: -1},
+				{Key: "_id", Value: -1},
+			},
+			Options: options.Index().SetName("chatroom_timestamp_id"),
+		},
 		{
 			Keys: bson.D{
 				{Key: "sender", Value: 1},
@@ -53,23 +73,66 @@ func (r *ChatRepository) ensureIndexes(ctx context.Context) error {
 			},
 			Options: options.Index().SetName("receiver_timestamp"),
 		},
+		{
+			Keys: bson.D{
+				{Key: "replyTo", Value: 1},
+				{Key: "createdTimestamp", Value: -1},
+			},
+			Options: options.Index().SetName("replyTo_timestamp").SetSparse(true),
+		},
+		{
+			Keys: bson.D{
+				{Key: "chatroom", Value: 1},
+				{Key: "updatedSequence", Value: 1},
+			},
+			Options: options.Index().SetName("chatroom_updatedSequence"),
+		},
+		{
+			Keys: bson.D{
+				{Key: "chatroom", Value: 1},
+				{Key: "sequence", Value: 1},
+			},
+			Options: options.Index().SetName("chatroom_sequence"),
+		},
+		{
+			Keys: bson.D{
+				{Key: "sender", Value: 1},
+				{Key: "clientMessageId", Value: 1},
+			},
+			Options: options.Index().
+				SetName("sender_clientMessageId").
+				SetUnique(true).
+				SetPartialFilterExpression(bson.M{"clientMessageId": bson.M{"$type": "string"}}),
+		},
+		{
+			Keys: bson.D{
+				{Key: "clientId", Value: 1},
+				{Key: "createdTimestamp", Value: -1},
+			},
+			Options: options.Index().SetName("clientId_timestamp"),
+		},
 	}
 
 	opts := options.CreateIndexes().SetMaxTime(10 * time.Second)
 
-	_, err := r.collection.Indexes().CreateMany(ctx, indexes, opts)
+	if _, err := r.collection.Indexes().CreateMany(ctx, indexes, opts); err != nil {
+		return err
+	}
+
+	_, err := r.removals.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{
+			{Key: "chatroom", Value: 1},
+			{Key: "updatedSequence", Value: 1},
+		},
+		Options: options.Index().SetName("chatroom_updatedSequence"),
+	}, opts)
 	return err
 }
 
 // Get retrieves a single chat message by ID
 func (r *ChatRepository) Get(ctx context.Context, id string) (*entity.Chat, error) {
-	objectID, err := primitive.ObjectIDFromHex(id)
-	if err != nil {
-		return nil, err
-	}
-
 	var chat entity.Chat
-	err = r.collection.FindOne(ctx, bson.M{"_id": objectID}).Decode(&chat)
+	err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&chat)
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
 			return nil, nil
@@ -82,13 +145,8 @@ func (r *ChatRepository) Get(ctx context.Context, id string) (*entity.Chat, erro
 
 // GetPopulated retrieves a single chat message with populated user references
 func (r *ChatRepository) GetPopulated(ctx context.Context, id string) (*entity.ChatPopulated, error) {
-	objectID, err := primitive.ObjectIDFromHex(id)
-	if err != nil {
-		return nil, err
-	}
-
 	pipeline := mongo.Pipeline{
-		{{Key: "$match", Value: bson.M{"_id": objectID}}},
+		{{Key: "$match", Value: bson.M{"_id": id}}},
 		{{Key: "$lookup", Value: bson.M{
 			"from":         "users",
 			"localField":   "sender",
@@ -128,6 +186,9 @@ func (r *ChatRepository) GetPopulated(ctx context.Context, id string) (*entity.C
 // GetAll retrieves multiple chat messages with filtering and pagination
 func (r *ChatRepository) GetAll(ctx context.Context, filter repository.ChatFilter, pag pagination.Pagination) ([]*entity.Chat, int64, error) {
 	query := bson.M{}
+	if filter.ClientID != "" {
+		query["clientId"] = filter.ClientID
+	}
 	if filter.ChatroomID != "" {
 		query["chatroom"] = filter.ChatroomID
 	}
@@ -137,6 +198,9 @@ func (r *ChatRepository) GetAll(ctx context.Context, filter repository.ChatFilte
 	if filter.ReceiverID != "" {
 		query["receiver"] = filter.ReceiverID
 	}
+	if filter.ReplyTo != "" {
+		query["replyTo"] = filter.ReplyTo
+	}
 	if filter.StartTime != nil {
 		query["createdTimestamp"] = bson.M{"$gte": *filter.StartTime}
 	}
@@ -172,9 +236,149 @@ func (r *ChatRepository) GetAll(ctx context.Context, filter repository.ChatFilte
 	return chats, total, nil
 }
 
+// GetAllByCursor retrieves chat messages newest first using keyset pagination on (createdTimestamp, _id)
+func (r *ChatRepository) GetAllByCursor(ctx context.Context, filter repository.ChatFilter, before *repository.ChatCursor, after *repository.ChatCursor, limit int) ([]*entity.Chat, bool, error) {
+	query := bson.M{}
+	if filter.ClientID != "" {
+		query["clientId"] = filter.ClientID
+	}
+	if filter.ChatroomID != "" {
+		query["chatroom"] = filter.ChatroomID
+	}
+	if filter.SenderID != "" {
+		query["sender"] = filter.SenderID
+	}
+	if filter.ReceiverID != "" {
+		query["receiver"] = filter.ReceiverID
+	}
+	if filter.ReplyTo != "" {
+		query["replyTo"] = filter.ReplyTo
+	}
+	if filter.StartTime != nil {
+		query["createdTimestamp"] = bson.M{"$gte": *filter.StartTime}
+	}
+	if filter.EndTime != nil {
+		if _, exists := query["createdTimestamp"]; exists {
+			query["createdTimestamp"].(bson.M)["$lte"] = *filter.EndTime
+		} else {
+			query["createdTimestamp"] = bson.M{"$lte": *filter.EndTime}
+		}
+	}
+
+	keyset := []bson.M{}
+	if before != nil {
+		keyset = append(keyset, bson.M{"$or": []bson.M{
+			{"createdTimestamp": bson.M{"$lt": before.Timestamp}},
+			{"createdTimestamp": before.Timestamp, "_id": bson.M{"$lt": before.ID}},
+		}})
+	}
+	if after != nil {
+		keyset = append(keyset, bson.M{"$or": []bson.M{
+			{"createdTimestamp": bson.M{"$gt": after.Timestamp}},
+			{"createdTimestamp": after.Timestamp, "_id": bson.M{"$gt": after.ID}},
+		}})
+	}
+	if len(keyset) > 0 {
+		query["$and"] = keyset
+	}
+
+	// Paging forward from an after cursor walks towards newer messages
+	ascending := after != nil && before == nil
+	direction := -1
+	if ascending {
+		direction = 1
+	}
+
+	// Fetch one extra message to know if there are more
+	opts := options.Find().
+		SetSort(bson.D{{Key: "createdTimestamp", Value: direction}, {Key: "_id", Value: direction}}).
+		SetLimit(int64(limit + 1))
+
+	cursor, err := r.collection.Find(ctx, query, opts)
+	if err != nil {
+		return nil, false, err
+	}
+	defer cursor.Close(ctx)
+
+	var chats []*entity.Chat
+	if err = cursor.All(ctx, &chats); err != nil {
+		return nil, false, err
+	}
+
+	hasMore := len(chats) > limit
+	if hasMore {
+		chats = chats[:limit]
+	}
+
+	// Always return newest first
+	if ascending {
+		for i, j := 0, len(chats)-1; i < j; i, j = i+1, j-1 {
+			chats[i], chats[j] = chats[j], chats[i]
+		}
+	}
+
+	return chats, hasMore, nil
+}
+
+// GetByClientMessageID retrieves a sender's chat message by the ID their client generated for it
+func (r *ChatRepository) GetByClientMessageID(ctx context.Context, senderID string, clientMessageID string) (*entity.Chat, error) {
+	var chat entity.Chat
+	err := r.collection.FindOne(ctx, bson.M{"sender": senderID, "clientMessageId": clientMessageID}).Decode(&chat)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &chat, nil
+}
+
+// GetAllAfterSequence retrieves a chatroom's chat messages created or changed after the given sequence, oldest change first
+// Removed messages are included as the tombstones recorded by DeleteBySender
+func (r *ChatRepository) GetAllAfterSequence(ctx context.Context, chatroomID string, sequence int64, limit int) ([]*entity.Chat, bool, error) {
+	// Fetch one extra message to know if there are more, from the messages and the removals alike
+	changes := bson.A{
+		bson.M{"$match": bson.M{
+			"chatroom":        chatroomID,
+			"updatedSequence": bson.M{"$gt": sequence},
+		}},
+		bson.M{"$sort": bson.M{"updatedSequence": 1}},
+		bson.M{"$limit": int64(limit + 1)},
+	}
+
+	pipeline := append(bson.A{}, changes...)
+	pipeline = append(pipeline,
+		bson.M{"$unionWith": bson.M{"coll": r.removals.Name(), "pipeline": changes}},
+		bson.M{"$sort": bson.M{"updatedSequence": 1}},
+		bson.M{"$limit": int64(limit + 1)},
+	)
+
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, false, err
+	}
+	defer cursor.Close(ctx)
+
+	var chats []*entity.Chat
+	if err = cursor.All(ctx, &chats); err != nil {
+		return nil, false, err
+	}
+
+	hasMore := len(chats) > limit
+	if hasMore {
+		chats = chats[:limit]
+	}
+
+	return chats, hasMore, nil
+}
+
 // GetAllPopulated retrieves multiple chat messages with populated user references
 func (r *ChatRepository) GetAllPopulated(ctx context.Context, filter repository.ChatFilter, pag pagination.Pagination) ([]*entity.ChatPopulated, int64, error) {
 	matchStage := bson.M{}
+	if filter.ClientID != "" {
+		matchStage["clientId"] = filter.ClientID
+	}
 	if filter.ChatroomID != "" {
 		matchStage["chatroom"] = filter.ChatroomID
 	}
@@ -184,6 +388,9 @@ func (r *ChatRepository) GetAllPopulated(ctx context.Context, filter repository.
 	if filter.ReceiverID != "" {
 		matchStage["receiver"] = filter.ReceiverID
 	}
+	if filter.ReplyTo != "" {
+		matchStage["replyTo"] = filter.ReplyTo
+	}
 	if filter.StartTime != nil {
 		matchStage["createdTimestamp"] = bson.M{"$gte": *filter.StartTime}
 	}
@@ -237,34 +444,462 @@ func (r *ChatRepository) GetAllPopulated(ctx context.Context, filter repository.
 	return chats, total, nil
 }
 
+// CountUnread counts, per chatroom, the messages not sent by the user that come
+// after the given sequence and haven't been deleted
+// Sequences are unique within a chatroom, unlike timestamps in seconds, so messages sent in the same second as the last read one still count
+func (r *ChatRepository) CountUnread(ctx context.Context, userID string, after map[string]int64) (map[string]int64, error) {
+	counts := make(map[string]int64, len(after))
+	if len(after) == 0 {
+		return counts, nil
+	}
+
+	conditions := make([]bson.M, 0, len(after))
+	for chatroomID, sequence := range after {
+		conditions = append(conditions, bson.M{
+			"chatroom": chatroomID,
+			"sequence": bson.M{"$gt": sequence},
+		})
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{
+			"$or":              conditions,
+			"sender":           bson.M{"$ne": userID},
+			"deletedTimestamp": bson.M{"$exists": false},
+		}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":   "$chatroom",
+			"count": bson.M{"$sum": 1},
+		}}},
+	}
+
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var results []struct {
+		Chatroom string `bson:"_id"`
+		Count    int64  `bson:"count"`
+	}
+	if err = cursor.All(ctx, &results); err != nil {
+		return nil, err
+	}
+
+	for _, result := range results {
+		counts[result.Chatroom] = result.Count
+	}
+
+	return counts, nil
+}
+
 // Create stores a new chat message
 func (r *ChatRepository) Create(ctx context.Context, chat *entity.Chat) error {
 	if chat.ID == "" {
 		chat.ID = primitive.NewObjectID().Hex()
 	}
 
-	_, err := r.collection.InsertOne(ctx, chat)
+	session, err := r.collection.Database().Client().StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+		return nil, r.insertWithStats(sc, chat)
+	})
+	if err == nil || !isTransactionUnsupported(err) {
+		return err
+	}
+
+	// Standalone servers don't support transactions, fall back to an ordered write
+	return r.insertWithStats(ctx, chat)
+}
+
+// insertWithStats assigns a chat message its chatroom's next sequence and inserts it, then updates its chatroom's stats
+func (r *ChatRepository) insertWithStats(ctx context.Context, chat *entity.Chat) error {
+	sequence, err := r.nextSequence(ctx, chat.Chatroom)
+	if err != nil {
+		return err
+	}
+	chat.Sequence = sequence
+	chat.UpdatedSequence = sequence
+
+	if _, err := r.collection.InsertOne(ctx, chat); err != nil {
+		return err
+	}
+
+	// Only move the last message forward, so concurrent sends can't overwrite a newer preview
+	isLatest := bson.M{"$gte": bson.A{chat.CreatedTimestamp, bson.M{"$ifNull": bson.A{"$lastMessageTimestamp", 0}}}}
+	update := mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{
+			"messagesCount":        bson.M{"$add": bson.A{bson.M{"$ifNull": bson.A{"$messagesCount", 0}}, 1}},
+			"lastMessage":          bson.M{"$cond": bson.A{isLatest, bson.M{"$literal": chat.Message}, "$lastMessage"}},
+			"lastSender":           bson.M{"$cond": bson.A{isLatest, bson.M{"$literal": chat.Sender}, "$lastSender"}},
+			"lastMessageTimestamp": bson.M{"$cond": bson.A{isLatest, chat.CreatedTimestamp, "$lastMessageTimestamp"}},
+		}}},
+	}
+
+	_, err = r.chatrooms.UpdateOne(ctx, bson.M{"_id": chat.Chatroom}, update)
 	return err
 }
 
+// Touch assigns the chatroom's next sequence to a chat message after it changed
+func (r *ChatRepository) Touch(ctx context.Context, id string, chatroomID string) (int64, error) {
+	sequence, err := r.nextSequence(ctx, chatroomID)
+	if err != nil {
+		return 0, err
+	}
+
+	// Concurrent changes can finish out of order, only ever move the sequence forward
+	_, err = r.collection.UpdateOne(
+		ctx,
+		bson.M{"_id": id, "updatedSequence": bson.M{"$not": bson.M{"$gte": sequence}}},
+		bson.M{"$set": bson.M{"updatedSequence": sequence}},
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	return sequence, nil
+}
+
+// nextSequence increments and returns a chatroom's sequence
+// Counters live in their own collection so replacing a chatroom document can't reset them
+func (r *ChatRepository) nextSequence(ctx context.Context, chatroomID string) (int64, error) {
+	return r.reserveSequences(ctx, chatroomID, 1)
+}
+
+// reserveSequences advances a chatroom's sequence by count and returns the first of the reserved sequences
+func (r *ChatRepository) reserveSequences(ctx context.Context, chatroomID string, count int) (int64, error) {
+	var counter struct {
+		Sequence int64 `bson:"sequence"`
+	}
+
+	err := r.sequences.FindOneAndUpdate(
+		ctx,
+		bson.M{"_id": chatroomID},
+		bson.M{"$inc": bson.M{"sequence": count}},
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+	).Decode(&counter)
+	if err != nil {
+		return 0, err
+	}
+
+	return counter.Sequence - int64(count) + 1, nil
+}
+
+// isTransactionUnsupported checks if an error comes from running a transaction on a standalone server
+func isTransactionUnsupported(err error) bool {
+	var cmdErr mongo.CommandError
+	if errors.As(err, &cmdErr) {
+		// IllegalOperation: Transaction numbers are only allowed on a replica set member or mongos
+		return cmdErr.Code == 20
+	}
+
+	return false
+}
+
 // Update modifies an existing chat message
 func (r *ChatRepository) Update(ctx context.Context, chat *entity.Chat) error {
-	objectID, err := primitive.ObjectIDFromHex(chat.ID)
+	_, err := r.collection.ReplaceOne(ctx, bson.M{"_id": chat.ID}, chat)
+	return err
+}
+
+// Edit replaces the content of a chat message and appends the previous content to its edit history
+func (r *ChatRepository) Edit(ctx context.Context, id string, message string, edit entity.ChatEdit) error {
+	result, err := r.collection.UpdateOne(
+		ctx,
+		bson.M{
+			"_id":              id,
+			"deletedTimestamp": bson.M{"$exists": false},
+		},
+		bson.M{
+			"$set": bson.M{
+				"message":         message,
+				"editedTimestamp": edit.EditedTimestamp,
+			},
+			"$push": bson.M{"editHistory": edit},
+		},
+	)
 	if err != nil {
 		return err
 	}
 
-	_, err = r.collection.ReplaceOne(ctx, bson.M{"_id": objectID}, chat)
-	return err
+	// The message was deleted, possibly by a concurrent request
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+
+	return nil
+}
+
+// ToggleReaction adds the user's reaction if it doesn't exist yet, or removes it otherwise
+func (r *ChatRepository) ToggleReaction(ctx context.Context, id string, reaction entity.ChatReaction) (bool, error) {
+	existing := bson.M{"emoji": reaction.Emoji, "user": reaction.User}
+
+	// Remove the reaction if the user already reacted with this emoji
+	result, err := r.collection.UpdateOne(
+		ctx,
+		bson.M{"_id": id, "reactions": bson.M{"$elemMatch": existing}},
+		bson.M{"$pull": bson.M{"reactions": existing}},
+	)
+	if err != nil {
+		return false, err
+	}
+	if result.ModifiedCount > 0 {
+		return false, nil
+	}
+
+	// Otherwise add it, guarding against a concurrent toggle adding it first
+	result, err = r.collection.UpdateOne(
+		ctx,
+		bson.M{"_id": id, "reactions": bson.M{"$not": bson.M{"$elemMatch": existing}}},
+		bson.M{"$push": bson.M{"reactions": reaction}},
+	)
+	if err != nil {
+		return false, err
+	}
+
+	return result.ModifiedCount > 0, nil
+}
+
+// SoftDelete turns a chat message into a tombstone, clearing its content, edit history, attachments and metadata
+func (r *ChatRepository) SoftDelete(ctx context.Context, id string, deletedBy string, timestamp int64) error {
+	result, err := r.collection.UpdateOne(
+		ctx,
+		bson.M{"_id": id},
+		bson.M{
+			"$set": bson.M{
+				"message":          "",
+				"deletedTimestamp": timestamp,
+				"deletedBy":        deletedBy,
+			},
+			"$unset": bson.M{"editHistory": "", "attachments": "", "metadata": ""},
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+
+	return nil
 }
 
 // Delete removes a chat message
 func (r *ChatRepository) Delete(ctx context.Context, id string) error {
-	objectID, err := primitive.ObjectIDFromHex(id)
+	_, err := r.collection.DeleteOne(ctx, bson.M{"_id": id})
+	return err
+}
+
+// ForEachByUser calls fn with every chat message the user sent or received directly, oldest first
+func (r *ChatRepository) ForEachByUser(ctx context.Context, userID string, fn func(chat *entity.Chat) error) error {
+	query := bson.M{"$or": []bson.M{
+		{"sender": userID},
+		{"receiver": userID},
+	}}
+	opts := options.Find().SetSort(bson.D{{Key: "createdTimestamp", Value: 1}, {Key: "_id", Value: 1}})
+
+	cursor, err := r.collection.Find(ctx, query, opts)
 	if err != nil {
 		return err
 	}
+	defer cursor.Close(ctx)
 
-	_, err = r.collection.DeleteOne(ctx, bson.M{"_id": objectID})
-	return err
+	for cursor.Next(ctx) {
+		var chat entity.Chat
+		if err := cursor.Decode(&chat); err != nil {
+			return err
+		}
+		if err := fn(&chat); err != nil {
+			return err
+		}
+	}
+
+	return cursor.Err()
+}
+
+// GetChatroomIDsBySender retrieves the IDs of every chatroom the user sent chat messages to
+func (r *ChatRepository) GetChatroomIDsBySender(ctx context.Context, senderID string) ([]string, error) {
+	values, err := r.collection.Distinct(ctx, "chatroom", bson.M{"sender": senderID})
+	if err != nil {
+		return nil, err
+	}
+
+	chatroomIDs := make([]string, 0, len(values))
+	for _, value := range values {
+		if id, ok := value.(string); ok {
+			chatroomIDs = append(chatroomIDs, id)
+		}
+	}
+
+	return chatroomIDs, nil
+}
+
+// SoftDeleteBySender turns the sender's chat messages in a chatroom into tombstones, also clearing their attachments and metadata
+// Messages are changed in batches, each batch reserving one sequence per message so resuming clients replay every tombstone
+func (r *ChatRepository) SoftDeleteBySender(ctx context.Context, chatroomID string, senderID string, timestamp int64) ([]*entity.Chat, error) {
+	// Tombstones deleted earlier may still hold attachments and metadata
+	query := bson.M{
+		"chatroom": chatroomID,
+		"sender":   senderID,
+		"$or": []bson.M{
+			{"deletedTimestamp": bson.M{"$exists": false}},
+			{"attachments": bson.M{"$exists": true}},
+			{"metadata": bson.M{"$exists": true}},
+		},
+	}
+
+	tombstones := []*entity.Chat{}
+	for {
+		chats, sequence, err := r.nextErasureBatch(ctx, chatroomID, query)
+		if err != nil || len(chats) == 0 {
+			return tombstones, err
+		}
+
+		models := make([]mongo.WriteModel, len(chats))
+		for i, chat := range chats {
+			update := mongo.Pipeline{
+				{{Key: "$set", Value: bson.M{
+					"message":          "",
+					"deletedTimestamp": bson.M{"$ifNull": bson.A{"$deletedTimestamp", timestamp}},
+					"deletedBy":        bson.M{"$ifNull": bson.A{"$deletedBy", senderID}},
+					"updatedSequence":  sequence + int64(i),
+				}}},
+				{{Key: "$unset", Value: bson.A{"editHistory", "attachments", "metadata"}}},
+			}
+			models[i] = mongo.NewUpdateOneModel().SetFilter(bson.M{"_id": chat.ID}).SetUpdate(update)
+
+			if chat.DeletedTimestamp == nil {
+				chat.DeletedTimestamp = &timestamp
+			}
+			if chat.DeletedBy == nil {
+				chat.DeletedBy = &senderID
+			}
+			chat.UpdatedSequence = sequence + int64(i)
+		}
+
+		if _, err := r.collection.BulkWrite(ctx, models, options.BulkWrite().SetOrdered(false)); err != nil {
+			return tombstones, err
+		}
+		tombstones = append(tombstones, chats...)
+	}
+}
+
+// DeleteBySender removes the sender's chat messages in a chatroom
+// Messages are removed in batches, each batch reserving one sequence per message that is recorded in the removals
+// before the messages are removed, so resuming clients replay every removal
+func (r *ChatRepository) DeleteBySender(ctx context.Context, chatroomID string, senderID string, timestamp int64) ([]*entity.Chat, error) {
+	query := bson.M{"chatroom": chatroomID, "sender": senderID}
+
+	removed := []*entity.Chat{}
+	for {
+		chats, sequence, err := r.nextErasureBatch(ctx, chatroomID, query)
+		if err != nil || len(chats) == 0 {
+			return removed, err
+		}
+
+		ids := make([]string, len(chats))
+		removals := make([]*entity.Chat, len(chats))
+		models := make([]mongo.WriteModel, len(chats))
+		for i, chat := range chats {
+			// Only what a resuming client needs to drop the message is kept, not who sent it
+			removal := &entity.Chat{
+				ID:               chat.ID,
+				ClientID:         chat.ClientID,
+				Chatroom:         chat.Chatroom,
+				CreatedTimestamp: chat.CreatedTimestamp,
+				DeletedTimestamp: &timestamp,
+				Sequence:         chat.Sequence,
+				UpdatedSequence:  sequence + int64(i),
+			}
+			ids[i] = chat.ID
+			models[i] = mongo.NewReplaceOneModel().
+				SetFilter(bson.M{"_id": chat.ID}).
+				SetReplacement(removal).
+				SetUpsert(true)
+			removals[i] = removal
+		}
+
+		if _, err := r.removals.BulkWrite(ctx, models, options.BulkWrite().SetOrdered(false)); err != nil {
+			return removed, err
+		}
+		if _, err := r.collection.DeleteMany(ctx, bson.M{"_id": bson.M{"$in": ids}}); err != nil {
+			return removed, err
+		}
+		removed = append(removed, removals...)
+	}
+}
+
+// nextErasureBatch retrieves the next batch of chat messages matching the query, holding only what their tombstones keep,
+// and reserves one sequence for each of them
+// Returns the first reserved sequence
+func (r *ChatRepository) nextErasureBatch(ctx context.Context, chatroomID string, query bson.M) ([]*entity.Chat, int64, error) {
+	opts := options.Find().
+		SetSort(bson.D{{Key: "createdTimestamp", Value: 1}}).
+		SetProjection(bson.M{
+			"clientId":         1,
+			"chatroom":         1,
+			"sender":           1,
+			"createdTimestamp": 1,
+			"deletedTimestamp": 1,
+			"deletedBy":        1,
+			"sequence":         1,
+		}).
+		SetLimit(erasureBatchSize)
+
+	cursor, err := r.collection.Find(ctx, query, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var chats []*entity.Chat
+	if err = cursor.All(ctx, &chats); err != nil {
+		return nil, 0, err
+	}
+	if len(chats) == 0 {
+		return nil, 0, nil
+	}
+
+	sequence, err := r.reserveSequences(ctx, chatroomID, len(chats))
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return chats, sequence, nil
+}
+
+// BackfillClientID copies the client of each chatroom to its messages stored before messages belonged to a client
+func (r *ChatRepository) BackfillClientID(ctx context.Context) (int64, error) {
+	query := bson.M{"clientId": bson.M{"$nin": bson.A{nil, ""}}}
+	opts := options.Find().SetProjection(bson.M{"clientId": 1})
+
+	cursor, err := r.chatrooms.Find(ctx, query, opts)
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(ctx)
+
+	var updated int64
+	for cursor.Next(ctx) {
+		var chatroom entity.Chatroom
+		if err := cursor.Decode(&chatroom); err != nil {
+			return updated, err
+		}
+
+		result, err := r.collection.UpdateMany(ctx,
+			bson.M{"chatroom": chatroom.ID, "clientId": bson.M{"$in": bson.A{nil, ""}}},
+			bson.M{"$set": bson.M{"clientId": chatroom.ClientID}},
+		)
+		if err != nil {
+			return updated, err
+		}
+		updated += result.ModifiedCount
+	}
+
+	return updated, cursor.Err()
 }
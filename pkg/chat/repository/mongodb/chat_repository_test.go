@@ -0,0 +1,55 @@
+package mongodb
+
+import (
+	"context"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestCountUnreadCountsMessagesAfterTheReadSequence(t *testing.T) {
+	ctx := context.Background()
+	db := testDatabase(t)
+
+	// Every message of room-1 is sent in the same second, only sequences tell them apart
+	second := int64(1773144000)
+	chats := []interface{}{
+		bson.M{"_id": "chat-1", "chatroom": "room-1", "sender": "user-b", "message": "read", "createdTimestamp": second, "sequence": 1},
+		bson.M{"_id": "chat-2", "chatroom": "room-1", "sender": "user-b", "message": "last read", "createdTimestamp": second, "sequence": 2},
+		bson.M{"_id": "chat-3", "chatroom": "room-1", "sender": "user-b", "message": "unread", "createdTimestamp": second, "sequence": 3},
+		bson.M{"_id": "chat-4", "chatroom": "room-1", "sender": "user-a", "message": "own", "createdTimestamp": second, "sequence": 4},
+		bson.M{"_id": "chat-5", "chatroom": "room-1", "sender": "user-b", "message": "", "createdTimestamp": second, "sequence": 5, "deletedTimestamp": second + 1},
+		bson.M{"_id": "chat-6", "chatroom": "room-1", "sender": "user-c", "message": "unread", "createdTimestamp": second, "sequence": 6},
+		bson.M{"_id": "chat-7", "chatroom": "room-2", "sender": "user-b", "message": "never read", "createdTimestamp": second, "sequence": 1},
+		bson.M{"_id": "chat-8", "chatroom": "room-3", "sender": "user-b", "message": "all read", "createdTimestamp": second, "sequence": 1},
+		bson.M{"_id": "chat-9", "chatroom": "room-4", "sender": "user-b", "message": "not asked", "createdTimestamp": second, "sequence": 1},
+	}
+	if _, err := db.Collection("chats").InsertMany(ctx, chats); err != nil {
+		t.Fatalf("Failed to store chats: %v", err)
+	}
+
+	chatRepository, err := NewChatRepository(db)
+	if err != nil {
+		t.Fatalf("Failed to create chat repository: %v", err)
+	}
+
+	counts, err := chatRepository.CountUnread(ctx, "user-a", map[string]int64{"room-1": 2, "room-2": 0, "room-3": 1})
+	if err != nil {
+		t.Fatalf("CountUnread: %v", err)
+	}
+
+	want := map[string]int64{"room-1": 2, "room-2": 1}
+	if len(counts) != len(want) {
+		t.Errorf("CountUnread = %v, want %v", counts, want)
+	}
+	for chatroomID, count := range want {
+		if counts[chatroomID] != count {
+			t.Errorf("CountUnread[%s] = %d, want %d", chatroomID, counts[chatroomID], count)
+		}
+	}
+
+	counts, err = chatRepository.CountUnread(ctx, "user-a", nil)
+	if err != nil || len(counts) != 0 {
+		t.Errorf("CountUnread without chatrooms = %v, %v, want no counts", counts, err)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"app/pkg/chat/domain/repository"
 	"app/pkg/types/pagination"
 	"context"
+	"fmt"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
@@ -46,6 +47,37 @@ func (r *ChatroomRepository) ensureIndexes(ctx context.Context) error {
 			},
 			Options: options.Index().SetName("type_timestamp"),
 		},
+		{
+			// Supports ranking public chatrooms by activity for discovery
+			Keys: bson.D{
+				{Key: "type", Value: 1},
+				{Key: "messagesCount", Value: -1},
+				{Key: "lastMessageTimestamp", Value: -1},
+			},
+			Options: options.Index().SetName("type_activity"),
+		},
+		{
+			// Supports discovery within a client
+			Keys: bson.D{
+				{Key: "clientId", Value: 1},
+				{Key: "type", Value: 1},
+				{Key: "messagesCount", Value: -1},
+				{Key: "lastMessageTimestamp", Value: -1},
+			},
+			Options: options.Index().SetName("clientId_type_activity"),
+		},
+		{
+			Keys:    bson.D{{Key: "name", Value: "text"}},
+			Options: options.Index().SetName("name_text"),
+		},
+		{
+			// A pair of users can only have one direct chatroom
+			Keys: bson.D{{Key: "directKey", Value: 1}},
+			Options: options.Index().
+				SetName("directKey").
+				SetUnique(true).
+				SetPartialFilterExpression(bson.M{"directKey": bson.M{"$type": "string"}}),
+		},
 	}
 
 	opts := options.CreateIndexes().SetMaxTime(10 * time.Second)
@@ -56,13 +88,8 @@ func (r *ChatroomRepository) ensureIndexes(ctx context.Context) error {
 
 // Get retrieves a single chatroom by ID
 func (r *ChatroomRepository) Get(ctx context.Context, id string) (*entity.Chatroom, error) {
-	objectID, err := primitive.ObjectIDFromHex(id)
-	if err != nil {
-		return nil, err
-	}
-
 	var chatroom entity.Chatroom
-	err = r.collection.FindOne(ctx, bson.M{"_id": objectID}).Decode(&chatroom)
+	err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&chatroom)
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
 			return nil, nil
@@ -75,13 +102,8 @@ func (r *ChatroomRepository) Get(ctx context.Context, id string) (*entity.Chatro
 
 // GetPopulated retrieves a single chatroom with populated user references
 func (r *ChatroomRepository) GetPopulated(ctx context.Context, id string) (*entity.ChatroomPopulated, error) {
-	objectID, err := primitive.ObjectIDFromHex(id)
-	if err != nil {
-		return nil, err
-	}
-
 	pipeline := mongo.Pipeline{
-		{{Key: "$match", Value: bson.M{"_id": objectID}}},
+		{{Key: "$match", Value: bson.M{"_id": id}}},
 		{{Key: "$lookup", Value: bson.M{
 			"from":         "users",
 			"localField":   "lastSender",
@@ -134,6 +156,9 @@ func (r *ChatroomRepository) GetPopulated(ctx context.Context, id string) (*enti
 // GetAll retrieves multiple chatrooms with filtering and pagination
 func (r *ChatroomRepository) GetAll(ctx context.Context, filter repository.ChatroomFilter, pag pagination.Pagination) ([]*entity.Chatroom, int64, error) {
 	query := bson.M{}
+	if filter.ClientID != "" {
+		query["clientId"] = filter.ClientID
+	}
 	if filter.ParticipantID != "" {
 		query["participants.user"] = filter.ParticipantID
 	}
@@ -178,9 +203,91 @@ func (r *ChatroomRepository) GetAll(ctx context.Context, filter repository.Chatr
 	return chatrooms, total, nil
 }
 
+// GetIDsByParticipant retrieves the IDs of every chatroom the user participates in
+func (r *ChatroomRepository) GetIDsByParticipant(ctx context.Context, userID string) ([]string, error) {
+	opts := options.Find().SetProjection(bson.M{"_id": 1})
+
+	cursor, err := r.collection.Find(ctx, bson.M{"participants.user": userID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var chatrooms []struct {
+		ID string `bson:"_id"`
+	}
+	if err = cursor.All(ctx, &chatrooms); err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, 0, len(chatrooms))
+	for _, chatroom := range chatrooms {
+		ids = append(ids, chatroom.ID)
+	}
+
+	return ids, nil
+}
+
+// SharesGroup checks if both users participate in at least one group chatroom
+func (r *ChatroomRepository) SharesGroup(ctx context.Context, userID string, otherUserID string) (bool, error) {
+	query := bson.M{
+		"isGroup":           true,
+		"participants.user": bson.M{"$all": []string{userID, otherUserID}},
+	}
+
+	count, err := r.collection.CountDocuments(ctx, query, options.Count().SetLimit(1))
+	if err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
+
+// GetSharedParticipants retrieves which of the given users participate in at least one chatroom with the user
+func (r *ChatroomRepository) GetSharedParticipants(ctx context.Context, userID string, userIDs []string) ([]string, error) {
+	if len(userIDs) == 0 {
+		return []string{}, nil
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{
+			"$and": bson.A{
+				bson.M{"participants.user": userID},
+				bson.M{"participants.user": bson.M{"$in": userIDs}},
+			},
+		}}},
+		{{Key: "$unwind", Value: "$participants"}},
+		{{Key: "$match", Value: bson.M{"participants.user": bson.M{"$in": userIDs}}}},
+		{{Key: "$group", Value: bson.M{"_id": "$participants.user"}}},
+	}
+
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var results []struct {
+		ID string `bson:"_id"`
+	}
+	if err := cursor.All(ctx, &results); err != nil {
+		return nil, err
+	}
+
+	shared := make([]string, len(results))
+	for i, result := range results {
+		shared[i] = result.ID
+	}
+
+	return shared, nil
+}
+
 // GetAllPopulated retrieves multiple chatrooms with populated user references
 func (r *ChatroomRepository) GetAllPopulated(ctx context.Context, filter repository.ChatroomFilter, pag pagination.Pagination) ([]*entity.ChatroomPopulated, int64, error) {
 	matchStage := bson.M{}
+	if filter.ClientID != "" {
+		matchStage["clientId"] = filter.ClientID
+	}
 	if filter.ParticipantID != "" {
 		matchStage["participants.user"] = filter.ParticipantID
 	}
@@ -256,6 +363,57 @@ func (r *ChatroomRepository) GetAllPopulated(ctx context.Context, filter reposit
 	return chatrooms, total, nil
 }
 
+// Discover retrieves public group chatrooms ranked by activity, most messages and most recent activity first
+func (r *ChatroomRepository) Discover(ctx context.Context, filter repository.ChatroomDiscoveryFilter, pag pagination.Pagination) ([]*entity.ChatroomListing, int64, error) {
+	matchStage := bson.M{
+		"clientId": filter.ClientID,
+		"type":     entity.ChatroomTypePublic,
+		"isGroup":  true,
+	}
+	if filter.Query != "" {
+		matchStage["$text"] = bson.M{"$search": filter.Query}
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: matchStage}},
+		{{Key: "$sort", Value: bson.D{
+			{Key: "messagesCount", Value: -1},
+			{Key: "lastMessageTimestamp", Value: -1},
+			{Key: "_id", Value: -1},
+		}}},
+		{{Key: "$skip", Value: int64((pag.Page - 1) * pag.Limit)}},
+		{{Key: "$limit", Value: int64(pag.Limit)}},
+		{{Key: "$project", Value: bson.M{
+			"name":                 1,
+			"type":                 1,
+			"createdTimestamp":     1,
+			"lastMessageTimestamp": 1,
+			"messagesCount":        1,
+			"maxParticipants":      1,
+			"participantsCount":    bson.M{"$size": "$participants"},
+			"joined":               bson.M{"$in": bson.A{filter.UserID, "$participants.user"}},
+		}}},
+	}
+
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	chatrooms := make([]*entity.ChatroomListing, 0)
+	if err = cursor.All(ctx, &chatrooms); err != nil {
+		return nil, 0, err
+	}
+
+	total, err := r.collection.CountDocuments(ctx, matchStage)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return chatrooms, total, nil
+}
+
 // Create stores a new chatroom
 func (r *ChatroomRepository) Create(ctx context.Context, chatroom *entity.Chatroom) error {
 	if chatroom.ID == "" {
@@ -266,53 +424,209 @@ func (r *ChatroomRepository) Create(ctx context.Context, chatroom *entity.Chatro
 	return err
 }
 
-// Update modifies an existing chatroom
-func (r *ChatroomRepository) Update(ctx context.Context, chatroom *entity.Chatroom) error {
-	objectID, err := primitive.ObjectIDFromHex(chatroom.ID)
+// FindOrCreateDirect atomically retrieves the chatroom with the given chatroom's direct key, or stores the given chatroom
+func (r *ChatroomRepository) FindOrCreateDirect(ctx context.Context, chatroom *entity.Chatroom) (*entity.Chatroom, bool, error) {
+	if chatroom.DirectKey == "" {
+		return nil, false, fmt.Errorf("direct key is required")
+	}
+	if chatroom.ID == "" {
+		chatroom.ID = primitive.NewObjectID().Hex()
+	}
+
+	filter := bson.M{"directKey": chatroom.DirectKey}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+
+	var result entity.Chatroom
+	err := r.collection.FindOneAndUpdate(ctx, filter, bson.M{"$setOnInsert": chatroom}, opts).Decode(&result)
+	if mongo.IsDuplicateKeyError(err) {
+		// Another upsert inserted the chatroom between our lookup and insert
+		err = r.collection.FindOne(ctx, filter).Decode(&result)
+	}
 	if err != nil {
-		return err
+		return nil, false, err
 	}
 
-	_, err = r.collection.ReplaceOne(ctx, bson.M{"_id": objectID}, chatroom)
-	return err
+	return &result, result.ID == chatroom.ID, nil
 }
 
-// Delete removes a chatroom
-func (r *ChatroomRepository) Delete(ctx context.Context, id string) error {
-	objectID, err := primitive.ObjectIDFromHex(id)
+// BackfillDirectKeys sets the direct key of direct chatrooms created before direct keys existed
+func (r *ChatroomRepository) BackfillDirectKeys(ctx context.Context) (int64, int64, error) {
+	query := bson.M{
+		"type":         entity.ChatroomTypePrivate,
+		"isGroup":      false,
+		"participants": bson.M{"$size": 2},
+		"directKey":    bson.M{"$exists": false},
+	}
+	opts := options.Find().
+		SetSort(bson.D{{Key: "createdTimestamp", Value: 1}}).
+		SetProjection(bson.M{"participants.user": 1})
+
+	cursor, err := r.collection.Find(ctx, query, opts)
 	if err != nil {
-		return err
+		return 0, 0, err
 	}
+	defer cursor.Close(ctx)
 
-	_, err = r.collection.DeleteOne(ctx, bson.M{"_id": objectID})
-	return err
+	var updated, duplicates int64
+	for cursor.Next(ctx) {
+		var chatroom entity.Chatroom
+		if err := cursor.Decode(&chatroom); err != nil {
+			return updated, duplicates, err
+		}
+
+		key := entity.DirectChatroomKey(chatroom.Participants[0].User, chatroom.Participants[1].User)
+		_, err := r.collection.UpdateOne(ctx, bson.M{"_id": chatroom.ID}, bson.M{"$set": bson.M{"directKey": key}})
+		if mongo.IsDuplicateKeyError(err) {
+			duplicates++
+			continue
+		}
+		if err != nil {
+			return updated, duplicates, err
+		}
+		updated++
+	}
+
+	return updated, duplicates, cursor.Err()
+}
+
+// BackfillClientID assigns chatrooms created before chatrooms belonged to a client to the given client
+func (r *ChatroomRepository) BackfillClientID(ctx context.Context, clientID string) (int64, error) {
+	result, err := r.collection.UpdateMany(ctx,
+		bson.M{"clientId": bson.M{"$in": bson.A{nil, ""}}},
+		bson.M{"$set": bson.M{"clientId": clientID}},
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.ModifiedCount, nil
 }
 
-// AddParticipant adds a participant to a chatroom
-func (r *ChatroomRepository) AddParticipant(ctx context.Context, chatroomID string, participant entity.ChatroomParticipant) error {
-	objectID, err := primitive.ObjectIDFromHex(chatroomID)
+// UpdateSettings changes the given settings of a chatroom, leaving the rest of the chatroom as it is
+// Returns the updated chatroom, or nil if it doesn't exist
+func (r *ChatroomRepository) UpdateSettings(ctx context.Context, id string, update repository.ChatroomUpdate) (*entity.Chatroom, error) {
+	// Only the changed fields are written, so participants and stats updated meanwhile aren't overwritten
+	set := bson.M{}
+	unset := bson.M{}
+	if update.Name != nil {
+		set["name"] = *update.Name
+	}
+	if update.JoinPolicy != nil {
+		set["joinPolicy"] = *update.JoinPolicy
+	}
+	if update.MaxParticipants != nil {
+		if *update.MaxParticipants == 0 {
+			unset["maxParticipants"] = ""
+		} else {
+			set["maxParticipants"] = *update.MaxParticipants
+		}
+	}
+
+	changes := bson.M{}
+	if len(set) > 0 {
+		changes["$set"] = set
+	}
+	if len(unset) > 0 {
+		changes["$unset"] = unset
+	}
+	if len(changes) == 0 {
+		return r.Get(ctx, id)
+	}
+
+	var chatroom entity.Chatroom
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	err := r.collection.FindOneAndUpdate(ctx, bson.M{"_id": id}, changes, opts).Decode(&chatroom)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &chatroom, nil
+}
+
+// RecomputeStats recomputes the last message and message count of chatrooms from their chat messages
+// Deleted messages are left out, so deleting the last message moves the preview back to the one before it
+func (r *ChatroomRepository) RecomputeStats(ctx context.Context, chatroomID string) error {
+	matchStage := bson.M{}
+	if chatroomID != "" {
+		matchStage["_id"] = chatroomID
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: matchStage}},
+		{{Key: "$lookup", Value: bson.M{
+			"from":         "chats",
+			"localField":   "_id",
+			"foreignField": "chatroom",
+			"pipeline": bson.A{
+				bson.M{"$match": bson.M{"deletedTimestamp": bson.M{"$exists": false}}},
+				bson.M{"$sort": bson.D{{Key: "createdTimestamp", Value: -1}, {Key: "_id", Value: -1}}},
+				bson.M{"$group": bson.M{
+					"_id":   nil,
+					"count": bson.M{"$sum": 1},
+					"last":  bson.M{"$first": "$$ROOT"},
+				}},
+			},
+			"as": "stats",
+		}}},
+		{{Key: "$set", Value: bson.M{"stats": bson.M{"$arrayElemAt": bson.A{"$stats", 0}}}}},
+		{{Key: "$project", Value: bson.M{
+			"messagesCount":        bson.M{"$ifNull": bson.A{"$stats.count", 0}},
+			"lastMessage":          bson.M{"$ifNull": bson.A{"$stats.last.message", nil}},
+			"lastSender":           bson.M{"$ifNull": bson.A{"$stats.last.sender", nil}},
+			"lastMessageTimestamp": bson.M{"$ifNull": bson.A{"$stats.last.createdTimestamp", nil}},
+		}}},
+		{{Key: "$merge", Value: bson.M{
+			"into":           r.collection.Name(),
+			"on":             "_id",
+			"whenMatched":    "merge",
+			"whenNotMatched": "discard",
+		}}},
+	}
+
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
 	if err != nil {
 		return err
 	}
+	return cursor.Close(ctx)
+}
 
-	_, err = r.collection.UpdateOne(
+// Delete removes a chatroom
+func (r *ChatroomRepository) Delete(ctx context.Context, id string) error {
+	_, err := r.collection.DeleteOne(ctx, bson.M{"_id": id})
+	return err
+}
+
+// AddParticipant atomically adds a participant to a chatroom
+func (r *ChatroomRepository) AddParticipant(ctx context.Context, chatroomID string, participant entity.ChatroomParticipant, maxParticipants int) (bool, error) {
+	query := bson.M{
+		"_id":               chatroomID,
+		"participants.user": bson.M{"$ne": participant.User},
+	}
+	if maxParticipants > 0 {
+		// The chatroom is full once the array has an element at the last allowed position
+		query[fmt.Sprintf("participants.%d", maxParticipants-1)] = bson.M{"$exists": false}
+	}
+
+	result, err := r.collection.UpdateOne(
 		ctx,
-		bson.M{"_id": objectID},
+		query,
 		bson.M{"$push": bson.M{"participants": participant}},
 	)
-	return err
+	if err != nil {
+		return false, err
+	}
+
+	return result.ModifiedCount > 0, nil
 }
 
 // RemoveParticipant removes a participant from a chatroom
 func (r *ChatroomRepository) RemoveParticipant(ctx context.Context, chatroomID string, userID string) error {
-	objectID, err := primitive.ObjectIDFromHex(chatroomID)
-	if err != nil {
-		return err
-	}
-
-	_, err = r.collection.UpdateOne(
+	_, err := r.collection.UpdateOne(
 		ctx,
-		bson.M{"_id": objectID},
+		bson.M{"_id": chatroomID},
 		bson.M{"$pull": bson.M{"participants": bson.M{"user": userID}}},
 	)
 	return err
@@ -320,15 +634,10 @@ func (r *ChatroomRepository) RemoveParticipant(ctx context.Context, chatroomID s
 
 // UpdateParticipant updates a participant's properties in a chatroom
 func (r *ChatroomRepository) UpdateParticipant(ctx context.Context, chatroomID string, participant entity.ChatroomParticipant) error {
-	objectID, err := primitive.ObjectIDFromHex(chatroomID)
-	if err != nil {
-		return err
-	}
-
-	_, err = r.collection.UpdateOne(
+	_, err := r.collection.UpdateOne(
 		ctx,
 		bson.M{
-			"_id":               objectID,
+			"_id":               chatroomID,
 			"participants.user": participant.User,
 		},
 		bson.M{"$set": bson.M{
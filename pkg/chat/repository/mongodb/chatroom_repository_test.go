@@ -0,0 +1,90 @@
+package mongodb
+
+import (
+	"app/pkg/chat/domain/entity"
+	"context"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestChatroomRepositoryMatchesStoredChatrooms(t *testing.T) {
+	ctx := context.Background()
+	db := testDatabase(t)
+
+	chatrooms, err := NewChatroomRepository(db)
+	if err != nil {
+		t.Fatalf("Failed to create chatroom repository: %v", err)
+	}
+
+	now := time.Now().Unix()
+	chatroom := &entity.Chatroom{
+		ClientID:         "client-1",
+		Name:             "Squad",
+		IsGroup:          true,
+		Type:             entity.ChatroomTypeSquad,
+		CreatedTimestamp: now,
+		Participants: []entity.ChatroomParticipant{
+			{User: "user-a", Role: entity.ParticipantRoleSuperAdmin, JoinedTimestamp: now},
+		},
+		MaxParticipants: 2,
+	}
+	if err := chatrooms.Create(ctx, chatroom); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	stored, err := chatrooms.Get(ctx, chatroom.ID)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if stored == nil || stored.Name != "Squad" {
+		t.Fatalf("Get = %+v, want the created chatroom", stored)
+	}
+
+	tests := []struct {
+		name  string
+		user  string
+		added bool
+	}{
+		{"new participant", "user-b", true},
+		{"existing participant", "user-b", false},
+		{"chatroom full", "user-c", false},
+	}
+	for _, tt := range tests {
+		participant := entity.ChatroomParticipant{User: tt.user, Role: entity.ParticipantRoleMember, JoinedTimestamp: now}
+		added, err := chatrooms.AddParticipant(ctx, chatroom.ID, participant, chatroom.MaxParticipants)
+		if err != nil {
+			t.Fatalf("AddParticipant %s: %v", tt.name, err)
+		}
+		if added != tt.added {
+			t.Errorf("AddParticipant %s = %v, want %v", tt.name, added, tt.added)
+		}
+	}
+
+	// The last message is deleted, the preview falls back to the one before it
+	chats := []interface{}{
+		bson.M{"_id": "chat-1", "clientId": "client-1", "chatroom": chatroom.ID, "sender": "user-a", "message": "first", "createdTimestamp": now},
+		bson.M{"_id": "chat-2", "clientId": "client-1", "chatroom": chatroom.ID, "sender": "user-b", "message": "second", "createdTimestamp": now + 1},
+		bson.M{"_id": "chat-3", "clientId": "client-1", "chatroom": chatroom.ID, "sender": "user-a", "message": "", "createdTimestamp": now + 2, "deletedTimestamp": now + 3},
+	}
+	if _, err := db.Collection("chats").InsertMany(ctx, chats); err != nil {
+		t.Fatalf("Failed to store chats: %v", err)
+	}
+
+	if err := chatrooms.RecomputeStats(ctx, chatroom.ID); err != nil {
+		t.Fatalf("RecomputeStats: %v", err)
+	}
+
+	stored, err = chatrooms.Get(ctx, chatroom.ID)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if len(stored.Participants) != 2 {
+		t.Errorf("Participants = %+v, want 2", stored.Participants)
+	}
+	if stored.MessagesCount != 2 || stored.LastMessage == nil || *stored.LastMessage != "second" ||
+		stored.LastSender == nil || *stored.LastSender != "user-b" {
+		t.Errorf("RecomputeStats = %d messages, last %v from %v, want 2 messages, last second from user-b", stored.MessagesCount, stored.LastMessage, stored.LastSender)
+	}
+}
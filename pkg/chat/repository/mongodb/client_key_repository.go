@@ -0,0 +1,130 @@
+package mongodb
+
+import (
+	"app/pkg/chat/domain/entity"
+	"app/pkg/chat/domain/repository"
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type ClientKeyRepository struct {
+	collection *mongo.Collection
+}
+
+func NewClientKeyRepository(db *mongo.Database) (repository.ClientKeyRepository, error) {
+	repo := &ClientKeyRepository{
+		collection: db.Collection("client_keys"),
+	}
+
+	if err := repo.ensureIndexes(context.Background()); err != nil {
+		return nil, err
+	}
+
+	return repo, nil
+}
+
+// ensureIndexes creates all necessary indexes for the client key collection
+func (r *ClientKeyRepository) ensureIndexes(ctx context.Context) error {
+	indexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				{Key: "hash", Value: 1},
+			},
+			Options: options.Index().SetName("hash").SetUnique(true),
+		},
+		{
+			Keys: bson.D{
+				{Key: "clientId", Value: 1},
+				{Key: "createdTimestamp", Value: -1},
+			},
+			Options: options.Index().SetName("clientId_timestamp"),
+		},
+	}
+
+	opts := options.CreateIndexes().SetMaxTime(10 * time.Second)
+
+	_, err := r.collection.Indexes().CreateMany(ctx, indexes, opts)
+	return err
+}
+
+// Get retrieves a single client key by ID
+func (r *ClientKeyRepository) Get(ctx context.Context, id string) (*entity.ClientKey, error) {
+	return r.findOne(ctx, bson.M{"_id": id})
+}
+
+// GetByHash retrieves a single client key by the hash of the key
+func (r *ClientKeyRepository) GetByHash(ctx context.Context, hash string) (*entity.ClientKey, error) {
+	return r.findOne(ctx, bson.M{"hash": hash})
+}
+
+func (r *ClientKeyRepository) findOne(ctx context.Context, filter bson.M) (*entity.ClientKey, error) {
+	var key entity.ClientKey
+	err := r.collection.FindOne(ctx, filter).Decode(&key)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &key, nil
+}
+
+// GetByClient retrieves all keys of a client, including expired and revoked ones, newest first
+func (r *ClientKeyRepository) GetByClient(ctx context.Context, clientID string) ([]*entity.ClientKey, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "createdTimestamp", Value: -1}})
+
+	cursor, err := r.collection.Find(ctx, bson.M{"clientId": clientID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	keys := []*entity.ClientKey{}
+	if err = cursor.All(ctx, &keys); err != nil {
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+// Create stores a new client key
+func (r *ClientKeyRepository) Create(ctx context.Context, key *entity.ClientKey) error {
+	if key.ID == "" {
+		key.ID = primitive.NewObjectID().Hex()
+	}
+	if key.CreatedTimestamp == 0 {
+		key.CreatedTimestamp = time.Now().UnixMilli()
+	}
+
+	_, err := r.collection.InsertOne(ctx, key)
+	return err
+}
+
+// SetExpiry sets when a client key expires, in milliseconds
+func (r *ClientKeyRepository) SetExpiry(ctx context.Context, id string, expiresTimestamp int64) error {
+	_, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{
+		"$set": bson.M{"expiresTimestamp": expiresTimestamp},
+	})
+	return err
+}
+
+// Revoke marks a client key as revoked, revoking a revoked key keeps its original timestamp
+func (r *ClientKeyRepository) Revoke(ctx context.Context, id string) error {
+	_, err := r.collection.UpdateOne(ctx,
+		bson.M{"_id": id, "revokedTimestamp": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"revokedTimestamp": time.Now().UnixMilli()}},
+	)
+	return err
+}
+
+// DeleteByClient removes all keys of a client
+func (r *ClientKeyRepository) DeleteByClient(ctx context.Context, clientID string) error {
+	_, err := r.collection.DeleteMany(ctx, bson.M{"clientId": clientID})
+	return err
+}
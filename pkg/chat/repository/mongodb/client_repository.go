@@ -5,6 +5,7 @@ import (
 	"app/pkg/chat/domain/repository"
 	"app/pkg/types/pagination"
 	"context"
+	"errors"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
@@ -31,12 +32,20 @@ func NewClientRepository(db *mongo.Database) (repository.ClientRepository, error
 
 // ensureIndexes creates all necessary indexes for the client collection
 func (r *ClientRepository) ensureIndexes(ctx context.Context) error {
+	// Every client used to have a unique plaintext key, new clients have none and would all collide on it
+	if _, err := r.collection.Indexes().DropOne(ctx, "clientKey"); err != nil && !isIndexNotFound(err) {
+		return err
+	}
+
 	indexes := []mongo.IndexModel{
 		{
 			Keys: bson.D{
 				{Key: "clientKey", Value: 1},
 			},
-			Options: options.Index().SetName("clientKey").SetUnique(true),
+			Options: options.Index().
+				SetName("legacyClientKey").
+				SetUnique(true).
+				SetPartialFilterExpression(bson.M{"clientKey": bson.M{"$type": "string"}}),
 		},
 		{
 			Keys: bson.D{
@@ -52,15 +61,21 @@ func (r *ClientRepository) ensureIndexes(ctx context.Context) error {
 	return err
 }
 
-// Get retrieves a single client by ID
-func (r *ClientRepository) Get(ctx context.Context, id string) (*entity.Client, error) {
-	objectID, err := primitive.ObjectIDFromHex(id)
-	if err != nil {
-		return nil, err
+// isIndexNotFound checks if an error comes from dropping an index, or the collection of an index, that doesn't exist
+func isIndexNotFound(err error) bool {
+	var cmdErr mongo.CommandError
+	if errors.As(err, &cmdErr) {
+		// NamespaceNotFound or IndexNotFound
+		return cmdErr.Code == 26 || cmdErr.Code == 27
 	}
 
+	return false
+}
+
+// Get retrieves a single client by ID
+func (r *ClientRepository) Get(ctx context.Context, id string) (*entity.Client, error) {
 	var client entity.Client
-	err = r.collection.FindOne(ctx, bson.M{"_id": objectID}).Decode(&client)
+	err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&client)
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
 			return nil, nil
@@ -71,8 +86,8 @@ func (r *ClientRepository) Get(ctx context.Context, id string) (*entity.Client,
 	return &client, nil
 }
 
-// GetByKey retrieves a single client by client key
-func (r *ClientRepository) GetByKey(ctx context.Context, clientKey string) (*entity.Client, error) {
+// GetByLegacyKey retrieves a single client by the plaintext key of clients created before keys were hashed
+func (r *ClientRepository) GetByLegacyKey(ctx context.Context, clientKey string) (*entity.Client, error) {
 	var client entity.Client
 	err := r.collection.FindOne(ctx, bson.M{"clientKey": clientKey}).Decode(&client)
 	if err != nil {
@@ -85,6 +100,22 @@ func (r *ClientRepository) GetByKey(ctx context.Context, clientKey string) (*ent
 	return &client, nil
 }
 
+// GetWithLegacyKey retrieves the clients that still have a plaintext key
+func (r *ClientRepository) GetWithLegacyKey(ctx context.Context) ([]*entity.Client, error) {
+	cursor, err := r.collection.Find(ctx, bson.M{"clientKey": bson.M{"$type": "string"}})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var clients []*entity.Client
+	if err = cursor.All(ctx, &clients); err != nil {
+		return nil, err
+	}
+
+	return clients, nil
+}
+
 // GetAll retrieves multiple clients with pagination
 func (r *ClientRepository) GetAll(ctx context.Context, pag pagination.Pagination) ([]*entity.Client, int64, error) {
 	opts := options.Find().
@@ -127,24 +158,38 @@ func (r *ClientRepository) Create(ctx context.Context, client *entity.Client) er
 
 // Update modifies an existing client
 func (r *ClientRepository) Update(ctx context.Context, client *entity.Client) error {
-	objectID, err := primitive.ObjectIDFromHex(client.ID)
-	if err != nil {
-		return err
-	}
-
 	client.UpdatedTimestamp = time.Now().UnixMilli()
 
-	_, err = r.collection.ReplaceOne(ctx, bson.M{"_id": objectID}, client)
+	_, err := r.collection.ReplaceOne(ctx, bson.M{"_id": client.ID}, client)
 	return err
 }
 
 // Delete removes a client
 func (r *ClientRepository) Delete(ctx context.Context, id string) error {
-	objectID, err := primitive.ObjectIDFromHex(id)
-	if err != nil {
-		return err
-	}
+	_, err := r.collection.DeleteOne(ctx, bson.M{"_id": id})
+	return err
+}
+
+// SetWebhookSecret replaces the secret webhook requests to the client are signed with
+func (r *ClientRepository) SetWebhookSecret(ctx context.Context, id string, secret string) error {
+	_, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{
+		"$set": bson.M{
+			"webhookSecret":    secret,
+			"updatedTimestamp": time.Now().UnixMilli(),
+		},
+	})
+	return err
+}
 
-	_, err = r.collection.DeleteOne(ctx, bson.M{"_id": objectID})
+// ClearLegacyKey removes the client's plaintext key once it's stored as a ClientKey
+// Clients without a webhook secret keep signing webhooks with it, so receivers don't have to change
+func (r *ClientRepository) ClearLegacyKey(ctx context.Context, id string) error {
+	_, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{
+			"webhookSecret":    bson.M{"$ifNull": bson.A{"$webhookSecret", "$clientKey"}},
+			"updatedTimestamp": time.Now().UnixMilli(),
+		}}},
+		{{Key: "$unset", Value: "clientKey"}},
+	})
 	return err
 }
@@ -0,0 +1,217 @@
+package mongodb
+
+import (
+	"app/pkg/chat/domain/entity"
+	"app/pkg/chat/domain/repository"
+	"app/pkg/types/pagination"
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type DataJobRepository struct {
+	collection *mongo.Collection
+}
+
+func NewDataJobRepository(db *mongo.Database) (repository.DataJobRepository, error) {
+	repo := &DataJobRepository{
+		collection: db.Collection("data_jobs"),
+	}
+
+	if err := repo.ensureIndexes(context.Background()); err != nil {
+		return nil, err
+	}
+
+	return repo, nil
+}
+
+// ensureIndexes creates all necessary indexes for the data job collection
+func (r *DataJobRepository) ensureIndexes(ctx context.Context) error {
+	indexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				{Key: "status", Value: 1},
+				{Key: "createdTimestamp", Value: 1},
+			},
+			Options: options.Index().SetName("status_timestamp"),
+		},
+		{
+			Keys: bson.D{
+				{Key: "userId", Value: 1},
+				{Key: "createdTimestamp", Value: -1},
+			},
+			Options: options.Index().SetName("userId_timestamp"),
+		},
+		{
+			Keys: bson.D{
+				{Key: "clientId", Value: 1},
+				{Key: "createdTimestamp", Value: -1},
+			},
+			Options: options.Index().SetName("clientId_timestamp"),
+		},
+		{
+			Keys: bson.D{
+				{Key: "expiresTimestamp", Value: 1},
+			},
+			Options: options.Index().
+				SetName("expiresTimestamp").
+				SetPartialFilterExpression(bson.M{"fileKey": bson.M{"$type": "string"}}),
+		},
+	}
+
+	opts := options.CreateIndexes().SetMaxTime(10 * time.Second)
+
+	_, err := r.collection.Indexes().CreateMany(ctx, indexes, opts)
+	return err
+}
+
+// Get retrieves a single data job by ID
+func (r *DataJobRepository) Get(ctx context.Context, id string) (*entity.DataJob, error) {
+	return r.findOne(ctx, bson.M{"_id": id})
+}
+
+// GetUnfinished retrieves the user's pending or running job of the given type, returning nil if there is none
+func (r *DataJobRepository) GetUnfinished(ctx context.Context, userID string, jobType entity.DataJobType) (*entity.DataJob, error) {
+	return r.findOne(ctx, bson.M{
+		"userId": userID,
+		"type":   jobType,
+		"status": bson.M{"$in": bson.A{entity.DataJobStatusPending, entity.DataJobStatusRunning}},
+	})
+}
+
+func (r *DataJobRepository) findOne(ctx context.Context, filter bson.M) (*entity.DataJob, error) {
+	var job entity.DataJob
+	err := r.collection.FindOne(ctx, filter).Decode(&job)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &job, nil
+}
+
+// GetAll retrieves multiple data jobs with filtering and pagination, newest first
+func (r *DataJobRepository) GetAll(ctx context.Context, filter repository.DataJobFilter, pag pagination.Pagination) ([]*entity.DataJob, int64, error) {
+	query := bson.M{}
+	if filter.ClientID != "" {
+		query["clientId"] = filter.ClientID
+	}
+	if filter.UserID != "" {
+		query["userId"] = filter.UserID
+	}
+	if filter.Type != nil {
+		query["type"] = *filter.Type
+	}
+	if filter.Status != nil {
+		query["status"] = *filter.Status
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "createdTimestamp", Value: -1}}).
+		SetSkip(int64((pag.Page - 1) * pag.Limit)).
+		SetLimit(int64(pag.Limit))
+
+	cursor, err := r.collection.Find(ctx, query, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	jobs := make([]*entity.DataJob, 0)
+	if err = cursor.All(ctx, &jobs); err != nil {
+		return nil, 0, err
+	}
+
+	total, err := r.collection.CountDocuments(ctx, query)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return jobs, total, nil
+}
+
+// GetExports retrieves the user's completed exports whose file is still stored
+func (r *DataJobRepository) GetExports(ctx context.Context, userID string) ([]*entity.DataJob, error) {
+	return r.find(ctx, bson.M{
+		"userId":  userID,
+		"type":    entity.DataJobTypeExport,
+		"fileKey": bson.M{"$type": "string"},
+	}, options.Find())
+}
+
+// GetExpiredExports retrieves completed exports whose file expired before the given timestamp
+func (r *DataJobRepository) GetExpiredExports(ctx context.Context, timestamp int64, limit int) ([]*entity.DataJob, error) {
+	return r.find(ctx, bson.M{
+		"fileKey":          bson.M{"$type": "string"},
+		"expiresTimestamp": bson.M{"$lt": timestamp},
+	}, options.Find().SetLimit(int64(limit)))
+}
+
+func (r *DataJobRepository) find(ctx context.Context, filter bson.M, opts *options.FindOptions) ([]*entity.DataJob, error) {
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	jobs := make([]*entity.DataJob, 0)
+	if err = cursor.All(ctx, &jobs); err != nil {
+		return nil, err
+	}
+
+	return jobs, nil
+}
+
+// Create stores a new data job
+func (r *DataJobRepository) Create(ctx context.Context, job *entity.DataJob) error {
+	if job.ID == "" {
+		job.ID = primitive.NewObjectID().Hex()
+	}
+	if job.CreatedTimestamp == 0 {
+		job.CreatedTimestamp = time.Now().UnixMilli()
+	}
+
+	_, err := r.collection.InsertOne(ctx, job)
+	return err
+}
+
+// Claim atomically marks the oldest pending job as running and returns it, returning nil if there is none
+func (r *DataJobRepository) Claim(ctx context.Context, staleTimestamp int64) (*entity.DataJob, error) {
+	filter := bson.M{"$or": []bson.M{
+		{"status": entity.DataJobStatusPending},
+		{"status": entity.DataJobStatusRunning, "startedTimestamp": bson.M{"$lt": staleTimestamp}},
+	}}
+	update := bson.M{
+		"$set": bson.M{
+			"status":           entity.DataJobStatusRunning,
+			"startedTimestamp": time.Now().UnixMilli(),
+		},
+		"$inc": bson.M{"attempts": 1},
+	}
+	opts := options.FindOneAndUpdate().
+		SetSort(bson.D{{Key: "createdTimestamp", Value: 1}}).
+		SetReturnDocument(options.After)
+
+	var job entity.DataJob
+	err := r.collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&job)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &job, nil
+}
+
+// Update modifies an existing data job
+func (r *DataJobRepository) Update(ctx context.Context, job *entity.DataJob) error {
+	_, err := r.collection.ReplaceOne(ctx, bson.M{"_id": job.ID}, job)
+	return err
+}
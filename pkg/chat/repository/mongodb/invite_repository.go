@@ -0,0 +1,135 @@
+package mongodb
+
+import (
+	"app/pkg/chat/domain/entity"
+	"app/pkg/chat/domain/repository"
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type InviteRepository struct {
+	collection *mongo.Collection
+}
+
+func NewInviteRepository(db *mongo.Database) (repository.InviteRepository, error) {
+	repo := &InviteRepository{
+		collection: db.Collection("chatroom_invites"),
+	}
+
+	if err := repo.ensureIndexes(context.Background()); err != nil {
+		return nil, err
+	}
+
+	return repo, nil
+}
+
+// ensureIndexes creates all necessary indexes for the invite collection
+func (r *InviteRepository) ensureIndexes(ctx context.Context) error {
+	indexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				{Key: "chatroom", Value: 1},
+				{Key: "createdTimestamp", Value: -1},
+			},
+			Options: options.Index().SetName("chatroom_timestamp"),
+		},
+	}
+
+	opts := options.CreateIndexes().SetMaxTime(10 * time.Second)
+
+	_, err := r.collection.Indexes().CreateMany(ctx, indexes, opts)
+	return err
+}
+
+// Get retrieves a single invite by ID
+func (r *InviteRepository) Get(ctx context.Context, id string) (*entity.ChatroomInvite, error) {
+	var invite entity.ChatroomInvite
+	err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&invite)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &invite, nil
+}
+
+// GetAllByChatroom retrieves the invites of a chatroom that are neither revoked nor expired, newest first
+func (r *InviteRepository) GetAllByChatroom(ctx context.Context, chatroomID string, now int64) ([]*entity.ChatroomInvite, error) {
+	query := bson.M{
+		"chatroom":         chatroomID,
+		"revokedTimestamp": bson.M{"$exists": false},
+		"expiresTimestamp": bson.M{"$gt": now},
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "createdTimestamp", Value: -1}})
+
+	cursor, err := r.collection.Find(ctx, query, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	invites := make([]*entity.ChatroomInvite, 0)
+	if err = cursor.All(ctx, &invites); err != nil {
+		return nil, err
+	}
+
+	return invites, nil
+}
+
+// Create stores a new invite
+func (r *InviteRepository) Create(ctx context.Context, invite *entity.ChatroomInvite) error {
+	if invite.ID == "" {
+		invite.ID = primitive.NewObjectID().Hex()
+	}
+
+	_, err := r.collection.InsertOne(ctx, invite)
+	return err
+}
+
+// Redeem atomically counts a use of an invite that is neither revoked, expired nor used up
+func (r *InviteRepository) Redeem(ctx context.Context, id string, now int64) (bool, error) {
+	query := bson.M{
+		"_id":              id,
+		"revokedTimestamp": bson.M{"$exists": false},
+		"expiresTimestamp": bson.M{"$gt": now},
+		"$or": bson.A{
+			bson.M{"maxUses": 0},
+			bson.M{"$expr": bson.M{"$lt": bson.A{"$uses", "$maxUses"}}},
+		},
+	}
+
+	result, err := r.collection.UpdateOne(ctx, query, bson.M{"$inc": bson.M{"uses": 1}})
+	if err != nil {
+		return false, err
+	}
+
+	return result.ModifiedCount > 0, nil
+}
+
+// Release takes back a use of an invite counted by Redeem
+func (r *InviteRepository) Release(ctx context.Context, id string) error {
+	_, err := r.collection.UpdateOne(
+		ctx,
+		bson.M{"_id": id, "uses": bson.M{"$gt": 0}},
+		bson.M{"$inc": bson.M{"uses": -1}},
+	)
+	return err
+}
+
+// Revoke marks an invite as revoked
+func (r *InviteRepository) Revoke(ctx context.Context, id string, timestamp int64) error {
+	_, err := r.collection.UpdateOne(
+		ctx,
+		bson.M{"_id": id, "revokedTimestamp": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"revokedTimestamp": timestamp}},
+	)
+	return err
+}
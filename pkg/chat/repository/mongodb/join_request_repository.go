@@ -0,0 +1,139 @@
+package mongodb
+
+import (
+	"app/pkg/chat/domain/entity"
+	"app/pkg/chat/domain/repository"
+	"app/pkg/types/pagination"
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type JoinRequestRepository struct {
+	collection *mongo.Collection
+}
+
+func NewJoinRequestRepository(db *mongo.Database) (repository.JoinRequestRepository, error) {
+	repo := &JoinRequestRepository{
+		collection: db.Collection("join_requests"),
+	}
+
+	if err := repo.ensureIndexes(context.Background()); err != nil {
+		return nil, err
+	}
+
+	return repo, nil
+}
+
+// ensureIndexes creates all necessary indexes for the join request collection
+func (r *JoinRequestRepository) ensureIndexes(ctx context.Context) error {
+	indexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				{Key: "chatroom", Value: 1},
+				{Key: "status", Value: 1},
+				{Key: "createdTimestamp", Value: 1},
+			},
+			Options: options.Index().SetName("chatroom_status_timestamp"),
+		},
+		{
+			// A user can only have one pending request per chatroom
+			Keys: bson.D{
+				{Key: "chatroom", Value: 1},
+				{Key: "user", Value: 1},
+			},
+			Options: options.Index().
+				SetName("chatroom_user_pending").
+				SetUnique(true).
+				SetPartialFilterExpression(bson.M{"status": entity.JoinRequestStatusPending}),
+		},
+	}
+
+	opts := options.CreateIndexes().SetMaxTime(10 * time.Second)
+
+	_, err := r.collection.Indexes().CreateMany(ctx, indexes, opts)
+	return err
+}
+
+// Get retrieves a single join request by ID
+func (r *JoinRequestRepository) Get(ctx context.Context, id string) (*entity.JoinRequest, error) {
+	var request entity.JoinRequest
+	err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&request)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &request, nil
+}
+
+// GetAll retrieves join requests with filtering and pagination, oldest first
+func (r *JoinRequestRepository) GetAll(ctx context.Context, filter repository.JoinRequestFilter, pag pagination.Pagination) ([]*entity.JoinRequest, int64, error) {
+	query := bson.M{}
+	if filter.ChatroomID != "" {
+		query["chatroom"] = filter.ChatroomID
+	}
+	if filter.UserID != "" {
+		query["user"] = filter.UserID
+	}
+	if filter.Status != nil {
+		query["status"] = *filter.Status
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "createdTimestamp", Value: 1}}).
+		SetSkip(int64((pag.Page - 1) * pag.Limit)).
+		SetLimit(int64(pag.Limit))
+
+	cursor, err := r.collection.Find(ctx, query, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	requests := make([]*entity.JoinRequest, 0)
+	if err = cursor.All(ctx, &requests); err != nil {
+		return nil, 0, err
+	}
+
+	total, err := r.collection.CountDocuments(ctx, query)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return requests, total, nil
+}
+
+// Create stores a new join request
+func (r *JoinRequestRepository) Create(ctx context.Context, request *entity.JoinRequest) error {
+	if request.ID == "" {
+		request.ID = primitive.NewObjectID().Hex()
+	}
+
+	_, err := r.collection.InsertOne(ctx, request)
+	return err
+}
+
+// Review moves a pending join request to the given status
+func (r *JoinRequestRepository) Review(ctx context.Context, id string, status entity.JoinRequestStatus, reviewedBy string, timestamp int64) (bool, error) {
+	result, err := r.collection.UpdateOne(
+		ctx,
+		bson.M{"_id": id, "status": entity.JoinRequestStatusPending},
+		bson.M{"$set": bson.M{
+			"status":            status,
+			"reviewedBy":        reviewedBy,
+			"reviewedTimestamp": timestamp,
+		}},
+	)
+	if err != nil {
+		return false, err
+	}
+
+	return result.ModifiedCount > 0, nil
+}
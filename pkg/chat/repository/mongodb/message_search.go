@@ -0,0 +1,192 @@
+package mongodb
+
+import (
+	"app/pkg/chat/domain/entity"
+	"app/pkg/chat/domain/repository"
+	"context"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MessageSearch implements full-text search over chat messages using a MongoDB text index
+type MessageSearch struct {
+	collection *mongo.Collection
+}
+
+func NewMessageSearch(db *mongo.Database) (repository.MessageSearch, error) {
+	search := &MessageSearch{
+		collection: db.Collection("chats"),
+	}
+
+	if err := search.ensureIndexes(context.Background()); err != nil {
+		return nil, err
+	}
+
+	return search, nil
+}
+
+// ensureIndexes creates the text index used for searching chat messages
+func (s *MessageSearch) ensureIndexes(ctx context.Context) error {
+	indexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "message", Value: "text"}},
+			Options: options.Index().SetName("message_text"),
+		},
+	}
+
+	opts := options.CreateIndexes().SetMaxTime(10 * time.Second)
+
+	_, err := s.collection.Indexes().CreateMany(ctx, indexes, opts)
+	return err
+}
+
+// Search returns matching messages newest first with their highlights
+func (s *MessageSearch) Search(ctx context.Context, query repository.MessageSearchQuery) ([]*entity.ChatSearchResult, bool, error) {
+	if len(query.ChatroomIDs) == 0 {
+		return []*entity.ChatSearchResult{}, false, nil
+	}
+
+	filter := bson.M{
+		"$text":            bson.M{"$search": query.Text},
+		"chatroom":         bson.M{"$in": query.ChatroomIDs},
+		"deletedTimestamp": bson.M{"$exists": false},
+	}
+	if query.Before != nil {
+		filter["$or"] = []bson.M{
+			{"createdTimestamp": bson.M{"$lt": query.Before.Timestamp}},
+			{"createdTimestamp": query.Before.Timestamp, "_id": bson.M{"$lt": query.Before.ID}},
+		}
+	}
+
+	// Fetch one extra message to know if there are more
+	opts := options.Find().
+		SetSort(bson.D{{Key: "createdTimestamp", Value: -1}, {Key: "_id", Value: -1}}).
+		SetLimit(int64(query.Limit + 1))
+
+	cursor, err := s.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, false, err
+	}
+	defer cursor.Close(ctx)
+
+	var chats []*entity.Chat
+	if err = cursor.All(ctx, &chats); err != nil {
+		return nil, false, err
+	}
+
+	hasMore := len(chats) > query.Limit
+	if hasMore {
+		chats = chats[:query.Limit]
+	}
+
+	terms := parseSearchTerms(query.Text)
+	results := make([]*entity.ChatSearchResult, 0, len(chats))
+	for _, chat := range chats {
+		results = append(results, &entity.ChatSearchResult{
+			Chat:       chat,
+			Highlights: highlight(chat.Message, terms),
+		})
+	}
+
+	return results, hasMore, nil
+}
+
+// searchTerm represents a word or phrase of a search query
+type searchTerm struct {
+	text   []rune
+	phrase bool // Phrases must match exactly, words also match as prefixes of longer words
+}
+
+// parseSearchTerms extracts the words and quoted phrases of a text search, skipping -excluded words
+func parseSearchTerms(text string) []searchTerm {
+	var terms []searchTerm
+
+	for i, part := range strings.Split(text, `"`) {
+		// Odd parts are between quotes
+		if i%2 == 1 {
+			if phrase := strings.TrimSpace(part); phrase != "" {
+				terms = append(terms, searchTerm{text: toLowerRunes(phrase), phrase: true})
+			}
+			continue
+		}
+
+		for _, word := range strings.Fields(part) {
+			if strings.HasPrefix(word, "-") {
+				continue
+			}
+			terms = append(terms, searchTerm{text: toLowerRunes(word)})
+		}
+	}
+
+	return terms
+}
+
+// highlight splits a message into segments, flagging the ones that match a search term
+// Text indexes don't report match positions, so matches are located case-insensitively here
+// Words are matched at word starts so stemmed matches (e.g. "run" in "running") are highlighted too
+func highlight(message string, terms []searchTerm) []entity.HighlightSegment {
+	runes := []rune(message)
+	lower := toLowerRunes(message)
+
+	type span struct{ start, end int }
+	var spans []span
+	for _, term := range terms {
+		n := len(term.text)
+		for i := 0; i+n <= len(lower); i++ {
+			if !term.phrase && i > 0 && isWordRune(lower[i-1]) {
+				continue
+			}
+			if string(lower[i:i+n]) == string(term.text) {
+				spans = append(spans, span{i, i + n})
+			}
+		}
+	}
+
+	// Merge overlapping matches
+	sort.Slice(spans, func(i, j int) bool { return spans[i].start < spans[j].start })
+	var merged []span
+	for _, s := range spans {
+		if len(merged) > 0 && s.start <= merged[len(merged)-1].end {
+			if s.end > merged[len(merged)-1].end {
+				merged[len(merged)-1].end = s.end
+			}
+			continue
+		}
+		merged = append(merged, s)
+	}
+
+	segments := make([]entity.HighlightSegment, 0, len(merged)*2+1)
+	pos := 0
+	for _, s := range merged {
+		if s.start > pos {
+			segments = append(segments, entity.HighlightSegment{Text: string(runes[pos:s.start])})
+		}
+		segments = append(segments, entity.HighlightSegment{Text: string(runes[s.start:s.end]), Match: true})
+		pos = s.end
+	}
+	if pos < len(runes) {
+		segments = append(segments, entity.HighlightSegment{Text: string(runes[pos:])})
+	}
+
+	return segments
+}
+
+// toLowerRunes lowercases a string rune by rune, keeping positions aligned with the original
+func toLowerRunes(text string) []rune {
+	runes := []rune(text)
+	for i, r := range runes {
+		runes[i] = unicode.ToLower(r)
+	}
+	return runes
+}
+
+// isWordRune checks if a rune is part of a word
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
@@ -0,0 +1,135 @@
+package mongodb
+
+import (
+	"app/pkg/chat/domain/entity"
+	"app/pkg/chat/domain/repository"
+	"app/pkg/types/pagination"
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type ModerationRepository struct {
+	settings  *mongo.Collection
+	decisions *mongo.Collection
+}
+
+func NewModerationRepository(db *mongo.Database) (repository.ModerationRepository, error) {
+	repo := &ModerationRepository{
+		settings:  db.Collection("moderation_settings"),
+		decisions: db.Collection("moderation_decisions"),
+	}
+
+	if err := repo.ensureIndexes(context.Background()); err != nil {
+		return nil, err
+	}
+
+	return repo, nil
+}
+
+// ensureIndexes creates all necessary indexes for the moderation decision collection
+func (r *ModerationRepository) ensureIndexes(ctx context.Context) error {
+	indexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				{Key: "chatroom", Value: 1},
+				{Key: "createdTimestamp", Value: -1},
+			},
+			Options: options.Index().SetName("chatroom_timestamp"),
+		},
+		{
+			Keys: bson.D{
+				{Key: "sender", Value: 1},
+				{Key: "createdTimestamp", Value: -1},
+			},
+			Options: options.Index().SetName("sender_timestamp"),
+		},
+		{
+			Keys: bson.D{
+				{Key: "createdTimestamp", Value: -1},
+			},
+			Options: options.Index().SetName("timestamp"),
+		},
+	}
+
+	opts := options.CreateIndexes().SetMaxTime(10 * time.Second)
+
+	_, err := r.decisions.Indexes().CreateMany(ctx, indexes, opts)
+	return err
+}
+
+// GetSettings retrieves a chatroom's moderation settings, returning nil if the chatroom has none stored
+func (r *ModerationRepository) GetSettings(ctx context.Context, chatroomID string) (*entity.ModerationSettings, error) {
+	var settings entity.ModerationSettings
+	err := r.settings.FindOne(ctx, bson.M{"_id": chatroomID}).Decode(&settings)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &settings, nil
+}
+
+// UpsertSettings stores a chatroom's moderation settings, replacing any existing ones
+func (r *ModerationRepository) UpsertSettings(ctx context.Context, settings *entity.ModerationSettings) error {
+	settings.UpdatedTimestamp = time.Now().Unix()
+
+	_, err := r.settings.ReplaceOne(ctx, bson.M{"_id": settings.ID}, settings, options.Replace().SetUpsert(true))
+	return err
+}
+
+// CreateDecision stores a new moderation decision
+func (r *ModerationRepository) CreateDecision(ctx context.Context, decision *entity.ModerationDecision) error {
+	if decision.ID == "" {
+		decision.ID = primitive.NewObjectID().Hex()
+	}
+
+	_, err := r.decisions.InsertOne(ctx, decision)
+	return err
+}
+
+// GetDecisions retrieves moderation decisions with filtering and pagination, newest first
+func (r *ModerationRepository) GetDecisions(ctx context.Context, filter repository.ModerationDecisionFilter, pag pagination.Pagination) ([]*entity.ModerationDecision, int64, error) {
+	query := bson.M{}
+	if filter.ChatroomID != "" {
+		query["chatroom"] = filter.ChatroomID
+	}
+	if filter.SenderID != "" {
+		query["sender"] = filter.SenderID
+	}
+	if filter.ClientID != "" {
+		query["clientId"] = filter.ClientID
+	}
+	if filter.Action != nil {
+		query["action"] = *filter.Action
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "createdTimestamp", Value: -1}}).
+		SetSkip(int64((pag.Page - 1) * pag.Limit)).
+		SetLimit(int64(pag.Limit))
+
+	cursor, err := r.decisions.Find(ctx, query, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	decisions := make([]*entity.ModerationDecision, 0)
+	if err = cursor.All(ctx, &decisions); err != nil {
+		return nil, 0, err
+	}
+
+	total, err := r.decisions.CountDocuments(ctx, query)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return decisions, total, nil
+}
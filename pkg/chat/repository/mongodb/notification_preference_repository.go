@@ -0,0 +1,78 @@
+package mongodb
+
+import (
+	"app/pkg/chat/domain/entity"
+	"app/pkg/chat/domain/repository"
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type NotificationPreferenceRepository struct {
+	collection *mongo.Collection
+}
+
+func NewNotificationPreferenceRepository(db *mongo.Database) (repository.NotificationPreferenceRepository, error) {
+	return &NotificationPreferenceRepository{
+		collection: db.Collection("notification_preferences"),
+	}, nil
+}
+
+// Get retrieves a user's notification preferences, returning nil if the user has none stored
+func (r *NotificationPreferenceRepository) Get(ctx context.Context, userID string) (*entity.NotificationPreference, error) {
+	var preference entity.NotificationPreference
+	err := r.collection.FindOne(ctx, bson.M{"_id": userID}).Decode(&preference)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &preference, nil
+}
+
+// Upsert stores a user's notification preferences, replacing any existing ones but the linked Telegram chat
+// The preference is updated to the stored one
+func (r *NotificationPreferenceRepository) Upsert(ctx context.Context, preference *entity.NotificationPreference) error {
+	// The Telegram chat is only linked through SetTelegramChatID, once the user proved they own it
+	update := bson.M{"$set": bson.M{
+		"muted":            preference.Muted,
+		"mutedChatrooms":   preference.MutedChatrooms,
+		"disabledChannels": preference.DisabledChannels,
+		"updatedTimestamp": time.Now().Unix(),
+	}}
+
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+	return r.collection.FindOneAndUpdate(ctx, bson.M{"_id": preference.ID}, update, opts).Decode(preference)
+}
+
+// SetTelegramChatID links a Telegram chat to a user's notification preferences, 0 unlinks it
+func (r *NotificationPreferenceRepository) SetTelegramChatID(ctx context.Context, userID string, chatID int64) error {
+	set := bson.M{"updatedTimestamp": time.Now().Unix()}
+	update := bson.M{
+		"$set": set,
+		"$setOnInsert": bson.M{
+			"muted":            false,
+			"mutedChatrooms":   bson.A{},
+			"disabledChannels": bson.A{},
+		},
+	}
+	if chatID == 0 {
+		update["$unset"] = bson.M{"telegramChatId": ""}
+	} else {
+		set["telegramChatId"] = chatID
+	}
+
+	_, err := r.collection.UpdateOne(ctx, bson.M{"_id": userID}, update, options.Update().SetUpsert(true))
+	return err
+}
+
+// Delete removes a user's notification preferences
+func (r *NotificationPreferenceRepository) Delete(ctx context.Context, userID string) error {
+	_, err := r.collection.DeleteOne(ctx, bson.M{"_id": userID})
+	return err
+}
@@ -0,0 +1,117 @@
+package mongodb
+
+import (
+	"app/pkg/chat/domain/entity"
+	"app/pkg/chat/domain/repository"
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type ReadStateRepository struct {
+	collection *mongo.Collection
+}
+
+func NewReadStateRepository(db *mongo.Database) (repository.ReadStateRepository, error) {
+	repo := &ReadStateRepository{
+		collection: db.Collection("read_states"),
+	}
+
+	if err := repo.ensureIndexes(context.Background()); err != nil {
+		return nil, err
+	}
+
+	return repo, nil
+}
+
+// ensureIndexes creates all necessary indexes for the read state collection
+func (r *ReadStateRepository) ensureIndexes(ctx context.Context) error {
+	indexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				{Key: "user", Value: 1},
+				{Key: "chatroom", Value: 1},
+			},
+			Options: options.Index().SetName("user_chatroom").SetUnique(true),
+		},
+	}
+
+	opts := options.CreateIndexes().SetMaxTime(10 * time.Second)
+
+	_, err := r.collection.Indexes().CreateMany(ctx, indexes, opts)
+	return err
+}
+
+// Get retrieves the read state of a user in a chatroom
+func (r *ReadStateRepository) Get(ctx context.Context, chatroomID string, userID string) (*entity.ReadState, error) {
+	var state entity.ReadState
+	err := r.collection.FindOne(ctx, bson.M{"chatroom": chatroomID, "user": userID}).Decode(&state)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &state, nil
+}
+
+// GetAllByUser retrieves the read states of a user in the given chatrooms
+func (r *ReadStateRepository) GetAllByUser(ctx context.Context, userID string, chatroomIDs []string) ([]*entity.ReadState, error) {
+	query := bson.M{
+		"user":     userID,
+		"chatroom": bson.M{"$in": chatroomIDs},
+	}
+
+	cursor, err := r.collection.Find(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var states []*entity.ReadState
+	if err = cursor.All(ctx, &states); err != nil {
+		return nil, err
+	}
+
+	return states, nil
+}
+
+// Upsert stores a read state, only moving the read position forward
+func (r *ReadStateRepository) Upsert(ctx context.Context, state *entity.ReadState) error {
+	state.UpdatedTimestamp = time.Now().UnixMilli()
+
+	// Match only older positions; a newer position makes the upsert collide with the unique index
+	// Read states stored before sequences have none, any position replaces them
+	filter := bson.M{
+		"chatroom": state.Chatroom,
+		"user":     state.User,
+		"$or": bson.A{
+			bson.M{"lastReadSequence": bson.M{"$lte": state.LastReadSequence}},
+			bson.M{"lastReadSequence": bson.M{"$exists": false}},
+		},
+	}
+
+	update := bson.M{
+		"$set": bson.M{
+			"lastReadMessage":   state.LastReadMessage,
+			"lastReadTimestamp": state.LastReadTimestamp,
+			"lastReadSequence":  state.LastReadSequence,
+			"updatedTimestamp":  state.UpdatedTimestamp,
+		},
+		"$setOnInsert": bson.M{
+			"_id": primitive.NewObjectID().Hex(),
+		},
+	}
+
+	_, err := r.collection.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+	if mongo.IsDuplicateKeyError(err) {
+		// The stored read position is already ahead
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,52 @@
+package mongodb
+
+import (
+	"app/pkg/chat/domain/entity"
+	"context"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestReadStateUpsertOnlyMovesForward(t *testing.T) {
+	ctx := context.Background()
+	db := testDatabase(t)
+
+	// A read state stored before sequences existed
+	legacy := bson.M{"_id": "state-1", "chatroom": "room-1", "user": "user-a", "lastReadMessage": "chat-2", "lastReadTimestamp": int64(1773144000)}
+	if _, err := db.Collection("read_states").InsertOne(ctx, legacy); err != nil {
+		t.Fatalf("Failed to store read state: %v", err)
+	}
+
+	readStateRepository, err := NewReadStateRepository(db)
+	if err != nil {
+		t.Fatalf("Failed to create read state repository: %v", err)
+	}
+
+	steps := []struct {
+		message  string
+		sequence int64
+		want     string
+	}{
+		{"chat-3", 3, "chat-3"}, // Replaces the legacy state
+		{"chat-5", 5, "chat-5"},
+		{"chat-4", 4, "chat-5"}, // A late request for an older message is ignored
+		{"chat-5", 5, "chat-5"},
+		{"chat-9", 9, "chat-9"},
+	}
+
+	for _, step := range steps {
+		state := &entity.ReadState{Chatroom: "room-1", User: "user-a", LastReadMessage: step.message, LastReadSequence: step.sequence}
+		if err := readStateRepository.Upsert(ctx, state); err != nil {
+			t.Fatalf("Upsert(%s): %v", step.message, err)
+		}
+
+		stored, err := readStateRepository.Get(ctx, "room-1", "user-a")
+		if err != nil || stored == nil {
+			t.Fatalf("Get = %v, %v", stored, err)
+		}
+		if stored.LastReadMessage != step.want {
+			t.Errorf("after Upsert(%s) last read = %s, want %s", step.message, stored.LastReadMessage, step.want)
+		}
+	}
+}
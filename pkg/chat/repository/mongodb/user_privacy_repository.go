@@ -0,0 +1,71 @@
+package mongodb
+
+import (
+	"app/pkg/chat/domain/entity"
+	"app/pkg/chat/domain/repository"
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type UserPrivacyRepository struct {
+	collection *mongo.Collection
+}
+
+func NewUserPrivacyRepository(db *mongo.Database) (repository.UserPrivacyRepository, error) {
+	return &UserPrivacyRepository{
+		collection: db.Collection("user_privacy"),
+	}, nil
+}
+
+// Get retrieves a user's privacy settings, returning nil if the user has none stored
+func (r *UserPrivacyRepository) Get(ctx context.Context, userID string) (*entity.UserPrivacy, error) {
+	var privacy entity.UserPrivacy
+	err := r.collection.FindOne(ctx, bson.M{"_id": userID}).Decode(&privacy)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &privacy, nil
+}
+
+// SetDMPrivacy sets who can send direct messages to the user
+func (r *UserPrivacyRepository) SetDMPrivacy(ctx context.Context, userID string, privacy entity.DMPrivacy) error {
+	return r.upsert(ctx, userID, bson.M{"$set": bson.M{"dmPrivacy": privacy}})
+}
+
+// Block adds a user to the user's block list, blocking an already blocked user does nothing
+func (r *UserPrivacyRepository) Block(ctx context.Context, userID string, blockedUserID string) error {
+	return r.upsert(ctx, userID, bson.M{"$addToSet": bson.M{"blockedUsers": blockedUserID}})
+}
+
+// Unblock removes a user from the user's block list
+func (r *UserPrivacyRepository) Unblock(ctx context.Context, userID string, blockedUserID string) error {
+	return r.upsert(ctx, userID, bson.M{"$pull": bson.M{"blockedUsers": blockedUserID}})
+}
+
+// Delete removes a user's privacy settings
+func (r *UserPrivacyRepository) Delete(ctx context.Context, userID string) error {
+	_, err := r.collection.DeleteOne(ctx, bson.M{"_id": userID})
+	return err
+}
+
+// upsert applies an update to a user's privacy settings, creating them if needed
+// Fields missing from created settings are defaulted by the service
+func (r *UserPrivacyRepository) upsert(ctx context.Context, userID string, update bson.M) error {
+	set, ok := update["$set"].(bson.M)
+	if !ok {
+		set = bson.M{}
+		update["$set"] = set
+	}
+	set["updatedTimestamp"] = time.Now().Unix()
+
+	_, err := r.collection.UpdateOne(ctx, bson.M{"_id": userID}, update, options.Update().SetUpsert(true))
+	return err
+}
@@ -31,18 +31,40 @@ func NewUserRepository(db *mongo.Database) (repository.UserRepository, error) {
 
 // ensureIndexes creates all necessary indexes for the user collection
 func (r *UserRepository) ensureIndexes(ctx context.Context) error {
+	// User IDs and usernames used to be unique across clients, different clients' users would collide on them
+	for _, name := range []string{"userId", "username", "clientId_username"} {
+		if _, err := r.collection.Indexes().DropOne(ctx, name); err != nil && !isIndexNotFound(err) {
+			return err
+		}
+	}
+
 	indexes := []mongo.IndexModel{
 		{
 			Keys: bson.D{
+				{Key: "clientId", Value: 1},
+				{Key: "externalId", Value: 1},
+			},
+			Options: options.Index().
+				SetName("client_externalId").
+				SetUnique(true).
+				SetPartialFilterExpression(bson.M{"externalId": bson.M{"$type": "string"}}),
+		},
+		{
+			Keys: bson.D{
+				{Key: "clientId", Value: 1},
 				{Key: "userId", Value: 1},
 			},
-			Options: options.Index().SetName("userId").SetUnique(true),
+			Options: options.Index().
+				SetName("client_userId").
+				SetUnique(true).
+				SetPartialFilterExpression(bson.M{"userId": bson.M{"$gt": ""}}),
 		},
 		{
 			Keys: bson.D{
+				{Key: "clientId", Value: 1},
 				{Key: "username", Value: 1},
 			},
-			Options: options.Index().SetName("username").SetUnique(true),
+			Options: options.Index().SetName("client_username").SetUnique(true),
 		},
 	}
 
@@ -54,13 +76,28 @@ func (r *UserRepository) ensureIndexes(ctx context.Context) error {
 
 // Get retrieves a single user by ID
 func (r *UserRepository) Get(ctx context.Context, id string) (*entity.User, error) {
-	objectID, err := primitive.ObjectIDFromHex(id)
+	var user entity.User
+	err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&user)
 	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
 		return nil, err
 	}
 
+	return &user, nil
+}
+
+// GetByExternalID retrieves a client's user by the ID the client's auth strategy returns for them
+// Users stored before external IDs existed were stored under that ID, they're found by it as long as no other client claimed them
+func (r *UserRepository) GetByExternalID(ctx context.Context, clientID string, externalID string) (*entity.User, error) {
+	query := bson.M{"$or": []bson.M{
+		{"clientId": clientID, "externalId": externalID},
+		{"_id": externalID, "externalId": bson.M{"$exists": false}, "clientId": bson.M{"$in": bson.A{clientID, "", nil}}},
+	}}
+
 	var user entity.User
-	err = r.collection.FindOne(ctx, bson.M{"_id": objectID}).Decode(&user)
+	err := r.collection.FindOne(ctx, query).Decode(&user)
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
 			return nil, nil
@@ -72,13 +109,18 @@ func (r *UserRepository) Get(ctx context.Context, id string) (*entity.User, erro
 }
 
 // GetAll retrieves multiple users with pagination
-func (r *UserRepository) GetAll(ctx context.Context, pag pagination.Pagination) ([]*entity.User, int64, error) {
+func (r *UserRepository) GetAll(ctx context.Context, filter repository.UserFilter, pag pagination.Pagination) ([]*entity.User, int64, error) {
+	query := bson.M{}
+	if filter.ClientID != "" {
+		query["clientId"] = filter.ClientID
+	}
+
 	opts := options.Find().
 		SetSort(bson.D{{Key: "username", Value: 1}}).
 		SetSkip(int64((pag.Page - 1) * pag.Limit)).
 		SetLimit(int64(pag.Limit))
 
-	cursor, err := r.collection.Find(ctx, bson.M{}, opts)
+	cursor, err := r.collection.Find(ctx, query, opts)
 	if err != nil {
 		return nil, 0, err
 	}
@@ -89,7 +131,7 @@ func (r *UserRepository) GetAll(ctx context.Context, pag pagination.Pagination)
 		return nil, 0, err
 	}
 
-	total, err := r.collection.CountDocuments(ctx, bson.M{})
+	total, err := r.collection.CountDocuments(ctx, query)
 	if err != nil {
 		return nil, 0, err
 	}
@@ -97,6 +139,27 @@ func (r *UserRepository) GetAll(ctx context.Context, pag pagination.Pagination)
 	return users, total, nil
 }
 
+// CountByClient counts how many of the given users belong to the client
+func (r *UserRepository) CountByClient(ctx context.Context, clientID string, userIDs []string) (int64, error) {
+	return r.collection.CountDocuments(ctx, bson.M{
+		"_id":      bson.M{"$in": userIDs},
+		"clientId": clientID,
+	})
+}
+
+// BackfillClientID assigns users stored before users belonged to a client to the given client
+func (r *UserRepository) BackfillClientID(ctx context.Context, clientID string) (int64, error) {
+	result, err := r.collection.UpdateMany(ctx,
+		bson.M{"clientId": bson.M{"$in": bson.A{nil, ""}}},
+		bson.M{"$set": bson.M{"clientId": clientID}},
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.ModifiedCount, nil
+}
+
 // Create stores a new user
 func (r *UserRepository) Create(ctx context.Context, user *entity.User) error {
 	if user.ID == "" {
@@ -107,24 +170,44 @@ func (r *UserRepository) Create(ctx context.Context, user *entity.User) error {
 	return err
 }
 
-// Update modifies an existing user
+// Update modifies an existing user that hasn't been erased
 func (r *UserRepository) Update(ctx context.Context, user *entity.User) error {
-	objectID, err := primitive.ObjectIDFromHex(user.ID)
+	// A replace racing an erasure must not restore the profile it anonymized
+	result, err := r.collection.ReplaceOne(ctx, bson.M{
+		"_id":             user.ID,
+		"erasedTimestamp": bson.M{"$exists": false},
+	}, user)
 	if err != nil {
 		return err
 	}
 
-	_, err = r.collection.ReplaceOne(ctx, bson.M{"_id": objectID}, user)
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+
+	return nil
+}
+
+// Erase anonymizes a user, replacing their profile with placeholders derived from their ID and marking them erased
+// The placeholders keep the unique user ID and username indexes satisfied
+func (r *UserRepository) Erase(ctx context.Context, id string, timestamp int64) error {
+	placeholder := "erased-" + id
+
+	_, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{
+		"$set": bson.M{
+			"userId":          placeholder,
+			"name":            "Deleted user",
+			"username":        placeholder,
+			"picture":         "",
+			"level":           0,
+			"erasedTimestamp": timestamp,
+		},
+	})
 	return err
 }
 
 // Delete removes a user
 func (r *UserRepository) Delete(ctx context.Context, id string) error {
-	objectID, err := primitive.ObjectIDFromHex(id)
-	if err != nil {
-		return err
-	}
-
-	_, err = r.collection.DeleteOne(ctx, bson.M{"_id": objectID})
+	_, err := r.collection.DeleteOne(ctx, bson.M{"_id": id})
 	return err
 }
@@ -0,0 +1,253 @@
+package analytics
+
+import (
+	"app/pkg/chat/domain/entity"
+	"app/pkg/chat/domain/repository"
+	"app/pkg/database/redis"
+	"app/pkg/exception"
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+const (
+	// Range covered when no start is given
+	defaultRange = 30 * 24 * time.Hour
+	// Longest range analytics can be computed over
+	maxRange = 366 * 24 * time.Hour
+	// Number of chatrooms returned when no limit is given
+	defaultTopChatrooms = 10
+	// Most chatrooms that can be returned at once
+	maxTopChatrooms = 100
+	// Longest gap between a direct message and its answer, later answers start a new conversation
+	maxResponseGap = 24 * time.Hour
+	// Cache duration for ranges still in progress
+	liveCacheDuration = 5 * time.Minute
+	// Cache duration for ranges that ended over a day ago, only edits and erasures still change them
+	pastCacheDuration = 1 * time.Hour
+	// Cache key prefix for analytics, followed by the metric and the range
+	analyticsCacheKeyPrefix = "analytics:"
+)
+
+type analyticsService struct {
+	chatAnalytics repository.ChatAnalytics
+	redisClient   *redis.Client
+}
+
+// NewAnalyticsService creates a new analytics service
+func NewAnalyticsService(chatAnalytics repository.ChatAnalytics, redisClient *redis.Client) AnalyticsService {
+	return &analyticsService{
+		chatAnalytics: chatAnalytics,
+		redisClient:   redisClient,
+	}
+}
+
+// GetSummary counts the messages, active senders and active chatrooms
+func (s *analyticsService) GetSummary(ctx context.Context, params AnalyticsParams) (*entity.ActivitySummary, error) {
+	query, err := newQuery(params)
+	if err != nil {
+		return nil, err
+	}
+
+	return cached(ctx, s, query.key("summary"), query.ttl(), func() (*entity.ActivitySummary, error) {
+		return s.chatAnalytics.GetSummary(ctx, query.filter)
+	})
+}
+
+// GetDailyActivity counts the messages and active senders of every day of the range, oldest first
+func (s *analyticsService) GetDailyActivity(ctx context.Context, params AnalyticsParams) ([]*entity.DailyActivity, error) {
+	query, err := newQuery(params)
+	if err != nil {
+		return nil, err
+	}
+
+	return cached(ctx, s, query.key("daily"), query.ttl(), func() ([]*entity.DailyActivity, error) {
+		activity, err := s.chatAnalytics.GetDailyActivity(ctx, query.filter)
+		if err != nil {
+			return nil, err
+		}
+
+		// Days without messages aren't aggregated at all, fill them in so the series has no gaps
+		byDate := make(map[string]*entity.DailyActivity, len(activity))
+		for _, day := range activity {
+			byDate[day.Date] = day
+		}
+
+		series := []*entity.DailyActivity{}
+		for day := query.firstDay(); day.Before(query.end); day = day.AddDate(0, 0, 1) {
+			date := day.Format("2006-01-02")
+			if activity, ok := byDate[date]; ok {
+				series = append(series, activity)
+			} else {
+				series = append(series, &entity.DailyActivity{Date: date})
+			}
+		}
+
+		return series, nil
+	})
+}
+
+// GetMonthlyActivity counts the messages and active senders of every month of the range, oldest first
+func (s *analyticsService) GetMonthlyActivity(ctx context.Context, params AnalyticsParams) ([]*entity.MonthlyActivity, error) {
+	query, err := newQuery(params)
+	if err != nil {
+		return nil, err
+	}
+
+	return cached(ctx, s, query.key("monthly"), query.ttl(), func() ([]*entity.MonthlyActivity, error) {
+		activity, err := s.chatAnalytics.GetMonthlyActivity(ctx, query.filter)
+		if err != nil {
+			return nil, err
+		}
+
+		// Months without messages aren't aggregated at all, fill them in so the series has no gaps
+		byMonth := make(map[string]*entity.MonthlyActivity, len(activity))
+		for _, month := range activity {
+			byMonth[month.Month] = month
+		}
+
+		firstDay := query.firstDay()
+		series := []*entity.MonthlyActivity{}
+		for month := firstDay.AddDate(0, 0, 1-firstDay.Day()); month.Before(query.end); month = month.AddDate(0, 1, 0) {
+			date := month.Format("2006-01")
+			if activity, ok := byMonth[date]; ok {
+				series = append(series, activity)
+			} else {
+				series = append(series, &entity.MonthlyActivity{Month: date})
+			}
+		}
+
+		return series, nil
+	})
+}
+
+// GetTopChatrooms retrieves the chatrooms with the most messages, most first
+func (s *analyticsService) GetTopChatrooms(ctx context.Context, params AnalyticsParams, limit int) ([]*entity.ChatroomActivity, error) {
+	query, err := newQuery(params)
+	if err != nil {
+		return nil, err
+	}
+	if limit <= 0 {
+		limit = defaultTopChatrooms
+	}
+	if limit > maxTopChatrooms {
+		limit = maxTopChatrooms
+	}
+
+	key := fmt.Sprintf("%s:%d", query.key("top-chatrooms"), limit)
+	return cached(ctx, s, key, query.ttl(), func() ([]*entity.ChatroomActivity, error) {
+		return s.chatAnalytics.GetTopChatrooms(ctx, query.filter, limit)
+	})
+}
+
+// GetDirectResponseTime averages how long users take to answer direct messages
+func (s *analyticsService) GetDirectResponseTime(ctx context.Context, params AnalyticsParams) (*entity.ResponseTime, error) {
+	query, err := newQuery(params)
+	if err != nil {
+		return nil, err
+	}
+
+	return cached(ctx, s, query.key("response-time"), query.ttl(), func() (*entity.ResponseTime, error) {
+		return s.chatAnalytics.GetDirectResponseTime(ctx, query.filter, int64(maxResponseGap.Seconds()))
+	})
+}
+
+// query represents validated analytics parameters
+type query struct {
+	filter   repository.AnalyticsFilter
+	start    time.Time
+	end      time.Time
+	location *time.Location
+}
+
+// newQuery validates the parameters and fills in their defaults
+// Times are truncated to the minute so repeated requests for the latest range share a cache entry
+func newQuery(params AnalyticsParams) (*query, error) {
+	timezone := params.Timezone
+	if timezone == "" {
+		timezone = "UTC"
+	}
+	// Local is the server's time zone, MongoDB doesn't know it
+	location, err := time.LoadLocation(timezone)
+	if err != nil || timezone == "Local" {
+		return nil, exception.BadRequest("Invalid timezone")
+	}
+
+	end := params.End.Truncate(time.Minute)
+	if params.End.IsZero() {
+		end = time.Now().Truncate(time.Minute).Add(time.Minute)
+	}
+	start := params.Start.Truncate(time.Minute)
+	if params.Start.IsZero() {
+		start = end.Add(-defaultRange)
+	}
+
+	if !end.After(start) {
+		return nil, exception.BadRequest("End must be after start")
+	}
+	if end.Sub(start) > maxRange {
+		return nil, exception.BadRequest(fmt.Sprintf("Range can't be longer than %d days", int(maxRange.Hours()/24)))
+	}
+
+	return &query{
+		filter: repository.AnalyticsFilter{
+			ClientID:  params.ClientID,
+			StartTime: start.Unix(),
+			EndTime:   end.Unix(),
+			Timezone:  timezone,
+		},
+		start:    start,
+		end:      end,
+		location: location,
+	}, nil
+}
+
+// firstDay returns the midnight starting the day of the start in the query's time zone
+func (q *query) firstDay() time.Time {
+	start := q.start.In(q.location)
+	return time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, q.location)
+}
+
+// key returns the cache key of a metric over the query
+func (q *query) key(metric string) string {
+	return fmt.Sprintf("%s%s:%s:%d:%d:%s", analyticsCacheKeyPrefix, metric, q.filter.ClientID, q.filter.StartTime, q.filter.EndTime, q.filter.Timezone)
+}
+
+// ttl returns how long the query's results can be cached
+func (q *query) ttl() time.Duration {
+	if time.Since(q.end) > 24*time.Hour {
+		return pastCacheDuration
+	}
+	return liveCacheDuration
+}
+
+// cached returns the cached result of a key, computing and caching it when missing
+// Redis failures are logged and the result computed, analytics stay available without the cache
+func cached[T any](ctx context.Context, s *analyticsService, key string, ttl time.Duration, compute func() (T, error)) (T, error) {
+	var result T
+
+	val, err := s.redisClient.Get(ctx, key)
+	if err == nil {
+		if err := json.Unmarshal([]byte(val), &result); err == nil {
+			return result, nil
+		}
+	} else if err != redis.Nil {
+		fmt.Printf("Error reading analytics cache: %v\n", err)
+	}
+
+	result, err = compute()
+	if err != nil {
+		return result, err
+	}
+
+	data, err := json.Marshal(result)
+	if err != nil {
+		return result, nil
+	}
+	if err := s.redisClient.Set(ctx, key, string(data), ttl); err != nil {
+		fmt.Printf("Error caching analytics: %v\n", err)
+	}
+
+	return result, nil
+}
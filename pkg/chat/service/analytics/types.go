@@ -0,0 +1,34 @@
+package analytics
+
+import (
+	"app/pkg/chat/domain/entity"
+	"context"
+	"time"
+)
+
+// AnalyticsParams represents the messages analytics are computed over
+type AnalyticsParams struct {
+	ClientID string    // Empty covers every client
+	Start    time.Time // Zero means 30 days before End
+	End      time.Time // Zero means now
+	Timezone string    // IANA time zone days and months are bucketed in, empty means UTC
+}
+
+// AnalyticsService defines the interface for reporting how chat is used
+// Results are cached in Redis, a range still in progress is recomputed every few minutes
+type AnalyticsService interface {
+	// GetSummary counts the messages, active senders and active chatrooms
+	GetSummary(ctx context.Context, params AnalyticsParams) (*entity.ActivitySummary, error)
+
+	// GetDailyActivity counts the messages and active senders of every day of the range, oldest first
+	GetDailyActivity(ctx context.Context, params AnalyticsParams) ([]*entity.DailyActivity, error)
+
+	// GetMonthlyActivity counts the messages and active senders of every month of the range, oldest first
+	GetMonthlyActivity(ctx context.Context, params AnalyticsParams) ([]*entity.MonthlyActivity, error)
+
+	// GetTopChatrooms retrieves the chatrooms with the most messages, most first
+	GetTopChatrooms(ctx context.Context, params AnalyticsParams, limit int) ([]*entity.ChatroomActivity, error)
+
+	// GetDirectResponseTime averages how long users take to answer direct messages
+	GetDirectResponseTime(ctx context.Context, params AnalyticsParams) (*entity.ResponseTime, error)
+}
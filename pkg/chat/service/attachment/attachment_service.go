@@ -0,0 +1,186 @@
+package attachment
+
+import (
+	"app/pkg/chat/domain/entity"
+	"app/pkg/chat/domain/repository"
+	"app/pkg/chat/service/chatroom"
+	"app/pkg/exception"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// sniffLength is the number of bytes used to detect the content type of an upload
+const sniffLength = 512
+
+// allowedContentTypes lists the sniffed content types accepted for attachments
+// Types that browsers could render as active content (e.g. text/html) are deliberately left out
+var allowedContentTypes = map[string]bool{
+	"image/jpeg":      true,
+	"image/png":       true,
+	"image/gif":       true,
+	"image/webp":      true,
+	"image/bmp":       true,
+	"audio/mpeg":      true,
+	"audio/wave":      true,
+	"audio/ogg":       true,
+	"video/mp4":       true,
+	"video/webm":      true,
+	"video/avi":       true,
+	"application/pdf": true,
+	"application/zip": true,
+	"application/ogg": true,
+	"text/plain":      true,
+}
+
+type attachmentService struct {
+	attachmentRepository repository.AttachmentRepository
+	fileStorage          repository.FileStorage
+	chatroomService      chatroom.ChatroomService
+	maxSize              int64
+}
+
+// NewAttachmentService creates a new instance of AttachmentService
+// maxSize is the largest accepted file size in bytes
+func NewAttachmentService(attachmentRepository repository.AttachmentRepository, fileStorage repository.FileStorage, chatroomService chatroom.ChatroomService, maxSize int64) AttachmentService {
+	return &attachmentService{
+		attachmentRepository: attachmentRepository,
+		fileStorage:          fileStorage,
+		chatroomService:      chatroomService,
+		maxSize:              maxSize,
+	}
+}
+
+// Upload stores a file for a chatroom so it can be attached to a message
+func (s *attachmentService) Upload(ctx context.Context, params UploadParams) (*entity.Attachment, error) {
+	isParticipant, err := s.chatroomService.IsParticipant(ctx, params.ChatroomID, params.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if !isParticipant {
+		return nil, exception.Forbidden()
+	}
+
+	// Detect the content type from the content itself instead of trusting the client
+	head := make([]byte, sniffLength)
+	n, err := io.ReadFull(params.Reader, head)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		if err == io.EOF {
+			return nil, exception.BadRequest("File is empty")
+		}
+		return nil, exception.BadRequest("Failed to read file")
+	}
+	head = head[:n]
+
+	contentType := http.DetectContentType(head)
+	mediaType := strings.TrimSpace(strings.Split(contentType, ";")[0])
+	if !allowedContentTypes[mediaType] {
+		return nil, exception.BadRequest(fmt.Sprintf("File type %s is not allowed", mediaType))
+	}
+
+	name := sanitizeName(params.Name)
+	now := time.Now()
+	key := fmt.Sprintf("%s/%d_%s", params.ChatroomID, now.UnixNano(), name)
+
+	// Read at most one byte past the limit so oversized files can be detected while streaming
+	reader := io.LimitReader(io.MultiReader(bytes.NewReader(head), params.Reader), s.maxSize+1)
+	size, err := s.fileStorage.Store(ctx, key, reader)
+	if err != nil {
+		return nil, err
+	}
+	if size > s.maxSize {
+		s.fileStorage.Delete(ctx, key)
+		return nil, exception.Http(http.StatusRequestEntityTooLarge, fmt.Sprintf("File exceeds the maximum size of %d bytes", s.maxSize))
+	}
+
+	kind := entity.AttachmentKindFile
+	if strings.HasPrefix(mediaType, "image/") {
+		kind = entity.AttachmentKindImage
+	}
+
+	attachment := &entity.Attachment{
+		Name:             name,
+		Key:              key,
+		ContentType:      contentType,
+		Kind:             kind,
+		Size:             size,
+		Chatroom:         params.ChatroomID,
+		Uploader:         params.UserID,
+		CreatedTimestamp: now.Unix(),
+	}
+
+	if err := s.attachmentRepository.Create(ctx, attachment); err != nil {
+		s.fileStorage.Delete(ctx, key) // Clean up on error
+		return nil, err
+	}
+
+	return attachment, nil
+}
+
+// Open retrieves an attachment and a reader for its content
+func (s *attachmentService) Open(ctx context.Context, attachmentID string, userID string) (*entity.Attachment, io.ReadCloser, error) {
+	attachment, err := s.attachmentRepository.Get(ctx, attachmentID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if attachment == nil {
+		return nil, nil, exception.NotFound("Attachment")
+	}
+
+	isParticipant, err := s.chatroomService.IsParticipant(ctx, attachment.Chatroom, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !isParticipant {
+		return nil, nil, exception.Forbidden()
+	}
+
+	// Unsent attachments are only visible to their uploader
+	if attachment.Chat == nil && attachment.Uploader != userID {
+		return nil, nil, exception.NotFound("Attachment")
+	}
+
+	reader, err := s.fileStorage.Open(ctx, attachment.Key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return attachment, reader, nil
+}
+
+// RemoveAttachments deletes the files and records of the attachments of a deleted message
+func (s *attachmentService) RemoveAttachments(ctx context.Context, attachments []entity.Attachment) error {
+	for _, attachment := range attachments {
+		if err := s.fileStorage.Delete(ctx, attachment.Key); err != nil {
+			return err
+		}
+		if err := s.attachmentRepository.Delete(ctx, attachment.ID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// sanitizeName strips directories and unsafe characters from an uploaded file name
+func sanitizeName(name string) string {
+	name = filepath.Base(strings.ReplaceAll(name, "\\", "/"))
+	name = strings.Map(func(r rune) rune {
+		switch {
+		case r < 0x20, r == 0x7f, strings.ContainsRune(`/\:*?"<>|`, r):
+			return '_'
+		}
+		return r
+	}, name)
+
+	if name == "" || name == "." || name == ".." {
+		return "file"
+	}
+
+	return name
+}
@@ -0,0 +1,34 @@
+package attachment
+
+import (
+	"app/pkg/chat/domain/entity"
+	"context"
+	"io"
+)
+
+// UploadParams represents parameters for uploading an attachment
+type UploadParams struct {
+	ChatroomID string
+	UserID     string // User uploading the attachment
+	Name       string // Original file name
+	Reader     io.Reader
+}
+
+// AttachmentService defines the interface for chat attachment operations
+type AttachmentService interface {
+	// Upload stores a file for a chatroom so it can be attached to a message
+	// It will validate:
+	// - The user is a participant in the chatroom
+	// - The file doesn't exceed the upload size limit
+	// - The sniffed content type is allowed
+	// Returns the created attachment
+	Upload(ctx context.Context, params UploadParams) (*entity.Attachment, error)
+
+	// Open retrieves an attachment and a reader for its content
+	// The user must be a participant in the attachment's chatroom
+	// The caller is responsible for closing the reader
+	Open(ctx context.Context, attachmentID string, userID string) (*entity.Attachment, io.ReadCloser, error)
+
+	// RemoveAttachments deletes the files and records of the attachments of a deleted message
+	RemoveAttachments(ctx context.Context, attachments []entity.Attachment) error
+}
@@ -4,31 +4,69 @@ import (
 	"app/pkg/chat/domain/entity"
 	"app/pkg/chat/domain/repository"
 	"app/pkg/chat/service/chatroom"
+	"app/pkg/chat/service/moderation"
+	"app/pkg/chat/service/ratelimit"
 	"app/pkg/exception"
 	"app/pkg/types/pagination"
 	"context"
 	"fmt"
 	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	// maxAttachmentsPerMessage is the maximum number of attachments a single message can carry
+	maxAttachmentsPerMessage = 10
+	// maxClientMessageIDLength is the maximum length of a client generated message ID
+	maxClientMessageIDLength = 64
+	// maxUpdatesLimit is the maximum number of changes replayed at once
+	maxUpdatesLimit = 100
 )
 
 type chatService struct {
-	chatRepository  repository.ChatRepository
-	chatroomService chatroom.ChatroomService
+	chatRepository       repository.ChatRepository
+	chatroomRepository   repository.ChatroomRepository
+	attachmentRepository repository.AttachmentRepository
+	chatroomService      chatroom.ChatroomService
+	publisher            EventPublisher
+	notifier             MessageNotifier
+	limiter              RateLimiter
+	moderator            MessageModerator
+	dmPolicy             DirectMessagePolicy
+	attachmentRemover    AttachmentRemover
 }
 
 // NewChatService creates a new instance of ChatService
-func NewChatService(chatRepository repository.ChatRepository, chatroomService chatroom.ChatroomService) ChatService {
+func NewChatService(chatRepository repository.ChatRepository, chatroomRepository repository.ChatroomRepository, attachmentRepository repository.AttachmentRepository, chatroomService chatroom.ChatroomService, publisher EventPublisher, notifier MessageNotifier, limiter RateLimiter, moderator MessageModerator, dmPolicy DirectMessagePolicy, attachmentRemover AttachmentRemover) ChatService {
 	return &chatService{
-		chatRepository:  chatRepository,
-		chatroomService: chatroomService,
+		chatRepository:       chatRepository,
+		chatroomRepository:   chatroomRepository,
+		attachmentRepository: attachmentRepository,
+		chatroomService:      chatroomService,
+		publisher:            publisher,
+		notifier:             notifier,
+		limiter:              limiter,
+		moderator:            moderator,
+		dmPolicy:             dmPolicy,
+		attachmentRemover:    attachmentRemover,
 	}
 }
 
-// GetChat retrieves a single chat message by ID
-func (s *chatService) GetChat(ctx context.Context, chatID string) (*entity.Chat, error) {
-	chat, err := s.chatRepository.Get(ctx, chatID)
+// GetChat retrieves a single chat message by ID, only for participants of its chatroom
+func (s *chatService) GetChat(ctx context.Context, params GetChatParams) (*entity.Chat, error) {
+	chat, err := s.chatRepository.Get(ctx, params.ChatID)
+	if err != nil || chat == nil {
+		return nil, exception.NotFound("Chat")
+	}
+
+	isParticipant, err := s.chatroomService.IsParticipant(ctx, chat.Chatroom, params.UserID)
 	if err != nil {
-		return nil, exception.NotFound("chatroom")
+		return nil, err
+	}
+	if !isParticipant {
+		return nil, exception.Forbidden()
 	}
 
 	return chat, nil
@@ -39,28 +77,308 @@ func (s *chatService) GetChats(ctx context.Context, filter repository.ChatFilter
 	return s.chatRepository.GetAll(ctx, filter, pag)
 }
 
-// UpdateChat modifies an existing chat message
-func (s *chatService) UpdateChat(ctx context.Context, chat *entity.Chat) error {
-	return s.chatRepository.Update(ctx, chat)
+// GetUpdates retrieves the chatroom's messages created or changed after the given sequence, oldest change first
+func (s *chatService) GetUpdates(ctx context.Context, params GetUpdatesParams, limit int) ([]*entity.Chat, bool, error) {
+	isParticipant, err := s.chatroomService.IsParticipant(ctx, params.ChatroomID, params.UserID)
+	if err != nil {
+		return nil, false, err
+	}
+	if !isParticipant {
+		return nil, false, exception.Forbidden()
+	}
+
+	if limit <= 0 || limit > maxUpdatesLimit {
+		limit = maxUpdatesLimit
+	}
+
+	return s.chatRepository.GetAllAfterSequence(ctx, params.ChatroomID, max(params.Sequence, 0), limit)
+}
+
+// GetHistory retrieves a chatroom's messages newest first using before/after cursors
+func (s *chatService) GetHistory(ctx context.Context, params GetHistoryParams, cursor pagination.Cursor) ([]*entity.Chat, pagination.CursorMetadata, error) {
+	metadata := pagination.CursorMetadata{Limit: cursor.Limit}
+
+	isParticipant, err := s.chatroomService.IsParticipant(ctx, params.ChatroomID, params.UserID)
+	if err != nil {
+		return nil, metadata, err
+	}
+	if !isParticipant {
+		return nil, metadata, exception.Forbidden()
+	}
+
+	before, err := decodeChatCursor(cursor.Before)
+	if err != nil {
+		return nil, metadata, err
+	}
+	after, err := decodeChatCursor(cursor.After)
+	if err != nil {
+		return nil, metadata, err
+	}
+
+	filter := repository.ChatFilter{
+		ChatroomID: params.ChatroomID,
+	}
+
+	chats, hasMore, err := s.chatRepository.GetAllByCursor(ctx, filter, before, after, cursor.Limit)
+	if err != nil {
+		return nil, metadata, err
+	}
+
+	metadata.Count = len(chats)
+	metadata.HasMore = hasMore
+	if len(chats) > 0 {
+		newest, oldest := chats[0], chats[len(chats)-1]
+		metadata.After = pagination.EncodeCursor(newest.CreatedTimestamp, newest.ID)
+		metadata.Before = pagination.EncodeCursor(oldest.CreatedTimestamp, oldest.ID)
+	} else {
+		// Keep the caller's position so polling for newer messages can continue
+		metadata.After = cursor.After
+		metadata.Before = cursor.Before
+	}
+
+	return chats, metadata, nil
+}
+
+// decodeChatCursor decodes an optional opaque cursor into a chat keyset position
+func decodeChatCursor(cursor string) (*repository.ChatCursor, error) {
+	if cursor == "" {
+		return nil, nil
+	}
+
+	timestamp, id, err := pagination.DecodeCursor(cursor)
+	if err != nil {
+		return nil, exception.BadRequest("Invalid cursor")
+	}
+
+	return &repository.ChatCursor{Timestamp: timestamp, ID: id}, nil
+}
+
+// GetReplies retrieves the replies to a message, newest first
+func (s *chatService) GetReplies(ctx context.Context, params GetRepliesParams, pag pagination.Pagination) ([]*entity.ChatPopulated, int64, error) {
+	parent, err := s.chatRepository.Get(ctx, params.ChatID)
+	if err != nil || parent == nil {
+		return nil, 0, exception.NotFound("Chat")
+	}
+
+	isParticipant, err := s.chatroomService.IsParticipant(ctx, parent.Chatroom, params.UserID)
+	if err != nil {
+		return nil, 0, err
+	}
+	if !isParticipant {
+		return nil, 0, exception.Forbidden()
+	}
+
+	filter := repository.ChatFilter{
+		ChatroomID: parent.Chatroom,
+		ReplyTo:    parent.ID,
+	}
+
+	return s.chatRepository.GetAllPopulated(ctx, filter, pag)
+}
+
+// ToggleReaction adds the user's emoji reaction to a message, or removes it if it already exists
+func (s *chatService) ToggleReaction(ctx context.Context, params ToggleReactionParams) (*entity.Chat, bool, error) {
+	if params.Emoji == "" {
+		return nil, false, exception.BadRequest("Emoji is required")
+	}
+
+	chat, err := s.chatRepository.Get(ctx, params.ChatID)
+	if err != nil || chat == nil {
+		return nil, false, exception.NotFound("Chat")
+	}
+
+	if chat.DeletedTimestamp != nil {
+		return nil, false, exception.BadRequest("Deleted messages can't be reacted to")
+	}
+
+	isParticipant, err := s.chatroomService.IsParticipant(ctx, chat.Chatroom, params.UserID)
+	if err != nil {
+		return nil, false, err
+	}
+	if !isParticipant {
+		return nil, false, exception.Forbidden()
+	}
+
+	reaction := entity.ChatReaction{
+		Emoji:            params.Emoji,
+		User:             params.UserID,
+		CreatedTimestamp: time.Now().Unix(),
+	}
+
+	added, err := s.chatRepository.ToggleReaction(ctx, chat.ID, reaction)
+	if err != nil {
+		return nil, false, err
+	}
+
+	sequence, err := s.chatRepository.Touch(ctx, chat.ID, chat.Chatroom)
+	if err != nil {
+		return nil, false, err
+	}
+	chat.UpdatedSequence = sequence
+
+	// Reload to return the reactions as stored
+	updated, err := s.chatRepository.Get(ctx, chat.ID)
+	if err != nil {
+		return nil, false, err
+	}
+	if updated != nil {
+		chat = updated
+	}
+
+	s.publisher.ReactionToggled(ctx, chat, reaction, added)
+
+	return chat, added, nil
 }
 
-// RemoveChat deletes a chat message by ID
-func (s *chatService) RemoveChat(ctx context.Context, chatID string) error {
-	chat, err := s.chatRepository.Get(ctx, chatID)
+// UpdateChat edits the content of an existing chat message
+func (s *chatService) UpdateChat(ctx context.Context, params UpdateChatParams) (*entity.Chat, error) {
+	chat, err := s.chatRepository.Get(ctx, params.ChatID)
+	if err != nil || chat == nil {
+		return nil, exception.NotFound("Chat")
+	}
+
+	if chat.DeletedTimestamp != nil {
+		return nil, exception.BadRequest("Deleted messages can't be edited")
+	}
+
+	if err := s.authorizeModification(ctx, chat, params.UserID); err != nil {
+		return nil, err
+	}
+
+	// Edits go through the same moderation as new messages
+	moderationParams := moderation.ModerateParams{
+		ClientID:   chat.ClientID,
+		ChatroomID: chat.Chatroom,
+		SenderID:   params.UserID,
+		Message:    params.Message,
+	}
+	message, err := s.moderator.Moderate(ctx, moderationParams)
+	if err != nil {
+		return nil, err
+	}
+
+	// Keep the previous content in the edit history
+	now := time.Now().Unix()
+	edit := entity.ChatEdit{
+		Message:         chat.Message,
+		EditedBy:        params.UserID,
+		EditedTimestamp: now,
+	}
+
+	if err := s.chatRepository.Edit(ctx, chat.ID, message, edit); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, exception.NotFound("Chat")
+		}
+		return nil, err
+	}
+
+	sequence, err := s.chatRepository.Touch(ctx, chat.ID, chat.Chatroom)
 	if err != nil {
-		return exception.NotFound("chatroom")
+		return nil, err
 	}
+	chat.UpdatedSequence = sequence
+
+	chat.Message = message
+	chat.EditedTimestamp = &now
+	chat.EditHistory = append(chat.EditHistory, edit)
+
+	s.publisher.MessageEdited(ctx, chat, params.UserID)
 
-	return s.chatRepository.Delete(ctx, chat.ID)
+	return chat, nil
+}
+
+// RemoveChat soft deletes a chat message, leaving a tombstone in its place
+func (s *chatService) RemoveChat(ctx context.Context, params RemoveChatParams) (*entity.Chat, error) {
+	chat, err := s.chatRepository.Get(ctx, params.ChatID)
+	if err != nil || chat == nil {
+		return nil, exception.NotFound("Chat")
+	}
+
+	// Deleting a tombstone again is a no-op
+	if chat.DeletedTimestamp != nil {
+		return chat, nil
+	}
+
+	if err := s.authorizeModification(ctx, chat, params.UserID); err != nil {
+		return nil, err
+	}
+
+	now := time.Now().Unix()
+	if err := s.chatRepository.SoftDelete(ctx, chat.ID, params.UserID, now); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, exception.NotFound("Chat")
+		}
+		return nil, err
+	}
+
+	// The deleted message may be the chatroom's preview
+	if err := s.chatroomRepository.RecomputeStats(ctx, chat.Chatroom); err != nil {
+		fmt.Printf("Error recomputing stats of chatroom %s: %v\n", chat.Chatroom, err)
+	}
+
+	// The tombstone no longer lists its attachments, remove them so they can't be opened either
+	if err := s.attachmentRemover.RemoveAttachments(ctx, chat.Attachments); err != nil {
+		fmt.Printf("Error removing attachments of chat %s: %v\n", chat.ID, err)
+	}
+
+	sequence, err := s.chatRepository.Touch(ctx, chat.ID, chat.Chatroom)
+	if err != nil {
+		return nil, err
+	}
+	chat.UpdatedSequence = sequence
+
+	chat.Message = ""
+	chat.EditHistory = nil
+	chat.Attachments = nil
+	chat.Metadata = nil
+	chat.DeletedTimestamp = &now
+	chat.DeletedBy = &params.UserID
+
+	s.publisher.MessageDeleted(ctx, chat, params.UserID)
+
+	return chat, nil
+}
+
+// authorizeModification checks that the user is the sender of the message or an admin of its chatroom
+func (s *chatService) authorizeModification(ctx context.Context, chat *entity.Chat, userID string) error {
+	if chat.Sender == userID {
+		return nil
+	}
+
+	participant, err := s.chatroomService.GetParticipant(ctx, chat.Chatroom, userID)
+	if err != nil {
+		return err
+	}
+
+	if participant == nil || participant.Role == entity.ParticipantRoleMember {
+		return exception.Forbidden()
+	}
+
+	return nil
 }
 
 // SendMessage sends a message to a chatroom
 func (s *chatService) SendMessage(ctx context.Context, params SendMessageParams) (*entity.Chat, error) {
+	// A retried send returns the message stored by the first attempt
+	if params.ClientMessageID != "" {
+		if len(params.ClientMessageID) > maxClientMessageIDLength {
+			return nil, exception.BadRequest(fmt.Sprintf("Client message ID can be at most %d characters", maxClientMessageIDLength))
+		}
+
+		sent, err := s.getSentMessage(ctx, params)
+		if err != nil || sent != nil {
+			return sent, err
+		}
+	}
+
 	// Validate chatroom exists and user is participant
 	chatroom, err := s.chatroomService.GetChatroom(ctx, params.ChatroomID)
 	if err != nil {
 		return nil, err
 	}
+	if chatroom == nil || chatroom.ClientID != params.ClientID {
+		return nil, exception.NotFound("Chatroom")
+	}
 
 	// Validate sender is a participant and not muted
 	var isParticipant bool
@@ -81,93 +399,182 @@ func (s *chatService) SendMessage(ctx context.Context, params SendMessageParams)
 		return nil, fmt.Errorf("sender is not a participant in the chatroom")
 	}
 
+	// Direct conversations stay subject to the other participant's block list and privacy
+	if !chatroom.IsGroup {
+		for _, p := range chatroom.Participants {
+			if p.User.ID == params.SenderID {
+				continue
+			}
+			if err := s.dmPolicy.CanDirectMessage(ctx, params.SenderID, p.User.ID); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	rateParams := ratelimit.AllowMessageParams{
+		UserID:     params.SenderID,
+		ChatroomID: params.ChatroomID,
+		ClientID:   params.ClientID,
+	}
+	if err := s.limiter.AllowMessage(ctx, rateParams); err != nil {
+		return nil, err
+	}
+
+	moderationParams := moderation.ModerateParams{
+		ClientID:   params.ClientID,
+		ChatroomID: params.ChatroomID,
+		SenderID:   params.SenderID,
+		Message:    params.Message,
+	}
+	message, err := s.moderator.Moderate(ctx, moderationParams)
+	if err != nil {
+		return nil, err
+	}
+
 	// Create and save the chat message
 	newChat := &entity.Chat{
-		Message:          params.Message,
+		ClientID:         chatroom.ClientID,
+		Message:          message,
 		Sender:           params.SenderID,
 		Chatroom:         params.ChatroomID,
 		CreatedTimestamp: time.Now().Unix(),
+		Metadata:         params.Metadata,
+		ClientMessageID:  params.ClientMessageID,
 	}
 
-	if err := s.chatRepository.Create(ctx, newChat); err != nil {
+	// Validate the replied message belongs to the same chatroom
+	if params.ReplyTo != "" {
+		parent, err := s.chatRepository.Get(ctx, params.ReplyTo)
+		if err != nil || parent == nil || parent.Chatroom != params.ChatroomID {
+			return nil, exception.NotFound("Replied chat")
+		}
+		newChat.ReplyTo = &parent.ID
+	}
+
+	attachments, err := s.resolveAttachments(ctx, params)
+	if err != nil {
 		return nil, err
 	}
+	newChat.Attachments = attachments
 
-	return newChat, nil
-}
+	if newChat.Message == "" && len(newChat.Attachments) == 0 {
+		return nil, exception.BadRequest("Message or attachment is required")
+	}
 
-// SendDirectMessage sends a direct message to another user
-func (s *chatService) SendDirectMessage(ctx context.Context, params SendDirectMessageParams) (*entity.Chat, *entity.Chatroom, error) {
-	chatroomType := entity.ChatroomTypePrivate
+	// Link the attachments to the message before storing it, so concurrent sends can't both use them
+	newChat.ID = primitive.NewObjectID().Hex()
+	if err := s.claimAttachments(ctx, newChat); err != nil {
+		return nil, err
+	}
+
+	if err := s.chatRepository.Create(ctx, newChat); err != nil {
+		s.releaseAttachments(ctx, newChat, len(newChat.Attachments))
 
-	// Find or create direct chatroom
-	filter := repository.ChatroomFilter{
-		Type:          &chatroomType,
-		ParticipantID: params.SenderID,
+		// A concurrent retry stored the message first
+		if params.ClientMessageID != "" && mongo.IsDuplicateKeyError(err) {
+			return s.getSentMessage(ctx, params)
+		}
+		return nil, err
 	}
 
-	chatrooms, _, err := s.chatroomService.GetChatrooms(ctx, filter, pagination.Pagination{})
+	s.publisher.MessageSent(ctx, newChat)
+	s.notifier.MessageSent(ctx, params.ClientID, newChat)
+
+	return newChat, nil
+}
+
+// getSentMessage retrieves the message the sender already sent with the params' client message ID, if any
+func (s *chatService) getSentMessage(ctx context.Context, params SendMessageParams) (*entity.Chat, error) {
+	sent, err := s.chatRepository.GetByClientMessageID(ctx, params.SenderID, params.ClientMessageID)
 	if err != nil {
-		return nil, nil, err
+		return nil, err
+	}
+	if sent != nil && sent.Chatroom != params.ChatroomID {
+		return nil, exception.BadRequest("Client message ID was already used in another chatroom")
 	}
 
-	var directChatroom *entity.ChatroomPopulated
-	for _, cr := range chatrooms {
-		if len(cr.Participants) == 2 {
-			hasReceiver := false
-			for _, p := range cr.Participants {
-				if p.User.ID == params.ReceiverID {
-					hasReceiver = true
-					break
-				}
-			}
-			if hasReceiver {
-				directChatroom = cr
-				break
-			}
-		}
+	return sent, nil
+}
+
+// resolveAttachments loads the attachments referenced by a new message
+// Only attachments uploaded by the sender to the same chatroom that haven't been sent yet are accepted
+func (s *chatService) resolveAttachments(ctx context.Context, params SendMessageParams) ([]entity.Attachment, error) {
+	if len(params.Attachments) == 0 {
+		return nil, nil
 	}
 
-	var resultChatroom *entity.Chatroom
+	if len(params.Attachments) > maxAttachmentsPerMessage {
+		return nil, exception.BadRequest(fmt.Sprintf("A message can have at most %d attachments", maxAttachmentsPerMessage))
+	}
 
-	// Create new direct chatroom if it doesn't exist
-	if directChatroom == nil {
-		createParams := chatroom.CreateChatroomParams{
-			Type:         chatroomType,
-			IsGroup:      false,
-			Creator:      params.SenderID,
-			Participants: []string{params.SenderID, params.ReceiverID},
+	attachments := make([]entity.Attachment, 0, len(params.Attachments))
+	seen := make(map[string]bool, len(params.Attachments))
+	for _, id := range params.Attachments {
+		if seen[id] {
+			continue
 		}
+		seen[id] = true
 
-		var err error
-		resultChatroom, err = s.chatroomService.CreateChatroom(ctx, createParams)
+		attachment, err := s.attachmentRepository.Get(ctx, id)
 		if err != nil {
-			return nil, nil, err
+			return nil, err
+		}
+		if attachment == nil || attachment.Chatroom != params.ChatroomID || attachment.Uploader != params.SenderID {
+			return nil, exception.NotFound("Attachment")
+		}
+		if attachment.Chat != nil {
+			return nil, exception.BadRequest("Attachment has already been sent")
 		}
 
-		// Get the populated version of the new chatroom
-		directChatroom, err = s.chatroomService.GetChatroom(ctx, resultChatroom.ID)
+		attachments = append(attachments, *attachment)
+	}
+
+	return attachments, nil
+}
+
+// claimAttachments links the attachments of a new message to it
+// Fails if another message claimed one of them first, releasing the ones already claimed
+func (s *chatService) claimAttachments(ctx context.Context, chat *entity.Chat) error {
+	for i, attachment := range chat.Attachments {
+		attached, err := s.attachmentRepository.Attach(ctx, attachment.ID, chat.ID)
+		if err == nil && !attached {
+			err = exception.Http(409, "Attachment has already been sent")
+		}
 		if err != nil {
-			return nil, nil, err
+			s.releaseAttachments(ctx, chat, i)
+			return err
 		}
-	} else {
-		// Convert populated chatroom to regular chatroom
-		resultChatroom = &entity.Chatroom{
-			ID:                   directChatroom.ID,
-			Name:                 directChatroom.Name,
-			IsGroup:              directChatroom.IsGroup,
-			Type:                 directChatroom.Type,
-			CreatedTimestamp:     directChatroom.CreatedTimestamp,
-			LastMessage:          directChatroom.LastMessage,
-			LastMessageTimestamp: directChatroom.LastMessageTimestamp,
-			MessagesCount:        directChatroom.MessagesCount,
+	}
+
+	return nil
+}
+
+// releaseAttachments unlinks the first count attachments of a message that couldn't be stored
+func (s *chatService) releaseAttachments(ctx context.Context, chat *entity.Chat, count int) {
+	for _, attachment := range chat.Attachments[:count] {
+		if err := s.attachmentRepository.Detach(ctx, attachment.ID, chat.ID); err != nil {
+			fmt.Printf("Error releasing attachment %s: %v\n", attachment.ID, err)
 		}
 	}
+}
+
+// SendDirectMessage sends a direct message to another user
+func (s *chatService) SendDirectMessage(ctx context.Context, params SendDirectMessageParams) (*entity.Chat, *entity.Chatroom, error) {
+	// Don't open a chatroom the receiver doesn't accept messages in
+	if err := s.dmPolicy.CanDirectMessage(ctx, params.SenderID, params.ReceiverID); err != nil {
+		return nil, nil, err
+	}
+
+	directChatroom, err := s.chatroomService.GetOrCreateDirectChatroom(ctx, params.ClientID, params.SenderID, params.ReceiverID)
+	if err != nil {
+		return nil, nil, err
+	}
 
 	// Send the message
 	msgParams := SendMessageParams{
 		ChatroomID: directChatroom.ID,
 		SenderID:   params.SenderID,
+		ClientID:   params.ClientID,
 		Message:    params.Message,
 	}
 
@@ -176,5 +583,5 @@ func (s *chatService) SendDirectMessage(ctx context.Context, params SendDirectMe
 		return nil, nil, err
 	}
 
-	return newChat, resultChatroom, nil
+	return newChat, directChatroom, nil
 }
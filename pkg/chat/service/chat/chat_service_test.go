@@ -0,0 +1,28 @@
+package chat
+
+import (
+	"app/pkg/exception"
+	"app/pkg/types/pagination"
+	"errors"
+	"testing"
+)
+
+func TestDecodeChatCursor(t *testing.T) {
+	cursor, err := decodeChatCursor("")
+	if cursor != nil || err != nil {
+		t.Errorf("decodeChatCursor(\"\") = %+v, %v, want no cursor", cursor, err)
+	}
+
+	cursor, err = decodeChatCursor(pagination.EncodeCursor(1718000000, "6650a1b2c3d4e5f607182930"))
+	if err != nil {
+		t.Fatalf("decodeChatCursor: %v", err)
+	}
+	if cursor.Timestamp != 1718000000 || cursor.ID != "6650a1b2c3d4e5f607182930" {
+		t.Errorf("decodeChatCursor = %+v, want the encoded position", cursor)
+	}
+
+	var httpError exception.HttpError
+	if _, err := decodeChatCursor("garbage!"); !errors.As(err, &httpError) || httpError.Code != 400 {
+		t.Errorf("decodeChatCursor(garbage) error = %v, want a bad request", err)
+	}
+}
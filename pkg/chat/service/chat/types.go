@@ -3,49 +3,179 @@ package chat
 import (
 	"app/pkg/chat/domain/entity"
 	"app/pkg/chat/domain/repository"
+	"app/pkg/chat/service/moderation"
+	"app/pkg/chat/service/ratelimit"
 	"app/pkg/types/pagination"
 	"context"
 )
 
 // SendMessageParams represents parameters for sending a message
 type SendMessageParams struct {
+	ChatroomID  string
+	SenderID    string
+	ClientID    string // Client the message is sent through, notifications for offline participants are routed to it
+	Message     string
+	ReplyTo     string                 // Optional parent message ID within the same chatroom
+	Metadata    map[string]interface{} // Optional client defined metadata
+	Attachments []string               // Optional IDs of attachments uploaded by the sender to the chatroom
+	// Optional ID generated by the sender's client, sending again with the same ID returns the stored message
+	ClientMessageID string
+}
+
+// UpdateChatParams represents parameters for editing a message
+type UpdateChatParams struct {
+	ChatID  string
+	UserID  string // User performing the edit
+	Message string
+}
+
+// RemoveChatParams represents parameters for deleting a message
+type RemoveChatParams struct {
+	ChatID string
+	UserID string // User performing the deletion
+}
+
+// GetHistoryParams represents parameters for browsing a chatroom's message history
+type GetHistoryParams struct {
 	ChatroomID string
-	SenderID   string
-	Message    string
+	UserID     string // User requesting the history
+}
+
+// ToggleReactionParams represents parameters for toggling a reaction on a message
+type ToggleReactionParams struct {
+	ChatID string
+	UserID string
+	Emoji  string
+}
+
+// GetChatParams represents parameters for retrieving a single chat message
+type GetChatParams struct {
+	ChatID string
+	UserID string // User requesting the message
+}
+
+// GetRepliesParams represents parameters for listing the replies of a thread
+type GetRepliesParams struct {
+	ChatID string
+	UserID string // User requesting the thread
+}
+
+// GetUpdatesParams represents parameters for replaying a chatroom's changes since a sequence
+type GetUpdatesParams struct {
+	ChatroomID string
+	UserID     string // User requesting the replay
+	Sequence   int64  // Last sequence the user has seen
+}
+
+// EventPublisher notifies connected clients about chat message changes
+type EventPublisher interface {
+	// MessageSent notifies that a new message was sent
+	MessageSent(ctx context.Context, chat *entity.Chat)
+
+	// MessageEdited notifies that a message was edited by the given user
+	MessageEdited(ctx context.Context, chat *entity.Chat, userID string)
+
+	// MessageDeleted notifies that a message was deleted by the given user
+	MessageDeleted(ctx context.Context, chat *entity.Chat, userID string)
+
+	// ReactionToggled notifies that a reaction was added to or removed from a message
+	ReactionToggled(ctx context.Context, chat *entity.Chat, reaction entity.ChatReaction, added bool)
+}
+
+// MessageNotifier notifies participants who aren't connected about new messages
+type MessageNotifier interface {
+	// MessageSent notifies the offline participants of the message's chatroom
+	MessageSent(ctx context.Context, clientID string, chat *entity.Chat)
+}
+
+// RateLimiter limits how fast messages can be sent
+type RateLimiter interface {
+	// AllowMessage returns a 429 error if the sender, chatroom or client sent too many messages recently
+	AllowMessage(ctx context.Context, params ratelimit.AllowMessageParams) error
+}
+
+// MessageModerator checks messages before they're stored
+type MessageModerator interface {
+	// Moderate returns the text to store, which may be masked, or an error if the message was rejected
+	Moderate(ctx context.Context, params moderation.ModerateParams) (string, error)
+}
+
+// AttachmentRemover removes the attachments of deleted messages
+type AttachmentRemover interface {
+	// RemoveAttachments deletes the files and records of the attachments
+	RemoveAttachments(ctx context.Context, attachments []entity.Attachment) error
+}
+
+// DirectMessagePolicy decides who can send direct messages to whom
+type DirectMessagePolicy interface {
+	// CanDirectMessage returns an error if the receiver blocked the sender or doesn't accept direct messages from them
+	CanDirectMessage(ctx context.Context, senderID string, receiverID string) error
 }
 
 // SendDirectMessageParams represents parameters for sending a direct message
 type SendDirectMessageParams struct {
 	SenderID   string
+	ClientID   string // Client the message is sent through
 	ReceiverID string
 	Message    string
 }
 
 // ChatService defines the interface for chat-related operations
 type ChatService interface {
-	// GetChat retrieves a single chat message by ID
-	GetChat(ctx context.Context, chatID string) (*entity.Chat, error)
+	// GetChat retrieves a single chat message by ID, only for participants of its chatroom
+	GetChat(ctx context.Context, params GetChatParams) (*entity.Chat, error)
 
 	// GetChats retrieves multiple chat messages with filtering and pagination
 	GetChats(ctx context.Context, filter repository.ChatFilter, pag pagination.Pagination) ([]*entity.Chat, int64, error)
 
-	// UpdateChat modifies an existing chat message
-	UpdateChat(ctx context.Context, chat *entity.Chat) error
+	// UpdateChat edits the content of an existing chat message
+	// Only the sender or a chatroom admin can edit a message, and deleted messages can't be edited
+	// The previous content is appended to the message's edit history
+	// Returns the updated chat message
+	UpdateChat(ctx context.Context, params UpdateChatParams) (*entity.Chat, error)
+
+	// RemoveChat soft deletes a chat message, leaving a tombstone in its place
+	// Only the sender or a chatroom admin can delete a message
+	// Returns the tombstoned chat message
+	RemoveChat(ctx context.Context, params RemoveChatParams) (*entity.Chat, error)
+
+	// GetHistory retrieves a chatroom's messages newest first using before/after cursors
+	// The requesting user must be a participant in the chatroom
+	// No count query is made; the metadata only reports whether more messages exist
+	GetHistory(ctx context.Context, params GetHistoryParams, cursor pagination.Cursor) ([]*entity.Chat, pagination.CursorMetadata, error)
+
+	// GetUpdates retrieves the chatroom's messages created or changed after the given sequence, oldest change first
+	// The requesting user must be a participant in the chatroom
+	// Returns whether more changes exist beyond the limit
+	GetUpdates(ctx context.Context, params GetUpdatesParams, limit int) ([]*entity.Chat, bool, error)
+
+	// GetReplies retrieves the replies to a message, newest first
+	// The requesting user must be a participant in the message's chatroom
+	GetReplies(ctx context.Context, params GetRepliesParams, pag pagination.Pagination) ([]*entity.ChatPopulated, int64, error)
 
-	// RemoveChat deletes a chat message by ID
-	RemoveChat(ctx context.Context, chatID string) error
+	// ToggleReaction adds the user's emoji reaction to a message, or removes it if it already exists
+	// The user must be a participant in the message's chatroom
+	// Returns the updated chat message and whether the reaction was added
+	ToggleReaction(ctx context.Context, params ToggleReactionParams) (*entity.Chat, bool, error)
 
 	// SendMessage sends a message to a chatroom
 	// It will validate:
-	// - The chatroom exists
+	// - The chatroom exists and belongs to the client
 	// - The sender is a participant in the chatroom
 	// - The sender is not muted
+	// - The replied message, if any, belongs to the same chatroom
+	// - The attachments, if any, were uploaded by the sender to the chatroom and aren't sent yet
+	// - The sender, chatroom and client are within their rate limits
+	// - The message passes moderation, which may mask parts of it
+	// - In direct chatrooms, the other participant accepts direct messages from the sender
+	// A message the sender already sent with the same client message ID is returned as is, without notifying anyone again
 	// Returns the created chat message
 	SendMessage(ctx context.Context, params SendMessageParams) (*entity.Chat, error)
 
 	// SendDirectMessage sends a direct message to another user
 	// It will:
-	// - Create a direct chatroom if it doesn't exist
+	// - Check the receiver hasn't blocked the sender and accepts direct messages from them
+	// - Create a direct chatroom if it doesn't exist, both users must belong to the client
 	// - Add both users as participants
 	// - Send the message
 	// Returns the created chat message and the chatroom
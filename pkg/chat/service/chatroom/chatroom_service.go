@@ -3,20 +3,26 @@ package chatroom
 import (
 	"app/pkg/chat/domain/entity"
 	"app/pkg/chat/domain/repository"
+	"app/pkg/exception"
 	"app/pkg/types/pagination"
 	"context"
-	"fmt"
+	"slices"
+	"strings"
 	"time"
 )
 
 type chatroomService struct {
 	chatroomRepo repository.ChatroomRepository
+	userRepo     repository.UserRepository
+	publisher    EventPublisher
 }
 
 // NewChatroomService creates a new instance of ChatroomService
-func NewChatroomService(chatroomRepo repository.ChatroomRepository) ChatroomService {
+func NewChatroomService(chatroomRepo repository.ChatroomRepository, userRepo repository.UserRepository, publisher EventPublisher) ChatroomService {
 	return &chatroomService{
 		chatroomRepo: chatroomRepo,
+		userRepo:     userRepo,
+		publisher:    publisher,
 	}
 }
 
@@ -30,10 +36,68 @@ func (s *chatroomService) GetChatrooms(ctx context.Context, filter repository.Ch
 	return s.chatroomRepo.GetAllPopulated(ctx, filter, pag)
 }
 
+// DiscoverChatrooms retrieves the client's public chatrooms ranked by activity, optionally searching their names
+func (s *chatroomService) DiscoverChatrooms(ctx context.Context, clientID string, userID string, query string, pag pagination.Pagination) ([]*entity.ChatroomListing, int64, error) {
+	filter := repository.ChatroomDiscoveryFilter{
+		ClientID: clientID,
+		Query:    strings.TrimSpace(query),
+		UserID:   userID,
+	}
+
+	return s.chatroomRepo.Discover(ctx, filter, pag)
+}
+
+// GetChatroomIDs retrieves the IDs of every chatroom the user participates in
+func (s *chatroomService) GetChatroomIDs(ctx context.Context, userID string) ([]string, error) {
+	return s.chatroomRepo.GetIDsByParticipant(ctx, userID)
+}
+
+// IsParticipant checks if a user is a participant in the chatroom
+func (s *chatroomService) IsParticipant(ctx context.Context, chatroomID string, userID string) (bool, error) {
+	participant, err := s.GetParticipant(ctx, chatroomID, userID)
+	if err != nil {
+		return false, err
+	}
+
+	return participant != nil, nil
+}
+
+// GetParticipant retrieves a user's participant entry in the chatroom
+func (s *chatroomService) GetParticipant(ctx context.Context, chatroomID string, userID string) (*entity.ChatroomParticipant, error) {
+	chatroom, err := s.chatroomRepo.Get(ctx, chatroomID)
+	if err != nil {
+		return nil, err
+	}
+	if chatroom == nil {
+		return nil, nil
+	}
+
+	return findParticipant(chatroom, userID), nil
+}
+
+// Authorize checks that the actor's role in the chatroom grants the permission
+func (s *chatroomService) Authorize(ctx context.Context, chatroomID string, actorID string, permission Permission) (*entity.Chatroom, error) {
+	chatroom, err := s.getChatroom(ctx, chatroomID)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := authorize(chatroom, actorID, permission); err != nil {
+		return nil, err
+	}
+
+	return chatroom, nil
+}
+
 // CreateChatroom creates a new chatroom
 func (s *chatroomService) CreateChatroom(ctx context.Context, data CreateChatroomParams) (*entity.Chatroom, error) {
+	if err := s.checkClient(ctx, data.ClientID, append([]string{data.Creator}, data.Participants...)...); err != nil {
+		return nil, err
+	}
+
 	// Create the chatroom
 	newChatroom := &entity.Chatroom{
+		ClientID:         data.ClientID,
 		Name:             data.Name,
 		Type:             data.Type,
 		IsGroup:          data.IsGroup,
@@ -42,10 +106,14 @@ func (s *chatroomService) CreateChatroom(ctx context.Context, data CreateChatroo
 		Participants:     make([]entity.ChatroomParticipant, 0, len(data.Participants)),
 	}
 
-	// Add creator as admin
+	// Add creator as super admin of group chats, direct chats have no roles
+	creatorRole := entity.ParticipantRoleMember
+	if data.IsGroup {
+		creatorRole = entity.ParticipantRoleSuperAdmin
+	}
 	creatorParticipant := entity.ChatroomParticipant{
 		User:            data.Creator,
-		Role:            entity.ParticipantRoleAdmin,
+		Role:            creatorRole,
 		JoinedTimestamp: time.Now().Unix(),
 	}
 	newChatroom.Participants = append(newChatroom.Participants, creatorParticipant)
@@ -67,184 +135,394 @@ func (s *chatroomService) CreateChatroom(ctx context.Context, data CreateChatroo
 		return nil, err
 	}
 
+	// Subscribe the participants' live connections to the new chatroom
+	for _, p := range newChatroom.Participants {
+		s.publisher.ParticipantAdded(ctx, newChatroom.ID, p.User)
+	}
+
 	return newChatroom, nil
 }
 
+// GetOrCreateDirectChatroom retrieves the direct chatroom between two users, creating it if they have none
+func (s *chatroomService) GetOrCreateDirectChatroom(ctx context.Context, clientID string, userID string, otherUserID string) (*entity.Chatroom, error) {
+	if userID == otherUserID {
+		return nil, exception.BadRequest("You can't send direct messages to yourself")
+	}
+
+	// Direct keys don't include the client, users of different clients must never share one
+	if err := s.checkClient(ctx, clientID, userID, otherUserID); err != nil {
+		return nil, err
+	}
+
+	now := time.Now().Unix()
+	newChatroom := &entity.Chatroom{
+		ClientID:         clientID,
+		Type:             entity.ChatroomTypePrivate,
+		IsGroup:          false,
+		CreatedTimestamp: now,
+		Participants: []entity.ChatroomParticipant{
+			{User: userID, Role: entity.ParticipantRoleMember, JoinedTimestamp: now},
+			{User: otherUserID, Role: entity.ParticipantRoleMember, JoinedTimestamp: now},
+		},
+		DirectKey: entity.DirectChatroomKey(userID, otherUserID),
+	}
+
+	chatroom, created, err := s.chatroomRepo.FindOrCreateDirect(ctx, newChatroom)
+	if err != nil {
+		return nil, err
+	}
+
+	if created {
+		// Subscribe the participants' live connections to the new chatroom
+		for _, p := range chatroom.Participants {
+			s.publisher.ParticipantAdded(ctx, chatroom.ID, p.User)
+		}
+	}
+
+	return chatroom, nil
+}
+
 // UpdateChatroom modifies an existing chatroom
 func (s *chatroomService) UpdateChatroom(ctx context.Context, data UpdateChatroomParams) (*entity.Chatroom, error) {
 	// Get existing chatroom
-	chatroom, err := s.chatroomRepo.Get(ctx, data.ID)
+	chatroom, err := s.getChatroom(ctx, data.ID)
 	if err != nil {
 		return nil, err
 	}
 
-	// Update fields if provided
-	if data.Name != nil {
-		chatroom.Name = *data.Name
+	if _, err := authorize(chatroom, data.ActorID, PermissionUpdateChatroom); err != nil {
+		return nil, err
+	}
+
+	// The type decides who can see and join the chatroom, and squads carry settings other types don't
+	if data.Type != nil && *data.Type != chatroom.Type {
+		return nil, exception.BadRequest("Chatroom type can't be changed")
+	}
+	if data.Name != nil && *data.Name == "" {
+		return nil, exception.BadRequest("Name can't be empty")
+	}
+	if data.JoinPolicy != nil || data.MaxParticipants != nil {
+		if chatroom.Type != entity.ChatroomTypeSquad {
+			return nil, exception.BadRequest("Join settings are only available for squads")
+		}
 	}
-	if data.Type != nil {
-		chatroom.Type = *data.Type
+	if data.JoinPolicy != nil {
+		if *data.JoinPolicy != entity.JoinPolicyOpen && *data.JoinPolicy != entity.JoinPolicyApproval {
+			return nil, exception.BadRequest("Invalid join policy")
+		}
+	}
+	if data.MaxParticipants != nil {
+		if *data.MaxParticipants < 0 || (*data.MaxParticipants > 0 && *data.MaxParticipants < len(chatroom.Participants)) {
+			return nil, exception.BadRequest("Participant cap can't be lower than the current number of participants")
+		}
 	}
 
 	// Save changes
-	if err := s.chatroomRepo.Update(ctx, chatroom); err != nil {
+	chatroom, err = s.chatroomRepo.UpdateSettings(ctx, data.ID, repository.ChatroomUpdate{
+		Name:            data.Name,
+		JoinPolicy:      data.JoinPolicy,
+		MaxParticipants: data.MaxParticipants,
+	})
+	if err != nil {
 		return nil, err
 	}
+	if chatroom == nil {
+		return nil, exception.NotFound("Chatroom")
+	}
 
+	s.publisher.ChatroomUpdated(ctx, chatroom, data.ActorID)
 	return chatroom, nil
 }
 
 // DeleteChatroom removes a chatroom and all its messages
-func (s *chatroomService) DeleteChatroom(ctx context.Context, id string) error {
+func (s *chatroomService) DeleteChatroom(ctx context.Context, id string, actorID string) error {
 	// Get chatroom to validate it exists
-	chatroom, err := s.chatroomRepo.Get(ctx, id)
+	chatroom, err := s.getChatroom(ctx, id)
 	if err != nil {
 		return err
 	}
 
+	if _, err := authorize(chatroom, actorID, PermissionDeleteChatroom); err != nil {
+		return err
+	}
+
 	// Delete the chatroom
-	return s.chatroomRepo.Delete(ctx, chatroom.ID)
+	if err := s.chatroomRepo.Delete(ctx, chatroom.ID); err != nil {
+		return err
+	}
+
+	// Unsubscribe the participants' live connections from the deleted chatroom
+	for _, p := range chatroom.Participants {
+		s.publisher.ParticipantRemoved(ctx, chatroom.ID, p.User)
+	}
+
+	return nil
 }
 
 // AddParticipant adds a participant to a chatroom
-func (s *chatroomService) AddParticipant(ctx context.Context, chatroomID string, participantID string) error {
+func (s *chatroomService) AddParticipant(ctx context.Context, chatroomID string, participantID string, actorID string) error {
 	// Get chatroom to validate it exists
-	chatroom, err := s.chatroomRepo.Get(ctx, chatroomID)
+	chatroom, err := s.getChatroom(ctx, chatroomID)
+	if err != nil {
+		return err
+	}
+
+	if _, err := authorize(chatroom, actorID, PermissionAddParticipant); err != nil {
+		return err
+	}
+
+	if err := s.checkClient(ctx, chatroom.ClientID, participantID); err != nil {
+		return err
+	}
+
+	return s.addParticipant(ctx, chatroom, participantID)
+}
+
+// JoinChatroom adds a user to a chatroom as a regular participant on their own behalf
+func (s *chatroomService) JoinChatroom(ctx context.Context, chatroomID string, userID string) error {
+	chatroom, err := s.getClientChatroom(ctx, chatroomID, userID)
 	if err != nil {
 		return err
 	}
 
+	return s.addParticipant(ctx, chatroom, userID)
+}
+
+// JoinPublicChatroom lets a user join a public group chatroom on their own
+func (s *chatroomService) JoinPublicChatroom(ctx context.Context, chatroomID string, userID string) error {
+	chatroom, err := s.getClientChatroom(ctx, chatroomID, userID)
+	if err != nil {
+		return err
+	}
+
+	if chatroom.Type != entity.ChatroomTypePublic || !chatroom.IsGroup {
+		return exception.Forbidden()
+	}
+
+	return s.addParticipant(ctx, chatroom, userID)
+}
+
+// addParticipant adds a regular participant within the chatroom's cap and subscribes their live connections
+func (s *chatroomService) addParticipant(ctx context.Context, chatroom *entity.Chatroom, userID string) error {
 	// Check if participant already exists
-	for _, p := range chatroom.Participants {
-		if p.User == participantID {
-			return fmt.Errorf("participant already exists in chatroom")
-		}
+	if findParticipant(chatroom, userID) != nil {
+		return exception.BadRequest("Participant already exists in chatroom")
 	}
 
 	// Create new participant
 	participant := entity.ChatroomParticipant{
-		User:            participantID,
+		User:            userID,
 		Role:            entity.ParticipantRoleMember,
 		JoinedTimestamp: time.Now().Unix(),
 	}
 
-	return s.chatroomRepo.AddParticipant(ctx, chatroomID, participant)
+	// The repository re-checks both conditions atomically in case of concurrent joins
+	added, err := s.chatroomRepo.AddParticipant(ctx, chatroom.ID, participant, chatroom.MaxParticipants)
+	if err != nil {
+		return err
+	}
+	if !added {
+		return exception.BadRequest("Chatroom is full")
+	}
+
+	s.publisher.ParticipantAdded(ctx, chatroom.ID, userID)
+	return nil
 }
 
 // RemoveParticipant removes a participant from a chatroom
-func (s *chatroomService) RemoveParticipant(ctx context.Context, chatroomID string, participantID string) error {
+func (s *chatroomService) RemoveParticipant(ctx context.Context, chatroomID string, participantID string, actorID string) error {
 	// Get chatroom to validate it exists and check roles
-	chatroom, err := s.chatroomRepo.Get(ctx, chatroomID)
+	chatroom, err := s.getChatroom(ctx, chatroomID)
 	if err != nil {
 		return err
 	}
 
-	// For direct chats, either participant can leave
-	if !chatroom.IsGroup {
-		return s.chatroomRepo.RemoveParticipant(ctx, chatroomID, participantID)
+	participant := findParticipant(chatroom, participantID)
+	if participant == nil {
+		return exception.NotFound("Participant")
 	}
 
-	// For group chats, validate roles
-	var participantRole entity.ParticipantRole
-	var found bool
-	for _, p := range chatroom.Participants {
-		if p.User == participantID {
-			participantRole = p.Role
-			found = true
-			break
-		}
+	// Any participant can leave, in direct chats as well as group chats
+	if participantID == actorID {
+		return s.removeParticipant(ctx, chatroomID, participantID)
 	}
 
-	if !found {
-		return fmt.Errorf("participant not found in chatroom")
+	if err := authorizeOver(chatroom, actorID, participant, PermissionRemoveParticipant); err != nil {
+		return err
 	}
 
-	// Regular members can only remove themselves
-	if participantRole == entity.ParticipantRoleMember {
-		return s.chatroomRepo.RemoveParticipant(ctx, chatroomID, participantID)
+	return s.removeParticipant(ctx, chatroomID, participantID)
+}
+
+// removeParticipant removes a participant and unsubscribes their live connections
+func (s *chatroomService) removeParticipant(ctx context.Context, chatroomID string, participantID string) error {
+	if err := s.chatroomRepo.RemoveParticipant(ctx, chatroomID, participantID); err != nil {
+		return err
 	}
 
-	return fmt.Errorf("operation not allowed for participant role")
+	s.publisher.ParticipantRemoved(ctx, chatroomID, participantID)
+	return nil
 }
 
 // UpdateParticipantRole updates a participant's role in the chatroom
 func (s *chatroomService) UpdateParticipantRole(ctx context.Context, data UpdateParticipantRoleParams) error {
+	if roleRank(data.NewRole) == 0 {
+		return exception.BadRequest("Invalid participant role")
+	}
+
 	// Get chatroom to validate it exists and check roles
-	chatroom, err := s.chatroomRepo.Get(ctx, data.ChatroomID)
+	chatroom, err := s.getChatroom(ctx, data.ChatroomID)
 	if err != nil {
 		return err
 	}
 
-	// Find the participant to update
-	var participant *entity.ChatroomParticipant
-	for i := range chatroom.Participants {
-		if chatroom.Participants[i].User == data.ParticipantID {
-			participant = &chatroom.Participants[i]
-			break
-		}
+	actor, err := authorize(chatroom, data.ActorID, PermissionUpdateRole)
+	if err != nil {
+		return err
 	}
 
+	// Find the participant to update
+	participant := findParticipant(chatroom, data.ParticipantID)
 	if participant == nil {
-		return fmt.Errorf("participant not found in chatroom")
+		return exception.NotFound("Participant")
+	}
+
+	// Nobody can change their own role, touch a higher role or grant a role above their own
+	if participant.User == actor.User ||
+		roleRank(participant.Role) > roleRank(actor.Role) ||
+		roleRank(data.NewRole) > roleRank(actor.Role) {
+		return exception.Forbidden()
 	}
 
 	// Update the role
 	participant.Role = data.NewRole
 
-	return s.chatroomRepo.UpdateParticipant(ctx, data.ChatroomID, *participant)
+	if err := s.chatroomRepo.UpdateParticipant(ctx, data.ChatroomID, *participant); err != nil {
+		return err
+	}
+
+	s.publisher.RoleUpdated(ctx, data.ChatroomID, *participant, data.ActorID)
+	return nil
 }
 
 // MuteParticipant temporarily mutes a participant
 func (s *chatroomService) MuteParticipant(ctx context.Context, data MuteParticipantParams) error {
 	// Get chatroom to validate it exists and check roles
-	chatroom, err := s.chatroomRepo.Get(ctx, data.ChatroomID)
+	chatroom, err := s.getChatroom(ctx, data.ChatroomID)
 	if err != nil {
 		return err
 	}
 
 	// Find the participant to mute
-	var participant *entity.ChatroomParticipant
-	for i := range chatroom.Participants {
-		if chatroom.Participants[i].User == data.ParticipantID {
-			participant = &chatroom.Participants[i]
-			break
-		}
+	participant := findParticipant(chatroom, data.ParticipantID)
+	if participant == nil {
+		return exception.NotFound("Participant")
 	}
 
-	if participant == nil {
-		return fmt.Errorf("participant not found in chatroom")
+	if err := authorizeOver(chatroom, data.ActorID, participant, PermissionMuteParticipant); err != nil {
+		return err
 	}
 
 	// Calculate mute end time
 	muteUntil := time.Now().Add(data.Duration).Unix()
 	participant.MutedUntilTimestamp = &muteUntil
 
-	return s.chatroomRepo.UpdateParticipant(ctx, data.ChatroomID, *participant)
+	if err := s.chatroomRepo.UpdateParticipant(ctx, data.ChatroomID, *participant); err != nil {
+		return err
+	}
+
+	s.publisher.ParticipantMuted(ctx, data.ChatroomID, *participant, data.ActorID)
+	return nil
 }
 
 // UnmuteParticipant removes a mute from a participant
-func (s *chatroomService) UnmuteParticipant(ctx context.Context, chatroomID string, participantID string) error {
+func (s *chatroomService) UnmuteParticipant(ctx context.Context, chatroomID string, participantID string, actorID string) error {
 	// Get chatroom to validate it exists and check roles
-	chatroom, err := s.chatroomRepo.Get(ctx, chatroomID)
+	chatroom, err := s.getChatroom(ctx, chatroomID)
 	if err != nil {
 		return err
 	}
 
 	// Find the participant to unmute
-	var participant *entity.ChatroomParticipant
-	for i := range chatroom.Participants {
-		if chatroom.Participants[i].User == participantID {
-			participant = &chatroom.Participants[i]
-			break
-		}
+	participant := findParticipant(chatroom, participantID)
+	if participant == nil {
+		return exception.NotFound("Participant")
 	}
 
-	if participant == nil {
-		return fmt.Errorf("participant not found in chatroom")
+	if err := authorizeOver(chatroom, actorID, participant, PermissionMuteParticipant); err != nil {
+		return err
 	}
 
 	// Remove mute
 	participant.MutedUntilTimestamp = nil
 
-	return s.chatroomRepo.UpdateParticipant(ctx, chatroomID, *participant)
+	if err := s.chatroomRepo.UpdateParticipant(ctx, chatroomID, *participant); err != nil {
+		return err
+	}
+
+	s.publisher.ParticipantUnmuted(ctx, chatroomID, *participant, actorID)
+	return nil
+}
+
+// getChatroom retrieves a chatroom, returning a not found error if it doesn't exist
+func (s *chatroomService) getChatroom(ctx context.Context, id string) (*entity.Chatroom, error) {
+	chatroom, err := s.chatroomRepo.Get(ctx, id)
+	if err != nil || chatroom == nil {
+		return nil, exception.NotFound("Chatroom")
+	}
+
+	return chatroom, nil
+}
+
+// getClientChatroom retrieves a chatroom the user joins on their own, chatrooms of other clients are reported as not found
+func (s *chatroomService) getClientChatroom(ctx context.Context, id string, userID string) (*entity.Chatroom, error) {
+	chatroom, err := s.getChatroom(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	ok, err := s.belongsToClient(ctx, chatroom.ClientID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, exception.NotFound("Chatroom")
+	}
+
+	return chatroom, nil
+}
+
+// checkClient returns a 404 error unless every given user belongs to the client
+// Users of other clients are reported as not found so clients can't probe each other's users
+func (s *chatroomService) checkClient(ctx context.Context, clientID string, userIDs ...string) error {
+	ok, err := s.belongsToClient(ctx, clientID, userIDs...)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return exception.NotFound("User")
+	}
+
+	return nil
+}
+
+// belongsToClient checks that every given user belongs to the client
+func (s *chatroomService) belongsToClient(ctx context.Context, clientID string, userIDs ...string) (bool, error) {
+	if clientID == "" {
+		return false, nil
+	}
+
+	unique := make([]string, 0, len(userIDs))
+	for _, id := range userIDs {
+		if !slices.Contains(unique, id) {
+			unique = append(unique, id)
+		}
+	}
+
+	count, err := s.userRepo.CountByClient(ctx, clientID, unique)
+	if err != nil {
+		return false, err
+	}
+
+	return count == int64(len(unique)), nil
 }
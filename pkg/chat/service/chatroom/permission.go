@@ -0,0 +1,90 @@
+package chatroom
+
+import (
+	"app/pkg/chat/domain/entity"
+	"app/pkg/exception"
+)
+
+// Permission represents an operation on a chatroom that requires a minimum participant role
+type Permission string
+
+const (
+	PermissionUpdateChatroom    Permission = "update_chatroom"
+	PermissionDeleteChatroom    Permission = "delete_chatroom"
+	PermissionAddParticipant    Permission = "add_participant"
+	PermissionRemoveParticipant Permission = "remove_participant"
+	PermissionUpdateRole        Permission = "update_role"
+	PermissionMuteParticipant   Permission = "mute_participant"
+	PermissionManageInvites     Permission = "manage_invites"
+)
+
+// permissions maps each permission to the minimum role required in group chatrooms
+var permissions = map[Permission]entity.ParticipantRole{
+	PermissionUpdateChatroom:    entity.ParticipantRoleAdmin,
+	PermissionDeleteChatroom:    entity.ParticipantRoleSuperAdmin,
+	PermissionAddParticipant:    entity.ParticipantRoleAdmin,
+	PermissionRemoveParticipant: entity.ParticipantRoleAdmin,
+	PermissionUpdateRole:        entity.ParticipantRoleSuperAdmin,
+	PermissionMuteParticipant:   entity.ParticipantRoleAdmin,
+	PermissionManageInvites:     entity.ParticipantRoleAdmin,
+}
+
+// roleRank returns the rank of a role, higher ranks have more privileges
+// Unknown roles rank below member
+func roleRank(role entity.ParticipantRole) int {
+	switch role {
+	case entity.ParticipantRoleMember:
+		return 1
+	case entity.ParticipantRoleAdmin:
+		return 2
+	case entity.ParticipantRoleSuperAdmin:
+		return 3
+	default:
+		return 0
+	}
+}
+
+// authorize checks that the actor is a participant whose role grants the permission
+// Direct chatrooms have no roles, so none of the permissions are granted there
+// Returns the actor's participant entry
+func authorize(chatroom *entity.Chatroom, actorID string, permission Permission) (*entity.ChatroomParticipant, error) {
+	if !chatroom.IsGroup {
+		return nil, exception.Forbidden()
+	}
+
+	actor := findParticipant(chatroom, actorID)
+	if actor == nil {
+		return nil, exception.Forbidden()
+	}
+
+	if roleRank(actor.Role) < roleRank(permissions[permission]) {
+		return nil, exception.Forbidden()
+	}
+
+	return actor, nil
+}
+
+// authorizeOver checks that the actor is granted the permission and outranks the target participant
+func authorizeOver(chatroom *entity.Chatroom, actorID string, target *entity.ChatroomParticipant, permission Permission) error {
+	actor, err := authorize(chatroom, actorID, permission)
+	if err != nil {
+		return err
+	}
+
+	if roleRank(actor.Role) <= roleRank(target.Role) {
+		return exception.Forbidden()
+	}
+
+	return nil
+}
+
+// findParticipant returns the user's participant entry in the chatroom, or nil if not a participant
+func findParticipant(chatroom *entity.Chatroom, userID string) *entity.ChatroomParticipant {
+	for i := range chatroom.Participants {
+		if chatroom.Participants[i].User == userID {
+			return &chatroom.Participants[i]
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,97 @@
+package chatroom
+
+import (
+	"app/pkg/chat/domain/entity"
+	"testing"
+)
+
+// groupChatroom returns a group chatroom with a participant of every role
+func groupChatroom() *entity.Chatroom {
+	return &entity.Chatroom{
+		IsGroup: true,
+		Participants: []entity.ChatroomParticipant{
+			{User: "member", Role: entity.ParticipantRoleMember},
+			{User: "admin", Role: entity.ParticipantRoleAdmin},
+			{User: "super-admin", Role: entity.ParticipantRoleSuperAdmin},
+			{User: "unknown", Role: entity.ParticipantRole("owner")},
+		},
+	}
+}
+
+func TestAuthorizeGrantsPermissionsByRole(t *testing.T) {
+	tests := []struct {
+		permission Permission
+		member     bool
+		admin      bool
+		superAdmin bool
+	}{
+		{PermissionUpdateChatroom, false, true, true},
+		{PermissionDeleteChatroom, false, false, true},
+		{PermissionAddParticipant, false, true, true},
+		{PermissionRemoveParticipant, false, true, true},
+		{PermissionUpdateRole, false, false, true},
+		{PermissionMuteParticipant, false, true, true},
+		{PermissionManageInvites, false, true, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.permission), func(t *testing.T) {
+			chatroom := groupChatroom()
+			for _, actor := range []struct {
+				id      string
+				granted bool
+			}{
+				{"member", tt.member},
+				{"admin", tt.admin},
+				{"super-admin", tt.superAdmin},
+				{"unknown", false},
+				{"outsider", false},
+			} {
+				_, err := authorize(chatroom, actor.id, tt.permission)
+				if granted := err == nil; granted != actor.granted {
+					t.Errorf("authorize(%s) granted = %v, want %v", actor.id, granted, actor.granted)
+				}
+			}
+		})
+	}
+}
+
+func TestAuthorizeGrantsNothingInDirectChatrooms(t *testing.T) {
+	chatroom := groupChatroom()
+	chatroom.IsGroup = false
+
+	for permission := range permissions {
+		if _, err := authorize(chatroom, "super-admin", permission); err == nil {
+			t.Errorf("authorize(%s) in a direct chatroom was granted", permission)
+		}
+	}
+}
+
+func TestAuthorizeOverRequiresOutrankingTheTarget(t *testing.T) {
+	tests := []struct {
+		actor   string
+		target  string
+		granted bool
+	}{
+		{"admin", "member", true},
+		{"admin", "admin", false},
+		{"admin", "super-admin", false},
+		{"admin", "unknown", true},
+		{"super-admin", "member", true},
+		{"super-admin", "admin", true},
+		{"super-admin", "super-admin", false},
+		{"member", "unknown", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.actor+" over "+tt.target, func(t *testing.T) {
+			chatroom := groupChatroom()
+			target := findParticipant(chatroom, tt.target)
+
+			err := authorizeOver(chatroom, tt.actor, target, PermissionRemoveParticipant)
+			if granted := err == nil; granted != tt.granted {
+				t.Errorf("authorizeOver granted = %v, want %v", granted, tt.granted)
+			}
+		})
+	}
+}
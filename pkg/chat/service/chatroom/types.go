@@ -10,6 +10,7 @@ import (
 
 // CreateChatroomParams represents data for creating a new chatroom
 type CreateChatroomParams struct {
+	ClientID     string // Client the chatroom belongs to, every participant must belong to it too
 	Name         string
 	Type         entity.ChatroomType
 	IsGroup      bool
@@ -19,13 +20,19 @@ type CreateChatroomParams struct {
 
 // UpdateChatroomParams represents data for updating a chatroom
 type UpdateChatroomParams struct {
-	ID   string
-	Name *string
-	Type *entity.ChatroomType
+	ActorID string // User performing the update
+	ID      string
+	Name    *string
+	Type    *entity.ChatroomType // Can't be changed, only checked against the chatroom's type
+
+	// Squad only settings
+	JoinPolicy      *entity.JoinPolicy
+	MaxParticipants *int // 0 removes the cap
 }
 
 // MuteParticipantParams represents data for muting a participant
 type MuteParticipantParams struct {
+	ActorID       string // User performing the mute
 	ChatroomID    string
 	ParticipantID string
 	Duration      time.Duration // How long to mute the participant
@@ -33,12 +40,36 @@ type MuteParticipantParams struct {
 
 // UpdateParticipantRoleParams represents data for updating a participant's role
 type UpdateParticipantRoleParams struct {
+	ActorID       string // User performing the role change
 	ChatroomID    string
 	ParticipantID string
 	NewRole       entity.ParticipantRole
 }
 
+// EventPublisher notifies connected clients about chatroom changes
+type EventPublisher interface {
+	// ParticipantAdded notifies that a user joined a chatroom
+	ParticipantAdded(ctx context.Context, chatroomID string, userID string)
+
+	// ParticipantRemoved notifies that a user left or was removed from a chatroom
+	ParticipantRemoved(ctx context.Context, chatroomID string, userID string)
+
+	// ChatroomUpdated notifies that the chatroom's properties were updated by the given user
+	ChatroomUpdated(ctx context.Context, chatroom *entity.Chatroom, actorID string)
+
+	// RoleUpdated notifies that a participant's role was changed by the given user
+	RoleUpdated(ctx context.Context, chatroomID string, participant entity.ChatroomParticipant, actorID string)
+
+	// ParticipantMuted notifies that a participant was muted by the given user
+	ParticipantMuted(ctx context.Context, chatroomID string, participant entity.ChatroomParticipant, actorID string)
+
+	// ParticipantUnmuted notifies that a participant's mute was removed by the given user
+	ParticipantUnmuted(ctx context.Context, chatroomID string, participant entity.ChatroomParticipant, actorID string)
+}
+
 // ChatroomService defines the interface for chatroom-related operations
+// Role checks only apply to group chats, direct chats can't be managed by their participants
+// Permission denials are returned as exception.Forbidden
 type ChatroomService interface {
 	// GetChatroom retrieves a single chatroom by ID with populated participant references
 	GetChatroom(ctx context.Context, id string) (*entity.ChatroomPopulated, error)
@@ -46,27 +77,64 @@ type ChatroomService interface {
 	// GetChatrooms retrieves multiple chatrooms with filtering and pagination
 	GetChatrooms(ctx context.Context, filter repository.ChatroomFilter, pag pagination.Pagination) ([]*entity.ChatroomPopulated, int64, error)
 
+	// DiscoverChatrooms retrieves the client's public chatrooms ranked by activity, optionally searching their names
+	DiscoverChatrooms(ctx context.Context, clientID string, userID string, query string, pag pagination.Pagination) ([]*entity.ChatroomListing, int64, error)
+
+	// GetChatroomIDs retrieves the IDs of every chatroom the user participates in
+	GetChatroomIDs(ctx context.Context, userID string) ([]string, error)
+
+	// IsParticipant checks if a user is a participant in the chatroom
+	IsParticipant(ctx context.Context, chatroomID string, userID string) (bool, error)
+
+	// GetParticipant retrieves a user's participant entry in the chatroom
+	// Returns nil if the chatroom does not exist or the user is not a participant
+	GetParticipant(ctx context.Context, chatroomID string, userID string) (*entity.ChatroomParticipant, error)
+
+	// Authorize checks that the actor's role in the chatroom grants the permission
+	// Returns the chatroom
+	Authorize(ctx context.Context, chatroomID string, actorID string, permission Permission) (*entity.Chatroom, error)
+
 	// CreateChatroom creates a new chatroom
 	// It will:
+	// - Check every participant belongs to the client
 	// - Create the chatroom with the given parameters
-	// - Add the creator as an admin participant
+	// - Add the creator as a super_admin participant of group chats
 	// - Add all specified participants as regular participants
 	CreateChatroom(ctx context.Context, data CreateChatroomParams) (*entity.Chatroom, error)
 
+	// GetOrCreateDirectChatroom retrieves the direct chatroom between two users of the client, creating it if they have none
+	// Concurrent calls for the same pair return the same chatroom
+	GetOrCreateDirectChatroom(ctx context.Context, clientID string, userID string, otherUserID string) (*entity.Chatroom, error)
+
 	// UpdateChatroom modifies an existing chatroom
 	// Only admin and super_admin participants can update chatroom properties
+	// The join policy and participant cap can only be set on squads, and the type can't be changed
+	// Only the given settings are written, participants and stats are left as they are
 	UpdateChatroom(ctx context.Context, data UpdateChatroomParams) (*entity.Chatroom, error)
 
 	// DeleteChatroom removes a chatroom and all its messages
 	// Only super_admin participants can delete a chatroom
-	DeleteChatroom(ctx context.Context, id string) error
+	DeleteChatroom(ctx context.Context, id string, actorID string) error
 
 	// AddParticipant adds a participant to a chatroom
 	// It will validate:
 	// - The chatroom exists
-	// - The participant exists
+	// - The actor is an admin or super_admin
+	// - The participant belongs to the chatroom's client
 	// - The participant is not already in the chatroom
-	AddParticipant(ctx context.Context, chatroomID string, participantID string) error
+	AddParticipant(ctx context.Context, chatroomID string, participantID string, actorID string) error
+
+	// JoinChatroom adds a user to a chatroom as a regular participant on their own behalf
+	// It doesn't check any role, callers are responsible for deciding whether the user may join
+	// It will validate:
+	// - The chatroom exists and belongs to the user's client
+	// - The user is not already in the chatroom
+	// - The chatroom is not full
+	JoinChatroom(ctx context.Context, chatroomID string, userID string) error
+
+	// JoinPublicChatroom lets a user join a public group chatroom on their own
+	// Other chatroom types can only be joined by being added or through an invite
+	JoinPublicChatroom(ctx context.Context, chatroomID string, userID string) error
 
 	// RemoveParticipant removes a participant from a chatroom
 	// It will validate:
@@ -74,7 +142,7 @@ type ChatroomService interface {
 	// - The participant is in the chatroom
 	// - For group chats, admin/super_admin can remove anyone with lower roles, regular participants can only remove themselves
 	// - For direct chats, either participant can leave
-	RemoveParticipant(ctx context.Context, chatroomID string, participantID string) error
+	RemoveParticipant(ctx context.Context, chatroomID string, participantID string, actorID string) error
 
 	// UpdateParticipantRole updates a participant's role in the chatroom
 	// Only super_admin can update roles, and they can't:
@@ -89,5 +157,5 @@ type ChatroomService interface {
 
 	// UnmuteParticipant removes a mute from a participant
 	// Only admin and super_admin can unmute participants with lower roles
-	UnmuteParticipant(ctx context.Context, chatroomID string, participantID string) error
+	UnmuteParticipant(ctx context.Context, chatroomID string, participantID string, actorID string) error
 }
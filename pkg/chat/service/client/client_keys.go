@@ -0,0 +1,196 @@
+package client
+
+import (
+	"app/pkg/chat/domain/entity"
+	"app/pkg/exception"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"slices"
+	"time"
+)
+
+const (
+	// Prefix of generated client keys
+	clientKeyPrefix = "ck_"
+	// Name of the key clients are created with
+	defaultKeyName = "Default"
+	// Longest a rotated key keeps working next to its replacement
+	maxRotationGracePeriod = 30 * 24 * time.Hour
+)
+
+// generateSecret generates a random key or secret with 256 bits of entropy
+func generateSecret(prefix string) string {
+	b := make([]byte, 32)
+	rand.Read(b)
+	return prefix + base64.RawURLEncoding.EncodeToString(b)
+}
+
+// GetKeys retrieves all keys of a client, including expired and revoked ones
+func (s *clientService) GetKeys(ctx context.Context, clientID string) ([]*entity.ClientKey, error) {
+	return s.clientKeyRepo.GetByClient(ctx, clientID)
+}
+
+// CreateKey creates a new key for a client
+func (s *clientService) CreateKey(ctx context.Context, params CreateKeyParams) (*IssuedKey, error) {
+	if params.ExpiresIn < 0 {
+		return nil, exception.BadRequest("Expiry must be positive")
+	}
+
+	scopes := params.Scopes
+	if len(scopes) == 0 {
+		scopes = entity.ClientKeyScopes
+	}
+	for _, scope := range scopes {
+		if !slices.Contains(entity.ClientKeyScopes, scope) {
+			return nil, exception.BadRequest(fmt.Sprintf("Unknown client key scope %q", scope))
+		}
+	}
+
+	client, err := s.clientRepo.Get(ctx, params.ClientID)
+	if err != nil {
+		return nil, err
+	}
+	if client == nil {
+		return nil, exception.NotFound("Client")
+	}
+
+	return s.issueKey(ctx, client.ID, params.Name, scopes, params.ExpiresIn)
+}
+
+// RotateKey replaces a key with a new one with the same name and scopes
+func (s *clientService) RotateKey(ctx context.Context, params RotateKeyParams) (*IssuedKey, error) {
+	if params.GracePeriod < 0 || params.GracePeriod > maxRotationGracePeriod {
+		return nil, exception.BadRequest(fmt.Sprintf("Grace period must be between 0 and %d days", maxRotationGracePeriod/(24*time.Hour)))
+	}
+
+	key, err := s.getClientKey(ctx, params.ClientID, params.KeyID)
+	if err != nil {
+		return nil, err
+	}
+	if !key.Active(time.Now().UnixMilli()) {
+		return nil, exception.BadRequest("Client key is no longer active")
+	}
+
+	issued, err := s.issueKey(ctx, key.ClientID, key.Name, key.Scopes, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	if params.GracePeriod == 0 {
+		err = s.clientKeyRepo.Revoke(ctx, key.ID)
+	} else if expires := time.Now().Add(params.GracePeriod).UnixMilli(); key.ExpiresTimestamp == nil || *key.ExpiresTimestamp > expires {
+		err = s.clientKeyRepo.SetExpiry(ctx, key.ID, expires)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// The replaced key is cached with its previous expiry
+	if err := s.evictKeys(ctx, key.Hash); err != nil {
+		return nil, err
+	}
+
+	return issued, nil
+}
+
+// RevokeKey stops a key from working right away
+func (s *clientService) RevokeKey(ctx context.Context, clientID string, keyID string) error {
+	key, err := s.getClientKey(ctx, clientID, keyID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.clientKeyRepo.Revoke(ctx, key.ID); err != nil {
+		return err
+	}
+
+	return s.evictKeys(ctx, key.Hash)
+}
+
+// RotateWebhookSecret replaces the secret the client's webhook requests are signed with and returns it
+func (s *clientService) RotateWebhookSecret(ctx context.Context, clientID string) (string, error) {
+	client, err := s.clientRepo.Get(ctx, clientID)
+	if err != nil {
+		return "", err
+	}
+	if client == nil {
+		return "", exception.NotFound("Client")
+	}
+
+	secret := entity.NewWebhookSecret()
+	if err := s.clientRepo.SetWebhookSecret(ctx, client.ID, secret); err != nil {
+		return "", err
+	}
+
+	return secret, nil
+}
+
+// issueKey generates and stores a new key for a client
+func (s *clientService) issueKey(ctx context.Context, clientID string, name string, scopes []entity.ClientKeyScope, expiresIn time.Duration) (*IssuedKey, error) {
+	secret := generateSecret(clientKeyPrefix)
+
+	key := &entity.ClientKey{
+		ClientID: clientID,
+		Name:     name,
+		Hash:     entity.HashClientKey(secret),
+		Prefix:   entity.ClientKeyPrefix(secret),
+		Scopes:   slices.Compact(slices.Sorted(slices.Values(scopes))),
+	}
+	if expiresIn > 0 {
+		expires := time.Now().Add(expiresIn).UnixMilli()
+		key.ExpiresTimestamp = &expires
+	}
+
+	if err := s.clientKeyRepo.Create(ctx, key); err != nil {
+		return nil, err
+	}
+
+	return &IssuedKey{ClientKey: key, Key: secret}, nil
+}
+
+// getClientKey retrieves a key of the client, keys of other clients aren't found
+func (s *clientService) getClientKey(ctx context.Context, clientID string, keyID string) (*entity.ClientKey, error) {
+	key, err := s.clientKeyRepo.Get(ctx, keyID)
+	if err != nil {
+		return nil, err
+	}
+	if key == nil || key.ClientID != clientID {
+		return nil, exception.NotFound("Client key")
+	}
+
+	return key, nil
+}
+
+// keyHashes returns the hashes of all keys of a client, including its plaintext key if it still has one
+func (s *clientService) keyHashes(ctx context.Context, client *entity.Client) ([]string, error) {
+	keys, err := s.clientKeyRepo.GetByClient(ctx, client.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	hashes := make([]string, 0, len(keys)+1)
+	for _, key := range keys {
+		hashes = append(hashes, key.Hash)
+	}
+	if client.LegacyKey != "" {
+		hashes = append(hashes, entity.HashClientKey(client.LegacyKey))
+	}
+
+	return hashes, nil
+}
+
+// evictKeys removes client keys from the cache, so changes to them apply right away
+func (s *clientService) evictKeys(ctx context.Context, hashes ...string) error {
+	if len(hashes) == 0 {
+		return nil
+	}
+
+	cacheKeys := make([]string, len(hashes))
+	for i, hash := range hashes {
+		cacheKeys[i] = clientCacheKeyPrefix + hash
+	}
+
+	return s.redisClient.Del(ctx, cacheKeys...)
+}
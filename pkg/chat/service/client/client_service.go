@@ -7,10 +7,15 @@ import (
 	"app/pkg/exception"
 	"app/pkg/types/pagination"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
-	"io"
+	"fmt"
 	"net/http"
 	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"go.mongodb.org/mongo-driver/mongo"
 )
 
 const (
@@ -18,27 +23,47 @@ const (
 	authCacheDuration = 15 * time.Minute
 	// Cache key prefix for auth tokens
 	authCacheKeyPrefix = "auth:token:"
-	// Cache duration for validated clients
+	// Key prefix for revoked auth tokens
+	revokedTokenKeyPrefix = "auth:revoked:"
+	// Key prefix for the set of a user's cached auth tokens, followed by the user ID
+	userTokensKeyPrefix = "auth:user:"
+	// Cache duration for validated client keys
 	clientCacheDuration = 1 * time.Hour
-	// Cache key prefix for client keys
+	// Cache key prefix for client keys, followed by the hash of the key
 	clientCacheKeyPrefix = "client:key:"
+	// Shortest accepted HMAC secret, HS256 keys shouldn't be shorter than the hash
+	minHMACSecretLength = 32
 )
 
 type clientService struct {
-	clientRepo  repository.ClientRepository
-	userRepo    repository.UserRepository
-	redisClient *redis.Client
-	httpClient  *http.Client
+	clientRepo    repository.ClientRepository
+	clientKeyRepo repository.ClientKeyRepository
+	userRepo      repository.UserRepository
+	redisClient   *redis.Client
+	verifiers     map[entity.AuthStrategy]tokenVerifier
 }
 
 // NewClientService creates a new instance of ClientService
-func NewClientService(clientRepo repository.ClientRepository, userRepo repository.UserRepository, redisClient *redis.Client) ClientService {
+func NewClientService(clientRepo repository.ClientRepository, clientKeyRepo repository.ClientKeyRepository, userRepo repository.UserRepository, redisClient *redis.Client) ClientService {
+	httpClient := &http.Client{
+		Timeout: 10 * time.Second,
+	}
+
+	endpoint := &endpointVerifier{httpClient: httpClient}
+	signed := &jwtVerifier{jwks: newJWKSCache(httpClient)}
+
 	return &clientService{
-		clientRepo:  clientRepo,
-		userRepo:    userRepo,
-		redisClient: redisClient,
-		httpClient: &http.Client{
-			Timeout: 10 * time.Second,
+		clientRepo:    clientRepo,
+		clientKeyRepo: clientKeyRepo,
+		userRepo:      userRepo,
+		redisClient:   redisClient,
+		verifiers: map[entity.AuthStrategy]tokenVerifier{
+			"":                               endpoint,
+			entity.AuthStrategyEndpoint:      endpoint,
+			entity.AuthStrategyHMAC:          signed,
+			entity.AuthStrategyPublicKey:     signed,
+			entity.AuthStrategyJWKS:          signed,
+			entity.AuthStrategyIntrospection: &introspectionVerifier{httpClient: httpClient},
 		},
 	}
 }
@@ -48,35 +73,127 @@ func (s *clientService) GetClient(ctx context.Context, id string) (*entity.Clien
 	return s.clientRepo.Get(ctx, id)
 }
 
-// GetByKey retrieves a client by client key
-func (s *clientService) GetByKey(ctx context.Context, clientKey string) (*entity.Client, error) {
-	return s.clientRepo.GetByKey(ctx, clientKey)
-}
-
 // GetClients retrieves multiple clients with pagination
 func (s *clientService) GetClients(ctx context.Context, pag pagination.Pagination) ([]*entity.Client, int64, error) {
 	return s.clientRepo.GetAll(ctx, pag)
 }
 
-// CreateClient creates a new client
-func (s *clientService) CreateClient(ctx context.Context, client *entity.Client) error {
-	return s.clientRepo.Create(ctx, client)
+// CreateClient creates a new client with a webhook secret and a first key with every scope
+func (s *clientService) CreateClient(ctx context.Context, client *entity.Client) (*IssuedKey, error) {
+	if err := validateAuth(client); err != nil {
+		return nil, err
+	}
+	if err := validateErasurePolicy(client); err != nil {
+		return nil, err
+	}
+
+	client.WebhookSecret = entity.NewWebhookSecret()
+	if err := s.clientRepo.Create(ctx, client); err != nil {
+		return nil, err
+	}
+
+	return s.issueKey(ctx, client.ID, defaultKeyName, entity.ClientKeyScopes, 0)
 }
 
 // UpdateClient modifies an existing client
+// Its cached keys are evicted, so a deactivated client is rejected right away
 func (s *clientService) UpdateClient(ctx context.Context, client *entity.Client) error {
-	return s.clientRepo.Update(ctx, client)
+	if err := validateAuth(client); err != nil {
+		return err
+	}
+	if err := validateErasurePolicy(client); err != nil {
+		return err
+	}
+	if err := s.clientRepo.Update(ctx, client); err != nil {
+		return err
+	}
+
+	hashes, err := s.keyHashes(ctx, client)
+	if err != nil {
+		return err
+	}
+
+	return s.evictKeys(ctx, hashes...)
 }
 
-// DeleteClient removes a client
+// validateAuth checks the client has what its auth strategy needs
+func validateAuth(client *entity.Client) error {
+	switch client.Auth.Strategy {
+	case "", entity.AuthStrategyEndpoint:
+		if client.AuthEndpoint == "" {
+			return exception.BadRequest("Auth endpoint is required")
+		}
+	case entity.AuthStrategyHMAC:
+		if len(client.Auth.Secret) < minHMACSecretLength {
+			return exception.BadRequest(fmt.Sprintf("HMAC secret must be at least %d characters", minHMACSecretLength))
+		}
+	case entity.AuthStrategyPublicKey:
+		if _, err := parsePublicKey(client.Auth.PublicKey); err != nil {
+			return exception.BadRequest("Invalid public key: " + err.Error())
+		}
+	case entity.AuthStrategyJWKS:
+		if client.Auth.JWKSURL == "" {
+			return exception.BadRequest("JWKS URL is required")
+		}
+	case entity.AuthStrategyIntrospection:
+		if client.Auth.IntrospectionEndpoint == "" {
+			return exception.BadRequest("Introspection endpoint is required")
+		}
+	default:
+		return exception.BadRequest(fmt.Sprintf("Unknown auth strategy %q", client.Auth.Strategy))
+	}
+
+	return nil
+}
+
+// validateErasurePolicy checks the client's erasure policy is known, empty means the default
+func validateErasurePolicy(client *entity.Client) error {
+	switch client.ErasurePolicy {
+	case "", entity.ErasurePolicyTombstone, entity.ErasurePolicyDelete:
+		return nil
+	default:
+		return exception.BadRequest(fmt.Sprintf("Unknown erasure policy %q", client.ErasurePolicy))
+	}
+}
+
+// DeleteClient removes a client and its keys
 func (s *clientService) DeleteClient(ctx context.Context, id string) error {
-	return s.clientRepo.Delete(ctx, id)
+	client, err := s.clientRepo.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	if client == nil {
+		return exception.NotFound("Client")
+	}
+
+	// The hashes are needed to evict the keys, which are gone once they're deleted
+	hashes, err := s.keyHashes(ctx, client)
+	if err != nil {
+		return err
+	}
+
+	if err := s.clientRepo.Delete(ctx, id); err != nil {
+		return err
+	}
+	if err := s.clientKeyRepo.DeleteByClient(ctx, id); err != nil {
+		return err
+	}
+
+	return s.evictKeys(ctx, hashes...)
 }
 
-// Authenticate validates a JWT token against the client's auth endpoint and returns the authenticated user
+// Authenticate verifies a user token with the client's auth strategy and returns the authenticated user
 func (s *clientService) Authenticate(ctx context.Context, clientID string, token string) (*entity.User, error) {
+	// Tokens are cached per client so they can't be replayed through another one
+	tokenHash := hashToken(token)
+	cacheKey := authCacheKeyPrefix + clientID + ":" + tokenHash
+
+	revoked, err := s.redisClient.Exists(ctx, revokedTokenKeyPrefix+clientID+":"+tokenHash)
+	if err == nil && revoked {
+		return nil, errInvalidToken
+	}
+
 	// Try to get from cache first
-	cacheKey := authCacheKeyPrefix + token
 	if cachedUser, err := s.getUserFromCache(ctx, cacheKey); err == nil && cachedUser != nil {
 		return cachedUser, nil
 	}
@@ -90,69 +207,89 @@ func (s *clientService) Authenticate(ctx context.Context, clientID string, token
 		return nil, exception.NotFound("Client")
 	}
 
-	// Create request to auth endpoint
-	req, err := http.NewRequestWithContext(ctx, "GET", client.AuthEndpoint, nil)
-	if err != nil {
-		return nil, exception.InternalError("Failed to create authentication request")
+	verifier, ok := s.verifiers[client.Auth.Strategy]
+	if !ok {
+		return nil, exception.InternalError(fmt.Sprintf("Unknown auth strategy %q", client.Auth.Strategy))
 	}
 
-	// Add authorization header
-	req.Header.Set("Authorization", "Bearer "+token)
-
-	// Make the request
-	resp, err := s.httpClient.Do(req)
+	user, expiresAt, err := verifier.Verify(ctx, client, token)
 	if err != nil {
-		return nil, exception.InternalError("Authentication request failed")
-	}
-	defer resp.Body.Close()
-
-	// Check response status
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		switch resp.StatusCode {
-		case http.StatusUnauthorized:
-			return nil, exception.Http(401, "Invalid token")
-		case http.StatusForbidden:
-			return nil, exception.Forbidden()
-		case http.StatusNotFound:
-			return nil, exception.NotFound("User")
-		default:
-			return nil, exception.InternalError("Authentication service error: " + string(body))
-		}
-	}
-
-	// Parse response body
-	var user entity.User
-	if err := json.NewDecoder(resp.Body).Decode(&user); err != nil {
-		return nil, exception.InternalError("Failed to decode authentication response")
+		return nil, err
 	}
+	// Different clients may return the same ID, users are identified by their client and that ID together
+	user.ClientID = client.ID
+	user.ExternalID = user.ID
+	user.ID = ""
 
 	// Check if user exists
-	existingUser, err := s.userRepo.Get(ctx, user.ID)
+	existingUser, err := s.userRepo.GetByExternalID(ctx, client.ID, user.ExternalID)
 	if err != nil {
 		return nil, exception.InternalError("Error checking user existence")
 	}
+	// Replacing an erased user with the client's copy would undo the erasure
+	if existingUser != nil && existingUser.ErasedTimestamp != nil {
+		return nil, exception.Http(403, "User has been erased")
+	}
 
 	// Create or update user
 	if existingUser == nil {
 		// Create new user
-		if err := s.userRepo.Create(ctx, &user); err != nil {
+		if err := s.userRepo.Create(ctx, user); err != nil {
 			return nil, exception.InternalError("Failed to create user")
 		}
 	} else {
-		// Update existing user
-		if err := s.userRepo.Update(ctx, &user); err != nil {
+		// Update existing user, unless it was erased since it was fetched
+		user.ID = existingUser.ID
+		if err := s.userRepo.Update(ctx, user); err != nil {
+			if err == mongo.ErrNoDocuments {
+				return nil, exception.Http(403, "User has been erased")
+			}
 			return nil, exception.InternalError("Failed to update user")
 		}
 	}
 
-	// Cache the authenticated user
-	if err := s.cacheUser(ctx, cacheKey, &user); err != nil {
-		// Log the error but don't fail the request
-		// fmt.Printf("Failed to cache user: %v\n", err)
+	// Cache the authenticated user, no longer than the token is valid
+	ttl := authCacheDuration
+	if !expiresAt.IsZero() {
+		ttl = min(ttl, time.Until(expiresAt))
+	}
+	if ttl > 0 {
+		if err := s.cacheUser(ctx, cacheKey, user, ttl); err != nil {
+			// Log the error but don't fail the request
+			// fmt.Printf("Failed to cache user: %v\n", err)
+		}
 	}
 
-	return &user, nil
+	return user, nil
+}
+
+// RevokeToken rejects the token from now on and evicts it from the cache
+func (s *clientService) RevokeToken(ctx context.Context, clientID string, token string) error {
+	if token == "" {
+		return exception.BadRequest("Token is required")
+	}
+
+	tokenHash := hashToken(token)
+
+	// Locally verified JWTs stay valid until they expire, so the revocation is kept as long
+	// Other tokens are rejected by the client itself once they're out of the cache
+	ttl := authCacheDuration
+	var claims jwt.RegisteredClaims
+	if _, _, err := jwt.NewParser().ParseUnverified(token, &claims); err == nil && claims.ExpiresAt != nil {
+		ttl = max(ttl, time.Until(claims.ExpiresAt.Time)+jwtLeeway)
+	}
+
+	if err := s.redisClient.Set(ctx, revokedTokenKeyPrefix+clientID+":"+tokenHash, "1", ttl); err != nil {
+		return err
+	}
+
+	return s.redisClient.Del(ctx, authCacheKeyPrefix+clientID+":"+tokenHash)
+}
+
+// hashToken returns the hex encoded SHA-256 of a token, tokens aren't stored in Redis as is
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
 }
 
 // getUserFromCache attempts to retrieve a user from Redis cache
@@ -171,30 +308,113 @@ func (s *clientService) getUserFromCache(ctx context.Context, key string) (*enti
 }
 
 // cacheUser stores a user in Redis cache with expiration
-func (s *clientService) cacheUser(ctx context.Context, key string, user *entity.User) error {
+// The key is also tracked per user, so every cached token of the user can be evicted at once
+func (s *clientService) cacheUser(ctx context.Context, key string, user *entity.User, expiration time.Duration) error {
 	data, err := json.Marshal(user)
 	if err != nil {
 		return err
 	}
 
-	return s.redisClient.Set(ctx, key, string(data), authCacheDuration)
+	userKey := userTokensKeyPrefix + user.ID
+	if err := s.redisClient.SAdd(ctx, userKey, key); err != nil {
+		return err
+	}
+	// Outlives every token it tracks, since tokens are cached no longer than authCacheDuration
+	if err := s.redisClient.Expire(ctx, userKey, authCacheDuration); err != nil {
+		return err
+	}
+
+	return s.redisClient.Set(ctx, key, string(data), expiration)
 }
 
-// ValidateKey validates a client key and returns the client if valid
-func (s *clientService) ValidateKey(ctx context.Context, clientKey string) (*entity.Client, error) {
+// EvictUser evicts every cached token of the user, so they're authenticated by their client again
+func (s *clientService) EvictUser(ctx context.Context, userID string) error {
+	userKey := userTokensKeyPrefix + userID
+
+	keys, err := s.redisClient.SMembers(ctx, userKey)
+	if err != nil {
+		return err
+	}
+
+	return s.redisClient.Del(ctx, append(keys, userKey)...)
+}
+
+// ValidateKey validates a client key and returns the client if the key is active and has the scope
+func (s *clientService) ValidateKey(ctx context.Context, clientKey string, scope entity.ClientKeyScope) (*entity.Client, error) {
 	if clientKey == "" {
 		return nil, exception.Http(401, "Client key is required")
 	}
 
-	// Try to get client from cache first
-	cacheKey := clientCacheKeyPrefix + clientKey
-	cachedClient, err := s.getClientFromCache(ctx, cacheKey)
-	if err == nil && cachedClient != nil {
-		return cachedClient, nil
+	// Keys are cached by their hash like they're stored, never as is
+	hash := entity.HashClientKey(clientKey)
+	cacheKey := clientCacheKeyPrefix + hash
+
+	// Try to get the key from cache first
+	validated, err := s.getKeyFromCache(ctx, cacheKey)
+	if err != nil || validated == nil {
+		// If not in cache, fetch from database
+		validated, err = s.lookupKey(ctx, clientKey, hash)
+		if err != nil {
+			return nil, err
+		}
+
+		// Store in cache for future requests, no longer than the key is valid
+		ttl := clientCacheDuration
+		if validated.Key.ExpiresTimestamp != nil {
+			ttl = min(ttl, time.Until(time.UnixMilli(*validated.Key.ExpiresTimestamp)))
+		}
+		if ttl > 0 {
+			if err := s.storeKeyInCache(ctx, cacheKey, validated, ttl); err != nil {
+				// Log the error but don't fail the request
+				// fmt.Printf("Error storing client key in cache: %v\n", err)
+			}
+		}
+	}
+
+	if !validated.Key.Active(time.Now().UnixMilli()) {
+		return nil, exception.Http(401, "Invalid client key")
+	}
+	if validated.Client.Status != "active" {
+		return nil, exception.Http(403, "Client is inactive")
+	}
+	if !validated.Key.Allows(scope) {
+		return nil, exception.Http(403, fmt.Sprintf("Client key doesn't have the %s scope", scope))
+	}
+
+	return validated.Client, nil
+}
+
+// validatedKey represents a client key and its client as cached once the key is validated
+type validatedKey struct {
+	Client *entity.Client    `json:"client"`
+	Key    *entity.ClientKey `json:"key"`
+}
+
+// lookupKey retrieves an active client key and its client
+func (s *clientService) lookupKey(ctx context.Context, clientKey string, hash string) (*validatedKey, error) {
+	key, err := s.clientKeyRepo.GetByHash(ctx, hash)
+	if err != nil {
+		return nil, exception.InternalError("Error fetching client key")
+	}
+	if key == nil {
+		// Clients created before keys were hashed keep working with their plaintext key until the backfill moves it
+		client, err := s.clientRepo.GetByLegacyKey(ctx, clientKey)
+		if err != nil {
+			return nil, exception.InternalError("Error fetching client")
+		}
+		if client == nil {
+			return nil, exception.Http(401, "Invalid client key")
+		}
+		return &validatedKey{
+			Client: client,
+			Key:    &entity.ClientKey{ClientID: client.ID, Scopes: entity.ClientKeyScopes},
+		}, nil
+	}
+	if !key.Active(time.Now().UnixMilli()) {
+		return nil, exception.Http(401, "Invalid client key")
 	}
 
-	// If not in cache, fetch from database
-	client, err := s.clientRepo.GetByKey(ctx, clientKey)
+	client, err := s.clientRepo.Get(ctx, key.ClientID)
 	if err != nil {
 		return nil, exception.InternalError("Error fetching client")
 	}
@@ -202,36 +422,33 @@ func (s *clientService) ValidateKey(ctx context.Context, clientKey string) (*ent
 		return nil, exception.Http(401, "Invalid client key")
 	}
 
-	// Store in cache for future requests
-	if err := s.storeClientInCache(ctx, cacheKey, client); err != nil {
-		// Log the error but don't fail the request
-		// fmt.Printf("Error storing client in cache: %v\n", err)
-	}
-
-	return client, nil
+	return &validatedKey{Client: client, Key: key}, nil
 }
 
-// getClientFromCache attempts to retrieve a client from Redis cache
-func (s *clientService) getClientFromCache(ctx context.Context, key string) (*entity.Client, error) {
+// getKeyFromCache attempts to retrieve a validated client key from Redis cache
+func (s *clientService) getKeyFromCache(ctx context.Context, key string) (*validatedKey, error) {
 	val, err := s.redisClient.Get(ctx, key)
 	if err != nil {
 		return nil, err
 	}
 
-	var client entity.Client
-	if err := json.Unmarshal([]byte(val), &client); err != nil {
+	var validated validatedKey
+	if err := json.Unmarshal([]byte(val), &validated); err != nil {
 		return nil, err
 	}
+	if validated.Client == nil || validated.Key == nil {
+		return nil, fmt.Errorf("incomplete cached client key")
+	}
 
-	return &client, nil
+	return &validated, nil
 }
 
-// storeClientInCache stores a client in Redis cache with expiration
-func (s *clientService) storeClientInCache(ctx context.Context, key string, client *entity.Client) error {
-	data, err := json.Marshal(client)
+// storeKeyInCache stores a validated client key in Redis cache with expiration
+func (s *clientService) storeKeyInCache(ctx context.Context, key string, validated *validatedKey, expiration time.Duration) error {
+	data, err := json.Marshal(validated)
 	if err != nil {
 		return err
 	}
 
-	return s.redisClient.Set(ctx, key, string(data), clientCacheDuration)
+	return s.redisClient.Set(ctx, key, string(data), expiration)
 }
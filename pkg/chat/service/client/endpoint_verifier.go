@@ -0,0 +1,58 @@
+package client
+
+import (
+	"app/pkg/chat/domain/entity"
+	"app/pkg/exception"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"time"
+)
+
+// endpointVerifier sends tokens to the client's auth endpoint, which returns the user they belong to
+type endpointVerifier struct {
+	httpClient *http.Client
+}
+
+// Verify calls the client's auth endpoint with the token as a bearer token
+func (v *endpointVerifier) Verify(ctx context.Context, client *entity.Client, token string) (*entity.User, time.Time, error) {
+	// Create request to auth endpoint
+	req, err := http.NewRequestWithContext(ctx, "GET", client.AuthEndpoint, nil)
+	if err != nil {
+		return nil, time.Time{}, exception.InternalError("Failed to create authentication request")
+	}
+
+	// Add authorization header
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	// Make the request
+	resp, err := v.httpClient.Do(req)
+	if err != nil {
+		return nil, time.Time{}, exception.InternalError("Authentication request failed")
+	}
+	defer resp.Body.Close()
+
+	// Check response status
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		switch resp.StatusCode {
+		case http.StatusUnauthorized:
+			return nil, time.Time{}, errInvalidToken
+		case http.StatusForbidden:
+			return nil, time.Time{}, exception.Forbidden()
+		case http.StatusNotFound:
+			return nil, time.Time{}, exception.NotFound("User")
+		default:
+			return nil, time.Time{}, exception.InternalError("Authentication service error: " + string(body))
+		}
+	}
+
+	// Parse response body
+	var user entity.User
+	if err := json.NewDecoder(resp.Body).Decode(&user); err != nil {
+		return nil, time.Time{}, exception.InternalError("Failed to decode authentication response")
+	}
+
+	return &user, time.Time{}, nil
+}
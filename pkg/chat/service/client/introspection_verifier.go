@@ -0,0 +1,74 @@
+package client
+
+import (
+	"app/pkg/chat/domain/entity"
+	"app/pkg/exception"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+// introspectionVerifier checks tokens with the client's OAuth 2.0 token introspection endpoint (RFC 7662)
+type introspectionVerifier struct {
+	httpClient *http.Client
+}
+
+// introspectionResponse represents the parts of an introspection response used to build the user
+type introspectionResponse struct {
+	Active bool `json:"active"`
+	userClaims
+}
+
+// Verify posts the token to the introspection endpoint and accepts it if it's reported active
+func (v *introspectionVerifier) Verify(ctx context.Context, client *entity.Client, token string) (*entity.User, time.Time, error) {
+	form := url.Values{
+		"token":           {token},
+		"token_type_hint": {"access_token"},
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", client.Auth.IntrospectionEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, time.Time{}, exception.InternalError("Failed to create introspection request")
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if client.Auth.IntrospectionClientID != "" {
+		req.SetBasicAuth(client.Auth.IntrospectionClientID, client.Auth.Secret)
+	}
+
+	resp, err := v.httpClient.Do(req)
+	if err != nil {
+		return nil, time.Time{}, exception.InternalError("Token introspection request failed")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, time.Time{}, exception.InternalError("Token introspection failed")
+	}
+
+	var result introspectionResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, time.Time{}, exception.InternalError("Failed to decode introspection response")
+	}
+
+	if !result.Active {
+		return nil, time.Time{}, errInvalidToken
+	}
+	if client.Auth.Issuer != "" && result.Issuer != client.Auth.Issuer {
+		return nil, time.Time{}, errInvalidToken
+	}
+	if client.Auth.Audience != "" && !slices.Contains(result.Audience, client.Auth.Audience) {
+		return nil, time.Time{}, errInvalidToken
+	}
+
+	user, err := result.user()
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	return user, result.expiry(), nil
+}
@@ -0,0 +1,226 @@
+package client
+
+import (
+	"app/pkg/exception"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	// How long fetched key sets are used before being fetched again
+	jwksCacheDuration = 1 * time.Hour
+	// Minimum time between fetches of a key set, so tokens with unknown key IDs can't hammer the client's JWKS URL
+	jwksRefetchInterval = 1 * time.Minute
+	// Maximum size of a key set response
+	jwksMaxResponseSize = 1 << 20
+)
+
+// jwksCache fetches and caches the key sets published at clients' JWKS URLs
+type jwksCache struct {
+	httpClient *http.Client
+	mu         sync.Mutex
+	sets       map[string]*jwkSet // Keyed by JWKS URL
+}
+
+// jwkSet represents the signing keys of a key set, by key ID
+type jwkSet struct {
+	keys      map[string]crypto.PublicKey
+	fetchedAt time.Time
+}
+
+// jwk represents a JSON Web Key, only the fields of RSA, EC and Ed25519 signing keys are decoded
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func newJWKSCache(httpClient *http.Client) *jwksCache {
+	return &jwksCache{
+		httpClient: httpClient,
+		sets:       make(map[string]*jwkSet),
+	}
+}
+
+// key returns the key with the ID from the key set at the URL
+// The set is fetched again once it's stale or when it misses the key, which happens after the client rotated its keys
+func (c *jwksCache) key(ctx context.Context, url string, kid string) (crypto.PublicKey, error) {
+	c.mu.Lock()
+	set := c.sets[url]
+	c.mu.Unlock()
+
+	if set != nil {
+		key, found := set.lookup(kid)
+		age := time.Since(set.fetchedAt)
+		if found && age < jwksCacheDuration {
+			return key, nil
+		}
+		if age < jwksRefetchInterval {
+			if found {
+				return key, nil
+			}
+			return nil, fmt.Errorf("unknown key %q", kid)
+		}
+	}
+
+	fetched, err := c.fetch(ctx, url)
+	if err != nil {
+		fmt.Printf("Error fetching JWKS from %s: %v\n", url, err)
+		// Keep using the previous keys while the client's JWKS URL is unavailable
+		if set != nil {
+			if key, found := set.lookup(kid); found {
+				return key, nil
+			}
+		}
+		return nil, exception.InternalError("Failed to fetch client signing keys")
+	}
+
+	c.mu.Lock()
+	c.sets[url] = fetched
+	c.mu.Unlock()
+
+	key, found := fetched.lookup(kid)
+	if !found {
+		return nil, fmt.Errorf("unknown key %q", kid)
+	}
+
+	return key, nil
+}
+
+// fetch retrieves and parses the key set at the URL, skipping keys it can't use for signatures
+func (c *jwksCache) fetch(ctx context.Context, url string) (*jwkSet, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	var body struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, jwksMaxResponseSize)).Decode(&body); err != nil {
+		return nil, err
+	}
+
+	set := &jwkSet{
+		keys:      make(map[string]crypto.PublicKey, len(body.Keys)),
+		fetchedAt: time.Now(),
+	}
+	for _, k := range body.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		key, err := k.publicKey()
+		if err != nil {
+			continue
+		}
+		set.keys[k.Kid] = key
+	}
+
+	return set, nil
+}
+
+// lookup returns the key with the ID, tokens without a key ID can only use a set holding a single key
+func (s *jwkSet) lookup(kid string) (crypto.PublicKey, bool) {
+	if kid == "" && len(s.keys) == 1 {
+		for _, key := range s.keys {
+			return key, true
+		}
+	}
+
+	key, found := s.keys[kid]
+	return key, found
+}
+
+// publicKey decodes the key's parameters
+func (k *jwk) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		if !e.IsInt64() || e.Int64() > 1<<31-1 {
+			return nil, errors.New("invalid RSA exponent")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, err
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid Ed25519 key size")
+		}
+		return ed25519.PublicKey(x), nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+}
+
+// decodeBigInt decodes a base64url encoded big-endian integer
+func decodeBigInt(value string) (*big.Int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == 0 {
+		return nil, errors.New("empty integer")
+	}
+	return new(big.Int).SetBytes(data), nil
+}
@@ -0,0 +1,109 @@
+package client
+
+import (
+	"app/pkg/chat/domain/entity"
+	"app/pkg/exception"
+	"context"
+	"crypto"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// jwtLeeway tolerates clock skew between the client's token issuer and us
+const jwtLeeway = 30 * time.Second
+
+var (
+	hmacMethods       = []string{"HS256", "HS384", "HS512"}
+	asymmetricMethods = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512", "EdDSA"}
+)
+
+// jwtVerifier verifies JWTs locally with the client's HMAC secret, public key or JWKS keys
+// Tokens must expire, a stateless token without an expiry could never be invalidated
+type jwtVerifier struct {
+	jwks *jwksCache
+}
+
+// Verify checks the token's signature, expiry and, when the client sets them, its issuer and audience
+func (v *jwtVerifier) Verify(ctx context.Context, client *entity.Client, token string) (*entity.User, time.Time, error) {
+	opts := []jwt.ParserOption{
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(jwtLeeway),
+	}
+	if client.Auth.Issuer != "" {
+		opts = append(opts, jwt.WithIssuer(client.Auth.Issuer))
+	}
+	if client.Auth.Audience != "" {
+		opts = append(opts, jwt.WithAudience(client.Auth.Audience))
+	}
+
+	var keyFunc jwt.Keyfunc
+	switch client.Auth.Strategy {
+	case entity.AuthStrategyHMAC:
+		opts = append(opts, jwt.WithValidMethods(hmacMethods))
+		keyFunc = func(*jwt.Token) (interface{}, error) {
+			return []byte(client.Auth.Secret), nil
+		}
+	case entity.AuthStrategyPublicKey:
+		key, err := parsePublicKey(client.Auth.PublicKey)
+		if err != nil {
+			return nil, time.Time{}, exception.InternalError("Invalid client public key")
+		}
+		opts = append(opts, jwt.WithValidMethods(asymmetricMethods))
+		keyFunc = func(*jwt.Token) (interface{}, error) {
+			return key, nil
+		}
+	case entity.AuthStrategyJWKS:
+		opts = append(opts, jwt.WithValidMethods(asymmetricMethods))
+		keyFunc = func(t *jwt.Token) (interface{}, error) {
+			kid, _ := t.Header["kid"].(string)
+			return v.jwks.key(ctx, client.Auth.JWKSURL, kid)
+		}
+	default:
+		return nil, time.Time{}, exception.InternalError(fmt.Sprintf("Auth strategy %q doesn't use JWTs", client.Auth.Strategy))
+	}
+
+	var claims userClaims
+	if _, err := jwt.ParseWithClaims(token, &claims, keyFunc, opts...); err != nil {
+		// The client's JWKS being unreachable isn't the token's fault
+		var httpErr exception.HttpError
+		if errors.As(err, &httpErr) {
+			return nil, time.Time{}, httpErr
+		}
+		return nil, time.Time{}, errInvalidToken
+	}
+
+	user, err := claims.user()
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	return user, claims.expiry(), nil
+}
+
+// parsePublicKey parses a PEM encoded RSA, ECDSA or Ed25519 public key, or the public key of a certificate
+func parsePublicKey(data string) (crypto.PublicKey, error) {
+	block, _ := pem.Decode([]byte(data))
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+
+	switch block.Type {
+	case "PUBLIC KEY":
+		return x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		return x509.ParsePKCS1PublicKey(block.Bytes)
+	case "CERTIFICATE":
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		return cert.PublicKey, nil
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+}
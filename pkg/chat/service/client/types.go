@@ -4,6 +4,7 @@ import (
 	"app/pkg/chat/domain/entity"
 	"app/pkg/types/pagination"
 	"context"
+	"time"
 )
 
 // ClientService defines the interface for client-related operations
@@ -11,24 +12,68 @@ type ClientService interface {
 	// GetGetClient retrieves a client by ID
 	GetClient(ctx context.Context, id string) (*entity.Client, error)
 
-	// GetByKey retrieves a client by client key
-	GetByKey(ctx context.Context, clientKey string) (*entity.Client, error)
-
 	// GetClients retrieves multiple clients with pagination
 	GetClients(ctx context.Context, pag pagination.Pagination) ([]*entity.Client, int64, error)
 
-	// CreateClient creates a new client
-	CreateClient(ctx context.Context, client *entity.Client) error
+	// CreateClient creates a new client with a webhook secret and a first key with every scope
+	CreateClient(ctx context.Context, client *entity.Client) (*IssuedKey, error)
 
 	// UpdateClient modifies an existing client
 	UpdateClient(ctx context.Context, client *entity.Client) error
 
-	// DeleteClient removes a client
+	// DeleteClient removes a client and its keys
 	DeleteClient(ctx context.Context, id string) error
 
-	// Authenticate validates a JWT token against the client's auth endpoint and returns the authenticated user
+	// Authenticate verifies a user token with the client's auth strategy and returns the authenticated user
+	// Users are cached for at most 15 minutes and never past their token's expiry
 	Authenticate(ctx context.Context, clientID string, token string) (*entity.User, error)
 
-	// ValidateKey validates a client key and returns the client if valid
-	ValidateKey(ctx context.Context, clientKey string) (*entity.Client, error)
+	// RevokeToken rejects the token from now on and evicts it from the cache
+	// Revoked JWTs stay rejected until they expire, even though they're verified locally
+	RevokeToken(ctx context.Context, clientID string, token string) error
+
+	// EvictUser evicts every cached token of the user, so they're authenticated by their client again
+	EvictUser(ctx context.Context, userID string) error
+
+	// ValidateKey validates a client key and returns the client if the key is active and has the scope
+	// An empty scope accepts any active key, validated keys are cached for at most an hour
+	ValidateKey(ctx context.Context, clientKey string, scope entity.ClientKeyScope) (*entity.Client, error)
+
+	// GetKeys retrieves all keys of a client, including expired and revoked ones
+	GetKeys(ctx context.Context, clientID string) ([]*entity.ClientKey, error)
+
+	// CreateKey creates a new key for a client
+	CreateKey(ctx context.Context, params CreateKeyParams) (*IssuedKey, error)
+
+	// RotateKey replaces a key with a new one with the same name and scopes
+	// The replaced key keeps working for the grace period, so it can be swapped without downtime
+	RotateKey(ctx context.Context, params RotateKeyParams) (*IssuedKey, error)
+
+	// RevokeKey stops a key from working right away
+	RevokeKey(ctx context.Context, clientID string, keyID string) error
+
+	// RotateWebhookSecret replaces the secret the client's webhook requests are signed with and returns it
+	RotateWebhookSecret(ctx context.Context, clientID string) (string, error)
+}
+
+// CreateKeyParams represents the parameters for creating a client key
+type CreateKeyParams struct {
+	ClientID  string
+	Name      string
+	Scopes    []entity.ClientKeyScope // Defaults to every scope
+	ExpiresIn time.Duration           // Zero means the key doesn't expire
+}
+
+// RotateKeyParams represents the parameters for rotating a client key
+type RotateKeyParams struct {
+	ClientID    string
+	KeyID       string
+	GracePeriod time.Duration // How long the replaced key keeps working, zero revokes it right away
+}
+
+// IssuedKey represents a newly created client key
+// The key itself is only available here, only its hash is stored
+type IssuedKey struct {
+	*entity.ClientKey
+	Key string `json:"key"`
 }
@@ -0,0 +1,60 @@
+package client
+
+import (
+	"app/pkg/chat/domain/entity"
+	"app/pkg/exception"
+	"context"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// errInvalidToken is returned for tokens that fail verification, whatever the reason
+var errInvalidToken = exception.Http(401, "Invalid token")
+
+// tokenVerifier verifies user tokens with one of the clients' auth strategies
+type tokenVerifier interface {
+	// Verify returns the user the token was issued to and when the token expires, zero if the strategy doesn't tell
+	Verify(ctx context.Context, client *entity.Client, token string) (*entity.User, time.Time, error)
+}
+
+// userClaims represents the claims of a user token mapped onto entity.User
+// Introspection responses use the same fields
+type userClaims struct {
+	jwt.RegisteredClaims
+	UserID            string `json:"userId"`
+	Name              string `json:"name"`
+	Username          string `json:"username"`
+	PreferredUsername string `json:"preferred_username"` // OpenID Connect name for the username
+	Picture           string `json:"picture"`
+	Level             int    `json:"level"`
+}
+
+// user maps the claims onto a user, the subject becomes the user ID
+func (c *userClaims) user() (*entity.User, error) {
+	if c.Subject == "" {
+		return nil, errInvalidToken
+	}
+
+	username := c.Username
+	if username == "" {
+		username = c.PreferredUsername
+	}
+
+	return &entity.User{
+		ID:       c.Subject,
+		UserID:   c.UserID,
+		Name:     c.Name,
+		Username: username,
+		Picture:  c.Picture,
+		Level:    c.Level,
+	}, nil
+}
+
+// expiry returns when the claims expire, zero if they don't
+func (c *userClaims) expiry() time.Time {
+	if c.ExpiresAt == nil {
+		return time.Time{}
+	}
+	return c.ExpiresAt.Time
+}
@@ -0,0 +1,116 @@
+package gdpr
+
+import (
+	"app/pkg/chat/domain/entity"
+	"context"
+	"errors"
+	"time"
+)
+
+// erase anonymizes the user and removes what they left behind
+// Every step can safely run again, so an interrupted erasure is completed by retrying it
+func (w *Worker) erase(ctx context.Context, job *entity.DataJob) error {
+	user, err := w.userRepo.Get(ctx, job.UserID)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return errors.New("user not found")
+	}
+
+	policy, err := w.erasurePolicy(ctx, user.ClientID)
+	if err != nil {
+		return err
+	}
+	job.ErasurePolicy = policy
+	job.Messages = 0
+
+	// Users and jobs are timestamped in milliseconds, chat messages in seconds
+	now := time.Now()
+
+	// Anonymize first, so the user can't authenticate again while the rest is erased
+	if err := w.userRepo.Erase(ctx, user.ID, now.UnixMilli()); err != nil {
+		return err
+	}
+	// Cached tokens would keep authenticating the user with their old profile
+	if err := w.userEvictor.EvictUser(ctx, user.ID); err != nil {
+		return err
+	}
+
+	chatroomIDs, err := w.chatRepo.GetChatroomIDsBySender(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+	for _, chatroomID := range chatroomIDs {
+		var erased []*entity.Chat
+		if policy == entity.ErasurePolicyDelete {
+			erased, err = w.chatRepo.DeleteBySender(ctx, chatroomID, user.ID, now.Unix())
+		} else {
+			erased, err = w.chatRepo.SoftDeleteBySender(ctx, chatroomID, user.ID, now.Unix())
+		}
+		// Connected clients drop whatever was erased before a failure too, the retry erases the rest
+		for _, chat := range erased {
+			deletedBy := ""
+			if chat.DeletedBy != nil {
+				deletedBy = *chat.DeletedBy
+			}
+			w.publisher.MessageDeleted(ctx, chat, deletedBy)
+		}
+		if err != nil {
+			return err
+		}
+		job.Messages += int64(len(erased))
+
+		// The chatroom's last message preview may have been one of the user's messages
+		if err := w.chatroomRepo.RecomputeStats(ctx, chatroomID); err != nil {
+			return err
+		}
+	}
+
+	attachments, err := w.attachmentRepo.GetByUploader(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+	for _, attachment := range attachments {
+		if err := w.fileStorage.Delete(ctx, attachment.Key); err != nil {
+			return err
+		}
+		if err := w.attachmentRepo.Delete(ctx, attachment.ID); err != nil {
+			return err
+		}
+	}
+
+	// Earlier exports hold the data that was just erased
+	exports, err := w.jobRepo.GetExports(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+	for _, export := range exports {
+		if err := w.expireExport(ctx, export); err != nil {
+			return err
+		}
+	}
+
+	if err := w.privacyRepo.Delete(ctx, user.ID); err != nil {
+		return err
+	}
+
+	return w.preferenceRepo.Delete(ctx, user.ID)
+}
+
+// erasurePolicy returns what the client wants done with an erased user's messages
+func (w *Worker) erasurePolicy(ctx context.Context, clientID string) (entity.ErasurePolicy, error) {
+	if clientID == "" {
+		return entity.ErasurePolicyTombstone, nil
+	}
+
+	client, err := w.clientRepo.Get(ctx, clientID)
+	if err != nil {
+		return "", err
+	}
+	if client == nil || client.ErasurePolicy == "" {
+		return entity.ErasurePolicyTombstone, nil
+	}
+
+	return client.ErasurePolicy, nil
+}
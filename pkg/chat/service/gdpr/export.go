@@ -0,0 +1,136 @@
+package gdpr
+
+import (
+	"app/pkg/chat/domain/entity"
+	"app/pkg/chat/domain/repository"
+	"app/pkg/types/pagination"
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Chatrooms fetched per page while exporting memberships
+const exportPageSize = 100
+
+// Record types of an export, every line of the file is one record
+const (
+	exportRecordUser     = "user"
+	exportRecordChatroom = "chatroom"
+	exportRecordChat     = "chat"
+)
+
+// exportRecord represents one line of an export
+type exportRecord struct {
+	Type string      `json:"type"`
+	Data interface{} `json:"data"`
+}
+
+// exportMembership represents the user's membership of a chatroom in an export
+type exportMembership struct {
+	Chatroom            string                 `json:"chatroom"`
+	Name                string                 `json:"name"`
+	Type                entity.ChatroomType    `json:"type"`
+	IsGroup             bool                   `json:"isGroup"`
+	Role                entity.ParticipantRole `json:"role"`
+	JoinedTimestamp     int64                  `json:"joinedTimestamp"`
+	MutedUntilTimestamp *int64                 `json:"mutedUntilTimestamp,omitempty"`
+}
+
+// export writes the user's profile, chatroom memberships and chat messages to a JSON Lines file
+// Chat messages are the ones the user sent and the direct messages they received, oldest first
+func (w *Worker) export(ctx context.Context, job *entity.DataJob) error {
+	user, err := w.userRepo.Get(ctx, job.UserID)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return errors.New("user not found")
+	}
+
+	key := fmt.Sprintf("exports/%s.jsonl", job.ID)
+	job.Chatrooms = 0
+	job.Messages = 0
+
+	// Stream the records into storage instead of building the file in memory
+	reader, writer := io.Pipe()
+	done := make(chan error, 1)
+	go func() {
+		err := w.writeExport(ctx, job, user, writer)
+		writer.CloseWithError(err)
+		done <- err
+	}()
+
+	size, err := w.fileStorage.Store(ctx, key, reader)
+	reader.Close() // Unblocks the writer if storing stopped early
+	if writeErr := <-done; writeErr != nil {
+		err = writeErr
+	}
+	if err != nil {
+		w.fileStorage.Delete(ctx, key)
+		return err
+	}
+
+	job.FileKey = key
+	job.Size = size
+	return nil
+}
+
+// writeExport encodes every record of the user's export, counting them on the job
+func (w *Worker) writeExport(ctx context.Context, job *entity.DataJob, user *entity.User, out io.Writer) error {
+	buffered := bufio.NewWriter(out)
+	encoder := json.NewEncoder(buffered)
+
+	if err := encoder.Encode(exportRecord{Type: exportRecordUser, Data: user}); err != nil {
+		return err
+	}
+
+	filter := repository.ChatroomFilter{
+		ClientID:      user.ClientID,
+		ParticipantID: user.ID,
+	}
+	for page := 1; ; page++ {
+		chatrooms, _, err := w.chatroomRepo.GetAll(ctx, filter, pagination.Pagination{Page: page, Limit: exportPageSize})
+		if err != nil {
+			return err
+		}
+
+		for _, chatroom := range chatrooms {
+			for _, participant := range chatroom.Participants {
+				if participant.User != user.ID {
+					continue
+				}
+
+				membership := exportMembership{
+					Chatroom:            chatroom.ID,
+					Name:                chatroom.Name,
+					Type:                chatroom.Type,
+					IsGroup:             chatroom.IsGroup,
+					Role:                participant.Role,
+					JoinedTimestamp:     participant.JoinedTimestamp,
+					MutedUntilTimestamp: participant.MutedUntilTimestamp,
+				}
+				if err := encoder.Encode(exportRecord{Type: exportRecordChatroom, Data: membership}); err != nil {
+					return err
+				}
+				job.Chatrooms++
+			}
+		}
+
+		if len(chatrooms) < exportPageSize {
+			break
+		}
+	}
+
+	err := w.chatRepo.ForEachByUser(ctx, user.ID, func(chat *entity.Chat) error {
+		job.Messages++
+		return encoder.Encode(exportRecord{Type: exportRecordChat, Data: chat})
+	})
+	if err != nil {
+		return err
+	}
+
+	return buffered.Flush()
+}
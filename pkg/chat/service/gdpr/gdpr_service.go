@@ -0,0 +1,109 @@
+package gdpr
+
+import (
+	"app/pkg/chat/domain/entity"
+	"app/pkg/chat/domain/repository"
+	"app/pkg/exception"
+	"app/pkg/types/pagination"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+type gdprService struct {
+	jobRepo     repository.DataJobRepository
+	userRepo    repository.UserRepository
+	fileStorage repository.FileStorage
+}
+
+// NewGDPRService creates a new GDPR service
+func NewGDPRService(jobRepo repository.DataJobRepository, userRepo repository.UserRepository, fileStorage repository.FileStorage) GDPRService {
+	return &gdprService{
+		jobRepo:     jobRepo,
+		userRepo:    userRepo,
+		fileStorage: fileStorage,
+	}
+}
+
+// RequestJob queues an export or erasure of a user's data
+func (s *gdprService) RequestJob(ctx context.Context, params RequestJobParams) (*entity.DataJob, error) {
+	switch params.Type {
+	case entity.DataJobTypeExport, entity.DataJobTypeErasure:
+	default:
+		return nil, exception.BadRequest(fmt.Sprintf("Unknown data job type %q", params.Type))
+	}
+
+	user, err := s.userRepo.Get(ctx, params.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, exception.NotFound("User")
+	}
+
+	existing, err := s.jobRepo.GetUnfinished(ctx, user.ID, params.Type)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return existing, nil
+	}
+
+	job := &entity.DataJob{
+		ClientID:    user.ClientID,
+		UserID:      user.ID,
+		Type:        params.Type,
+		Status:      entity.DataJobStatusPending,
+		RequestedBy: params.RequestedBy,
+	}
+	if err := s.jobRepo.Create(ctx, job); err != nil {
+		return nil, err
+	}
+
+	return job, nil
+}
+
+// GetJob retrieves a single data job by ID
+func (s *gdprService) GetJob(ctx context.Context, id string) (*entity.DataJob, error) {
+	job, err := s.jobRepo.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if job == nil {
+		return nil, exception.NotFound("Data job")
+	}
+
+	return job, nil
+}
+
+// GetJobs retrieves multiple data jobs with filtering and pagination, newest first
+func (s *gdprService) GetJobs(ctx context.Context, filter repository.DataJobFilter, pag pagination.Pagination) ([]*entity.DataJob, int64, error) {
+	return s.jobRepo.GetAll(ctx, filter, pag)
+}
+
+// OpenExport retrieves a completed export and a reader for its file
+func (s *gdprService) OpenExport(ctx context.Context, id string) (*entity.DataJob, io.ReadCloser, error) {
+	job, err := s.GetJob(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if job.Type != entity.DataJobTypeExport {
+		return nil, nil, exception.NotFound("Export")
+	}
+
+	switch job.Status {
+	case entity.DataJobStatusCompleted:
+	case entity.DataJobStatusExpired:
+		return nil, nil, exception.Http(http.StatusGone, "Export has expired")
+	default:
+		return nil, nil, exception.Http(http.StatusConflict, fmt.Sprintf("Export is %s", job.Status))
+	}
+
+	reader, err := s.fileStorage.Open(ctx, job.FileKey)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return job, reader, nil
+}
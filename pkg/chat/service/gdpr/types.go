@@ -0,0 +1,49 @@
+package gdpr
+
+import (
+	"app/pkg/chat/domain/entity"
+	"app/pkg/chat/domain/repository"
+	"app/pkg/types/pagination"
+	"context"
+	"io"
+)
+
+// RequestedByAdmin is recorded as the requester of jobs requested through the admin API
+const RequestedByAdmin = "admin"
+
+// UserEvictor evicts users from the authentication cache
+type UserEvictor interface {
+	// EvictUser evicts every cached token of the user, so they're authenticated by their client again
+	EvictUser(ctx context.Context, userID string) error
+}
+
+// MessagePublisher notifies connected clients about erased chat messages
+type MessagePublisher interface {
+	// MessageDeleted notifies that a message was deleted by the given user
+	MessageDeleted(ctx context.Context, chat *entity.Chat, userID string)
+}
+
+// RequestJobParams represents parameters for requesting an export or erasure of a user's data
+type RequestJobParams struct {
+	UserID      string
+	Type        entity.DataJobType
+	RequestedBy string // The user's ID, or RequestedByAdmin
+}
+
+// GDPRService defines the interface for exporting and erasing a user's data
+// Jobs are queued and run in the background by a Worker, their status is polled with GetJob
+type GDPRService interface {
+	// RequestJob queues an export or erasure of a user's data
+	// The user's pending or running job of the same type is returned instead of queueing another one
+	RequestJob(ctx context.Context, params RequestJobParams) (*entity.DataJob, error)
+
+	// GetJob retrieves a single data job by ID
+	GetJob(ctx context.Context, id string) (*entity.DataJob, error)
+
+	// GetJobs retrieves multiple data jobs with filtering and pagination, newest first
+	GetJobs(ctx context.Context, filter repository.DataJobFilter, pagination pagination.Pagination) ([]*entity.DataJob, int64, error)
+
+	// OpenExport retrieves a completed export and a reader for its file
+	// The caller is responsible for closing the reader
+	OpenExport(ctx context.Context, id string) (*entity.DataJob, io.ReadCloser, error)
+}
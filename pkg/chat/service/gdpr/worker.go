@@ -0,0 +1,176 @@
+package gdpr
+
+import (
+	"app/pkg/chat/domain/entity"
+	"app/pkg/chat/domain/repository"
+	"context"
+	"fmt"
+	"time"
+)
+
+const (
+	// How long a worker waits before looking for jobs again once there are none
+	pollInterval = 5 * time.Second
+	// Time limit of a single attempt at a job
+	jobTimeout = 30 * time.Minute
+	// Running jobs started this long ago were abandoned by a stopped worker, must exceed the job timeout
+	staleJobTimeout = 1 * time.Hour
+	// Attempts before a job is marked as failed
+	maxJobAttempts = 3
+	// How often expired export files are removed
+	cleanupInterval = 1 * time.Hour
+	// Expired exports removed per cleanup
+	cleanupBatchSize = 100
+)
+
+// Worker runs queued data jobs
+// Several nodes can run a worker at once, each job is claimed by one of them
+type Worker struct {
+	jobRepo         repository.DataJobRepository
+	userRepo        repository.UserRepository
+	clientRepo      repository.ClientRepository
+	chatRepo        repository.ChatRepository
+	chatroomRepo    repository.ChatroomRepository
+	attachmentRepo  repository.AttachmentRepository
+	privacyRepo     repository.UserPrivacyRepository
+	preferenceRepo  repository.NotificationPreferenceRepository
+	fileStorage     repository.FileStorage
+	userEvictor     UserEvictor
+	publisher       MessagePublisher
+	exportRetention time.Duration
+}
+
+// NewWorker creates a new Worker, export files are removed once the retention period is over
+func NewWorker(jobRepo repository.DataJobRepository, userRepo repository.UserRepository, clientRepo repository.ClientRepository, chatRepo repository.ChatRepository, chatroomRepo repository.ChatroomRepository, attachmentRepo repository.AttachmentRepository, privacyRepo repository.UserPrivacyRepository, preferenceRepo repository.NotificationPreferenceRepository, fileStorage repository.FileStorage, userEvictor UserEvictor, publisher MessagePublisher, exportRetention time.Duration) *Worker {
+	return &Worker{
+		jobRepo:         jobRepo,
+		userRepo:        userRepo,
+		clientRepo:      clientRepo,
+		chatRepo:        chatRepo,
+		chatroomRepo:    chatroomRepo,
+		attachmentRepo:  attachmentRepo,
+		privacyRepo:     privacyRepo,
+		preferenceRepo:  preferenceRepo,
+		fileStorage:     fileStorage,
+		userEvictor:     userEvictor,
+		publisher:       publisher,
+		exportRetention: exportRetention,
+	}
+}
+
+// Run runs queued jobs and removes expired exports until the context is cancelled
+func (w *Worker) Run(ctx context.Context) {
+	var lastCleanup time.Time
+
+	for ctx.Err() == nil {
+		if time.Since(lastCleanup) >= cleanupInterval {
+			w.removeExpiredExports(ctx)
+			lastCleanup = time.Now()
+		}
+
+		job, err := w.jobRepo.Claim(ctx, time.Now().Add(-staleJobTimeout).UnixMilli())
+		if err != nil && ctx.Err() == nil {
+			fmt.Printf("Error claiming data job: %v\n", err)
+		}
+		if job == nil {
+			select {
+			case <-ctx.Done():
+			case <-time.After(pollInterval):
+			}
+			continue
+		}
+
+		w.process(ctx, job)
+	}
+}
+
+// process makes one attempt at a job and records its outcome
+func (w *Worker) process(ctx context.Context, job *entity.DataJob) {
+	if job.Attempts > maxJobAttempts {
+		w.finish(job, fmt.Errorf("gave up after %d attempts", maxJobAttempts))
+		return
+	}
+
+	jobCtx, cancel := context.WithTimeout(ctx, jobTimeout)
+	defer cancel()
+
+	var err error
+	switch job.Type {
+	case entity.DataJobTypeExport:
+		err = w.export(jobCtx, job)
+	case entity.DataJobTypeErasure:
+		err = w.erase(jobCtx, job)
+	default:
+		err = fmt.Errorf("unknown data job type %q", job.Type)
+	}
+
+	// A job interrupted by shutdown is left to the next worker instead of failing
+	if err != nil && ctx.Err() != nil {
+		job.Status = entity.DataJobStatusPending
+		job.Attempts--
+		w.save(job)
+		return
+	}
+
+	w.finish(job, err)
+}
+
+// finish marks a job as completed, or as failed with the error
+func (w *Worker) finish(job *entity.DataJob, err error) {
+	now := time.Now()
+	completed := now.UnixMilli()
+	job.CompletedTimestamp = &completed
+
+	if err != nil {
+		fmt.Printf("Error running %s job %s: %v\n", job.Type, job.ID, err)
+		job.Status = entity.DataJobStatusFailed
+		job.Error = err.Error()
+	} else {
+		job.Status = entity.DataJobStatusCompleted
+		job.Error = ""
+		if job.Type == entity.DataJobTypeExport {
+			expires := now.Add(w.exportRetention).UnixMilli()
+			job.ExpiresTimestamp = &expires
+		}
+	}
+
+	w.save(job)
+}
+
+// save stores a job's progress, even while the worker is shutting down
+func (w *Worker) save(job *entity.DataJob) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := w.jobRepo.Update(ctx, job); err != nil {
+		fmt.Printf("Error updating data job %s: %v\n", job.ID, err)
+	}
+}
+
+// removeExpiredExports removes the files of exports past their retention period
+func (w *Worker) removeExpiredExports(ctx context.Context) {
+	jobs, err := w.jobRepo.GetExpiredExports(ctx, time.Now().UnixMilli(), cleanupBatchSize)
+	if err != nil {
+		if ctx.Err() == nil {
+			fmt.Printf("Error fetching expired exports: %v\n", err)
+		}
+		return
+	}
+
+	for _, job := range jobs {
+		if err := w.expireExport(ctx, job); err != nil {
+			fmt.Printf("Error removing export %s: %v\n", job.ID, err)
+		}
+	}
+}
+
+// expireExport removes the file of a completed export
+func (w *Worker) expireExport(ctx context.Context, job *entity.DataJob) error {
+	if err := w.fileStorage.Delete(ctx, job.FileKey); err != nil {
+		return err
+	}
+
+	job.Status = entity.DataJobStatusExpired
+	job.FileKey = ""
+	return w.jobRepo.Update(ctx, job)
+}
@@ -0,0 +1,85 @@
+package moderation
+
+import (
+	"app/pkg/chat/domain/entity"
+	"context"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// linkPattern matches links starting with a scheme or www
+var linkPattern = regexp.MustCompile(`(?i)\b(?:https?://|www\.)[^\s<>"]+`)
+
+// maskedLink replaces filtered links in masked messages
+const maskedLink = "[link removed]"
+
+// LinkFilter masks or rejects messages linking to blocked domains, or to domains outside a chatroom's allow list
+// A domain also covers its subdomains
+type LinkFilter struct {
+	blockedDomains []string // Blocked in every chatroom
+}
+
+// NewLinkFilter creates a new LinkFilter blocking the given domains in every chatroom
+func NewLinkFilter(blockedDomains []string) *LinkFilter {
+	return &LinkFilter{blockedDomains: normalizeList(blockedDomains)}
+}
+
+// Name identifies the stage in moderation decisions
+func (f *LinkFilter) Name() string {
+	return "links"
+}
+
+// Moderate replaces each filtered link, or rejects the message if the chatroom says so
+func (f *LinkFilter) Moderate(ctx context.Context, message *Message, settings *entity.ModerationSettings) (Verdict, error) {
+	filtered := false
+	masked := linkPattern.ReplaceAllStringFunc(message.Text, func(link string) string {
+		if f.allowed(link, settings) {
+			return link
+		}
+		filtered = true
+		return maskedLink
+	})
+
+	if !filtered {
+		return Verdict{Action: entity.ModerationActionAllow}, nil
+	}
+
+	verdict := Verdict{Action: settings.LinkAction, Reason: "contains links to domains that aren't allowed"}
+	if verdict.Action == entity.ModerationActionMask {
+		verdict.Text = masked
+	}
+
+	return verdict, nil
+}
+
+// allowed checks if a link can be posted in the chatroom
+func (f *LinkFilter) allowed(link string, settings *entity.ModerationSettings) bool {
+	if !strings.Contains(link, "://") {
+		link = "http://" + link
+	}
+
+	parsed, err := url.Parse(link)
+	if err != nil || parsed.Hostname() == "" {
+		// Links without a readable host can't be checked against the allow list
+		return len(settings.AllowedDomains) == 0
+	}
+	host := strings.ToLower(strings.TrimSuffix(parsed.Hostname(), "."))
+
+	if slices.ContainsFunc(f.blockedDomains, matchesDomain(host)) || slices.ContainsFunc(settings.BlockedDomains, matchesDomain(host)) {
+		return false
+	}
+	if len(settings.AllowedDomains) > 0 {
+		return slices.ContainsFunc(settings.AllowedDomains, matchesDomain(host))
+	}
+
+	return true
+}
+
+// matchesDomain returns a function checking if a domain is the host or one of its parents
+func matchesDomain(host string) func(domain string) bool {
+	return func(domain string) bool {
+		return host == domain || strings.HasSuffix(host, "."+domain)
+	}
+}
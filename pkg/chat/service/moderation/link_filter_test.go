@@ -0,0 +1,43 @@
+package moderation
+
+import (
+	"app/pkg/chat/domain/entity"
+	"context"
+	"testing"
+)
+
+func TestLinkFilter(t *testing.T) {
+	tests := []struct {
+		name       string
+		blocked    []string // Blocked in every chatroom
+		settings   entity.ModerationSettings
+		text       string
+		wantAction entity.ModerationAction
+		wantText   string
+	}{
+		{"no links", []string{"spam.com"}, entity.ModerationSettings{LinkAction: entity.ModerationActionMask}, "no links here", entity.ModerationActionAllow, ""},
+		{"unlisted domain", []string{"spam.com"}, entity.ModerationSettings{LinkAction: entity.ModerationActionMask}, "see https://example.com/page", entity.ModerationActionAllow, ""},
+		{"globally blocked domain", []string{"spam.com"}, entity.ModerationSettings{LinkAction: entity.ModerationActionMask}, "see https://spam.com/offer now", entity.ModerationActionMask, "see [link removed] now"},
+		{"chatroom blocked domain", nil, entity.ModerationSettings{BlockedDomains: []string{"spam.com"}, LinkAction: entity.ModerationActionMask}, "see http://spam.com", entity.ModerationActionMask, "see [link removed]"},
+		{"blocked subdomain", []string{"spam.com"}, entity.ModerationSettings{LinkAction: entity.ModerationActionMask}, "see www.deals.spam.com", entity.ModerationActionMask, "see [link removed]"},
+		{"lookalike domain", []string{"spam.com"}, entity.ModerationSettings{LinkAction: entity.ModerationActionMask}, "see https://notspam.com", entity.ModerationActionAllow, ""},
+		{"host case and trailing dot", []string{"spam.com"}, entity.ModerationSettings{LinkAction: entity.ModerationActionMask}, "see HTTPS://SPAM.COM./x", entity.ModerationActionMask, "see [link removed]"},
+		{"allow list", nil, entity.ModerationSettings{AllowedDomains: []string{"example.com"}, LinkAction: entity.ModerationActionMask}, "https://docs.example.com and https://other.org", entity.ModerationActionMask, "https://docs.example.com and [link removed]"},
+		{"blocked beats allowed", nil, entity.ModerationSettings{AllowedDomains: []string{"example.com"}, BlockedDomains: []string{"bad.example.com"}, LinkAction: entity.ModerationActionMask}, "https://bad.example.com", entity.ModerationActionMask, "[link removed]"},
+		{"rejects", []string{"spam.com"}, entity.ModerationSettings{LinkAction: entity.ModerationActionReject}, "https://spam.com", entity.ModerationActionReject, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter := NewLinkFilter(tt.blocked)
+
+			verdict, err := filter.Moderate(context.Background(), &Message{Text: tt.text}, &tt.settings)
+			if err != nil {
+				t.Fatalf("Moderate: %v", err)
+			}
+			if verdict.Action != tt.wantAction || verdict.Text != tt.wantText {
+				t.Errorf("Moderate(%q) = %s %q, want %s %q", tt.text, verdict.Action, verdict.Text, tt.wantAction, tt.wantText)
+			}
+		})
+	}
+}
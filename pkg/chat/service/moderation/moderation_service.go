@@ -0,0 +1,168 @@
+package moderation
+
+import (
+	"app/pkg/chat/domain/entity"
+	"app/pkg/chat/domain/repository"
+	"app/pkg/chat/service/chatroom"
+	"app/pkg/exception"
+	"app/pkg/types/pagination"
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+type moderationService struct {
+	moderationRepo  repository.ModerationRepository
+	chatroomService chatroom.ChatroomService
+	stages          []Stage
+}
+
+// NewModerationService creates a new moderation service running the given stages in order
+func NewModerationService(moderationRepo repository.ModerationRepository, chatroomService chatroom.ChatroomService, stages ...Stage) ModerationService {
+	return &moderationService{
+		moderationRepo:  moderationRepo,
+		chatroomService: chatroomService,
+		stages:          stages,
+	}
+}
+
+// Moderate runs a message through every stage of the pipeline in order
+// A failing stage is skipped so an outage of its backing store doesn't stop the chat
+func (s *moderationService) Moderate(ctx context.Context, params ModerateParams) (string, error) {
+	if strings.TrimSpace(params.Message) == "" {
+		return params.Message, nil
+	}
+
+	settings, err := s.GetSettings(ctx, params.ChatroomID)
+	if err != nil {
+		return "", err
+	}
+	if settings.Disabled {
+		return params.Message, nil
+	}
+
+	message := &Message{
+		ClientID:   params.ClientID,
+		ChatroomID: params.ChatroomID,
+		SenderID:   params.SenderID,
+		Text:       params.Message,
+	}
+
+	for _, stage := range s.stages {
+		verdict, err := stage.Moderate(ctx, message, settings)
+		if err != nil {
+			fmt.Printf("Error running moderation stage %s: %v\n", stage.Name(), err)
+			continue
+		}
+
+		switch verdict.Action {
+		case entity.ModerationActionMask:
+			s.record(ctx, params, stage, verdict)
+			message.Text = verdict.Text
+		case entity.ModerationActionReject:
+			s.record(ctx, params, stage, verdict)
+			return "", exception.Http(http.StatusUnprocessableEntity, fmt.Sprintf("Message rejected: %s", verdict.Reason))
+		}
+	}
+
+	return message.Text, nil
+}
+
+// record stores a moderation decision, failures are only logged since the decision is already made
+func (s *moderationService) record(ctx context.Context, params ModerateParams, stage Stage, verdict Verdict) {
+	decision := &entity.ModerationDecision{
+		ClientID:         params.ClientID,
+		Chatroom:         params.ChatroomID,
+		Sender:           params.SenderID,
+		Stage:            stage.Name(),
+		Action:           verdict.Action,
+		Reason:           verdict.Reason,
+		Message:          params.Message,
+		ModeratedMessage: verdict.Text,
+		CreatedTimestamp: time.Now().Unix(),
+	}
+
+	if err := s.moderationRepo.CreateDecision(ctx, decision); err != nil {
+		fmt.Printf("Error recording moderation decision: %v\n", err)
+	}
+}
+
+// GetSettings retrieves a chatroom's moderation settings, defaults are returned if none are stored
+func (s *moderationService) GetSettings(ctx context.Context, chatroomID string) (*entity.ModerationSettings, error) {
+	settings, err := s.moderationRepo.GetSettings(ctx, chatroomID)
+	if err != nil {
+		return nil, err
+	}
+	if settings == nil {
+		settings = &entity.ModerationSettings{ID: chatroomID}
+	}
+
+	if settings.WordAction == "" {
+		settings.WordAction = entity.ModerationActionMask
+	}
+	if settings.LinkAction == "" {
+		settings.LinkAction = entity.ModerationActionReject
+	}
+
+	return settings, nil
+}
+
+// UpdateSettings replaces a chatroom's moderation settings
+func (s *moderationService) UpdateSettings(ctx context.Context, params UpdateSettingsParams) (*entity.ModerationSettings, error) {
+	chatroom, err := s.chatroomService.GetChatroom(ctx, params.ChatroomID)
+	if err != nil || chatroom == nil {
+		return nil, exception.NotFound("Chatroom")
+	}
+
+	if !isFilterAction(params.WordAction) || !isFilterAction(params.LinkAction) {
+		return nil, exception.BadRequest("Actions must be mask or reject")
+	}
+	if params.RepeatLimit < 0 || params.RepeatWindow < 0 {
+		return nil, exception.BadRequest("Repeat limit and window can't be negative")
+	}
+
+	settings := &entity.ModerationSettings{
+		ID:             params.ChatroomID,
+		Disabled:       params.Disabled,
+		BlockedWords:   normalizeList(params.BlockedWords),
+		WordAction:     params.WordAction,
+		AllowedDomains: normalizeList(params.AllowedDomains),
+		BlockedDomains: normalizeList(params.BlockedDomains),
+		LinkAction:     params.LinkAction,
+		RepeatLimit:    params.RepeatLimit,
+		RepeatWindow:   params.RepeatWindow,
+	}
+
+	if err := s.moderationRepo.UpsertSettings(ctx, settings); err != nil {
+		return nil, err
+	}
+
+	return s.GetSettings(ctx, params.ChatroomID)
+}
+
+// GetDecisions retrieves moderation decisions with filtering and pagination, newest first
+func (s *moderationService) GetDecisions(ctx context.Context, filter repository.ModerationDecisionFilter, pag pagination.Pagination) ([]*entity.ModerationDecision, int64, error) {
+	return s.moderationRepo.GetDecisions(ctx, filter, pag)
+}
+
+// isFilterAction checks if an action can be taken by the word and link filters, empty uses the default
+func isFilterAction(action entity.ModerationAction) bool {
+	return action == "" || action == entity.ModerationActionMask || action == entity.ModerationActionReject
+}
+
+// normalizeList lowercases and trims words or domains, dropping empty and duplicate entries
+func normalizeList(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	result := make([]string, 0, len(values))
+	for _, value := range values {
+		value = strings.ToLower(strings.TrimSpace(value))
+		if value == "" || seen[value] {
+			continue
+		}
+		seen[value] = true
+		result = append(result, value)
+	}
+	return result
+}
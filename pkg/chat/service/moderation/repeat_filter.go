@@ -0,0 +1,72 @@
+package moderation
+
+import (
+	"app/pkg/chat/domain/entity"
+	"app/pkg/database/redis"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// repeatKeyPrefix prefixes the Redis counters of identical messages
+const repeatKeyPrefix = "moderation:repeat:"
+
+// RepeatFilter rejects messages a sender already sent to the chatroom too often within a window
+// Messages are compared ignoring case and whitespace
+type RepeatFilter struct {
+	redisClient *redis.Client
+	limit       int           // Identical messages allowed per window unless the chatroom sets its own
+	window      time.Duration // Window unless the chatroom sets its own
+}
+
+// NewRepeatFilter creates a new RepeatFilter, a zero limit or window disables it for chatrooms that don't set their own
+func NewRepeatFilter(redisClient *redis.Client, limit int, window time.Duration) *RepeatFilter {
+	return &RepeatFilter{
+		redisClient: redisClient,
+		limit:       limit,
+		window:      window,
+	}
+}
+
+// Name identifies the stage in moderation decisions
+func (f *RepeatFilter) Name() string {
+	return "repeat"
+}
+
+// Moderate counts the message and rejects it once the sender exceeds the limit
+func (f *RepeatFilter) Moderate(ctx context.Context, message *Message, settings *entity.ModerationSettings) (Verdict, error) {
+	limit, window := f.limit, f.window
+	if settings.RepeatLimit > 0 {
+		limit = settings.RepeatLimit
+	}
+	if settings.RepeatWindow > 0 {
+		window = time.Duration(settings.RepeatWindow) * time.Second
+	}
+	if limit <= 0 || window <= 0 {
+		return Verdict{Action: entity.ModerationActionAllow}, nil
+	}
+
+	normalized := strings.ToLower(strings.Join(strings.Fields(message.Text), " "))
+	hash := sha256.Sum256([]byte(normalized))
+	key := fmt.Sprintf("%s%s:%s:%s", repeatKeyPrefix, message.ChatroomID, message.SenderID, hex.EncodeToString(hash[:16]))
+
+	count, err := f.redisClient.Incr(ctx, key)
+	if err != nil {
+		return Verdict{}, err
+	}
+	if count == 1 {
+		// The window starts with the first message
+		if err := f.redisClient.Expire(ctx, key, window); err != nil {
+			return Verdict{}, err
+		}
+	}
+
+	if count > int64(limit) {
+		return Verdict{Action: entity.ModerationActionReject, Reason: "repeated message"}, nil
+	}
+
+	return Verdict{Action: entity.ModerationActionAllow}, nil
+}
@@ -0,0 +1,114 @@
+package moderation
+
+import (
+	"app/pkg/chat/domain/entity"
+	"app/pkg/database"
+	"app/pkg/database/redis"
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"testing"
+	"time"
+)
+
+// testRedis connects to the Redis of REDIS_TEST_ADDR, a host and port
+func testRedis(t *testing.T) *redis.Client {
+	t.Helper()
+
+	addr := os.Getenv("REDIS_TEST_ADDR")
+	if addr == "" {
+		t.Skip("REDIS_TEST_ADDR is not set")
+	}
+
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		t.Fatalf("Invalid REDIS_TEST_ADDR: %v", err)
+	}
+
+	client := redis.NewClient(database.DatabaseConfig{Host: host, Port: port})
+	if err := client.Connect(); err != nil {
+		t.Fatalf("Failed to connect to Redis: %v", err)
+	}
+	t.Cleanup(func() {
+		client.Disconnect()
+	})
+
+	return client
+}
+
+func TestRepeatFilterIsDisabledWithoutALimitAndWindow(t *testing.T) {
+	tests := []struct {
+		name     string
+		limit    int
+		window   time.Duration
+		settings entity.ModerationSettings
+	}{
+		{"no limit", 0, time.Minute, entity.ModerationSettings{}},
+		{"no window", 3, 0, entity.ModerationSettings{}},
+		{"chatroom sets only the limit", 0, 0, entity.ModerationSettings{RepeatLimit: 3}},
+		{"chatroom sets only the window", 0, 0, entity.ModerationSettings{RepeatWindow: 60}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// A disabled filter never reaches Redis
+			filter := NewRepeatFilter(nil, tt.limit, tt.window)
+
+			verdict, err := filter.Moderate(context.Background(), &Message{ChatroomID: "room-1", SenderID: "user-1", Text: "hi"}, &tt.settings)
+			if err != nil || verdict.Action != entity.ModerationActionAllow {
+				t.Errorf("Moderate = %s, %v, want allow", verdict.Action, err)
+			}
+		})
+	}
+}
+
+func TestRepeatFilterRejectsMessagesOverTheLimit(t *testing.T) {
+	client := testRedis(t)
+	ctx := context.Background()
+	filter := NewRepeatFilter(client, 2, time.Minute)
+	chatroomID := fmt.Sprintf("room-%d", time.Now().UnixNano())
+
+	tests := []struct {
+		sender     string
+		text       string
+		wantAction entity.ModerationAction
+	}{
+		{"user-1", "hello there", entity.ModerationActionAllow},
+		{"user-1", "  HELLO   there ", entity.ModerationActionAllow}, // Same message ignoring case and whitespace
+		{"user-1", "hello there", entity.ModerationActionReject},
+		{"user-1", "something else", entity.ModerationActionAllow},
+		{"user-2", "hello there", entity.ModerationActionAllow}, // Counted per sender
+	}
+
+	for i, tt := range tests {
+		verdict, err := filter.Moderate(ctx, &Message{ChatroomID: chatroomID, SenderID: tt.sender, Text: tt.text}, &entity.ModerationSettings{})
+		if err != nil {
+			t.Fatalf("Moderate #%d: %v", i+1, err)
+		}
+		if verdict.Action != tt.wantAction {
+			t.Errorf("Moderate #%d (%s %q) = %s, want %s", i+1, tt.sender, tt.text, verdict.Action, tt.wantAction)
+		}
+	}
+}
+
+func TestRepeatFilterUsesTheChatroomWindow(t *testing.T) {
+	client := testRedis(t)
+	ctx := context.Background()
+	filter := NewRepeatFilter(client, 1, time.Hour)
+	message := &Message{ChatroomID: fmt.Sprintf("room-%d", time.Now().UnixNano()), SenderID: "user-1", Text: "hi"}
+	settings := &entity.ModerationSettings{RepeatWindow: 1}
+
+	if verdict, err := filter.Moderate(ctx, message, settings); err != nil || verdict.Action != entity.ModerationActionAllow {
+		t.Fatalf("first Moderate = %s, %v, want allow", verdict.Action, err)
+	}
+	if verdict, err := filter.Moderate(ctx, message, settings); err != nil || verdict.Action != entity.ModerationActionReject {
+		t.Fatalf("second Moderate = %s, %v, want reject", verdict.Action, err)
+	}
+
+	// The chatroom's one second window replaces the service's hour
+	time.Sleep(1100 * time.Millisecond)
+	if verdict, err := filter.Moderate(ctx, message, settings); err != nil || verdict.Action != entity.ModerationActionAllow {
+		t.Errorf("Moderate after the window = %s, %v, want allow", verdict.Action, err)
+	}
+}
@@ -0,0 +1,71 @@
+package moderation
+
+import (
+	"app/pkg/chat/domain/entity"
+	"app/pkg/chat/domain/repository"
+	"app/pkg/types/pagination"
+	"context"
+)
+
+// Message represents a message going through the moderation pipeline
+type Message struct {
+	ClientID   string
+	ChatroomID string
+	SenderID   string
+	Text       string // Holds the masked text once a stage masked it
+}
+
+// Verdict represents the decision of a single stage
+type Verdict struct {
+	Action entity.ModerationAction
+	Text   string // Masked text, only set when the action is mask
+	Reason string // Why the message was masked or rejected
+}
+
+// Stage is a step of the moderation pipeline
+type Stage interface {
+	// Name identifies the stage in moderation decisions
+	Name() string
+
+	// Moderate decides what to do with a message given the settings of its chatroom, which are never nil
+	Moderate(ctx context.Context, message *Message, settings *entity.ModerationSettings) (Verdict, error)
+}
+
+// ModerateParams represents parameters for moderating a message before it's stored
+type ModerateParams struct {
+	ClientID   string // Client the message is sent through
+	ChatroomID string
+	SenderID   string
+	Message    string
+}
+
+// UpdateSettingsParams represents parameters for updating a chatroom's moderation settings
+type UpdateSettingsParams struct {
+	ChatroomID     string
+	Disabled       bool
+	BlockedWords   []string
+	WordAction     entity.ModerationAction // mask or reject, empty defaults to mask
+	AllowedDomains []string
+	BlockedDomains []string
+	LinkAction     entity.ModerationAction // mask or reject, empty defaults to reject
+	RepeatLimit    int                     // 0 uses the service default
+	RepeatWindow   int64                   // In seconds, 0 uses the service default
+}
+
+// ModerationService defines the interface for message moderation operations
+type ModerationService interface {
+	// Moderate runs a message through every stage of the pipeline in order
+	// Masked text is passed on to the following stages and the first rejection stops the pipeline
+	// Returns the text to store, or a 422 error with the reason if the message was rejected
+	// Masks and rejections are recorded as moderation decisions
+	Moderate(ctx context.Context, params ModerateParams) (string, error)
+
+	// GetSettings retrieves a chatroom's moderation settings, defaults are returned if none are stored
+	GetSettings(ctx context.Context, chatroomID string) (*entity.ModerationSettings, error)
+
+	// UpdateSettings replaces a chatroom's moderation settings
+	UpdateSettings(ctx context.Context, params UpdateSettingsParams) (*entity.ModerationSettings, error)
+
+	// GetDecisions retrieves moderation decisions with filtering and pagination, newest first
+	GetDecisions(ctx context.Context, filter repository.ModerationDecisionFilter, pag pagination.Pagination) ([]*entity.ModerationDecision, int64, error)
+}
@@ -0,0 +1,81 @@
+package moderation
+
+import (
+	"app/pkg/chat/domain/entity"
+	"context"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// WordFilter masks or rejects messages containing blocked words
+// Words match case insensitively and only as whole words
+type WordFilter struct {
+	words []string // Blocked in every chatroom
+}
+
+// NewWordFilter creates a new WordFilter blocking the given words in every chatroom
+func NewWordFilter(words []string) *WordFilter {
+	return &WordFilter{words: normalizeList(words)}
+}
+
+// Name identifies the stage in moderation decisions
+func (f *WordFilter) Name() string {
+	return "words"
+}
+
+// Moderate replaces each blocked word with asterisks, or rejects the message if the chatroom says so
+func (f *WordFilter) Moderate(ctx context.Context, message *Message, settings *entity.ModerationSettings) (Verdict, error) {
+	words := append(append([]string{}, f.words...), settings.BlockedWords...)
+	if len(words) == 0 {
+		return Verdict{Action: entity.ModerationActionAllow}, nil
+	}
+
+	quoted := make([]string, 0, len(words))
+	for _, word := range words {
+		quoted = append(quoted, regexp.QuoteMeta(word))
+	}
+	pattern, err := regexp.Compile(`(?i)(?:` + strings.Join(quoted, "|") + `)`)
+	if err != nil {
+		return Verdict{}, err
+	}
+
+	matches := wholeWords(message.Text, pattern.FindAllStringIndex(message.Text, -1))
+	if len(matches) == 0 {
+		return Verdict{Action: entity.ModerationActionAllow}, nil
+	}
+
+	verdict := Verdict{Action: settings.WordAction, Reason: "contains blocked words"}
+	if verdict.Action == entity.ModerationActionMask {
+		var masked strings.Builder
+		last := 0
+		for _, match := range matches {
+			masked.WriteString(message.Text[last:match[0]])
+			masked.WriteString(strings.Repeat("*", utf8.RuneCountInString(message.Text[match[0]:match[1]])))
+			last = match[1]
+		}
+		masked.WriteString(message.Text[last:])
+		verdict.Text = masked.String()
+	}
+
+	return verdict, nil
+}
+
+// wholeWords keeps the matches not surrounded by word characters
+// Regexp's \b only knows ASCII, so words like "café" would never match
+func wholeWords(text string, matches [][]int) [][]int {
+	words := matches[:0]
+	for _, match := range matches {
+		before, _ := utf8.DecodeLastRuneInString(text[:match[0]])
+		after, _ := utf8.DecodeRuneInString(text[match[1]:])
+		if !isWordRune(before) && !isWordRune(after) {
+			words = append(words, match)
+		}
+	}
+	return words
+}
+
+func isWordRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.Is(unicode.Mn, r)
+}
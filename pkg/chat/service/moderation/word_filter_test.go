@@ -0,0 +1,47 @@
+package moderation
+
+import (
+	"app/pkg/chat/domain/entity"
+	"context"
+	"testing"
+)
+
+func TestWordFilter(t *testing.T) {
+	tests := []struct {
+		name         string
+		globalWords  []string
+		chatroomWord []string
+		action       entity.ModerationAction
+		text         string
+		wantAction   entity.ModerationAction
+		wantText     string
+	}{
+		{"no words", nil, nil, entity.ModerationActionMask, "darn it", entity.ModerationActionAllow, ""},
+		{"clean message", []string{"darn"}, nil, entity.ModerationActionMask, "hello there", entity.ModerationActionAllow, ""},
+		{"masks global word", []string{"darn"}, nil, entity.ModerationActionMask, "darn it", entity.ModerationActionMask, "**** it"},
+		{"masks chatroom word", nil, []string{"heck"}, entity.ModerationActionMask, "what the heck", entity.ModerationActionMask, "what the ****"},
+		{"ignores case", []string{"darn"}, nil, entity.ModerationActionMask, "DaRn it", entity.ModerationActionMask, "**** it"},
+		{"masks every occurrence", []string{"darn", "heck"}, nil, entity.ModerationActionMask, "darn, heck and darn", entity.ModerationActionMask, "****, **** and ****"},
+		{"whole words only", []string{"ass"}, nil, entity.ModerationActionMask, "a classic assessment", entity.ModerationActionAllow, ""},
+		{"skips partial matches", []string{"ass"}, nil, entity.ModerationActionMask, "classic ass", entity.ModerationActionMask, "classic ***"},
+		{"masks by characters", []string{"café"}, nil, entity.ModerationActionMask, "café time", entity.ModerationActionMask, "**** time"},
+		{"unicode boundaries", []string{"дурак"}, nil, entity.ModerationActionMask, "ты дурак, дураки", entity.ModerationActionMask, "ты *****, дураки"},
+		{"quotes patterns", []string{"a.b"}, nil, entity.ModerationActionMask, "axb a.b", entity.ModerationActionMask, "axb ***"},
+		{"rejects", []string{"darn"}, nil, entity.ModerationActionReject, "darn it", entity.ModerationActionReject, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter := NewWordFilter(tt.globalWords)
+			settings := &entity.ModerationSettings{BlockedWords: tt.chatroomWord, WordAction: tt.action}
+
+			verdict, err := filter.Moderate(context.Background(), &Message{Text: tt.text}, settings)
+			if err != nil {
+				t.Fatalf("Moderate: %v", err)
+			}
+			if verdict.Action != tt.wantAction || verdict.Text != tt.wantText {
+				t.Errorf("Moderate(%q) = %s %q, want %s %q", tt.text, verdict.Action, verdict.Text, tt.wantAction, tt.wantText)
+			}
+		})
+	}
+}
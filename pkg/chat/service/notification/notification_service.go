@@ -0,0 +1,323 @@
+package notification
+
+import (
+	"app/pkg/chat/domain/entity"
+	"app/pkg/chat/domain/repository"
+	"app/pkg/database/redis"
+	"app/pkg/exception"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	// Characters of the message included in a notification
+	previewLength = 200
+	// Time limit for queueing the notifications of a single message
+	queueTimeout = 30 * time.Second
+	// Redis key prefix of Telegram link codes, followed by the code and holding the user ID
+	telegramLinkKeyPrefix = "notification:telegram-link:"
+	// How long a Telegram link code can be sent to the bot
+	telegramLinkExpiry = 10 * time.Minute
+)
+
+// consumeLinkScript returns the user of a Telegram link code and deletes the code, so it links a single chat
+const consumeLinkScript = `
+local userID = redis.call('GET', KEYS[1])
+if userID then
+	redis.call('DEL', KEYS[1])
+end
+return userID
+`
+
+type notificationService struct {
+	preferenceRepo repository.NotificationPreferenceRepository
+	chatroomRepo   repository.ChatroomRepository
+	userRepo       repository.UserRepository
+	privacyRepo    repository.UserPrivacyRepository
+	presence       PresenceChecker
+	outbox         *Outbox
+	redisClient    *redis.Client
+	telegramBotID  string
+}
+
+// NewNotificationService creates a new instance of NotificationService
+// The telegramBotID is the client ID of the bot sending Telegram notifications, Telegram chats can't be linked without one
+func NewNotificationService(
+	preferenceRepo repository.NotificationPreferenceRepository,
+	chatroomRepo repository.ChatroomRepository,
+	userRepo repository.UserRepository,
+	privacyRepo repository.UserPrivacyRepository,
+	presence PresenceChecker,
+	outbox *Outbox,
+	redisClient *redis.Client,
+	telegramBotID string,
+) NotificationService {
+	return &notificationService{
+		preferenceRepo: preferenceRepo,
+		chatroomRepo:   chatroomRepo,
+		userRepo:       userRepo,
+		privacyRepo:    privacyRepo,
+		presence:       presence,
+		outbox:         outbox,
+		redisClient:    redisClient,
+		telegramBotID:  telegramBotID,
+	}
+}
+
+// GetPreference retrieves a user's notification preferences, defaults are returned if none are stored
+func (s *notificationService) GetPreference(ctx context.Context, userID string) (*entity.NotificationPreference, error) {
+	return loadPreference(ctx, s.preferenceRepo, userID)
+}
+
+// UpdatePreference replaces a user's notification preferences, the linked Telegram chat is kept
+func (s *notificationService) UpdatePreference(ctx context.Context, params UpdatePreferenceParams) (*entity.NotificationPreference, error) {
+	for _, channel := range params.DisabledChannels {
+		if channel != entity.NotificationChannelWebhook && channel != entity.NotificationChannelTelegram {
+			return nil, exception.BadRequest(fmt.Sprintf("Unknown notification channel %q", channel))
+		}
+	}
+
+	chatroomIDs, err := s.chatroomRepo.GetIDsByParticipant(ctx, params.UserID)
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range params.MutedChatrooms {
+		if !slices.Contains(chatroomIDs, id) {
+			return nil, exception.BadRequest("Muted chatrooms must be chatrooms you participate in")
+		}
+	}
+
+	preference := &entity.NotificationPreference{
+		ID:               params.UserID,
+		Muted:            params.Muted,
+		MutedChatrooms:   dedupe(params.MutedChatrooms),
+		DisabledChannels: dedupe(params.DisabledChannels),
+	}
+
+	if err := s.preferenceRepo.Upsert(ctx, preference); err != nil {
+		return nil, err
+	}
+
+	return preference, nil
+}
+
+// CreateTelegramLink issues a one-time code the user sends to the notification bot to link their Telegram chat
+func (s *notificationService) CreateTelegramLink(ctx context.Context, userID string) (*entity.TelegramLink, error) {
+	if err := s.checkTelegramEnabled(); err != nil {
+		return nil, err
+	}
+
+	link := &entity.TelegramLink{
+		Code:             newLinkCode(),
+		ExpiresTimestamp: time.Now().Add(telegramLinkExpiry).Unix(),
+	}
+	if err := s.redisClient.Set(ctx, telegramLinkKeyPrefix+link.Code, userID, telegramLinkExpiry); err != nil {
+		return nil, err
+	}
+
+	return link, nil
+}
+
+// LinkTelegram links the Telegram chat that sent a link code to the bot to the user the code was issued to
+func (s *notificationService) LinkTelegram(ctx context.Context, params LinkTelegramParams) error {
+	if err := s.checkTelegramEnabled(); err != nil {
+		return err
+	}
+	// Chats that reached another bot can't be sent notifications
+	if params.BotID != s.telegramBotID {
+		return exception.Forbidden()
+	}
+	if params.Code == "" || params.ChatID == 0 {
+		return exception.BadRequest("Code and chat ID are required")
+	}
+
+	result, err := s.redisClient.Eval(ctx, consumeLinkScript, []string{telegramLinkKeyPrefix + params.Code})
+	if err == redis.Nil {
+		return exception.NotFound("Link code")
+	}
+	if err != nil {
+		return err
+	}
+	userID, ok := result.(string)
+	if !ok {
+		return exception.NotFound("Link code")
+	}
+
+	return s.preferenceRepo.SetTelegramChatID(ctx, userID, params.ChatID)
+}
+
+// UnlinkTelegram stops sending a user's notifications to their Telegram chat
+func (s *notificationService) UnlinkTelegram(ctx context.Context, userID string) error {
+	return s.preferenceRepo.SetTelegramChatID(ctx, userID, 0)
+}
+
+// checkTelegramEnabled rejects linking Telegram chats when no bot sends Telegram notifications
+func (s *notificationService) checkTelegramEnabled() error {
+	if s.telegramBotID == "" {
+		return exception.Http(http.StatusServiceUnavailable, "Telegram notifications are not configured")
+	}
+
+	return nil
+}
+
+// MessageSent queues notifications about a new message for every participant that isn't connected
+func (s *notificationService) MessageSent(ctx context.Context, clientID string, chat *entity.Chat) {
+	// The request context ends with the request, queueing continues on its own
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), queueTimeout)
+		defer cancel()
+
+		if err := s.queue(ctx, clientID, chat); err != nil {
+			fmt.Printf("Error queueing notifications for chat %s: %v\n", chat.ID, err)
+		}
+	}()
+}
+
+// queue enqueues a notification for every offline participant on each channel they haven't opted out of
+func (s *notificationService) queue(ctx context.Context, clientID string, chat *entity.Chat) error {
+	channels := s.outbox.Channels()
+	if len(channels) == 0 {
+		return nil
+	}
+
+	chatroom, err := s.chatroomRepo.Get(ctx, chat.Chatroom)
+	if err != nil {
+		return err
+	}
+	if chatroom == nil {
+		return nil
+	}
+
+	senderName := chat.Sender
+	if sender, err := s.userRepo.Get(ctx, chat.Sender); err == nil && sender != nil && sender.Name != "" {
+		senderName = sender.Name
+	}
+
+	for _, participant := range chatroom.Participants {
+		if participant.User == chat.Sender {
+			continue
+		}
+
+		connected, err := s.presence.IsUserConnected(ctx, participant.User)
+		if err != nil {
+			return err
+		}
+		if connected {
+			continue
+		}
+
+		// Users who blocked the sender aren't notified of their messages, like connected clients aren't sent them
+		privacy, err := s.privacyRepo.Get(ctx, participant.User)
+		if err != nil {
+			return err
+		}
+		if privacy != nil && slices.Contains(privacy.BlockedUsers, chat.Sender) {
+			continue
+		}
+
+		preference, err := loadPreference(ctx, s.preferenceRepo, participant.User)
+		if err != nil {
+			return err
+		}
+
+		notification := &entity.Notification{
+			ID:               newNotificationID(),
+			ClientID:         clientID,
+			Recipient:        participant.User,
+			Chatroom:         chatroom.ID,
+			ChatroomName:     chatroom.Name,
+			IsGroup:          chatroom.IsGroup,
+			Chat:             chat.ID,
+			Sender:           chat.Sender,
+			SenderName:       senderName,
+			Preview:          preview(chat),
+			CreatedTimestamp: chat.CreatedTimestamp,
+		}
+
+		for _, channel := range channels {
+			if !wants(preference, chatroom.ID, channel) {
+				continue
+			}
+			if err := s.outbox.Enqueue(ctx, notification, channel); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// loadPreference retrieves a user's notification preferences, falling back to the defaults
+func loadPreference(ctx context.Context, preferenceRepo repository.NotificationPreferenceRepository, userID string) (*entity.NotificationPreference, error) {
+	preference, err := preferenceRepo.Get(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if preference == nil {
+		preference = &entity.NotificationPreference{
+			ID:               userID,
+			MutedChatrooms:   []string{},
+			DisabledChannels: []entity.NotificationChannel{},
+		}
+	}
+
+	return preference, nil
+}
+
+// wants reports whether the preferences allow notifications from the chatroom through the channel
+func wants(preference *entity.NotificationPreference, chatroomID string, channel entity.NotificationChannel) bool {
+	return !preference.Muted &&
+		!slices.Contains(preference.MutedChatrooms, chatroomID) &&
+		!slices.Contains(preference.DisabledChannels, channel)
+}
+
+// preview shortens a message for notifications, describing attachments when there's no text
+func preview(chat *entity.Chat) string {
+	if chat.Message == "" {
+		switch len(chat.Attachments) {
+		case 0:
+			return ""
+		case 1:
+			return "Sent an attachment"
+		default:
+			return fmt.Sprintf("Sent %d attachments", len(chat.Attachments))
+		}
+	}
+
+	if utf8.RuneCountInString(chat.Message) <= previewLength {
+		return chat.Message
+	}
+
+	return string([]rune(chat.Message)[:previewLength]) + "…"
+}
+
+// newNotificationID generates a random notification ID receivers can use to drop duplicate deliveries
+func newNotificationID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// newLinkCode generates a random Telegram link code, short enough for a /start payload
+func newLinkCode() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// dedupe removes repeated values while keeping their order
+func dedupe[T comparable](values []T) []T {
+	result := make([]T, 0, len(values))
+	for _, value := range values {
+		if !slices.Contains(result, value) {
+			result = append(result, value)
+		}
+	}
+	return result
+}
@@ -0,0 +1,224 @@
+package notification
+
+import (
+	"app/pkg/chat/domain/entity"
+	"app/pkg/chat/domain/repository"
+	"app/pkg/database/redis"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sort"
+	"strconv"
+	"time"
+)
+
+const (
+	// Redis list of notifications ready for delivery
+	outboxQueueKey = "notification:outbox"
+	// Redis sorted set of failed notifications scored by the unix time of their next attempt
+	outboxRetryKey = "notification:retry"
+	// Redis list of the latest notifications that ran out of attempts
+	outboxDeadKey = "notification:dead"
+
+	// Attempts before a notification is moved to the dead letter list
+	maxDeliveryAttempts = 6
+	// Delay before the first retry, doubled for every following attempt
+	retryBaseDelay = 30 * time.Second
+	// Upper bound of the delay between attempts
+	retryMaxDelay = 30 * time.Minute
+	// How often due retries are moved back to the queue
+	retryPollInterval = time.Second
+	// Retries moved back to the queue per poll
+	retryBatchSize = 100
+	// Dead letters kept for inspection
+	deadLetterLimit = 1000
+	// How long a worker blocks waiting for the queue
+	popTimeout = 5 * time.Second
+	// Time limit of a single delivery attempt
+	deliveryTimeout = 15 * time.Second
+)
+
+// outboxEntry is a notification queued for delivery through one channel
+// Channels are retried independently so a failing channel doesn't resend through the others
+type outboxEntry struct {
+	Notification entity.Notification        `json:"notification"`
+	Channel      entity.NotificationChannel `json:"channel"`
+	Attempts     int                        `json:"attempts"`
+	LastError    string                     `json:"lastError,omitempty"`
+}
+
+// Outbox queues notifications in Redis and delivers them through the registered notifiers
+// Several nodes can run the outbox at once, each notification is delivered by one of them
+type Outbox struct {
+	redisClient    *redis.Client
+	preferenceRepo repository.NotificationPreferenceRepository
+	notifiers      map[entity.NotificationChannel]Notifier
+}
+
+// NewOutbox creates a new Outbox delivering through the given notifiers
+func NewOutbox(redisClient *redis.Client, preferenceRepo repository.NotificationPreferenceRepository, notifiers ...Notifier) *Outbox {
+	registered := make(map[entity.NotificationChannel]Notifier, len(notifiers))
+	for _, notifier := range notifiers {
+		registered[notifier.Channel()] = notifier
+	}
+
+	return &Outbox{
+		redisClient:    redisClient,
+		preferenceRepo: preferenceRepo,
+		notifiers:      registered,
+	}
+}
+
+// Channels returns the channels notifications can be delivered through
+func (o *Outbox) Channels() []entity.NotificationChannel {
+	channels := make([]entity.NotificationChannel, 0, len(o.notifiers))
+	for channel := range o.notifiers {
+		channels = append(channels, channel)
+	}
+	sort.Slice(channels, func(i, j int) bool { return channels[i] < channels[j] })
+
+	return channels
+}
+
+// Enqueue queues a notification for delivery through a channel
+func (o *Outbox) Enqueue(ctx context.Context, notification *entity.Notification, channel entity.NotificationChannel) error {
+	data, err := json.Marshal(outboxEntry{
+		Notification: *notification,
+		Channel:      channel,
+	})
+	if err != nil {
+		return err
+	}
+
+	return o.redisClient.LPush(ctx, outboxQueueKey, string(data))
+}
+
+// Run delivers queued notifications until the context is cancelled
+func (o *Outbox) Run(ctx context.Context) {
+	go o.promoteRetries(ctx)
+
+	for ctx.Err() == nil {
+		data, err := o.redisClient.BRPop(ctx, popTimeout, outboxQueueKey)
+		if err != nil {
+			if err != redis.Nil && ctx.Err() == nil {
+				fmt.Printf("Error reading notification outbox: %v\n", err)
+				time.Sleep(retryPollInterval)
+			}
+			continue
+		}
+
+		o.deliver(ctx, data)
+	}
+}
+
+// deliver makes one delivery attempt, scheduling a retry if it fails
+func (o *Outbox) deliver(ctx context.Context, data string) {
+	var entry outboxEntry
+	if err := json.Unmarshal([]byte(data), &entry); err != nil {
+		fmt.Printf("Error unmarshaling notification: %v\n", err)
+		return
+	}
+
+	notifier, ok := o.notifiers[entry.Channel]
+	if !ok {
+		// The channel is no longer configured on this deployment
+		return
+	}
+
+	preference, err := loadPreference(ctx, o.preferenceRepo, entry.Notification.Recipient)
+	if err != nil {
+		o.retry(ctx, entry, err)
+		return
+	}
+
+	// The recipient may have muted the chatroom since the notification was queued
+	if !wants(preference, entry.Notification.Chatroom, entry.Channel) {
+		return
+	}
+
+	deliveryCtx, cancel := context.WithTimeout(ctx, deliveryTimeout)
+	err = notifier.Notify(deliveryCtx, &entry.Notification, preference)
+	cancel()
+
+	if err == nil || errors.Is(err, ErrUndeliverable) {
+		return
+	}
+
+	o.retry(ctx, entry, err)
+}
+
+// retry schedules another attempt with exponential backoff, or moves the notification to the dead letters
+func (o *Outbox) retry(ctx context.Context, entry outboxEntry, cause error) {
+	entry.Attempts++
+	entry.LastError = cause.Error()
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		fmt.Printf("Error marshaling notification: %v\n", err)
+		return
+	}
+
+	if entry.Attempts >= maxDeliveryAttempts {
+		if err := o.redisClient.LPush(ctx, outboxDeadKey, string(data)); err != nil {
+			fmt.Printf("Error storing undelivered notification: %v\n", err)
+			return
+		}
+		if err := o.redisClient.LTrim(ctx, outboxDeadKey, 0, deadLetterLimit-1); err != nil {
+			fmt.Printf("Error trimming undelivered notifications: %v\n", err)
+		}
+		return
+	}
+
+	due := time.Now().Add(retryDelay(entry.Attempts))
+	if err := o.redisClient.ZAdd(ctx, outboxRetryKey, float64(due.Unix()), string(data)); err != nil {
+		fmt.Printf("Error scheduling notification retry: %v\n", err)
+	}
+}
+
+// promoteRetries moves retries that are due back to the queue until the context is cancelled
+func (o *Outbox) promoteRetries(ctx context.Context) {
+	ticker := time.NewTicker(retryPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		now := strconv.FormatInt(time.Now().Unix(), 10)
+		due, err := o.redisClient.ZRangeByScore(ctx, outboxRetryKey, "-inf", now, retryBatchSize)
+		if err != nil {
+			if ctx.Err() == nil {
+				fmt.Printf("Error reading notification retries: %v\n", err)
+			}
+			continue
+		}
+
+		for _, data := range due {
+			// Only the node that removes the retry requeues it
+			removed, err := o.redisClient.ZRem(ctx, outboxRetryKey, data)
+			if err != nil || removed == 0 {
+				continue
+			}
+
+			if err := o.redisClient.LPush(ctx, outboxQueueKey, data); err != nil {
+				fmt.Printf("Error requeueing notification: %v\n", err)
+			}
+		}
+	}
+}
+
+// retryDelay returns the backoff before the retry following the given number of attempts
+// Half of the delay is randomized so retries of a failing notifier are spread out
+func retryDelay(attempts int) time.Duration {
+	delay := retryMaxDelay
+	if attempts < 16 {
+		delay = min(retryBaseDelay<<(attempts-1), retryMaxDelay)
+	}
+
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+}
@@ -0,0 +1,86 @@
+package notification
+
+import (
+	"app/pkg/chat/domain/entity"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+type telegramNotifier struct {
+	baseURL    string
+	apiKey     string
+	botID      string
+	httpClient *http.Client
+}
+
+// NewTelegramNotifier creates a Notifier sending notifications as Telegram bot messages through the telegram service
+// The baseURL is the telegram service API root, apiKey its admin API key and botID the client ID of the bot sending the messages
+// Only users who linked a Telegram chat in their preferences are notified
+func NewTelegramNotifier(baseURL string, apiKey string, botID string) Notifier {
+	return &telegramNotifier{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		apiKey:  apiKey,
+		botID:   botID,
+		httpClient: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+	}
+}
+
+// Channel returns the Telegram channel
+func (n *telegramNotifier) Channel() entity.NotificationChannel {
+	return entity.NotificationChannelTelegram
+}
+
+// Notify sends the notification to the user's linked Telegram chat
+func (n *telegramNotifier) Notify(ctx context.Context, notification *entity.Notification, preference *entity.NotificationPreference) error {
+	if preference.TelegramChatID == 0 {
+		return fmt.Errorf("%w: no telegram chat linked", ErrUndeliverable)
+	}
+
+	body, err := json.Marshal(map[string]interface{}{
+		"chatId": preference.TelegramChatID,
+		"text":   telegramText(notification),
+	})
+	if err != nil {
+		return err
+	}
+
+	endpoint := fmt.Sprintf("%s/v1/bots/%s/messages", n.baseURL, url.PathEscape(n.botID))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrUndeliverable, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-API-Key", n.apiKey)
+
+	resp, err := n.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return nil
+	case resp.StatusCode == http.StatusBadRequest:
+		return fmt.Errorf("%w: telegram service rejected the message", ErrUndeliverable)
+	default:
+		// A stopped bot or an unreachable Telegram API may recover
+		return fmt.Errorf("telegram service responded with status %d", resp.StatusCode)
+	}
+}
+
+// telegramText formats a notification as a Telegram message
+func telegramText(notification *entity.Notification) string {
+	if notification.IsGroup {
+		return fmt.Sprintf("%s in %s:\n%s", notification.SenderName, notification.ChatroomName, notification.Preview)
+	}
+	return fmt.Sprintf("%s:\n%s", notification.SenderName, notification.Preview)
+}
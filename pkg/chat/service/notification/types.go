@@ -0,0 +1,66 @@
+package notification
+
+import (
+	"app/pkg/chat/domain/entity"
+	"context"
+	"errors"
+)
+
+// ErrUndeliverable marks notifications that can't be delivered through a channel, they are dropped instead of retried
+var ErrUndeliverable = errors.New("notification can't be delivered")
+
+// Notifier delivers notifications through a single channel
+type Notifier interface {
+	// Channel returns the channel the notifier delivers through
+	Channel() entity.NotificationChannel
+
+	// Notify delivers a notification to its recipient, the recipient's preferences are never nil
+	// Errors wrapping ErrUndeliverable aren't retried
+	Notify(ctx context.Context, notification *entity.Notification, preference *entity.NotificationPreference) error
+}
+
+// PresenceChecker reports whether a user has a live connection on any hub node
+type PresenceChecker interface {
+	IsUserConnected(ctx context.Context, userID string) (bool, error)
+}
+
+// UpdatePreferenceParams represents parameters for updating a user's notification preferences
+type UpdatePreferenceParams struct {
+	UserID           string
+	Muted            bool
+	MutedChatrooms   []string
+	DisabledChannels []entity.NotificationChannel
+}
+
+// LinkTelegramParams represents a link code the notification bot received
+type LinkTelegramParams struct {
+	BotID  string // Client ID of the bot that received the code
+	ChatID int64  // Telegram chat that sent the code
+	Code   string
+}
+
+// NotificationService defines the interface for offline notification operations
+type NotificationService interface {
+	// GetPreference retrieves a user's notification preferences, defaults are returned if none are stored
+	GetPreference(ctx context.Context, userID string) (*entity.NotificationPreference, error)
+
+	// UpdatePreference replaces a user's notification preferences, the linked Telegram chat is kept
+	// Muted chatrooms must be chatrooms the user participates in
+	UpdatePreference(ctx context.Context, params UpdatePreferenceParams) (*entity.NotificationPreference, error)
+
+	// CreateTelegramLink issues a one-time code the user sends to the notification bot to link their Telegram chat
+	// Codes expire after 10 minutes
+	CreateTelegramLink(ctx context.Context, userID string) (*entity.TelegramLink, error)
+
+	// LinkTelegram links the Telegram chat that sent a link code to the bot to the user the code was issued to
+	// Only chats that reached the bot sending notifications can be linked, and each code links a single chat
+	LinkTelegram(ctx context.Context, params LinkTelegramParams) error
+
+	// UnlinkTelegram stops sending a user's notifications to their Telegram chat
+	UnlinkTelegram(ctx context.Context, userID string) error
+
+	// MessageSent queues notifications about a new message for every participant that isn't connected
+	// The clientID is the client the message was sent through, its webhook receives the notifications
+	// Queueing happens in the background so sending a message isn't slowed down
+	MessageSent(ctx context.Context, clientID string, chat *entity.Chat)
+}
@@ -0,0 +1,105 @@
+package notification
+
+import (
+	"app/pkg/chat/domain/entity"
+	"app/pkg/chat/domain/repository"
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// webhookEvent is the event name sent with webhook notifications
+const webhookEvent = "message.offline"
+
+// webhookPayload represents the body posted to a client's webhook endpoint
+type webhookPayload struct {
+	Event        string              `json:"event"`
+	Notification entity.Notification `json:"notification"`
+}
+
+type webhookNotifier struct {
+	clientRepo repository.ClientRepository
+	httpClient *http.Client
+}
+
+// NewWebhookNotifier creates a Notifier posting notifications to the webhook endpoint of the recipient's client
+// Requests are signed with the client's webhook secret in the X-Chat-Signature header as sha256=<hex HMAC of the body>
+func NewWebhookNotifier(clientRepo repository.ClientRepository) Notifier {
+	return &webhookNotifier{
+		clientRepo: clientRepo,
+		httpClient: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+	}
+}
+
+// Channel returns the webhook channel
+func (n *webhookNotifier) Channel() entity.NotificationChannel {
+	return entity.NotificationChannelWebhook
+}
+
+// Notify posts the notification to the client's webhook endpoint
+func (n *webhookNotifier) Notify(ctx context.Context, notification *entity.Notification, preference *entity.NotificationPreference) error {
+	if notification.ClientID == "" {
+		return fmt.Errorf("%w: no client", ErrUndeliverable)
+	}
+
+	client, err := n.clientRepo.Get(ctx, notification.ClientID)
+	if err != nil {
+		return err
+	}
+	if client == nil || client.WebhookEndpoint == "" || client.Status != "active" {
+		return fmt.Errorf("%w: client has no webhook", ErrUndeliverable)
+	}
+
+	body, err := json.Marshal(webhookPayload{
+		Event:        webhookEvent,
+		Notification: *notification,
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, client.WebhookEndpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrUndeliverable, err)
+	}
+
+	// Clients whose plaintext key isn't moved yet keep getting requests signed with it
+	secret := client.WebhookSecret
+	if secret == "" {
+		secret = client.LegacyKey
+	}
+	if secret == "" {
+		return fmt.Errorf("%w: client has no webhook secret", ErrUndeliverable)
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Chat-Event", webhookEvent)
+	req.Header.Set("X-Chat-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+
+	resp, err := n.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return nil
+	case resp.StatusCode == http.StatusRequestTimeout || resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	default:
+		// Other client errors won't succeed on retry
+		return fmt.Errorf("%w: webhook responded with status %d", ErrUndeliverable, resp.StatusCode)
+	}
+}
@@ -0,0 +1,258 @@
+package presence
+
+import (
+	"app/pkg/chat/domain/entity"
+	"app/pkg/chat/domain/repository"
+	"app/pkg/database/redis"
+	"app/pkg/exception"
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	// Redis keys, formatted with the user ID
+	sessionsKey = "presence:sessions:%s"  // Set of the user's session IDs
+	lastSeenKey = "presence:last_seen:%s" // When the user's last session ended, in milliseconds
+	statusKey   = "presence:status:%s"    // The user's custom status
+
+	// Maximum number of users whose presence can be queried at once
+	maxPresenceUsers = 100
+	// Maximum length of a custom status text
+	maxStatusTextLength = 100
+)
+
+// connectScript adds the session in ARGV[1] to the set in KEYS[1] and returns the number of sessions
+const connectScript = `
+redis.call('SADD', KEYS[1], ARGV[1])
+return redis.call('SCARD', KEYS[1])
+`
+
+// disconnectScript removes the session in ARGV[1] from the set in KEYS[1]
+// When it was the last one the time in ARGV[2] is stored as the user's last seen time in KEYS[2]
+// It returns the number of sessions left, or -1 when the session was already gone
+const disconnectScript = `
+if redis.call('SREM', KEYS[1], ARGV[1]) == 0 then
+	return -1
+end
+
+local count = redis.call('SCARD', KEYS[1])
+if count == 0 then
+	redis.call('SET', KEYS[2], ARGV[2])
+end
+
+return count
+`
+
+// loadScript returns the session count, last seen time and custom status of every user
+// KEYS holds the sessions, last seen and status keys of each user in turn
+const loadScript = `
+local result = {}
+for i = 1, #KEYS, 3 do
+	table.insert(result, redis.call('SCARD', KEYS[i]))
+	table.insert(result, redis.call('GET', KEYS[i + 1]) or '')
+	table.insert(result, redis.call('GET', KEYS[i + 2]) or '')
+end
+return result
+`
+
+// customStatus represents a user's custom status as stored in Redis
+type customStatus struct {
+	Status entity.PresenceStatus `json:"status"`
+	Text   string                `json:"text,omitempty"`
+}
+
+type presenceService struct {
+	redisClient  *redis.Client
+	chatroomRepo repository.ChatroomRepository
+	publisher    EventPublisher
+}
+
+// NewPresenceService creates a new presence service
+func NewPresenceService(redisClient *redis.Client, chatroomRepo repository.ChatroomRepository, publisher EventPublisher) PresenceService {
+	return &presenceService{
+		redisClient:  redisClient,
+		chatroomRepo: chatroomRepo,
+		publisher:    publisher,
+	}
+}
+
+// Connect records a new session of the user, the first session brings the user online
+func (s *presenceService) Connect(ctx context.Context, userID string, sessionID string) error {
+	result, err := s.redisClient.Eval(ctx, connectScript, []string{fmt.Sprintf(sessionsKey, userID)}, sessionID)
+	if err != nil {
+		return err
+	}
+
+	if count, ok := result.(int64); ok && count == 1 {
+		s.publish(ctx, userID)
+	}
+	return nil
+}
+
+// Disconnect removes a session of the user, the user goes offline when their last session ends
+func (s *presenceService) Disconnect(ctx context.Context, userID string, sessionID string) error {
+	keys := []string{fmt.Sprintf(sessionsKey, userID), fmt.Sprintf(lastSeenKey, userID)}
+
+	result, err := s.redisClient.Eval(ctx, disconnectScript, keys, sessionID, time.Now().UnixMilli())
+	if err != nil {
+		return err
+	}
+
+	if count, ok := result.(int64); ok && count == 0 {
+		s.publish(ctx, userID)
+	}
+	return nil
+}
+
+// SetStatus sets the custom status shown while the user is online
+func (s *presenceService) SetStatus(ctx context.Context, params SetStatusParams) (*entity.Presence, error) {
+	switch params.Status {
+	case entity.PresenceStatusOnline, entity.PresenceStatusAway, entity.PresenceStatusBusy:
+	default:
+		return nil, exception.BadRequest(fmt.Sprintf("Unknown presence status %q", params.Status))
+	}
+	if utf8.RuneCountInString(params.Text) > maxStatusTextLength {
+		return nil, exception.BadRequest(fmt.Sprintf("Status text must be at most %d characters", maxStatusTextLength))
+	}
+
+	key := fmt.Sprintf(statusKey, params.UserID)
+	if params.Status == entity.PresenceStatusOnline && params.Text == "" {
+		if err := s.redisClient.Del(ctx, key); err != nil {
+			return nil, err
+		}
+	} else {
+		data, err := json.Marshal(customStatus{Status: params.Status, Text: params.Text})
+		if err != nil {
+			return nil, err
+		}
+		if err := s.redisClient.Set(ctx, key, string(data), 0); err != nil {
+			return nil, err
+		}
+	}
+
+	presences, err := s.load(ctx, []string{params.UserID})
+	if err != nil {
+		return nil, err
+	}
+
+	// Offline users only show up as offline, their status is announced once they connect
+	presence := presences[0]
+	if presence.Status != entity.PresenceStatusOffline {
+		s.announce(ctx, presence)
+	}
+
+	return presence, nil
+}
+
+// GetPresence retrieves the presence of the given users sharing a chatroom with the viewer, other users are left out
+func (s *presenceService) GetPresence(ctx context.Context, viewerID string, userIDs []string) ([]*entity.Presence, error) {
+	userIDs = slices.Compact(slices.Sorted(slices.Values(userIDs)))
+	if len(userIDs) > maxPresenceUsers {
+		return nil, exception.BadRequest(fmt.Sprintf("At most %d users can be queried at once", maxPresenceUsers))
+	}
+
+	others := slices.DeleteFunc(slices.Clone(userIDs), func(userID string) bool {
+		return userID == viewerID
+	})
+
+	visible, err := s.chatroomRepo.GetSharedParticipants(ctx, viewerID, others)
+	if err != nil {
+		return nil, err
+	}
+	if len(others) < len(userIDs) {
+		visible = append(visible, viewerID)
+	}
+
+	// Keep the sorted order instead of the database's
+	userIDs = slices.DeleteFunc(userIDs, func(userID string) bool {
+		return !slices.Contains(visible, userID)
+	})
+
+	return s.load(ctx, userIDs)
+}
+
+// IsUserConnected reports whether the user has a session on any hub node
+func (s *presenceService) IsUserConnected(ctx context.Context, userID string) (bool, error) {
+	return s.redisClient.Exists(ctx, fmt.Sprintf(sessionsKey, userID))
+}
+
+// load retrieves the presence of users in a single round trip
+func (s *presenceService) load(ctx context.Context, userIDs []string) ([]*entity.Presence, error) {
+	presences := make([]*entity.Presence, 0, len(userIDs))
+	if len(userIDs) == 0 {
+		return presences, nil
+	}
+
+	keys := make([]string, 0, len(userIDs)*3)
+	for _, userID := range userIDs {
+		keys = append(keys, fmt.Sprintf(sessionsKey, userID), fmt.Sprintf(lastSeenKey, userID), fmt.Sprintf(statusKey, userID))
+	}
+
+	result, err := s.redisClient.Eval(ctx, loadScript, keys)
+	if err != nil {
+		return nil, err
+	}
+
+	values, ok := result.([]interface{})
+	if !ok || len(values) != len(keys) {
+		return nil, fmt.Errorf("unexpected presence result %v", result)
+	}
+
+	for i, userID := range userIDs {
+		sessions, _ := values[i*3].(int64)
+		lastSeen, _ := values[i*3+1].(string)
+		status, _ := values[i*3+2].(string)
+
+		presence := &entity.Presence{
+			UserID:   userID,
+			Status:   entity.PresenceStatusOffline,
+			Sessions: int(sessions),
+		}
+		if timestamp, err := strconv.ParseInt(lastSeen, 10, 64); err == nil {
+			presence.LastSeenTimestamp = &timestamp
+		}
+		if sessions > 0 {
+			presence.Status = entity.PresenceStatusOnline
+
+			var custom customStatus
+			if status != "" && json.Unmarshal([]byte(status), &custom) == nil {
+				presence.Status = custom.Status
+				presence.StatusText = custom.Text
+			}
+		}
+
+		presences = append(presences, presence)
+	}
+
+	return presences, nil
+}
+
+// publish announces the user's current presence
+func (s *presenceService) publish(ctx context.Context, userID string) {
+	presences, err := s.load(ctx, []string{userID})
+	if err != nil {
+		fmt.Printf("Error loading presence: %v\n", err)
+		return
+	}
+
+	s.announce(ctx, presences[0])
+}
+
+// announce sends a presence to the users sharing a chatroom with its user
+func (s *presenceService) announce(ctx context.Context, presence *entity.Presence) {
+	chatroomIDs, err := s.chatroomRepo.GetIDsByParticipant(ctx, presence.UserID)
+	if err != nil {
+		fmt.Printf("Error fetching chatrooms for presence: %v\n", err)
+		return
+	}
+	if len(chatroomIDs) == 0 {
+		return
+	}
+
+	s.publisher.PresenceChanged(ctx, presence, chatroomIDs)
+}
@@ -0,0 +1,38 @@
+package presence
+
+import (
+	"app/pkg/chat/domain/entity"
+	"context"
+)
+
+// EventPublisher notifies connected clients about presence changes
+type EventPublisher interface {
+	// PresenceChanged notifies the users sharing one of the chatrooms with the user that the user's presence changed
+	PresenceChanged(ctx context.Context, presence *entity.Presence, chatroomIDs []string)
+}
+
+// SetStatusParams represents parameters for setting a user's custom status
+type SetStatusParams struct {
+	UserID string
+	Status entity.PresenceStatus // Online clears the custom status
+	Text   string
+}
+
+// PresenceService defines the interface for tracking which users are online
+// A user is online while they have at least one session, every WebSocket connection is a session
+type PresenceService interface {
+	// Connect records a new session of the user, the first session brings the user online
+	Connect(ctx context.Context, userID string, sessionID string) error
+
+	// Disconnect removes a session of the user, the user goes offline when their last session ends
+	Disconnect(ctx context.Context, userID string, sessionID string) error
+
+	// SetStatus sets the custom status shown while the user is online
+	SetStatus(ctx context.Context, params SetStatusParams) (*entity.Presence, error)
+
+	// GetPresence retrieves the presence of the given users sharing a chatroom with the viewer, other users are left out
+	GetPresence(ctx context.Context, viewerID string, userIDs []string) ([]*entity.Presence, error)
+
+	// IsUserConnected reports whether the user has a session on any hub node
+	IsUserConnected(ctx context.Context, userID string) (bool, error)
+}
@@ -0,0 +1,135 @@
+package privacy
+
+import (
+	"app/pkg/chat/domain/entity"
+	"app/pkg/chat/domain/repository"
+	"app/pkg/exception"
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+)
+
+// maxBlockedUsers is the maximum number of users a user can block
+const maxBlockedUsers = 1000
+
+// errDirectMessageRefused is returned for blocked senders and senders excluded by the receiver's privacy alike,
+// so users can't tell they were blocked
+var errDirectMessageRefused = exception.Http(http.StatusForbidden, "This user doesn't accept direct messages from you")
+
+type privacyService struct {
+	privacyRepo  repository.UserPrivacyRepository
+	chatroomRepo repository.ChatroomRepository
+	publisher    EventPublisher
+}
+
+// NewPrivacyService creates a new privacy service
+func NewPrivacyService(privacyRepo repository.UserPrivacyRepository, chatroomRepo repository.ChatroomRepository, publisher EventPublisher) PrivacyService {
+	return &privacyService{
+		privacyRepo:  privacyRepo,
+		chatroomRepo: chatroomRepo,
+		publisher:    publisher,
+	}
+}
+
+// GetSettings retrieves a user's privacy settings, defaults are returned if none are stored
+func (s *privacyService) GetSettings(ctx context.Context, userID string) (*entity.UserPrivacy, error) {
+	privacy, err := s.privacyRepo.Get(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if privacy == nil {
+		privacy = &entity.UserPrivacy{ID: userID}
+	}
+
+	if privacy.DMPrivacy == "" {
+		privacy.DMPrivacy = entity.DMPrivacyEveryone
+	}
+	if privacy.BlockedUsers == nil {
+		privacy.BlockedUsers = []string{}
+	}
+
+	return privacy, nil
+}
+
+// UpdateSettings sets who can send direct messages to the user
+func (s *privacyService) UpdateSettings(ctx context.Context, params UpdateSettingsParams) (*entity.UserPrivacy, error) {
+	switch params.DMPrivacy {
+	case entity.DMPrivacyEveryone, entity.DMPrivacySharedRoom, entity.DMPrivacyNobody:
+	default:
+		return nil, exception.BadRequest(fmt.Sprintf("Unknown direct message privacy %q", params.DMPrivacy))
+	}
+
+	if err := s.privacyRepo.SetDMPrivacy(ctx, params.UserID, params.DMPrivacy); err != nil {
+		return nil, err
+	}
+
+	return s.GetSettings(ctx, params.UserID)
+}
+
+// BlockUser adds a user to the user's block list
+func (s *privacyService) BlockUser(ctx context.Context, params BlockParams) (*entity.UserPrivacy, error) {
+	if params.BlockedUserID == "" {
+		return nil, exception.BadRequest("User ID is required")
+	}
+	if params.BlockedUserID == params.UserID {
+		return nil, exception.BadRequest("You can't block yourself")
+	}
+
+	privacy, err := s.GetSettings(ctx, params.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if slices.Contains(privacy.BlockedUsers, params.BlockedUserID) {
+		return privacy, nil
+	}
+	if len(privacy.BlockedUsers) >= maxBlockedUsers {
+		return nil, exception.BadRequest(fmt.Sprintf("You can block at most %d users", maxBlockedUsers))
+	}
+
+	if err := s.privacyRepo.Block(ctx, params.UserID, params.BlockedUserID); err != nil {
+		return nil, err
+	}
+
+	s.publisher.BlockUpdated(ctx, params.UserID, params.BlockedUserID, true)
+
+	return s.GetSettings(ctx, params.UserID)
+}
+
+// UnblockUser removes a user from the user's block list
+func (s *privacyService) UnblockUser(ctx context.Context, params BlockParams) (*entity.UserPrivacy, error) {
+	if err := s.privacyRepo.Unblock(ctx, params.UserID, params.BlockedUserID); err != nil {
+		return nil, err
+	}
+
+	s.publisher.BlockUpdated(ctx, params.UserID, params.BlockedUserID, false)
+
+	return s.GetSettings(ctx, params.UserID)
+}
+
+// CanDirectMessage returns a 403 error if the receiver blocked the sender or their privacy excludes the sender
+func (s *privacyService) CanDirectMessage(ctx context.Context, senderID string, receiverID string) error {
+	privacy, err := s.GetSettings(ctx, receiverID)
+	if err != nil {
+		return err
+	}
+
+	if slices.Contains(privacy.BlockedUsers, senderID) {
+		return errDirectMessageRefused
+	}
+
+	switch privacy.DMPrivacy {
+	case entity.DMPrivacyNobody:
+		return errDirectMessageRefused
+	case entity.DMPrivacySharedRoom:
+		shared, err := s.chatroomRepo.SharesGroup(ctx, senderID, receiverID)
+		if err != nil {
+			return err
+		}
+		if !shared {
+			return errDirectMessageRefused
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,43 @@
+package privacy
+
+import (
+	"app/pkg/chat/domain/entity"
+	"context"
+)
+
+// EventPublisher notifies connected clients about privacy changes
+type EventPublisher interface {
+	// BlockUpdated notifies that the user blocked or unblocked the other user
+	BlockUpdated(ctx context.Context, userID string, blockedUserID string, blocked bool)
+}
+
+// UpdateSettingsParams represents parameters for updating a user's privacy settings
+type UpdateSettingsParams struct {
+	UserID    string
+	DMPrivacy entity.DMPrivacy
+}
+
+// BlockParams represents parameters for blocking or unblocking a user
+type BlockParams struct {
+	UserID        string // User managing their block list
+	BlockedUserID string
+}
+
+// PrivacyService defines the interface for user blocking and direct message privacy operations
+type PrivacyService interface {
+	// GetSettings retrieves a user's privacy settings, defaults are returned if none are stored
+	GetSettings(ctx context.Context, userID string) (*entity.UserPrivacy, error)
+
+	// UpdateSettings sets who can send direct messages to the user
+	UpdateSettings(ctx context.Context, params UpdateSettingsParams) (*entity.UserPrivacy, error)
+
+	// BlockUser stops the other user's messages from reaching the user and from starting direct conversations with them
+	// Users can't block themselves and block lists are capped
+	BlockUser(ctx context.Context, params BlockParams) (*entity.UserPrivacy, error)
+
+	// UnblockUser removes a user from the user's block list
+	UnblockUser(ctx context.Context, params BlockParams) (*entity.UserPrivacy, error)
+
+	// CanDirectMessage returns a 403 error if the receiver blocked the sender or their privacy excludes the sender
+	CanDirectMessage(ctx context.Context, senderID string, receiverID string) error
+}
@@ -0,0 +1,94 @@
+package ratelimit
+
+import (
+	"app/pkg/database/redis"
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// takeScript takes a token from every bucket in KEYS, or from none of them if any bucket is empty
+// ARGV holds the capacity and the milliseconds it takes to refill a token of each bucket
+// It returns 0 when the tokens were taken, otherwise the milliseconds until they can be
+// Buckets are hashes of their tokens and the time they were last updated, which expire once full again
+const takeScript = `
+local time = redis.call('TIME')
+local now = tonumber(time[1]) * 1000 + math.floor(tonumber(time[2]) / 1000)
+local tokens = {}
+local wait = 0
+
+for i, key in ipairs(KEYS) do
+	local capacity = tonumber(ARGV[i * 2 - 1])
+	local interval = tonumber(ARGV[i * 2])
+	local state = redis.call('HMGET', key, 'tokens', 'updated')
+	local available = tonumber(state[1])
+	local updated = tonumber(state[2])
+
+	if available == nil or updated == nil then
+		available = capacity
+	else
+		available = math.min(capacity, available + math.max(now - updated, 0) / interval)
+	end
+
+	tokens[i] = available
+	if available < 1 then
+		wait = math.max(wait, math.ceil((1 - available) * interval))
+	end
+end
+
+if wait > 0 then
+	return wait
+end
+
+for i, key in ipairs(KEYS) do
+	local capacity = tonumber(ARGV[i * 2 - 1])
+	local interval = tonumber(ARGV[i * 2])
+	redis.call('HSET', key, 'tokens', tostring(tokens[i] - 1), 'updated', now)
+	redis.call('PEXPIRE', key, math.ceil(capacity * interval))
+end
+
+return 0
+`
+
+// Limiter keeps token buckets in Redis so the limits hold across nodes
+type Limiter struct {
+	redisClient *redis.Client
+}
+
+// NewLimiter creates a new Limiter
+func NewLimiter(redisClient *redis.Client) *Limiter {
+	return &Limiter{redisClient: redisClient}
+}
+
+// Take takes a token from every enabled bucket at once
+// If any bucket is empty no token is taken and the time until all of them have one is returned
+func (l *Limiter) Take(ctx context.Context, buckets ...Bucket) (time.Duration, error) {
+	keys := make([]string, 0, len(buckets))
+	args := make([]interface{}, 0, len(buckets)*2)
+	for _, bucket := range buckets {
+		if !bucket.Limit.Enabled() {
+			continue
+		}
+
+		interval := float64(bucket.Limit.interval()) / float64(time.Millisecond)
+		keys = append(keys, bucket.Key)
+		args = append(args, bucket.Limit.capacity(), strconv.FormatFloat(interval, 'f', -1, 64))
+	}
+
+	if len(keys) == 0 {
+		return 0, nil
+	}
+
+	result, err := l.redisClient.Eval(ctx, takeScript, keys, args...)
+	if err != nil {
+		return 0, err
+	}
+
+	wait, ok := result.(int64)
+	if !ok {
+		return 0, fmt.Errorf("unexpected rate limit result %v", result)
+	}
+
+	return time.Duration(wait) * time.Millisecond, nil
+}
@@ -0,0 +1,191 @@
+package ratelimit
+
+import (
+	"app/pkg/database"
+	"app/pkg/database/redis"
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"testing"
+	"time"
+)
+
+// testRedis connects to the Redis of REDIS_TEST_ADDR, a host and port
+func testRedis(t *testing.T) *redis.Client {
+	t.Helper()
+
+	addr := os.Getenv("REDIS_TEST_ADDR")
+	if addr == "" {
+		t.Skip("REDIS_TEST_ADDR is not set")
+	}
+
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		t.Fatalf("Invalid REDIS_TEST_ADDR: %v", err)
+	}
+
+	client := redis.NewClient(database.DatabaseConfig{Host: host, Port: port})
+	if err := client.Connect(); err != nil {
+		t.Fatalf("Failed to connect to Redis: %v", err)
+	}
+	t.Cleanup(func() {
+		client.Disconnect()
+	})
+
+	return client
+}
+
+// testKey returns a bucket key no other test run uses
+func testKey(t *testing.T, name string) string {
+	return fmt.Sprintf("ratelimit:test:%s:%s:%d", t.Name(), name, time.Now().UnixNano())
+}
+
+func TestLimitCapacityAndInterval(t *testing.T) {
+	tests := []struct {
+		limit        Limit
+		enabled      bool
+		wantCapacity int
+		wantInterval time.Duration
+	}{
+		{Limit{Rate: 10, Period: time.Second}, true, 10, 100 * time.Millisecond},
+		{Limit{Rate: 1, Period: time.Minute, Burst: 5}, true, 5, time.Minute},
+		{Limit{Rate: 0, Period: time.Second}, false, 0, 0},
+		{Limit{Rate: 10}, false, 0, 0},
+	}
+
+	for _, tt := range tests {
+		if got := tt.limit.Enabled(); got != tt.enabled {
+			t.Errorf("%+v Enabled() = %v, want %v", tt.limit, got, tt.enabled)
+		}
+		if !tt.enabled {
+			continue
+		}
+		if got := tt.limit.capacity(); got != tt.wantCapacity {
+			t.Errorf("%+v capacity() = %d, want %d", tt.limit, got, tt.wantCapacity)
+		}
+		if got := tt.limit.interval(); got != tt.wantInterval {
+			t.Errorf("%+v interval() = %v, want %v", tt.limit, got, tt.wantInterval)
+		}
+	}
+}
+
+func TestLimiterTakesUpToTheBucketCapacity(t *testing.T) {
+	tests := []struct {
+		name    string
+		limit   Limit
+		takes   int
+		allowed int
+	}{
+		{"rate", Limit{Rate: 3, Period: time.Minute}, 5, 3},
+		{"burst", Limit{Rate: 1, Period: time.Minute, Burst: 4}, 6, 4},
+		{"disabled", Limit{}, 5, 5},
+	}
+
+	limiter := NewLimiter(testRedis(t))
+	ctx := context.Background()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bucket := Bucket{Key: testKey(t, "bucket"), Limit: tt.limit}
+
+			allowed := 0
+			var wait time.Duration
+			for i := 0; i < tt.takes; i++ {
+				var err error
+				wait, err = limiter.Take(ctx, bucket)
+				if err != nil {
+					t.Fatalf("Take: %v", err)
+				}
+				if wait == 0 {
+					allowed++
+				}
+			}
+
+			if allowed != tt.allowed {
+				t.Errorf("allowed %d of %d takes, want %d", allowed, tt.takes, tt.allowed)
+			}
+			// An empty bucket waits for a single token to refill
+			if allowed < tt.takes && (wait <= 0 || wait > tt.limit.interval()) {
+				t.Errorf("wait = %v, want up to %v", wait, tt.limit.interval())
+			}
+		})
+	}
+}
+
+func TestLimiterTakesFromAllBucketsOrNone(t *testing.T) {
+	limiter := NewLimiter(testRedis(t))
+	ctx := context.Background()
+
+	open := Bucket{Key: testKey(t, "open"), Limit: Limit{Rate: 2, Period: time.Minute}}
+	full := Bucket{Key: testKey(t, "full"), Limit: Limit{Rate: 1, Period: time.Minute}}
+
+	if wait, err := limiter.Take(ctx, full); err != nil || wait != 0 {
+		t.Fatalf("Take(full) = %v, %v, want 0", wait, err)
+	}
+
+	// The empty bucket rejects the take, the other bucket must keep its tokens
+	if wait, err := limiter.Take(ctx, open, full); err != nil || wait == 0 {
+		t.Fatalf("Take(open, full) = %v, %v, want a wait", wait, err)
+	}
+	for i := 0; i < 2; i++ {
+		if wait, err := limiter.Take(ctx, open); err != nil || wait != 0 {
+			t.Errorf("Take(open) #%d = %v, %v, want 0", i+1, wait, err)
+		}
+	}
+}
+
+func TestLimiterRefillsTokensOverTime(t *testing.T) {
+	limiter := NewLimiter(testRedis(t))
+	ctx := context.Background()
+
+	bucket := Bucket{Key: testKey(t, "bucket"), Limit: Limit{Rate: 1, Period: 50 * time.Millisecond}}
+
+	if wait, err := limiter.Take(ctx, bucket); err != nil || wait != 0 {
+		t.Fatalf("first Take = %v, %v, want 0", wait, err)
+	}
+	if wait, err := limiter.Take(ctx, bucket); err != nil || wait == 0 {
+		t.Fatalf("second Take = %v, %v, want a wait", wait, err)
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	if wait, err := limiter.Take(ctx, bucket); err != nil || wait != 0 {
+		t.Errorf("Take after refill = %v, %v, want 0", wait, err)
+	}
+}
+
+func TestMessageLimiterAllowsMessagesWhenRedisIsUnavailable(t *testing.T) {
+	// Nothing listens on port 1, every Redis call fails
+	client := redis.NewClient(database.DatabaseConfig{Host: "127.0.0.1", Port: "1"})
+	if err := client.Connect(); err != nil {
+		t.Fatalf("Failed to create Redis client: %v", err)
+	}
+	t.Cleanup(func() {
+		client.Disconnect()
+	})
+
+	limit := Limit{Rate: 1, Period: time.Hour}
+	limiter := NewMessageLimiter(NewLimiter(client), MessageLimits{User: limit, Chatroom: limit, Client: limit})
+
+	tests := []struct {
+		name   string
+		params AllowMessageParams
+	}{
+		{"with client", AllowMessageParams{UserID: "user-1", ChatroomID: "room-1", ClientID: "client-1"}},
+		{"without client", AllowMessageParams{UserID: "user-1", ChatroomID: "room-1"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			// Repeated messages would exceed the limit if Redis were reachable
+			for i := 0; i < 3; i++ {
+				if err := limiter.AllowMessage(ctx, tt.params); err != nil {
+					t.Fatalf("AllowMessage #%d = %v, want nil", i+1, err)
+				}
+			}
+		})
+	}
+}
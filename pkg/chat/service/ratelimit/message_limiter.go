@@ -0,0 +1,55 @@
+package ratelimit
+
+import (
+	"app/pkg/exception"
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+)
+
+// Redis key prefixes of the message buckets
+const (
+	userKeyPrefix     = "ratelimit:message:user:"
+	chatroomKeyPrefix = "ratelimit:message:chatroom:"
+	clientKeyPrefix   = "ratelimit:message:client:"
+)
+
+// MessageLimiter limits how fast messages can be sent per user, chatroom and client
+type MessageLimiter struct {
+	limiter *Limiter
+	limits  MessageLimits
+}
+
+// NewMessageLimiter creates a new MessageLimiter
+func NewMessageLimiter(limiter *Limiter, limits MessageLimits) *MessageLimiter {
+	return &MessageLimiter{
+		limiter: limiter,
+		limits:  limits,
+	}
+}
+
+// AllowMessage takes a token from the sender's buckets, or returns a 429 error if any of them is empty
+// Messages are allowed when Redis is unavailable, so a Redis outage doesn't stop the chat
+func (l *MessageLimiter) AllowMessage(ctx context.Context, params AllowMessageParams) error {
+	buckets := []Bucket{
+		{Key: userKeyPrefix + params.UserID, Limit: l.limits.User},
+		{Key: chatroomKeyPrefix + params.ChatroomID, Limit: l.limits.Chatroom},
+	}
+	if params.ClientID != "" {
+		buckets = append(buckets, Bucket{Key: clientKeyPrefix + params.ClientID, Limit: l.limits.Client})
+	}
+
+	wait, err := l.limiter.Take(ctx, buckets...)
+	if err != nil {
+		fmt.Printf("Error checking message rate limit: %v\n", err)
+		return nil
+	}
+
+	if wait > 0 {
+		seconds := int(math.Ceil(wait.Seconds()))
+		return exception.Http(http.StatusTooManyRequests, fmt.Sprintf("Too many messages, try again in %d seconds", seconds))
+	}
+
+	return nil
+}
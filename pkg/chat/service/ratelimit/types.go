@@ -0,0 +1,51 @@
+package ratelimit
+
+import (
+	"time"
+)
+
+// Limit configures a token bucket refilled with Rate tokens every Period and holding up to Burst tokens
+// A zero rate or period disables the limit
+type Limit struct {
+	Rate   int
+	Period time.Duration
+	Burst  int // Defaults to Rate
+}
+
+// Enabled reports whether the limit restricts anything
+func (l Limit) Enabled() bool {
+	return l.Rate > 0 && l.Period > 0
+}
+
+// capacity returns the maximum number of tokens in the bucket
+func (l Limit) capacity() int {
+	if l.Burst > 0 {
+		return l.Burst
+	}
+	return l.Rate
+}
+
+// interval returns the time it takes to refill a single token
+func (l Limit) interval() time.Duration {
+	return l.Period / time.Duration(l.Rate)
+}
+
+// Bucket is a token bucket identified by its Redis key
+type Bucket struct {
+	Key   string
+	Limit Limit
+}
+
+// MessageLimits configures how many messages can be sent per scope
+type MessageLimits struct {
+	User     Limit // Messages a single user can send across all chatrooms
+	Chatroom Limit // Messages all participants of a chatroom can send together
+	Client   Limit // Messages all users of a client can send together
+}
+
+// AllowMessageParams represents the sender of a message to rate limit
+type AllowMessageParams struct {
+	UserID     string
+	ChatroomID string
+	ClientID   string // Optional, the client isn't limited when empty
+}
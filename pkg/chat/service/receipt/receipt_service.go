@@ -0,0 +1,74 @@
+package receipt
+
+import (
+	"app/pkg/chat/domain/entity"
+	"app/pkg/chat/domain/repository"
+	"app/pkg/chat/service/chatroom"
+	"app/pkg/exception"
+	"context"
+)
+
+type receiptService struct {
+	readStateRepo   repository.ReadStateRepository
+	chatRepo        repository.ChatRepository
+	chatroomService chatroom.ChatroomService
+}
+
+// NewReceiptService creates a new instance of ReceiptService
+func NewReceiptService(readStateRepo repository.ReadStateRepository, chatRepo repository.ChatRepository, chatroomService chatroom.ChatroomService) ReceiptService {
+	return &receiptService{
+		readStateRepo:   readStateRepo,
+		chatRepo:        chatRepo,
+		chatroomService: chatroomService,
+	}
+}
+
+// MarkAsRead stores the read position of a user in a chatroom
+func (s *receiptService) MarkAsRead(ctx context.Context, params MarkAsReadParams) error {
+	isParticipant, err := s.chatroomService.IsParticipant(ctx, params.ChatroomID, params.UserID)
+	if err != nil {
+		return err
+	}
+	if !isParticipant {
+		return exception.Forbidden()
+	}
+
+	chat, err := s.chatRepo.Get(ctx, params.MessageID)
+	if err != nil || chat == nil || chat.Chatroom != params.ChatroomID {
+		return exception.NotFound("Chat")
+	}
+
+	state := &entity.ReadState{
+		Chatroom:          params.ChatroomID,
+		User:              params.UserID,
+		LastReadMessage:   chat.ID,
+		LastReadTimestamp: chat.CreatedTimestamp,
+		LastReadSequence:  chat.Sequence,
+	}
+
+	return s.readStateRepo.Upsert(ctx, state)
+}
+
+// GetReadState retrieves the read position of a user in a chatroom
+func (s *receiptService) GetReadState(ctx context.Context, chatroomID string, userID string) (*entity.ReadState, error) {
+	return s.readStateRepo.Get(ctx, chatroomID, userID)
+}
+
+// GetUnreadCounts returns the number of unread messages per chatroom for a user
+func (s *receiptService) GetUnreadCounts(ctx context.Context, userID string, chatroomIDs []string) (map[string]int64, error) {
+	states, err := s.readStateRepo.GetAllByUser(ctx, userID, chatroomIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	// Chatrooms without a read state count every message as unread
+	after := make(map[string]int64, len(chatroomIDs))
+	for _, id := range chatroomIDs {
+		after[id] = 0
+	}
+	for _, state := range states {
+		after[state.Chatroom] = state.LastReadSequence
+	}
+
+	return s.chatRepo.CountUnread(ctx, userID, after)
+}
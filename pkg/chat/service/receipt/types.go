@@ -0,0 +1,29 @@
+package receipt
+
+import (
+	"app/pkg/chat/domain/entity"
+	"context"
+)
+
+// MarkAsReadParams represents parameters for marking a message as read
+type MarkAsReadParams struct {
+	ChatroomID string
+	UserID     string
+	MessageID  string
+}
+
+// ReceiptService defines the interface for read receipt operations
+type ReceiptService interface {
+	// MarkAsRead stores the read position of a user in a chatroom
+	// It will validate:
+	// - The user is a participant in the chatroom
+	// - The message belongs to the chatroom
+	// The read position never moves backwards
+	MarkAsRead(ctx context.Context, params MarkAsReadParams) error
+
+	// GetReadState retrieves the read position of a user in a chatroom
+	GetReadState(ctx context.Context, chatroomID string, userID string) (*entity.ReadState, error)
+
+	// GetUnreadCounts returns the number of unread messages per chatroom for a user
+	GetUnreadCounts(ctx context.Context, userID string, chatroomIDs []string) (map[string]int64, error)
+}
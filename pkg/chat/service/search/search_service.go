@@ -0,0 +1,90 @@
+package search
+
+import (
+	"app/pkg/chat/domain/entity"
+	"app/pkg/chat/domain/repository"
+	"app/pkg/chat/service/chatroom"
+	"app/pkg/exception"
+	"app/pkg/types/pagination"
+	"context"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxQueryLength is the longest accepted search query in characters
+const maxQueryLength = 200
+
+type searchService struct {
+	messageSearch   repository.MessageSearch
+	chatroomService chatroom.ChatroomService
+}
+
+// NewSearchService creates a new instance of SearchService
+func NewSearchService(messageSearch repository.MessageSearch, chatroomService chatroom.ChatroomService) SearchService {
+	return &searchService{
+		messageSearch:   messageSearch,
+		chatroomService: chatroomService,
+	}
+}
+
+// SearchMessages searches the messages of the user's chatrooms, newest first
+func (s *searchService) SearchMessages(ctx context.Context, params SearchMessagesParams, cursor pagination.Cursor) ([]*entity.ChatSearchResult, pagination.CursorMetadata, error) {
+	metadata := pagination.CursorMetadata{Limit: cursor.Limit}
+
+	text := strings.TrimSpace(params.Query)
+	if text == "" {
+		return nil, metadata, exception.BadRequest("Search query is required")
+	}
+	if utf8.RuneCountInString(text) > maxQueryLength {
+		return nil, metadata, exception.BadRequest("Search query is too long")
+	}
+	if cursor.After != "" {
+		return nil, metadata, exception.BadRequest("Search only supports the before cursor")
+	}
+
+	var chatroomIDs []string
+	if params.ChatroomID != "" {
+		isParticipant, err := s.chatroomService.IsParticipant(ctx, params.ChatroomID, params.UserID)
+		if err != nil {
+			return nil, metadata, err
+		}
+		if !isParticipant {
+			return nil, metadata, exception.Forbidden()
+		}
+		chatroomIDs = []string{params.ChatroomID}
+	} else {
+		ids, err := s.chatroomService.GetChatroomIDs(ctx, params.UserID)
+		if err != nil {
+			return nil, metadata, err
+		}
+		chatroomIDs = ids
+	}
+
+	query := repository.MessageSearchQuery{
+		Text:        text,
+		ChatroomIDs: chatroomIDs,
+		Limit:       cursor.Limit,
+	}
+
+	if cursor.Before != "" {
+		timestamp, id, err := pagination.DecodeCursor(cursor.Before)
+		if err != nil {
+			return nil, metadata, exception.BadRequest("Invalid cursor")
+		}
+		query.Before = &repository.ChatCursor{Timestamp: timestamp, ID: id}
+	}
+
+	results, hasMore, err := s.messageSearch.Search(ctx, query)
+	if err != nil {
+		return nil, metadata, err
+	}
+
+	metadata.Count = len(results)
+	metadata.HasMore = hasMore
+	if len(results) > 0 {
+		oldest := results[len(results)-1].Chat
+		metadata.Before = pagination.EncodeCursor(oldest.CreatedTimestamp, oldest.ID)
+	}
+
+	return results, metadata, nil
+}
@@ -0,0 +1,21 @@
+package search
+
+import (
+	"app/pkg/chat/domain/entity"
+	"app/pkg/types/pagination"
+	"context"
+)
+
+// SearchMessagesParams represents parameters for searching chat messages
+type SearchMessagesParams struct {
+	UserID     string // User searching, only their chatrooms are searched
+	Query      string // Keywords, with "quoted phrases" and -excluded words
+	ChatroomID string // Optional chatroom to restrict the search to
+}
+
+// SearchService defines the interface for searching chat history
+type SearchService interface {
+	// SearchMessages searches the messages of the user's chatrooms, newest first
+	// Only the before cursor is supported, to page towards older results
+	SearchMessages(ctx context.Context, params SearchMessagesParams, cursor pagination.Cursor) ([]*entity.ChatSearchResult, pagination.CursorMetadata, error)
+}
@@ -0,0 +1,335 @@
+package squad
+
+import (
+	"app/pkg/chat/domain/entity"
+	"app/pkg/chat/domain/repository"
+	"app/pkg/chat/service/chatroom"
+	"app/pkg/exception"
+	"app/pkg/types/pagination"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	// DefaultInviteExpiry is used when an invite is created without an expiry
+	DefaultInviteExpiry = 7 * 24 * time.Hour
+
+	// MaxInviteExpiry is the longest an invite can stay valid
+	MaxInviteExpiry = 30 * 24 * time.Hour
+)
+
+type squadService struct {
+	inviteRepo      repository.InviteRepository
+	joinRequestRepo repository.JoinRequestRepository
+	chatroomService chatroom.ChatroomService
+	secret          []byte
+}
+
+// NewSquadService creates a new instance of SquadService
+// secret is the key used to sign invite codes, invites are disabled without one
+func NewSquadService(inviteRepo repository.InviteRepository, joinRequestRepo repository.JoinRequestRepository, chatroomService chatroom.ChatroomService, secret string) SquadService {
+	return &squadService{
+		inviteRepo:      inviteRepo,
+		joinRequestRepo: joinRequestRepo,
+		chatroomService: chatroomService,
+		secret:          []byte(secret),
+	}
+}
+
+// CreateInvite creates a signed, expiring invite code for a squad
+func (s *squadService) CreateInvite(ctx context.Context, params CreateInviteParams) (*entity.ChatroomInvite, error) {
+	if err := s.checkInvitesEnabled(); err != nil {
+		return nil, err
+	}
+
+	squad, err := s.authorize(ctx, params.ChatroomID, params.ActorID)
+	if err != nil {
+		return nil, err
+	}
+
+	expiresIn := params.ExpiresIn
+	if expiresIn <= 0 {
+		expiresIn = DefaultInviteExpiry
+	}
+	if expiresIn > MaxInviteExpiry {
+		return nil, exception.BadRequest("Invite expiry is too long")
+	}
+	if params.MaxUses < 0 {
+		return nil, exception.BadRequest("Invalid max uses")
+	}
+
+	now := time.Now()
+	invite := &entity.ChatroomInvite{
+		ID:               primitive.NewObjectID().Hex(),
+		Chatroom:         squad.ID,
+		CreatedBy:        params.ActorID,
+		MaxUses:          params.MaxUses,
+		ExpiresTimestamp: now.Add(expiresIn).Unix(),
+		CreatedTimestamp: now.Unix(),
+	}
+	invite.Code = s.sign(invite.ID, invite.ExpiresTimestamp)
+
+	if err := s.inviteRepo.Create(ctx, invite); err != nil {
+		return nil, err
+	}
+
+	return invite, nil
+}
+
+// GetInvites retrieves the squad's invites that are neither revoked nor expired
+func (s *squadService) GetInvites(ctx context.Context, chatroomID string, actorID string) ([]*entity.ChatroomInvite, error) {
+	if _, err := s.authorize(ctx, chatroomID, actorID); err != nil {
+		return nil, err
+	}
+
+	return s.inviteRepo.GetAllByChatroom(ctx, chatroomID, time.Now().Unix())
+}
+
+// RevokeInvite revokes an invite so it can't be redeemed anymore
+func (s *squadService) RevokeInvite(ctx context.Context, chatroomID string, inviteID string, actorID string) error {
+	if _, err := s.authorize(ctx, chatroomID, actorID); err != nil {
+		return err
+	}
+
+	invite, err := s.inviteRepo.Get(ctx, inviteID)
+	if err != nil {
+		return err
+	}
+	if invite == nil || invite.Chatroom != chatroomID {
+		return exception.NotFound("Invite")
+	}
+
+	return s.inviteRepo.Revoke(ctx, inviteID, time.Now().Unix())
+}
+
+// Join redeems an invite code
+func (s *squadService) Join(ctx context.Context, params JoinParams) (*JoinResult, error) {
+	if err := s.checkInvitesEnabled(); err != nil {
+		return nil, err
+	}
+
+	inviteID, err := s.verify(params.Code)
+	if err != nil {
+		return nil, err
+	}
+
+	invite, err := s.inviteRepo.Get(ctx, inviteID)
+	if err != nil {
+		return nil, err
+	}
+	if invite == nil || invite.RevokedTimestamp != nil {
+		return nil, exception.BadRequest("Invalid invite code")
+	}
+
+	squad, err := s.chatroomService.GetChatroom(ctx, invite.Chatroom)
+	if err != nil || squad == nil || squad.Type != entity.ChatroomTypeSquad {
+		return nil, exception.NotFound("Chatroom")
+	}
+
+	participant, err := s.chatroomService.GetParticipant(ctx, squad.ID, params.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if participant != nil {
+		return nil, exception.BadRequest("Already a participant in the chatroom")
+	}
+	if squad.MaxParticipants > 0 && len(squad.Participants) >= squad.MaxParticipants {
+		return nil, exception.BadRequest("Chatroom is full")
+	}
+
+	// Count the use before joining so concurrent redemptions can't exceed the invite's max uses
+	// The use is released again if the user doesn't get to join or request to join
+	redeemed, err := s.inviteRepo.Redeem(ctx, invite.ID, time.Now().Unix())
+	if err != nil {
+		return nil, err
+	}
+	if !redeemed {
+		return nil, exception.BadRequest("Invite has expired or has been used up")
+	}
+
+	result := &JoinResult{ChatroomID: squad.ID}
+
+	if squad.JoinPolicy == entity.JoinPolicyApproval {
+		request := &entity.JoinRequest{
+			Chatroom:         squad.ID,
+			User:             params.UserID,
+			Invite:           invite.ID,
+			Message:          params.Message,
+			Status:           entity.JoinRequestStatusPending,
+			CreatedTimestamp: time.Now().Unix(),
+		}
+
+		if err := s.joinRequestRepo.Create(ctx, request); err != nil {
+			s.releaseInvite(ctx, invite.ID)
+			if mongo.IsDuplicateKeyError(err) {
+				return nil, exception.BadRequest("A join request is already pending")
+			}
+			return nil, err
+		}
+
+		result.Request = request
+		return result, nil
+	}
+
+	if err := s.chatroomService.JoinChatroom(ctx, squad.ID, params.UserID); err != nil {
+		s.releaseInvite(ctx, invite.ID)
+		return nil, err
+	}
+
+	result.Joined = true
+	return result, nil
+}
+
+// releaseInvite takes back a use of an invite that didn't let the user in, failures are only logged
+func (s *squadService) releaseInvite(ctx context.Context, inviteID string) {
+	if err := s.inviteRepo.Release(ctx, inviteID); err != nil {
+		fmt.Printf("Error releasing invite %s: %v\n", inviteID, err)
+	}
+}
+
+// GetJoinRequests retrieves the squad's join requests, oldest first
+func (s *squadService) GetJoinRequests(ctx context.Context, chatroomID string, actorID string, status *entity.JoinRequestStatus, pag pagination.Pagination) ([]*entity.JoinRequest, int64, error) {
+	if _, err := s.authorize(ctx, chatroomID, actorID); err != nil {
+		return nil, 0, err
+	}
+
+	if status == nil {
+		pending := entity.JoinRequestStatusPending
+		status = &pending
+	}
+
+	filter := repository.JoinRequestFilter{
+		ChatroomID: chatroomID,
+		Status:     status,
+	}
+
+	return s.joinRequestRepo.GetAll(ctx, filter, pag)
+}
+
+// ReviewJoinRequest approves or rejects a pending join request
+func (s *squadService) ReviewJoinRequest(ctx context.Context, params ReviewJoinRequestParams) (*entity.JoinRequest, error) {
+	if _, err := s.authorize(ctx, params.ChatroomID, params.ActorID); err != nil {
+		return nil, err
+	}
+
+	request, err := s.joinRequestRepo.Get(ctx, params.RequestID)
+	if err != nil {
+		return nil, err
+	}
+	if request == nil || request.Chatroom != params.ChatroomID {
+		return nil, exception.NotFound("Join request")
+	}
+	if request.Status != entity.JoinRequestStatusPending {
+		return nil, exception.BadRequest("Join request has already been reviewed")
+	}
+
+	status := entity.JoinRequestStatusRejected
+	if params.Approve {
+		// Join first so a full squad leaves the request pending for a later review
+		if err := s.chatroomService.JoinChatroom(ctx, params.ChatroomID, request.User); err != nil {
+			return nil, err
+		}
+		status = entity.JoinRequestStatusApproved
+	}
+
+	now := time.Now().Unix()
+	reviewed, err := s.joinRequestRepo.Review(ctx, request.ID, status, params.ActorID, now)
+	if err != nil {
+		return nil, err
+	}
+	if !reviewed {
+		return nil, exception.BadRequest("Join request has already been reviewed")
+	}
+	// A rejected request didn't let the user in either
+	if status == entity.JoinRequestStatusRejected && request.Invite != "" {
+		s.releaseInvite(ctx, request.Invite)
+	}
+
+	request.Status = status
+	request.ReviewedBy = &params.ActorID
+	request.ReviewedTimestamp = &now
+
+	return request, nil
+}
+
+// authorize checks that the actor can manage the invites and requests of a squad
+func (s *squadService) authorize(ctx context.Context, chatroomID string, actorID string) (*entity.Chatroom, error) {
+	squad, err := s.chatroomService.Authorize(ctx, chatroomID, actorID, chatroom.PermissionManageInvites)
+	if err != nil {
+		return nil, err
+	}
+	if squad.Type != entity.ChatroomTypeSquad {
+		return nil, exception.BadRequest("Invites are only available for squads")
+	}
+
+	return squad, nil
+}
+
+// checkInvitesEnabled checks if a secret to sign invite codes is configured
+func (s *squadService) checkInvitesEnabled() error {
+	if len(s.secret) == 0 {
+		return exception.Http(http.StatusServiceUnavailable, "Squad invites are not configured")
+	}
+
+	return nil
+}
+
+// sign builds an invite code from the invite ID and expiry, signed with HMAC-SHA256
+func (s *squadService) sign(inviteID string, expiresTimestamp int64) string {
+	payload := fmt.Sprintf("%s:%d", inviteID, expiresTimestamp)
+
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(payload))
+
+	return base64.RawURLEncoding.EncodeToString([]byte(payload)) + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// verify checks an invite code's signature and expiry and returns its invite ID
+func (s *squadService) verify(code string) (string, error) {
+	invalid := exception.BadRequest("Invalid invite code")
+
+	encodedPayload, encodedSignature, ok := strings.Cut(code, ".")
+	if !ok {
+		return "", invalid
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(encodedPayload)
+	if err != nil {
+		return "", invalid
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(encodedSignature)
+	if err != nil {
+		return "", invalid
+	}
+
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write(payload)
+	if !hmac.Equal(signature, mac.Sum(nil)) {
+		return "", invalid
+	}
+
+	inviteID, expires, ok := strings.Cut(string(payload), ":")
+	if !ok {
+		return "", invalid
+	}
+
+	expiresTimestamp, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil {
+		return "", invalid
+	}
+	if time.Now().Unix() >= expiresTimestamp {
+		return "", exception.BadRequest("Invite has expired")
+	}
+
+	return inviteID, nil
+}
@@ -0,0 +1,68 @@
+package squad
+
+import (
+	"app/pkg/chat/domain/entity"
+	"app/pkg/types/pagination"
+	"context"
+	"time"
+)
+
+// CreateInviteParams represents parameters for creating a squad invite
+type CreateInviteParams struct {
+	ChatroomID string
+	ActorID    string        // User creating the invite
+	ExpiresIn  time.Duration // How long the invite stays valid, defaults to DefaultInviteExpiry
+	MaxUses    int           // 0 means unlimited
+}
+
+// JoinParams represents parameters for joining a squad with an invite code
+type JoinParams struct {
+	Code    string
+	UserID  string
+	Message string // Optional message shown to the admins reviewing the request
+}
+
+// JoinResult represents the outcome of redeeming an invite code
+// Request is set when the squad requires approval, otherwise the user has joined
+type JoinResult struct {
+	ChatroomID string              `json:"chatroomId"`
+	Joined     bool                `json:"joined"`
+	Request    *entity.JoinRequest `json:"request,omitempty"`
+}
+
+// ReviewJoinRequestParams represents parameters for approving or rejecting a join request
+type ReviewJoinRequestParams struct {
+	ChatroomID string
+	RequestID  string
+	ActorID    string // User reviewing the request
+	Approve    bool
+}
+
+// SquadService defines the interface for squad invite and join request operations
+// Managing invites and requests requires an admin or super_admin role in the squad
+type SquadService interface {
+	// CreateInvite creates a signed, expiring invite code for a squad
+	CreateInvite(ctx context.Context, params CreateInviteParams) (*entity.ChatroomInvite, error)
+
+	// GetInvites retrieves the squad's invites that are neither revoked nor expired
+	GetInvites(ctx context.Context, chatroomID string, actorID string) ([]*entity.ChatroomInvite, error)
+
+	// RevokeInvite revokes an invite so it can't be redeemed anymore
+	RevokeInvite(ctx context.Context, chatroomID string, inviteID string, actorID string) error
+
+	// Join redeems an invite code
+	// It will validate:
+	// - The code signature and expiry
+	// - The invite is not revoked or used up
+	// - The user is not already in the squad and the squad is not full
+	// Open squads are joined immediately, otherwise a pending join request is created
+	Join(ctx context.Context, params JoinParams) (*JoinResult, error)
+
+	// GetJoinRequests retrieves the squad's join requests, oldest first
+	// Defaults to pending requests when no status is given
+	GetJoinRequests(ctx context.Context, chatroomID string, actorID string, status *entity.JoinRequestStatus, pag pagination.Pagination) ([]*entity.JoinRequest, int64, error)
+
+	// ReviewJoinRequest approves or rejects a pending join request
+	// Approving adds the user to the squad if it isn't full
+	ReviewJoinRequest(ctx context.Context, params ReviewJoinRequestParams) (*entity.JoinRequest, error)
+}
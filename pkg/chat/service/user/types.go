@@ -2,6 +2,7 @@ package user
 
 import (
 	"app/pkg/chat/domain/entity"
+	"app/pkg/chat/domain/repository"
 	"app/pkg/types/pagination"
 	"context"
 )
@@ -11,13 +12,13 @@ type UserService interface {
 	// Get retrieves a user by ID
 	Get(ctx context.Context, id string) (*entity.User, error)
 
-	// GetAll retrieves multiple users with pagination
-	GetAll(ctx context.Context, pag pagination.Pagination) ([]*entity.User, int64, error)
+	// GetAll retrieves multiple users with filtering and pagination
+	GetAll(ctx context.Context, filter repository.UserFilter, pag pagination.Pagination) ([]*entity.User, int64, error)
 
 	// Create creates a new user
 	Create(ctx context.Context, user *entity.User) error
 
-	// Update modifies an existing user
+	// Update modifies an existing user, erased users are reported as not found
 	Update(ctx context.Context, user *entity.User) error
 
 	// Delete removes a user
@@ -3,8 +3,11 @@ package user
 import (
 	"app/pkg/chat/domain/entity"
 	"app/pkg/chat/domain/repository"
+	"app/pkg/exception"
 	"app/pkg/types/pagination"
 	"context"
+
+	"go.mongodb.org/mongo-driver/mongo"
 )
 
 type userService struct {
@@ -23,9 +26,9 @@ func (s *userService) Get(ctx context.Context, id string) (*entity.User, error)
 	return s.userRepo.Get(ctx, id)
 }
 
-// GetAll retrieves multiple users with pagination
-func (s *userService) GetAll(ctx context.Context, pag pagination.Pagination) ([]*entity.User, int64, error) {
-	return s.userRepo.GetAll(ctx, pag)
+// GetAll retrieves multiple users with filtering and pagination
+func (s *userService) GetAll(ctx context.Context, filter repository.UserFilter, pag pagination.Pagination) ([]*entity.User, int64, error) {
+	return s.userRepo.GetAll(ctx, filter, pag)
 }
 
 // Create creates a new user
@@ -33,9 +36,16 @@ func (s *userService) Create(ctx context.Context, user *entity.User) error {
 	return s.userRepo.Create(ctx, user)
 }
 
-// Update modifies an existing user
+// Update modifies an existing user, erased users can't be updated
 func (s *userService) Update(ctx context.Context, user *entity.User) error {
-	return s.userRepo.Update(ctx, user)
+	if err := s.userRepo.Update(ctx, user); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return exception.NotFound("User")
+		}
+		return err
+	}
+
+	return nil
 }
 
 // Delete removes a user
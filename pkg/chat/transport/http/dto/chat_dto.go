@@ -2,7 +2,11 @@ package dto
 
 // SendMessageRequest represents the request body for sending a message to a chatroom
 type SendMessageRequest struct {
-	Message string `json:"message" validate:"required"`
+	Message         string                 `json:"message" validate:"required_without=Attachments"`
+	ReplyTo         string                 `json:"replyTo,omitempty"`
+	Metadata        map[string]interface{} `json:"metadata,omitempty"`
+	Attachments     []string               `json:"attachments,omitempty" validate:"max=10"`               // IDs returned by the attachment upload endpoint
+	ClientMessageID string                 `json:"clientMessageId,omitempty" validate:"omitempty,max=64"` // Resending a message with the same ID doesn't store it twice
 }
 
 // SendDirectMessageRequest represents the request body for sending a direct message
@@ -15,3 +19,8 @@ type SendDirectMessageRequest struct {
 type UpdateChatRequest struct {
 	Message string `json:"message" validate:"required"`
 }
+
+// ToggleReactionRequest represents the request body for toggling a reaction on a message
+type ToggleReactionRequest struct {
+	Emoji string `json:"emoji" validate:"required,max=32"`
+}
@@ -8,10 +8,12 @@ type CreateChatroomRequest struct {
 	Participants []string `json:"participants,omitempty" validate:"omitempty,min=1"`
 }
 
-// UpdateChatroomRequest represents the request body for updating a chatroom
+// UpdateChatroomRequest represents the request body for updating a chatroom, omitted fields are left as they are
 type UpdateChatroomRequest struct {
-	Name string `json:"name" validate:"required"`
-	Type string `json:"type" validate:"required,oneof=public private squad"`
+	Name            *string `json:"name,omitempty" validate:"omitempty,min=1"`
+	Type            *string `json:"type,omitempty" validate:"omitempty,oneof=public private squad"` // Must be the chatroom's type, it can't be changed
+	JoinPolicy      *string `json:"joinPolicy,omitempty" validate:"omitempty,oneof=open approval"`  // Squads only
+	MaxParticipants *int    `json:"maxParticipants,omitempty" validate:"omitempty,min=0"`           // Squads only, 0 removes the cap
 }
 
 // AddParticipantRequest represents the request body for adding a participant to a chatroom
@@ -25,6 +27,18 @@ type UpdateParticipantRequest struct {
 	Role string `json:"role" validate:"required,oneof=member admin super_admin"`
 }
 
+// CreateInviteRequest represents the request body for creating a squad invite
+type CreateInviteRequest struct {
+	ExpiresIn int64 `json:"expiresIn,omitempty" validate:"omitempty,min=1"` // Duration in minutes, defaults to 7 days
+	MaxUses   int   `json:"maxUses,omitempty" validate:"omitempty,min=0"`   // 0 means unlimited
+}
+
+// JoinChatroomRequest represents the request body for joining a squad with an invite code
+type JoinChatroomRequest struct {
+	Code    string `json:"code" validate:"required"`
+	Message string `json:"message,omitempty" validate:"omitempty,max=500"`
+}
+
 // MuteParticipantRequest represents the request body for muting a participant
 type MuteParticipantRequest struct {
 	Duration int64 `json:"duration" validate:"required,min=1"` // Duration in minutes
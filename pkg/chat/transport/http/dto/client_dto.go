@@ -2,17 +2,50 @@ package dto
 
 // CreateClientRequest represents the request body for creating a client
 type CreateClientRequest struct {
-	Name         string `json:"name" validate:"required"`
-	Description  string `json:"description"`
-	ClientKey    string `json:"clientKey" validate:"required"`
-	AuthEndpoint string `json:"authEndpoint" validate:"required,url"`
+	Name            string             `json:"name" validate:"required"`
+	Description     string             `json:"description"`
+	AuthEndpoint    string             `json:"authEndpoint" validate:"omitempty,url"` // Required by the endpoint auth strategy
+	Auth            *ClientAuthRequest `json:"auth,omitempty"`                        // Defaults to the endpoint auth strategy
+	WebhookEndpoint string             `json:"webhookEndpoint,omitempty" validate:"omitempty,url"`
+	ErasurePolicy   string             `json:"erasurePolicy,omitempty" validate:"omitempty,oneof=tombstone delete"` // Defaults to tombstone
 }
 
 // UpdateClientRequest represents the request body for updating a client
 type UpdateClientRequest struct {
-	Name         string `json:"name" validate:"required"`
-	Description  string `json:"description"`
-	ClientKey    string `json:"clientKey" validate:"required"`
-	AuthEndpoint string `json:"authEndpoint" validate:"required,url"`
-	Status       string `json:"status" validate:"required,oneof=active inactive"`
+	Name            string             `json:"name" validate:"required"`
+	Description     string             `json:"description"`
+	AuthEndpoint    string             `json:"authEndpoint" validate:"omitempty,url"` // Required by the endpoint auth strategy
+	Auth            *ClientAuthRequest `json:"auth,omitempty"`                        // Defaults to the endpoint auth strategy
+	WebhookEndpoint string             `json:"webhookEndpoint,omitempty" validate:"omitempty,url"`
+	ErasurePolicy   string             `json:"erasurePolicy,omitempty" validate:"omitempty,oneof=tombstone delete"` // Defaults to tombstone
+	Status          string             `json:"status" validate:"required,oneof=active inactive"`
+}
+
+// ClientAuthRequest represents how a client's user tokens are verified
+type ClientAuthRequest struct {
+	Strategy              string `json:"strategy" validate:"omitempty,oneof=endpoint hmac public_key jwks introspection"`
+	Secret                string `json:"secret,omitempty"` // HMAC or introspection client secret, kept as is when omitted on update
+	PublicKey             string `json:"publicKey,omitempty"`
+	JWKSURL               string `json:"jwksUrl,omitempty" validate:"omitempty,url"`
+	IntrospectionEndpoint string `json:"introspectionEndpoint,omitempty" validate:"omitempty,url"`
+	IntrospectionClientID string `json:"introspectionClientId,omitempty"`
+	Issuer                string `json:"issuer,omitempty"`
+	Audience              string `json:"audience,omitempty"`
+}
+
+// RevokeTokenRequest represents the request body for revoking a user token
+type RevokeTokenRequest struct {
+	Token string `json:"token" validate:"required"`
+}
+
+// CreateClientKeyRequest represents the request body for creating a client key
+type CreateClientKeyRequest struct {
+	Name      string   `json:"name"`
+	Scopes    []string `json:"scopes,omitempty" validate:"omitempty,dive,oneof=read write websocket"` // Defaults to every scope
+	ExpiresIn int64    `json:"expiresIn,omitempty" validate:"omitempty,min=1"`                        // Duration in minutes, the key doesn't expire when omitted
+}
+
+// RotateClientKeyRequest represents the request body for rotating a client key
+type RotateClientKeyRequest struct {
+	GracePeriod int64 `json:"gracePeriod" validate:"min=0,max=43200"` // Minutes the replaced key keeps working, 0 revokes it right away
 }
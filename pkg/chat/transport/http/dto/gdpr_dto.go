@@ -0,0 +1,7 @@
+package dto
+
+// CreateDataJobRequest represents the request body for requesting an export or erasure of a user's data
+type CreateDataJobRequest struct {
+	UserID string `json:"userId" validate:"required"`
+	Type   string `json:"type" validate:"required,oneof=export erasure"`
+}
@@ -0,0 +1,13 @@
+package dto
+
+// UpdateModerationSettingsRequest represents the request body for updating a chatroom's moderation settings
+type UpdateModerationSettingsRequest struct {
+	Disabled       bool     `json:"disabled"` // Skips moderation in the chatroom
+	BlockedWords   []string `json:"blockedWords"`
+	WordAction     string   `json:"wordAction,omitempty" validate:"omitempty,oneof=mask reject"` // Defaults to mask
+	AllowedDomains []string `json:"allowedDomains"`                                              // When set, links to any other domain are filtered
+	BlockedDomains []string `json:"blockedDomains"`
+	LinkAction     string   `json:"linkAction,omitempty" validate:"omitempty,oneof=mask reject"` // Defaults to reject
+	RepeatLimit    int      `json:"repeatLimit" validate:"min=0"`                                // 0 uses the service default
+	RepeatWindow   int64    `json:"repeatWindow" validate:"min=0"`                               // In seconds, 0 uses the service default
+}
@@ -0,0 +1,15 @@
+package dto
+
+// UpdateNotificationPreferenceRequest represents the request body for updating notification preferences
+type UpdateNotificationPreferenceRequest struct {
+	Muted            bool     `json:"muted"`                                                   // Mutes every notification
+	MutedChatrooms   []string `json:"mutedChatrooms"`                                          // Chatrooms that don't send notifications
+	DisabledChannels []string `json:"disabledChannels" validate:"dive,oneof=webhook telegram"` // Channels that don't deliver notifications
+}
+
+// LinkTelegramRequest represents the request body the telegram service sends when its bot receives a link code
+type LinkTelegramRequest struct {
+	BotID  string `json:"botId"`  // Client ID of the bot that received the code
+	ChatID int64  `json:"chatId"` // Telegram chat that sent the code
+	Code   string `json:"code"`
+}
@@ -9,6 +9,22 @@ type CreateUserRequest struct {
 	Level    int    `json:"level" validate:"min=0"`
 }
 
+// UpdatePrivacyRequest represents the request body for updating the current user's privacy settings
+type UpdatePrivacyRequest struct {
+	DMPrivacy string `json:"dmPrivacy" validate:"required,oneof=everyone shared_room nobody"`
+}
+
+// BlockUserRequest represents the request body for blocking a user
+type BlockUserRequest struct {
+	UserID string `json:"userId" validate:"required"`
+}
+
+// SetPresenceStatusRequest represents the request body for setting the current user's custom status
+type SetPresenceStatusRequest struct {
+	Status string `json:"status" validate:"required,oneof=online away busy"`
+	Text   string `json:"text,omitempty" validate:"max=100"`
+}
+
 // UpdateUserRequest represents the request body for updating a user
 type UpdateUserRequest struct {
 	Name     string `json:"name" validate:"required"`
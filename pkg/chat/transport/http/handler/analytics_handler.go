@@ -0,0 +1,222 @@
+package handler
+
+import (
+	"app/pkg/chat/service/analytics"
+	"app/pkg/exception"
+	"app/pkg/middleware"
+	"app/pkg/types/http"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type AnalyticsHandler struct {
+	analyticsService analytics.AnalyticsService
+	keyMiddleware    *middleware.KeyMiddleware
+}
+
+func NewAnalyticsHandler(analyticsService analytics.AnalyticsService, keyMiddleware *middleware.KeyMiddleware) *AnalyticsHandler {
+	return &AnalyticsHandler{
+		analyticsService: analyticsService,
+		keyMiddleware:    keyMiddleware,
+	}
+}
+
+// RegisterRoutes registers all routes for chat analytics
+func (h *AnalyticsHandler) RegisterRoutes(app fiber.Router) {
+	v1 := app.Group("/v1")
+
+	// Admin protected routes
+	admin := v1.Group("/admin/analytics", h.keyMiddleware.ValidateKey())
+	admin.Get("/summary", h.GetSummary)
+	admin.Get("/daily", h.GetDailyActivity)
+	admin.Get("/monthly", h.GetMonthlyActivity)
+	admin.Get("/top-chatrooms", h.GetTopChatrooms)
+	admin.Get("/response-time", h.GetDirectResponseTime)
+}
+
+// GetSummary godoc
+// @Summary Get an activity summary
+// @Description Counts the messages, active senders and active chatrooms over a time range, the last 30 days by default
+// @Tags analytics
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param clientId query string false "Filter by client ID"
+// @Param from query int false "Start of the range in milliseconds, inclusive"
+// @Param to query int false "End of the range in milliseconds, exclusive"
+// @Param timezone query string false "IANA time zone, defaults to UTC"
+// @Success 200 {object} http.GeneralResponse{data=entity.ActivitySummary}
+// @Failure 400,401 {object} http.ErrorResponse
+// @Router /v1/admin/analytics/summary [get]
+func (h *AnalyticsHandler) GetSummary(c *fiber.Ctx) error {
+	params, err := h.parseParams(c)
+	if err != nil {
+		return err
+	}
+
+	summary, err := h.analyticsService.GetSummary(c.Context(), params)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.GeneralResponse{
+		Status:  fiber.StatusOK,
+		Message: "Summary retrieved successfully",
+		Data:    summary,
+	})
+}
+
+// GetDailyActivity godoc
+// @Summary Get daily activity
+// @Description Counts the messages and daily active senders of every day of a time range, days without messages included
+// @Tags analytics
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param clientId query string false "Filter by client ID"
+// @Param from query int false "Start of the range in milliseconds, inclusive"
+// @Param to query int false "End of the range in milliseconds, exclusive"
+// @Param timezone query string false "IANA time zone days are bucketed in, defaults to UTC"
+// @Success 200 {object} http.GeneralResponse{data=[]entity.DailyActivity}
+// @Failure 400,401 {object} http.ErrorResponse
+// @Router /v1/admin/analytics/daily [get]
+func (h *AnalyticsHandler) GetDailyActivity(c *fiber.Ctx) error {
+	params, err := h.parseParams(c)
+	if err != nil {
+		return err
+	}
+
+	activity, err := h.analyticsService.GetDailyActivity(c.Context(), params)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.GeneralResponse{
+		Status:  fiber.StatusOK,
+		Message: "Daily activity retrieved successfully",
+		Data:    activity,
+	})
+}
+
+// GetMonthlyActivity godoc
+// @Summary Get monthly activity
+// @Description Counts the messages and monthly active senders of every month of a time range, months without messages included
+// @Tags analytics
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param clientId query string false "Filter by client ID"
+// @Param from query int false "Start of the range in milliseconds, inclusive"
+// @Param to query int false "End of the range in milliseconds, exclusive"
+// @Param timezone query string false "IANA time zone months are bucketed in, defaults to UTC"
+// @Success 200 {object} http.GeneralResponse{data=[]entity.MonthlyActivity}
+// @Failure 400,401 {object} http.ErrorResponse
+// @Router /v1/admin/analytics/monthly [get]
+func (h *AnalyticsHandler) GetMonthlyActivity(c *fiber.Ctx) error {
+	params, err := h.parseParams(c)
+	if err != nil {
+		return err
+	}
+
+	activity, err := h.analyticsService.GetMonthlyActivity(c.Context(), params)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.GeneralResponse{
+		Status:  fiber.StatusOK,
+		Message: "Monthly activity retrieved successfully",
+		Data:    activity,
+	})
+}
+
+// GetTopChatrooms godoc
+// @Summary Get top chatrooms
+// @Description Retrieves the chatrooms with the most messages over a time range, most first
+// @Tags analytics
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param clientId query string false "Filter by client ID"
+// @Param from query int false "Start of the range in milliseconds, inclusive"
+// @Param to query int false "End of the range in milliseconds, exclusive"
+// @Param limit query int false "Number of chatrooms (default 10, max 100)"
+// @Success 200 {object} http.GeneralResponse{data=[]entity.ChatroomActivity}
+// @Failure 400,401 {object} http.ErrorResponse
+// @Router /v1/admin/analytics/top-chatrooms [get]
+func (h *AnalyticsHandler) GetTopChatrooms(c *fiber.Ctx) error {
+	params, err := h.parseParams(c)
+	if err != nil {
+		return err
+	}
+	limit, _ := strconv.Atoi(c.Query("limit", "10"))
+
+	chatrooms, err := h.analyticsService.GetTopChatrooms(c.Context(), params, limit)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.GeneralResponse{
+		Status:  fiber.StatusOK,
+		Message: "Top chatrooms retrieved successfully",
+		Data:    chatrooms,
+	})
+}
+
+// GetDirectResponseTime godoc
+// @Summary Get direct message response time
+// @Description Averages how long users take to answer direct messages over a time range. Answers sent more than a day later start a new conversation and are left out
+// @Tags analytics
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param clientId query string false "Filter by client ID"
+// @Param from query int false "Start of the range in milliseconds, inclusive"
+// @Param to query int false "End of the range in milliseconds, exclusive"
+// @Success 200 {object} http.GeneralResponse{data=entity.ResponseTime}
+// @Failure 400,401 {object} http.ErrorResponse
+// @Router /v1/admin/analytics/response-time [get]
+func (h *AnalyticsHandler) GetDirectResponseTime(c *fiber.Ctx) error {
+	params, err := h.parseParams(c)
+	if err != nil {
+		return err
+	}
+
+	responseTime, err := h.analyticsService.GetDirectResponseTime(c.Context(), params)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.GeneralResponse{
+		Status:  fiber.StatusOK,
+		Message: "Response time retrieved successfully",
+		Data:    responseTime,
+	})
+}
+
+// parseParams reads the client, time range and time zone from the query
+func (h *AnalyticsHandler) parseParams(c *fiber.Ctx) (analytics.AnalyticsParams, error) {
+	params := analytics.AnalyticsParams{
+		ClientID: c.Query("clientId"),
+		Timezone: c.Query("timezone"),
+	}
+
+	if from := c.Query("from"); from != "" {
+		timestamp, err := strconv.ParseInt(from, 10, 64)
+		if err != nil {
+			return params, exception.BadRequest("Invalid from timestamp")
+		}
+		params.Start = time.UnixMilli(timestamp)
+	}
+	if to := c.Query("to"); to != "" {
+		timestamp, err := strconv.ParseInt(to, 10, 64)
+		if err != nil {
+			return params, exception.BadRequest("Invalid to timestamp")
+		}
+		params.End = time.UnixMilli(timestamp)
+	}
+
+	return params, nil
+}
@@ -2,29 +2,38 @@ package handler
 
 import (
 	"app/pkg/chat/domain/entity"
-	"app/pkg/chat/domain/repository"
+	"app/pkg/chat/service/attachment"
 	"app/pkg/chat/service/chat"
+	"app/pkg/chat/service/search"
 	"app/pkg/chat/transport/http/dto"
 	"app/pkg/chat/transport/http/middleware"
 	"app/pkg/exception"
 	"app/pkg/types/http"
 	"app/pkg/types/pagination"
+	"fmt"
 	"strconv"
 
 	"github.com/gofiber/fiber/v2"
 )
 
+// maxCursorLimit is the largest page size accepted for cursor-based message history
+const maxCursorLimit = 100
+
 type ChatHandler struct {
-	chatService      chat.ChatService
-	clientMiddleware *middleware.ClientMiddleware
-	authMiddleware   *middleware.AuthMiddleware
+	chatService       chat.ChatService
+	attachmentService attachment.AttachmentService
+	searchService     search.SearchService
+	clientMiddleware  *middleware.ClientMiddleware
+	authMiddleware    *middleware.AuthMiddleware
 }
 
-func NewChatHandler(chatService chat.ChatService, clientMiddleware *middleware.ClientMiddleware, authMiddleware *middleware.AuthMiddleware) *ChatHandler {
+func NewChatHandler(chatService chat.ChatService, attachmentService attachment.AttachmentService, searchService search.SearchService, clientMiddleware *middleware.ClientMiddleware, authMiddleware *middleware.AuthMiddleware) *ChatHandler {
 	return &ChatHandler{
-		chatService:      chatService,
-		clientMiddleware: clientMiddleware,
-		authMiddleware:   authMiddleware,
+		chatService:       chatService,
+		attachmentService: attachmentService,
+		searchService:     searchService,
+		clientMiddleware:  clientMiddleware,
+		authMiddleware:    authMiddleware,
 	}
 }
 
@@ -36,53 +45,117 @@ func (h *ChatHandler) RegisterRoutes(app fiber.Router) {
 	chats := v1.Group("/chats", h.clientMiddleware.ValidateKey(), h.authMiddleware.Authenticate())
 
 	// Chat message operations
-	chats.Get("/", h.GetChats)                  // Get chat messages with filtering
-	chats.Get("/:id", h.GetChat)                // Get single chat message
-	chats.Put("/:id", h.UpdateChat)             // Update chat message
-	chats.Delete("/:id", h.RemoveChat)          // Delete chat message
-	chats.Post("/rooms/:roomId", h.SendMessage) // Send message to chatroom
-	chats.Post("/direct", h.SendDirectMessage)  // Send direct message
+	chats.Get("/", h.GetChats)                                   // Get chat messages with filtering
+	chats.Get("/search", h.SearchChats)                          // Search messages, registered before /:id
+	chats.Get("/:id", h.GetChat)                                 // Get single chat message
+	chats.Put("/:id", h.UpdateChat)                              // Update chat message
+	chats.Delete("/:id", h.RemoveChat)                           // Delete chat message
+	chats.Get("/:id/replies", h.GetReplies)                      // Get thread replies
+	chats.Post("/:id/reactions", h.ToggleReaction)               // Toggle reaction
+	chats.Post("/rooms/:roomId", h.SendMessage)                  // Send message to chatroom
+	chats.Post("/rooms/:roomId/attachments", h.UploadAttachment) // Upload attachment to chatroom
+	chats.Get("/attachments/:id", h.GetAttachment)               // Download attachment
+	chats.Post("/direct", h.SendDirectMessage)                   // Send direct message
 }
 
 // GetChats godoc
 // @Summary Get chat messages
-// @Description Retrieves chat messages with filtering and pagination
+// @Description Retrieves a chatroom's messages newest first using cursor-based pagination
 // @Tags chats
 // @Accept json
 // @Produce json
 // @Security ApiKeyAuth
 // @Security BearerAuth
-// @Param page query int false "Page number"
+// @Param roomId query string true "Chatroom ID"
 // @Param limit query int false "Items per page"
-// @Param roomId query string false "Filter by chatroom ID"
-// @Success 200 {object} http.GeneralResponse{data=http.PaginatedResponse{result=[]entity.Chat}}
-// @Failure 401 {object} http.ErrorResponse
+// @Param before query string false "Cursor to fetch messages older than"
+// @Param after query string false "Cursor to fetch messages newer than"
+// @Success 200 {object} http.GeneralResponse{data=pagination.CursorPaginatedResult[entity.Chat]}
+// @Failure 400,401,403 {object} http.ErrorResponse
 // @Router /v1/chats [get]
 func (h *ChatHandler) GetChats(c *fiber.Ctx) error {
-	page, _ := strconv.Atoi(c.Query("page", "1"))
-	limit, _ := strconv.Atoi(c.Query("limit", "10"))
+	user := c.Locals("user").(*entity.User)
 	roomID := c.Query("roomId")
+	if roomID == "" {
+		return exception.BadRequest("roomId is required")
+	}
 
-	pag := pagination.Pagination{
-		Page:  page,
-		Limit: limit,
+	limit, _ := strconv.Atoi(c.Query("limit", "10"))
+	if limit < 1 {
+		limit = 10
+	}
+	if limit > maxCursorLimit {
+		limit = maxCursorLimit
 	}
 
-	filter := repository.ChatFilter{
+	cursor := pagination.Cursor{
+		Before: c.Query("before"),
+		After:  c.Query("after"),
+		Limit:  limit,
+	}
+
+	params := chat.GetHistoryParams{
 		ChatroomID: roomID,
+		UserID:     user.ID,
 	}
 
-	chats, total, err := h.chatService.GetChats(c.Context(), filter, pag)
+	chats, metadata, err := h.chatService.GetHistory(c.Context(), params, cursor)
 	if err != nil {
 		return err
 	}
 
-	metadata := pagination.Metadata{
-		Pagination: pag,
-		Total:      total,
-		Count:      len(chats),
-		HasPrev:    page > 1,
-		HasNext:    len(chats) > 0 && int64(page*limit) < total,
+	return c.JSON(http.GeneralResponse{
+		Status:  fiber.StatusOK,
+		Message: "Chats fetched successfully",
+		Data: map[string]interface{}{
+			"metadata": metadata,
+			"result":   chats,
+		},
+	})
+}
+
+// SearchChats godoc
+// @Summary Search chat messages
+// @Description Searches the messages of the current user's chatrooms newest first, using cursor-based pagination
+// @Description Supports keywords, "quoted phrases" and -excluded words
+// @Tags chats
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Param q query string true "Search query"
+// @Param roomId query string false "Restrict the search to a chatroom"
+// @Param limit query int false "Items per page"
+// @Param before query string false "Cursor to fetch older results"
+// @Success 200 {object} http.GeneralResponse{data=pagination.CursorPaginatedResult[entity.ChatSearchResult]}
+// @Failure 400,401,403 {object} http.ErrorResponse
+// @Router /v1/chats/search [get]
+func (h *ChatHandler) SearchChats(c *fiber.Ctx) error {
+	user := c.Locals("user").(*entity.User)
+
+	limit, _ := strconv.Atoi(c.Query("limit", "10"))
+	if limit < 1 {
+		limit = 10
+	}
+	if limit > maxCursorLimit {
+		limit = maxCursorLimit
+	}
+
+	cursor := pagination.Cursor{
+		Before: c.Query("before"),
+		After:  c.Query("after"),
+		Limit:  limit,
+	}
+
+	params := search.SearchMessagesParams{
+		UserID:     user.ID,
+		Query:      c.Query("q"),
+		ChatroomID: c.Query("roomId"),
+	}
+
+	results, metadata, err := h.searchService.SearchMessages(c.Context(), params, cursor)
+	if err != nil {
+		return err
 	}
 
 	return c.JSON(http.GeneralResponse{
@@ -90,7 +163,7 @@ func (h *ChatHandler) GetChats(c *fiber.Ctx) error {
 		Message: "Chats fetched successfully",
 		Data: map[string]interface{}{
 			"metadata": metadata,
-			"result":   chats,
+			"result":   results,
 		},
 	})
 }
@@ -105,15 +178,22 @@ func (h *ChatHandler) GetChats(c *fiber.Ctx) error {
 // @Security BearerAuth
 // @Param id path string true "Chat ID"
 // @Success 200 {object} http.GeneralResponse{data=entity.Chat}
-// @Failure 404 {object} http.ErrorResponse
+// @Failure 403,404 {object} http.ErrorResponse
 // @Router /v1/chats/{id} [get]
 func (h *ChatHandler) GetChat(c *fiber.Ctx) error {
-	id := c.Params("id")
-	chat, err := h.chatService.GetChat(c.Context(), id)
+	user := c.Locals("user").(*entity.User)
+	client := c.Locals("client").(*entity.Client)
+
+	params := chat.GetChatParams{
+		ChatID: c.Params("id"),
+		UserID: user.ID,
+	}
+
+	chat, err := h.chatService.GetChat(c.Context(), params)
 	if err != nil {
 		return err
 	}
-	if chat == nil {
+	if chat.ClientID != client.ID {
 		return exception.NotFound("Chat")
 	}
 
@@ -123,40 +203,134 @@ func (h *ChatHandler) GetChat(c *fiber.Ctx) error {
 	})
 }
 
-// UpdateChat godoc
-// @Summary Update a chat message
-// @Description Updates an existing chat message
+// GetReplies godoc
+// @Summary Get thread replies
+// @Description Retrieves the replies to a chat message with pagination
 // @Tags chats
 // @Accept json
 // @Produce json
 // @Security ApiKeyAuth
 // @Security BearerAuth
 // @Param id path string true "Chat ID"
-// @Param chat body dto.UpdateChatRequest true "Chat details"
+// @Param page query int false "Page number"
+// @Param limit query int false "Items per page"
+// @Success 200 {object} http.GeneralResponse{data=http.PaginatedResponse{result=[]entity.ChatPopulated}}
+// @Failure 403,404 {object} http.ErrorResponse
+// @Router /v1/chats/{id}/replies [get]
+func (h *ChatHandler) GetReplies(c *fiber.Ctx) error {
+	id := c.Params("id")
+	user := c.Locals("user").(*entity.User)
+	page, _ := strconv.Atoi(c.Query("page", "1"))
+	limit, _ := strconv.Atoi(c.Query("limit", "10"))
+
+	pag := pagination.Pagination{
+		Page:  page,
+		Limit: limit,
+	}
+
+	params := chat.GetRepliesParams{
+		ChatID: id,
+		UserID: user.ID,
+	}
+
+	replies, total, err := h.chatService.GetReplies(c.Context(), params, pag)
+	if err != nil {
+		return err
+	}
+
+	metadata := pagination.Metadata{
+		Pagination: pag,
+		Total:      total,
+		Count:      len(replies),
+		HasPrev:    page > 1,
+		HasNext:    len(replies) > 0 && int64(page*limit) < total,
+	}
+
+	return c.JSON(http.GeneralResponse{
+		Status:  fiber.StatusOK,
+		Message: "Replies fetched successfully",
+		Data: map[string]interface{}{
+			"metadata": metadata,
+			"result":   replies,
+		},
+	})
+}
+
+// ToggleReaction godoc
+// @Summary Toggle a reaction
+// @Description Adds the user's emoji reaction to a chat message, or removes it if it already exists
+// @Tags chats
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Param id path string true "Chat ID"
+// @Param reaction body dto.ToggleReactionRequest true "Reaction details"
 // @Success 200 {object} http.GeneralResponse{data=entity.Chat}
-// @Failure 400,404 {object} http.ErrorResponse
-// @Router /v1/chats/{id} [put]
-func (h *ChatHandler) UpdateChat(c *fiber.Ctx) error {
+// @Failure 400,403,404 {object} http.ErrorResponse
+// @Router /v1/chats/{id}/reactions [post]
+func (h *ChatHandler) ToggleReaction(c *fiber.Ctx) error {
 	id := c.Params("id")
+	user := c.Locals("user").(*entity.User)
+
+	var req dto.ToggleReactionRequest
+	if err := c.BodyParser(&req); err != nil {
+		return exception.BadRequest("Invalid request body")
+	}
+
+	params := chat.ToggleReactionParams{
+		ChatID: id,
+		UserID: user.ID,
+		Emoji:  req.Emoji,
+	}
 
-	// Check if chat exists
-	chat, err := h.chatService.GetChat(c.Context(), id)
+	chat, added, err := h.chatService.ToggleReaction(c.Context(), params)
 	if err != nil {
 		return err
 	}
-	if chat == nil {
-		return exception.NotFound("Chat")
+
+	message := "Reaction removed successfully"
+	if added {
+		message = "Reaction added successfully"
 	}
 
+	return c.JSON(http.GeneralResponse{
+		Status:  fiber.StatusOK,
+		Message: message,
+		Data:    chat,
+	})
+}
+
+// UpdateChat godoc
+// @Summary Update a chat message
+// @Description Edits an existing chat message, keeping the previous content in its edit history. Only the sender or a chatroom admin can edit.
+// @Tags chats
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Param id path string true "Chat ID"
+// @Param chat body dto.UpdateChatRequest true "Chat details"
+// @Success 200 {object} http.GeneralResponse{data=entity.Chat}
+// @Failure 400,403,404 {object} http.ErrorResponse
+// @Router /v1/chats/{id} [put]
+func (h *ChatHandler) UpdateChat(c *fiber.Ctx) error {
+	id := c.Params("id")
+	user := c.Locals("user").(*entity.User)
+
 	var req dto.UpdateChatRequest
 	if err := c.BodyParser(&req); err != nil {
 		return exception.BadRequest("Invalid request body")
 	}
 
-	// Update only the message content
-	chat.Message = req.Message
+	params := chat.UpdateChatParams{
+		ChatID:  id,
+		UserID:  user.ID,
+		Message: req.Message,
+	}
 
-	if err := h.chatService.UpdateChat(c.Context(), chat); err != nil {
+	chat, err := h.chatService.UpdateChat(c.Context(), params)
+	if err != nil {
 		return err
 	}
 
@@ -169,7 +343,7 @@ func (h *ChatHandler) UpdateChat(c *fiber.Ctx) error {
 
 // RemoveChat godoc
 // @Summary Delete a chat message
-// @Description Deletes an existing chat message
+// @Description Soft deletes an existing chat message, leaving a tombstone in its place. Only the sender or a chatroom admin can delete.
 // @Tags chats
 // @Accept json
 // @Produce json
@@ -177,27 +351,26 @@ func (h *ChatHandler) UpdateChat(c *fiber.Ctx) error {
 // @Security BearerAuth
 // @Param id path string true "Chat ID"
 // @Success 200 {object} http.GeneralResponse{data=entity.Chat}
-// @Failure 404 {object} http.ErrorResponse
+// @Failure 403,404 {object} http.ErrorResponse
 // @Router /v1/chats/{id} [delete]
 func (h *ChatHandler) RemoveChat(c *fiber.Ctx) error {
 	id := c.Params("id")
+	user := c.Locals("user").(*entity.User)
 
-	// Check if chat exists
-	chat, err := h.chatService.GetChat(c.Context(), id)
-	if err != nil {
-		return err
-	}
-	if chat == nil {
-		return exception.NotFound("Chat")
+	params := chat.RemoveChatParams{
+		ChatID: id,
+		UserID: user.ID,
 	}
 
-	if err := h.chatService.RemoveChat(c.Context(), id); err != nil {
+	chat, err := h.chatService.RemoveChat(c.Context(), params)
+	if err != nil {
 		return err
 	}
 
 	return c.JSON(http.GeneralResponse{
 		Status:  fiber.StatusOK,
 		Message: "Chat message deleted successfully",
+		Data:    chat,
 	})
 }
 
@@ -212,7 +385,7 @@ func (h *ChatHandler) RemoveChat(c *fiber.Ctx) error {
 // @Param roomId path string true "Chatroom ID"
 // @Param message body dto.SendMessageRequest true "Message details"
 // @Success 201 {object} http.GeneralResponse{data=entity.Chat}
-// @Failure 400,404 {object} http.ErrorResponse
+// @Failure 400,404,429 {object} http.ErrorResponse
 // @Router /v1/chats/rooms/{roomId} [post]
 func (h *ChatHandler) SendMessage(c *fiber.Ctx) error {
 	roomID := c.Params("roomId")
@@ -223,10 +396,17 @@ func (h *ChatHandler) SendMessage(c *fiber.Ctx) error {
 		return exception.BadRequest("Invalid request body")
 	}
 
+	client := c.Locals("client").(*entity.Client)
+
 	params := chat.SendMessageParams{
-		ChatroomID: roomID,
-		SenderID:   user.ID,
-		Message:    req.Message,
+		ChatroomID:      roomID,
+		SenderID:        user.ID,
+		ClientID:        client.ID,
+		Message:         req.Message,
+		ReplyTo:         req.ReplyTo,
+		Metadata:        req.Metadata,
+		Attachments:     req.Attachments,
+		ClientMessageID: req.ClientMessageID,
 	}
 
 	chat, err := h.chatService.SendMessage(c.Context(), params)
@@ -241,6 +421,86 @@ func (h *ChatHandler) SendMessage(c *fiber.Ctx) error {
 	})
 }
 
+// UploadAttachment godoc
+// @Summary Upload an attachment to a chatroom
+// @Description Uploads a file that can be attached to a message by passing its ID in the message's attachments
+// @Tags chats
+// @Accept multipart/form-data
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Param roomId path string true "Chatroom ID"
+// @Param file formData file true "File to upload"
+// @Success 201 {object} http.GeneralResponse{data=entity.Attachment}
+// @Failure 400,403,413 {object} http.ErrorResponse
+// @Router /v1/chats/rooms/{roomId}/attachments [post]
+func (h *ChatHandler) UploadAttachment(c *fiber.Ctx) error {
+	roomID := c.Params("roomId")
+	user := c.Locals("user").(*entity.User)
+
+	file, err := c.FormFile("file")
+	if err != nil || file == nil {
+		return exception.BadRequest("File is required")
+	}
+
+	src, err := file.Open()
+	if err != nil {
+		return exception.BadRequest("Failed to read file")
+	}
+	defer src.Close()
+
+	params := attachment.UploadParams{
+		ChatroomID: roomID,
+		UserID:     user.ID,
+		Name:       file.Filename,
+		Reader:     src,
+	}
+
+	result, err := h.attachmentService.Upload(c.Context(), params)
+	if err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(http.GeneralResponse{
+		Status:  fiber.StatusCreated,
+		Message: "Attachment uploaded successfully",
+		Data:    result,
+	})
+}
+
+// GetAttachment godoc
+// @Summary Download an attachment
+// @Description Streams the content of an attachment of a chatroom the user participates in
+// @Tags chats
+// @Produce octet-stream
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Param id path string true "Attachment ID"
+// @Success 200 {file} file
+// @Failure 403,404 {object} http.ErrorResponse
+// @Router /v1/chats/attachments/{id} [get]
+func (h *ChatHandler) GetAttachment(c *fiber.Ctx) error {
+	id := c.Params("id")
+	user := c.Locals("user").(*entity.User)
+
+	result, reader, err := h.attachmentService.Open(c.Context(), id, user.ID)
+	if err != nil {
+		return err
+	}
+
+	disposition := "attachment"
+	if result.Kind == entity.AttachmentKindImage {
+		disposition = "inline"
+	}
+
+	c.Set(fiber.HeaderContentType, result.ContentType)
+	c.Set(fiber.HeaderContentDisposition, fmt.Sprintf("%s; filename=%q", disposition, result.Name))
+	c.Set(fiber.HeaderXContentTypeOptions, "nosniff")
+
+	// The stream is closed by fasthttp once the response has been written
+	return c.SendStream(reader, int(result.Size))
+}
+
 // SendDirectMessage godoc
 // @Summary Send a direct message
 // @Description Sends a direct message to another user
@@ -251,7 +511,7 @@ func (h *ChatHandler) SendMessage(c *fiber.Ctx) error {
 // @Security BearerAuth
 // @Param message body dto.SendDirectMessageRequest true "Message details"
 // @Success 201 {object} http.GeneralResponse{data=entity.Chat}
-// @Failure 400,404 {object} http.ErrorResponse
+// @Failure 400,404,429 {object} http.ErrorResponse
 // @Router /v1/chats/direct [post]
 func (h *ChatHandler) SendDirectMessage(c *fiber.Ctx) error {
 	user := c.Locals("user").(*entity.User)
@@ -261,8 +521,11 @@ func (h *ChatHandler) SendDirectMessage(c *fiber.Ctx) error {
 		return exception.BadRequest("Invalid request body")
 	}
 
+	client := c.Locals("client").(*entity.Client)
+
 	params := chat.SendDirectMessageParams{
 		SenderID:   user.ID,
+		ClientID:   client.ID,
 		ReceiverID: req.ReceiverID,
 		Message:    req.Message,
 	}
@@ -4,6 +4,8 @@ import (
 	"app/pkg/chat/domain/entity"
 	"app/pkg/chat/domain/repository"
 	"app/pkg/chat/service/chatroom"
+	"app/pkg/chat/service/receipt"
+	"app/pkg/chat/service/squad"
 	"app/pkg/chat/transport/http/dto"
 	"app/pkg/chat/transport/http/middleware"
 	"app/pkg/exception"
@@ -17,13 +19,17 @@ import (
 
 type ChatroomHandler struct {
 	chatroomService  chatroom.ChatroomService
+	receiptService   receipt.ReceiptService
+	squadService     squad.SquadService
 	clientMiddleware *middleware.ClientMiddleware
 	authMiddleware   *middleware.AuthMiddleware
 }
 
-func NewChatroomHandler(chatroomService chatroom.ChatroomService, clientMiddleware *middleware.ClientMiddleware, authMiddleware *middleware.AuthMiddleware) *ChatroomHandler {
+func NewChatroomHandler(chatroomService chatroom.ChatroomService, receiptService receipt.ReceiptService, squadService squad.SquadService, clientMiddleware *middleware.ClientMiddleware, authMiddleware *middleware.AuthMiddleware) *ChatroomHandler {
 	return &ChatroomHandler{
 		chatroomService:  chatroomService,
+		receiptService:   receiptService,
+		squadService:     squadService,
 		clientMiddleware: clientMiddleware,
 		authMiddleware:   authMiddleware,
 	}
@@ -37,11 +43,14 @@ func (h *ChatroomHandler) RegisterRoutes(app fiber.Router) {
 	chatrooms := v1.Group("/chatrooms", h.clientMiddleware.ValidateKey(), h.authMiddleware.Authenticate())
 
 	// Chatroom operations
-	chatrooms.Post("/", h.CreateChatroom)      // Create new chatroom
-	chatrooms.Get("/", h.GetChatrooms)         // List chatrooms
-	chatrooms.Get("/:id", h.GetChatroom)       // Get single chatroom
-	chatrooms.Put("/:id", h.UpdateChatroom)    // Update chatroom
-	chatrooms.Delete("/:id", h.DeleteChatroom) // Delete chatroom
+	chatrooms.Post("/", h.CreateChatroom)             // Create new chatroom
+	chatrooms.Get("/", h.GetChatrooms)                // List chatrooms
+	chatrooms.Get("/discover", h.DiscoverChatrooms)   // Discover public chatrooms, registered before /:id
+	chatrooms.Get("/:id", h.GetChatroom)              // Get single chatroom
+	chatrooms.Put("/:id", h.UpdateChatroom)           // Update chatroom
+	chatrooms.Delete("/:id", h.DeleteChatroom)        // Delete chatroom
+	chatrooms.Post("/join", h.JoinChatroom)           // Join squad with invite code
+	chatrooms.Post("/:id/join", h.JoinPublicChatroom) // Join public chatroom
 
 	// Participant management
 	chatrooms.Post("/:id/participants", h.AddParticipant)                    // Add participant
@@ -49,6 +58,14 @@ func (h *ChatroomHandler) RegisterRoutes(app fiber.Router) {
 	chatrooms.Put("/:id/participants/:userId/role", h.UpdateParticipantRole) // Update participant role
 	chatrooms.Post("/:id/participants/:userId/mute", h.MuteParticipant)      // Mute participant
 	chatrooms.Post("/:id/participants/:userId/unmute", h.UnmuteParticipant)  // Unmute participant
+
+	// Squad invites and join requests
+	chatrooms.Post("/:id/invites", h.CreateInvite)                           // Create invite
+	chatrooms.Get("/:id/invites", h.GetInvites)                              // List active invites
+	chatrooms.Delete("/:id/invites/:inviteId", h.RevokeInvite)               // Revoke invite
+	chatrooms.Get("/:id/requests", h.GetJoinRequests)                        // List join requests
+	chatrooms.Post("/:id/requests/:requestId/approve", h.ApproveJoinRequest) // Approve join request
+	chatrooms.Post("/:id/requests/:requestId/reject", h.RejectJoinRequest)   // Reject join request
 }
 
 // CreateChatroom godoc
@@ -65,6 +82,7 @@ func (h *ChatroomHandler) RegisterRoutes(app fiber.Router) {
 // @Router /v1/chatrooms [post]
 func (h *ChatroomHandler) CreateChatroom(c *fiber.Ctx) error {
 	user := c.Locals("user").(*entity.User)
+	client := c.Locals("client").(*entity.Client)
 
 	var req dto.CreateChatroomRequest
 	if err := c.BodyParser(&req); err != nil {
@@ -72,6 +90,7 @@ func (h *ChatroomHandler) CreateChatroom(c *fiber.Ctx) error {
 	}
 
 	params := chatroom.CreateChatroomParams{
+		ClientID:     client.ID,
 		Name:         req.Name,
 		Type:         entity.ChatroomType(req.Type),
 		IsGroup:      req.IsGroup,
@@ -93,7 +112,7 @@ func (h *ChatroomHandler) CreateChatroom(c *fiber.Ctx) error {
 
 // GetChatrooms godoc
 // @Summary Get chatrooms
-// @Description Retrieves chatrooms with filtering and pagination
+// @Description Retrieves the current user's chatrooms with their unread message counts
 // @Tags chatrooms
 // @Accept json
 // @Produce json
@@ -105,6 +124,8 @@ func (h *ChatroomHandler) CreateChatroom(c *fiber.Ctx) error {
 // @Failure 401 {object} http.ErrorResponse
 // @Router /v1/chatrooms [get]
 func (h *ChatroomHandler) GetChatrooms(c *fiber.Ctx) error {
+	user := c.Locals("user").(*entity.User)
+	client := c.Locals("client").(*entity.Client)
 	page, _ := strconv.Atoi(c.Query("page", "1"))
 	limit, _ := strconv.Atoi(c.Query("limit", "10"))
 
@@ -113,14 +134,79 @@ func (h *ChatroomHandler) GetChatrooms(c *fiber.Ctx) error {
 		Limit: limit,
 	}
 
-	// Create empty filter for now, add filter params as needed
-	filter := repository.ChatroomFilter{}
+	filter := repository.ChatroomFilter{
+		ClientID:      client.ID,
+		ParticipantID: user.ID,
+	}
 
 	chatrooms, total, err := h.chatroomService.GetChatrooms(c.Context(), filter, pag)
 	if err != nil {
 		return err
 	}
 
+	// Attach unread message counts for the current user
+	chatroomIDs := make([]string, 0, len(chatrooms))
+	for _, cr := range chatrooms {
+		chatroomIDs = append(chatroomIDs, cr.ID)
+	}
+
+	unreadCounts, err := h.receiptService.GetUnreadCounts(c.Context(), user.ID, chatroomIDs)
+	if err != nil {
+		return err
+	}
+
+	for _, cr := range chatrooms {
+		cr.UnreadCount = unreadCounts[cr.ID]
+	}
+
+	metadata := pagination.Metadata{
+		Pagination: pag,
+		Total:      total,
+		Count:      len(chatrooms),
+		HasPrev:    page > 1,
+		HasNext:    len(chatrooms) > 0 && int64(page*limit) < total,
+	}
+
+	return c.JSON(http.GeneralResponse{
+		Status:  fiber.StatusOK,
+		Message: "Chatrooms fetched successfully",
+		Data: map[string]interface{}{
+			"metadata": metadata,
+			"result":   chatrooms,
+		},
+	})
+}
+
+// DiscoverChatrooms godoc
+// @Summary Discover public chatrooms
+// @Description Lists public chatrooms ranked by activity, optionally searching their names
+// @Tags chatrooms
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Param q query string false "Text to search in chatroom names"
+// @Param page query int false "Page number"
+// @Param limit query int false "Items per page"
+// @Success 200 {object} http.GeneralResponse{data=http.PaginatedResponse{result=[]entity.ChatroomListing}}
+// @Failure 401 {object} http.ErrorResponse
+// @Router /v1/chatrooms/discover [get]
+func (h *ChatroomHandler) DiscoverChatrooms(c *fiber.Ctx) error {
+	user := c.Locals("user").(*entity.User)
+	client := c.Locals("client").(*entity.Client)
+	page, _ := strconv.Atoi(c.Query("page", "1"))
+	limit, _ := strconv.Atoi(c.Query("limit", "10"))
+
+	pag := pagination.Pagination{
+		Page:  page,
+		Limit: limit,
+	}
+
+	chatrooms, total, err := h.chatroomService.DiscoverChatrooms(c.Context(), client.ID, user.ID, c.Query("q"), pag)
+	if err != nil {
+		return err
+	}
+
 	metadata := pagination.Metadata{
 		Pagination: pag,
 		Total:      total,
@@ -139,6 +225,32 @@ func (h *ChatroomHandler) GetChatrooms(c *fiber.Ctx) error {
 	})
 }
 
+// JoinPublicChatroom godoc
+// @Summary Join a public chatroom
+// @Description Adds the current user to a public chatroom as a regular participant
+// @Tags chatrooms
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Param id path string true "Chatroom ID"
+// @Success 200 {object} http.GeneralResponse
+// @Failure 400,403,404 {object} http.ErrorResponse
+// @Router /v1/chatrooms/{id}/join [post]
+func (h *ChatroomHandler) JoinPublicChatroom(c *fiber.Ctx) error {
+	id := c.Params("id")
+	user := c.Locals("user").(*entity.User)
+
+	if err := h.chatroomService.JoinPublicChatroom(c.Context(), id, user.ID); err != nil {
+		return err
+	}
+
+	return c.JSON(http.GeneralResponse{
+		Status:  fiber.StatusOK,
+		Message: "Joined chatroom successfully",
+	})
+}
+
 // GetChatroom godoc
 // @Summary Get a chatroom
 // @Description Retrieves a single chatroom by ID
@@ -153,11 +265,13 @@ func (h *ChatroomHandler) GetChatrooms(c *fiber.Ctx) error {
 // @Router /v1/chatrooms/{id} [get]
 func (h *ChatroomHandler) GetChatroom(c *fiber.Ctx) error {
 	id := c.Params("id")
+	client := c.Locals("client").(*entity.Client)
+
 	chatroom, err := h.chatroomService.GetChatroom(c.Context(), id)
 	if err != nil {
 		return err
 	}
-	if chatroom == nil {
+	if chatroom == nil || chatroom.ClientID != client.ID {
 		return exception.NotFound("Chatroom")
 	}
 
@@ -169,7 +283,7 @@ func (h *ChatroomHandler) GetChatroom(c *fiber.Ctx) error {
 
 // UpdateChatroom godoc
 // @Summary Update a chatroom
-// @Description Updates an existing chatroom
+// @Description Updates the name and squad settings of a chatroom, omitted fields are left as they are. The type can't be changed
 // @Tags chatrooms
 // @Accept json
 // @Produce json
@@ -178,17 +292,19 @@ func (h *ChatroomHandler) GetChatroom(c *fiber.Ctx) error {
 // @Param id path string true "Chatroom ID"
 // @Param chatroom body dto.UpdateChatroomRequest true "Chatroom details"
 // @Success 200 {object} http.GeneralResponse{data=entity.Chatroom}
-// @Failure 400,404 {object} http.ErrorResponse
+// @Failure 400,403,404 {object} http.ErrorResponse
 // @Router /v1/chatrooms/{id} [put]
 func (h *ChatroomHandler) UpdateChatroom(c *fiber.Ctx) error {
 	id := c.Params("id")
+	user := c.Locals("user").(*entity.User)
+	client := c.Locals("client").(*entity.Client)
 
 	// Check if chatroom exists
 	existingChatroom, err := h.chatroomService.GetChatroom(c.Context(), id)
 	if err != nil {
 		return err
 	}
-	if existingChatroom == nil {
+	if existingChatroom == nil || existingChatroom.ClientID != client.ID {
 		return exception.NotFound("Chatroom")
 	}
 
@@ -197,11 +313,19 @@ func (h *ChatroomHandler) UpdateChatroom(c *fiber.Ctx) error {
 		return exception.BadRequest("Invalid request body")
 	}
 
-	chatroomType := entity.ChatroomType(req.Type)
 	params := chatroom.UpdateChatroomParams{
-		ID:   id,
-		Name: &req.Name,
-		Type: &chatroomType,
+		ActorID:         user.ID,
+		ID:              id,
+		Name:            req.Name,
+		MaxParticipants: req.MaxParticipants,
+	}
+	if req.Type != nil {
+		chatroomType := entity.ChatroomType(*req.Type)
+		params.Type = &chatroomType
+	}
+	if req.JoinPolicy != nil {
+		joinPolicy := entity.JoinPolicy(*req.JoinPolicy)
+		params.JoinPolicy = &joinPolicy
 	}
 
 	updatedChatroom, err := h.chatroomService.UpdateChatroom(c.Context(), params)
@@ -226,21 +350,23 @@ func (h *ChatroomHandler) UpdateChatroom(c *fiber.Ctx) error {
 // @Security BearerAuth
 // @Param id path string true "Chatroom ID"
 // @Success 200 {object} http.GeneralResponse{data=entity.Chatroom}
-// @Failure 404 {object} http.ErrorResponse
+// @Failure 403,404 {object} http.ErrorResponse
 // @Router /v1/chatrooms/{id} [delete]
 func (h *ChatroomHandler) DeleteChatroom(c *fiber.Ctx) error {
 	id := c.Params("id")
+	user := c.Locals("user").(*entity.User)
+	client := c.Locals("client").(*entity.Client)
 
 	// Check if chatroom exists
 	chatroom, err := h.chatroomService.GetChatroom(c.Context(), id)
 	if err != nil {
 		return err
 	}
-	if chatroom == nil {
+	if chatroom == nil || chatroom.ClientID != client.ID {
 		return exception.NotFound("Chatroom")
 	}
 
-	if err := h.chatroomService.DeleteChatroom(c.Context(), id); err != nil {
+	if err := h.chatroomService.DeleteChatroom(c.Context(), id, user.ID); err != nil {
 		return err
 	}
 
@@ -261,17 +387,18 @@ func (h *ChatroomHandler) DeleteChatroom(c *fiber.Ctx) error {
 // @Param id path string true "Chatroom ID"
 // @Param participant body dto.AddParticipantRequest true "Participant details"
 // @Success 200 {object} http.GeneralResponse{data=entity.Chatroom}
-// @Failure 400,404 {object} http.ErrorResponse
+// @Failure 400,403,404 {object} http.ErrorResponse
 // @Router /v1/chatrooms/{id}/participants [post]
 func (h *ChatroomHandler) AddParticipant(c *fiber.Ctx) error {
 	id := c.Params("id")
+	user := c.Locals("user").(*entity.User)
 
 	var req dto.AddParticipantRequest
 	if err := c.BodyParser(&req); err != nil {
 		return exception.BadRequest("Invalid request body")
 	}
 
-	if err := h.chatroomService.AddParticipant(c.Context(), id, req.UserID); err != nil {
+	if err := h.chatroomService.AddParticipant(c.Context(), id, req.UserID, user.ID); err != nil {
 		return err
 	}
 
@@ -292,13 +419,14 @@ func (h *ChatroomHandler) AddParticipant(c *fiber.Ctx) error {
 // @Param id path string true "Chatroom ID"
 // @Param userId path string true "User ID"
 // @Success 200 {object} http.GeneralResponse{data=entity.Chatroom}
-// @Failure 404 {object} http.ErrorResponse
+// @Failure 403,404 {object} http.ErrorResponse
 // @Router /v1/chatrooms/{id}/participants/{userId} [delete]
 func (h *ChatroomHandler) RemoveParticipant(c *fiber.Ctx) error {
 	id := c.Params("id")
 	userID := c.Params("userId")
+	user := c.Locals("user").(*entity.User)
 
-	if err := h.chatroomService.RemoveParticipant(c.Context(), id, userID); err != nil {
+	if err := h.chatroomService.RemoveParticipant(c.Context(), id, userID, user.ID); err != nil {
 		return err
 	}
 
@@ -320,11 +448,12 @@ func (h *ChatroomHandler) RemoveParticipant(c *fiber.Ctx) error {
 // @Param userId path string true "User ID"
 // @Param role body dto.UpdateParticipantRequest true "Role details"
 // @Success 200 {object} http.GeneralResponse{data=entity.Chatroom}
-// @Failure 400,404 {object} http.ErrorResponse
+// @Failure 400,403,404 {object} http.ErrorResponse
 // @Router /v1/chatrooms/{id}/participants/{userId}/role [put]
 func (h *ChatroomHandler) UpdateParticipantRole(c *fiber.Ctx) error {
 	id := c.Params("id")
 	userID := c.Params("userId")
+	user := c.Locals("user").(*entity.User)
 
 	var req dto.UpdateParticipantRequest
 	if err := c.BodyParser(&req); err != nil {
@@ -332,6 +461,7 @@ func (h *ChatroomHandler) UpdateParticipantRole(c *fiber.Ctx) error {
 	}
 
 	params := chatroom.UpdateParticipantRoleParams{
+		ActorID:       user.ID,
 		ChatroomID:    id,
 		ParticipantID: userID,
 		NewRole:       entity.ParticipantRole(req.Role),
@@ -359,11 +489,12 @@ func (h *ChatroomHandler) UpdateParticipantRole(c *fiber.Ctx) error {
 // @Param userId path string true "User ID"
 // @Param mute body dto.MuteParticipantRequest true "Mute details"
 // @Success 200 {object} http.GeneralResponse{data=entity.Chatroom}
-// @Failure 400,404 {object} http.ErrorResponse
+// @Failure 400,403,404 {object} http.ErrorResponse
 // @Router /v1/chatrooms/{id}/participants/{userId}/mute [post]
 func (h *ChatroomHandler) MuteParticipant(c *fiber.Ctx) error {
 	id := c.Params("id")
 	userID := c.Params("userId")
+	user := c.Locals("user").(*entity.User)
 
 	var req dto.MuteParticipantRequest
 	if err := c.BodyParser(&req); err != nil {
@@ -371,6 +502,7 @@ func (h *ChatroomHandler) MuteParticipant(c *fiber.Ctx) error {
 	}
 
 	params := chatroom.MuteParticipantParams{
+		ActorID:       user.ID,
 		ChatroomID:    id,
 		ParticipantID: userID,
 		Duration:      time.Duration(req.Duration) * time.Minute,
@@ -397,13 +529,14 @@ func (h *ChatroomHandler) MuteParticipant(c *fiber.Ctx) error {
 // @Param id path string true "Chatroom ID"
 // @Param userId path string true "User ID"
 // @Success 200 {object} http.GeneralResponse{data=entity.Chatroom}
-// @Failure 404 {object} http.ErrorResponse
+// @Failure 403,404 {object} http.ErrorResponse
 // @Router /v1/chatrooms/{id}/participants/{userId}/unmute [post]
 func (h *ChatroomHandler) UnmuteParticipant(c *fiber.Ctx) error {
 	id := c.Params("id")
 	userID := c.Params("userId")
+	user := c.Locals("user").(*entity.User)
 
-	if err := h.chatroomService.UnmuteParticipant(c.Context(), id, userID); err != nil {
+	if err := h.chatroomService.UnmuteParticipant(c.Context(), id, userID, user.ID); err != nil {
 		return err
 	}
 
@@ -412,3 +545,260 @@ func (h *ChatroomHandler) UnmuteParticipant(c *fiber.Ctx) error {
 		Message: "Participant unmuted successfully",
 	})
 }
+
+// JoinChatroom godoc
+// @Summary Join a squad
+// @Description Redeems a squad invite code. Open squads are joined immediately, otherwise a pending join request is created
+// @Tags chatrooms
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Param join body dto.JoinChatroomRequest true "Invite code"
+// @Success 200 {object} http.GeneralResponse{data=squad.JoinResult}
+// @Failure 400,404,503 {object} http.ErrorResponse
+// @Router /v1/chatrooms/join [post]
+func (h *ChatroomHandler) JoinChatroom(c *fiber.Ctx) error {
+	user := c.Locals("user").(*entity.User)
+
+	var req dto.JoinChatroomRequest
+	if err := c.BodyParser(&req); err != nil {
+		return exception.BadRequest("Invalid request body")
+	}
+
+	params := squad.JoinParams{
+		Code:    req.Code,
+		UserID:  user.ID,
+		Message: req.Message,
+	}
+
+	result, err := h.squadService.Join(c.Context(), params)
+	if err != nil {
+		return err
+	}
+
+	message := "Joined chatroom successfully"
+	if !result.Joined {
+		message = "Join request submitted successfully"
+	}
+
+	return c.JSON(http.GeneralResponse{
+		Status:  fiber.StatusOK,
+		Message: message,
+		Data:    result,
+	})
+}
+
+// CreateInvite godoc
+// @Summary Create a squad invite
+// @Description Creates a signed, expiring invite code for a squad
+// @Tags chatrooms
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Param id path string true "Chatroom ID"
+// @Param invite body dto.CreateInviteRequest true "Invite details"
+// @Success 201 {object} http.GeneralResponse{data=entity.ChatroomInvite}
+// @Failure 400,403,404,503 {object} http.ErrorResponse
+// @Router /v1/chatrooms/{id}/invites [post]
+func (h *ChatroomHandler) CreateInvite(c *fiber.Ctx) error {
+	id := c.Params("id")
+	user := c.Locals("user").(*entity.User)
+
+	var req dto.CreateInviteRequest
+	if err := c.BodyParser(&req); err != nil {
+		return exception.BadRequest("Invalid request body")
+	}
+
+	params := squad.CreateInviteParams{
+		ChatroomID: id,
+		ActorID:    user.ID,
+		ExpiresIn:  time.Duration(req.ExpiresIn) * time.Minute,
+		MaxUses:    req.MaxUses,
+	}
+
+	invite, err := h.squadService.CreateInvite(c.Context(), params)
+	if err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(http.GeneralResponse{
+		Status:  fiber.StatusCreated,
+		Message: "Invite created successfully",
+		Data:    invite,
+	})
+}
+
+// GetInvites godoc
+// @Summary Get squad invites
+// @Description Retrieves the squad's invites that are neither revoked nor expired
+// @Tags chatrooms
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Param id path string true "Chatroom ID"
+// @Success 200 {object} http.GeneralResponse{data=[]entity.ChatroomInvite}
+// @Failure 400,403,404 {object} http.ErrorResponse
+// @Router /v1/chatrooms/{id}/invites [get]
+func (h *ChatroomHandler) GetInvites(c *fiber.Ctx) error {
+	id := c.Params("id")
+	user := c.Locals("user").(*entity.User)
+
+	invites, err := h.squadService.GetInvites(c.Context(), id, user.ID)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.GeneralResponse{
+		Status:  fiber.StatusOK,
+		Message: "Invites fetched successfully",
+		Data:    invites,
+	})
+}
+
+// RevokeInvite godoc
+// @Summary Revoke a squad invite
+// @Description Revokes an invite so it can't be redeemed anymore
+// @Tags chatrooms
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Param id path string true "Chatroom ID"
+// @Param inviteId path string true "Invite ID"
+// @Success 200 {object} http.GeneralResponse
+// @Failure 400,403,404 {object} http.ErrorResponse
+// @Router /v1/chatrooms/{id}/invites/{inviteId} [delete]
+func (h *ChatroomHandler) RevokeInvite(c *fiber.Ctx) error {
+	id := c.Params("id")
+	inviteID := c.Params("inviteId")
+	user := c.Locals("user").(*entity.User)
+
+	if err := h.squadService.RevokeInvite(c.Context(), id, inviteID, user.ID); err != nil {
+		return err
+	}
+
+	return c.JSON(http.GeneralResponse{
+		Status:  fiber.StatusOK,
+		Message: "Invite revoked successfully",
+	})
+}
+
+// GetJoinRequests godoc
+// @Summary Get squad join requests
+// @Description Retrieves the squad's join requests oldest first, pending ones by default
+// @Tags chatrooms
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Param id path string true "Chatroom ID"
+// @Param status query string false "Request status" Enums(pending, approved, rejected)
+// @Param page query int false "Page number"
+// @Param limit query int false "Items per page"
+// @Success 200 {object} http.GeneralResponse{data=http.PaginatedResponse{result=[]entity.JoinRequest}}
+// @Failure 400,403,404 {object} http.ErrorResponse
+// @Router /v1/chatrooms/{id}/requests [get]
+func (h *ChatroomHandler) GetJoinRequests(c *fiber.Ctx) error {
+	id := c.Params("id")
+	user := c.Locals("user").(*entity.User)
+	page, _ := strconv.Atoi(c.Query("page", "1"))
+	limit, _ := strconv.Atoi(c.Query("limit", "10"))
+
+	pag := pagination.Pagination{
+		Page:  page,
+		Limit: limit,
+	}
+
+	var status *entity.JoinRequestStatus
+	if s := c.Query("status"); s != "" {
+		requestStatus := entity.JoinRequestStatus(s)
+		status = &requestStatus
+	}
+
+	requests, total, err := h.squadService.GetJoinRequests(c.Context(), id, user.ID, status, pag)
+	if err != nil {
+		return err
+	}
+
+	metadata := pagination.Metadata{
+		Pagination: pag,
+		Total:      total,
+		Count:      len(requests),
+		HasPrev:    page > 1,
+		HasNext:    len(requests) > 0 && int64(page*limit) < total,
+	}
+
+	return c.JSON(http.GeneralResponse{
+		Status:  fiber.StatusOK,
+		Message: "Join requests fetched successfully",
+		Data: map[string]interface{}{
+			"metadata": metadata,
+			"result":   requests,
+		},
+	})
+}
+
+// ApproveJoinRequest godoc
+// @Summary Approve a squad join request
+// @Description Approves a pending join request and adds the user to the squad
+// @Tags chatrooms
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Param id path string true "Chatroom ID"
+// @Param requestId path string true "Join request ID"
+// @Success 200 {object} http.GeneralResponse{data=entity.JoinRequest}
+// @Failure 400,403,404 {object} http.ErrorResponse
+// @Router /v1/chatrooms/{id}/requests/{requestId}/approve [post]
+func (h *ChatroomHandler) ApproveJoinRequest(c *fiber.Ctx) error {
+	return h.reviewJoinRequest(c, true)
+}
+
+// RejectJoinRequest godoc
+// @Summary Reject a squad join request
+// @Description Rejects a pending join request
+// @Tags chatrooms
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Param id path string true "Chatroom ID"
+// @Param requestId path string true "Join request ID"
+// @Success 200 {object} http.GeneralResponse{data=entity.JoinRequest}
+// @Failure 400,403,404 {object} http.ErrorResponse
+// @Router /v1/chatrooms/{id}/requests/{requestId}/reject [post]
+func (h *ChatroomHandler) RejectJoinRequest(c *fiber.Ctx) error {
+	return h.reviewJoinRequest(c, false)
+}
+
+// reviewJoinRequest approves or rejects the join request of the current route
+func (h *ChatroomHandler) reviewJoinRequest(c *fiber.Ctx, approve bool) error {
+	user := c.Locals("user").(*entity.User)
+
+	params := squad.ReviewJoinRequestParams{
+		ChatroomID: c.Params("id"),
+		RequestID:  c.Params("requestId"),
+		ActorID:    user.ID,
+		Approve:    approve,
+	}
+
+	request, err := h.squadService.ReviewJoinRequest(c.Context(), params)
+	if err != nil {
+		return err
+	}
+
+	message := "Join request rejected successfully"
+	if approve {
+		message = "Join request approved successfully"
+	}
+
+	return c.JSON(http.GeneralResponse{
+		Status:  fiber.StatusOK,
+		Message: message,
+		Data:    request,
+	})
+}
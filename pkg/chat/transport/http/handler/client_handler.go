@@ -4,24 +4,28 @@ import (
 	"app/pkg/chat/domain/entity"
 	"app/pkg/chat/service/client"
 	"app/pkg/chat/transport/http/dto"
+	chatMiddleware "app/pkg/chat/transport/http/middleware"
 	"app/pkg/exception"
 	"app/pkg/middleware"
 	"app/pkg/types/http"
 	"app/pkg/types/pagination"
 	"strconv"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 )
 
 type ClientHandler struct {
-	clientService client.ClientService
-	keyMiddleware *middleware.KeyMiddleware
+	clientService    client.ClientService
+	keyMiddleware    *middleware.KeyMiddleware
+	clientMiddleware *chatMiddleware.ClientMiddleware
 }
 
-func NewClientHandler(clientService client.ClientService, keyMiddleware *middleware.KeyMiddleware) *ClientHandler {
+func NewClientHandler(clientService client.ClientService, keyMiddleware *middleware.KeyMiddleware, clientMiddleware *chatMiddleware.ClientMiddleware) *ClientHandler {
 	return &ClientHandler{
-		clientService: clientService,
-		keyMiddleware: keyMiddleware,
+		clientService:    clientService,
+		keyMiddleware:    keyMiddleware,
+		clientMiddleware: clientMiddleware,
 	}
 }
 
@@ -33,6 +37,9 @@ func (h *ClientHandler) RegisterRoutes(app fiber.Router) {
 	clients := v1.Group("/clients")
 	clients.Get("/validate", h.ValidateClientKey) // For testing client keys
 
+	// Client protected routes
+	clients.Post("/tokens/revoke", h.clientMiddleware.ValidateKey(), h.RevokeToken) // Revoke a user token, e.g. on logout
+
 	// Admin protected routes
 	adminClients := v1.Group("/admin/clients", h.keyMiddleware.ValidateKey())
 	adminClients.Post("/", h.CreateClient)
@@ -40,6 +47,11 @@ func (h *ClientHandler) RegisterRoutes(app fiber.Router) {
 	adminClients.Get("/:id", h.GetClient)
 	adminClients.Put("/:id", h.UpdateClient)
 	adminClients.Delete("/:id", h.DeleteClient)
+	adminClients.Get("/:id/keys", h.GetClientKeys)
+	adminClients.Post("/:id/keys", h.CreateClientKey)
+	adminClients.Post("/:id/keys/:keyId/rotate", h.RotateClientKey)
+	adminClients.Delete("/:id/keys/:keyId", h.RevokeClientKey)
+	adminClients.Post("/:id/webhook-secret/rotate", h.RotateWebhookSecret)
 }
 
 // ValidateClientKey godoc
@@ -54,7 +66,7 @@ func (h *ClientHandler) RegisterRoutes(app fiber.Router) {
 // @Router /v1/clients/validate [get]
 func (h *ClientHandler) ValidateClientKey(c *fiber.Ctx) error {
 	clientKey := c.Get("X-Client-Key")
-	client, err := h.clientService.ValidateKey(c.Context(), clientKey)
+	client, err := h.clientService.ValidateKey(c.Context(), clientKey, "")
 	if err != nil {
 		return err
 	}
@@ -67,13 +79,13 @@ func (h *ClientHandler) ValidateClientKey(c *fiber.Ctx) error {
 
 // CreateClient godoc
 // @Summary Create a new client
-// @Description Creates a new client with the provided details
+// @Description Creates a new client with the provided details, its first key and webhook secret are only returned here
 // @Tags clients
 // @Accept json
 // @Produce json
 // @Security ApiKeyAuth
 // @Param client body dto.CreateClientRequest true "Client details"
-// @Success 201 {object} http.GeneralResponse{data=entity.Client}
+// @Success 201 {object} http.GeneralResponse{data=map[string]interface{}}
 // @Failure 400 {object} http.ErrorResponse
 // @Router /v1/admin/clients [post]
 func (h *ClientHandler) CreateClient(c *fiber.Ctx) error {
@@ -83,21 +95,28 @@ func (h *ClientHandler) CreateClient(c *fiber.Ctx) error {
 	}
 
 	client := &entity.Client{
-		Name:         req.Name,
-		Description:  req.Description,
-		ClientKey:    req.ClientKey,
-		AuthEndpoint: req.AuthEndpoint,
-		Status:       "active", // Default status for new clients
+		Name:            req.Name,
+		Description:     req.Description,
+		AuthEndpoint:    req.AuthEndpoint,
+		Auth:            toClientAuth(req.Auth, entity.ClientAuth{}),
+		WebhookEndpoint: req.WebhookEndpoint,
+		ErasurePolicy:   entity.ErasurePolicy(req.ErasurePolicy),
+		Status:          "active", // Default status for new clients
 	}
 
-	if err := h.clientService.CreateClient(c.Context(), client); err != nil {
+	key, err := h.clientService.CreateClient(c.Context(), client)
+	if err != nil {
 		return err
 	}
 
 	return c.Status(fiber.StatusCreated).JSON(http.GeneralResponse{
 		Status:  fiber.StatusCreated,
 		Message: "Client created successfully",
-		Data:    client,
+		Data: map[string]interface{}{
+			"client":        client,
+			"key":           key,
+			"webhookSecret": client.WebhookSecret,
+		},
 	})
 }
 
@@ -202,12 +221,17 @@ func (h *ClientHandler) UpdateClient(c *fiber.Ctx) error {
 	}
 
 	client := &entity.Client{
-		ID:           id,
-		Name:         req.Name,
-		Description:  req.Description,
-		ClientKey:    req.ClientKey,
-		AuthEndpoint: req.AuthEndpoint,
-		Status:       req.Status,
+		ID:               id,
+		Name:             req.Name,
+		Description:      req.Description,
+		LegacyKey:        existingClient.LegacyKey,
+		AuthEndpoint:     req.AuthEndpoint,
+		Auth:             toClientAuth(req.Auth, existingClient.Auth),
+		WebhookEndpoint:  req.WebhookEndpoint,
+		WebhookSecret:    existingClient.WebhookSecret,
+		ErasurePolicy:    entity.ErasurePolicy(req.ErasurePolicy),
+		Status:           req.Status,
+		CreatedTimestamp: existingClient.CreatedTimestamp,
 	}
 
 	if err := h.clientService.UpdateClient(c.Context(), client); err != nil {
@@ -253,3 +277,215 @@ func (h *ClientHandler) DeleteClient(c *fiber.Ctx) error {
 		Message: "Client deleted successfully",
 	})
 }
+
+// RevokeToken godoc
+// @Summary Revoke a user token
+// @Description Rejects a user token issued by the client from now on, e.g. when the user logs out
+// @Tags clients
+// @Accept json
+// @Produce json
+// @Param X-Client-Key header string true "Client Key"
+// @Param token body dto.RevokeTokenRequest true "Token to revoke"
+// @Success 200 {object} http.GeneralResponse
+// @Failure 400,401 {object} http.ErrorResponse
+// @Router /v1/clients/tokens/revoke [post]
+func (h *ClientHandler) RevokeToken(c *fiber.Ctx) error {
+	client := c.Locals("client").(*entity.Client)
+
+	var req dto.RevokeTokenRequest
+	if err := c.BodyParser(&req); err != nil {
+		return exception.BadRequest("Invalid request body")
+	}
+
+	if err := h.clientService.RevokeToken(c.Context(), client.ID, req.Token); err != nil {
+		return err
+	}
+
+	return c.JSON(http.GeneralResponse{
+		Status:  fiber.StatusOK,
+		Message: "Token revoked successfully",
+	})
+}
+
+// GetClientKeys godoc
+// @Summary Get client keys
+// @Description Retrieves all keys of a client, including expired and revoked ones
+// @Tags clients
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path string true "Client ID"
+// @Success 200 {object} http.GeneralResponse{data=[]entity.ClientKey}
+// @Failure 404 {object} http.ErrorResponse
+// @Router /v1/admin/clients/{id}/keys [get]
+func (h *ClientHandler) GetClientKeys(c *fiber.Ctx) error {
+	id := c.Params("id")
+
+	// Check if client exists
+	existingClient, err := h.clientService.GetClient(c.Context(), id)
+	if err != nil {
+		return err
+	}
+	if existingClient == nil {
+		return exception.NotFound("Client")
+	}
+
+	keys, err := h.clientService.GetKeys(c.Context(), id)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.GeneralResponse{
+		Status:  fiber.StatusOK,
+		Message: "Client keys fetched successfully",
+		Data:    keys,
+	})
+}
+
+// CreateClientKey godoc
+// @Summary Create a client key
+// @Description Creates a new key for a client, the key is only returned here
+// @Tags clients
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path string true "Client ID"
+// @Param key body dto.CreateClientKeyRequest true "Key details"
+// @Success 201 {object} http.GeneralResponse{data=client.IssuedKey}
+// @Failure 400,404 {object} http.ErrorResponse
+// @Router /v1/admin/clients/{id}/keys [post]
+func (h *ClientHandler) CreateClientKey(c *fiber.Ctx) error {
+	var req dto.CreateClientKeyRequest
+	if err := c.BodyParser(&req); err != nil {
+		return exception.BadRequest("Invalid request body")
+	}
+
+	scopes := make([]entity.ClientKeyScope, len(req.Scopes))
+	for i, scope := range req.Scopes {
+		scopes[i] = entity.ClientKeyScope(scope)
+	}
+
+	key, err := h.clientService.CreateKey(c.Context(), client.CreateKeyParams{
+		ClientID:  c.Params("id"),
+		Name:      req.Name,
+		Scopes:    scopes,
+		ExpiresIn: time.Duration(req.ExpiresIn) * time.Minute,
+	})
+	if err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(http.GeneralResponse{
+		Status:  fiber.StatusCreated,
+		Message: "Client key created successfully",
+		Data:    key,
+	})
+}
+
+// RotateClientKey godoc
+// @Summary Rotate a client key
+// @Description Replaces a client key with a new one with the same name and scopes, the replaced key keeps working for the grace period
+// @Tags clients
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path string true "Client ID"
+// @Param keyId path string true "Client key ID"
+// @Param rotation body dto.RotateClientKeyRequest true "Rotation details"
+// @Success 201 {object} http.GeneralResponse{data=client.IssuedKey}
+// @Failure 400,404 {object} http.ErrorResponse
+// @Router /v1/admin/clients/{id}/keys/{keyId}/rotate [post]
+func (h *ClientHandler) RotateClientKey(c *fiber.Ctx) error {
+	var req dto.RotateClientKeyRequest
+	if err := c.BodyParser(&req); err != nil {
+		return exception.BadRequest("Invalid request body")
+	}
+
+	key, err := h.clientService.RotateKey(c.Context(), client.RotateKeyParams{
+		ClientID:    c.Params("id"),
+		KeyID:       c.Params("keyId"),
+		GracePeriod: time.Duration(req.GracePeriod) * time.Minute,
+	})
+	if err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(http.GeneralResponse{
+		Status:  fiber.StatusCreated,
+		Message: "Client key rotated successfully",
+		Data:    key,
+	})
+}
+
+// RevokeClientKey godoc
+// @Summary Revoke a client key
+// @Description Stops a client key from working right away
+// @Tags clients
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path string true "Client ID"
+// @Param keyId path string true "Client key ID"
+// @Success 200 {object} http.GeneralResponse
+// @Failure 404 {object} http.ErrorResponse
+// @Router /v1/admin/clients/{id}/keys/{keyId} [delete]
+func (h *ClientHandler) RevokeClientKey(c *fiber.Ctx) error {
+	if err := h.clientService.RevokeKey(c.Context(), c.Params("id"), c.Params("keyId")); err != nil {
+		return err
+	}
+
+	return c.JSON(http.GeneralResponse{
+		Status:  fiber.StatusOK,
+		Message: "Client key revoked successfully",
+	})
+}
+
+// RotateWebhookSecret godoc
+// @Summary Rotate a client's webhook secret
+// @Description Replaces the secret webhook requests to the client are signed with, the new secret is only returned here
+// @Tags clients
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path string true "Client ID"
+// @Success 200 {object} http.GeneralResponse{data=map[string]string}
+// @Failure 404 {object} http.ErrorResponse
+// @Router /v1/admin/clients/{id}/webhook-secret/rotate [post]
+func (h *ClientHandler) RotateWebhookSecret(c *fiber.Ctx) error {
+	secret, err := h.clientService.RotateWebhookSecret(c.Context(), c.Params("id"))
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.GeneralResponse{
+		Status:  fiber.StatusOK,
+		Message: "Webhook secret rotated successfully",
+		Data: map[string]string{
+			"webhookSecret": secret,
+		},
+	})
+}
+
+// toClientAuth converts an auth request into a client's auth settings
+// The current secret is kept when the request omits it and doesn't change the strategy
+func toClientAuth(req *dto.ClientAuthRequest, current entity.ClientAuth) entity.ClientAuth {
+	if req == nil {
+		return entity.ClientAuth{}
+	}
+
+	auth := entity.ClientAuth{
+		Strategy:              entity.AuthStrategy(req.Strategy),
+		Secret:                req.Secret,
+		PublicKey:             req.PublicKey,
+		JWKSURL:               req.JWKSURL,
+		IntrospectionEndpoint: req.IntrospectionEndpoint,
+		IntrospectionClientID: req.IntrospectionClientID,
+		Issuer:                req.Issuer,
+		Audience:              req.Audience,
+	}
+	if auth.Secret == "" && auth.Strategy == current.Strategy {
+		auth.Secret = current.Secret
+	}
+
+	return auth
+}
@@ -0,0 +1,321 @@
+package handler
+
+import (
+	"app/pkg/chat/domain/entity"
+	"app/pkg/chat/domain/repository"
+	"app/pkg/chat/service/gdpr"
+	"app/pkg/chat/transport/http/dto"
+	chatMiddleware "app/pkg/chat/transport/http/middleware"
+	"app/pkg/exception"
+	"app/pkg/middleware"
+	"app/pkg/types/http"
+	"app/pkg/types/pagination"
+	"fmt"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type GDPRHandler struct {
+	gdprService      gdpr.GDPRService
+	keyMiddleware    *middleware.KeyMiddleware
+	clientMiddleware *chatMiddleware.ClientMiddleware
+	authMiddleware   *chatMiddleware.AuthMiddleware
+}
+
+func NewGDPRHandler(gdprService gdpr.GDPRService, keyMiddleware *middleware.KeyMiddleware, clientMiddleware *chatMiddleware.ClientMiddleware, authMiddleware *chatMiddleware.AuthMiddleware) *GDPRHandler {
+	return &GDPRHandler{
+		gdprService:      gdprService,
+		keyMiddleware:    keyMiddleware,
+		clientMiddleware: clientMiddleware,
+		authMiddleware:   authMiddleware,
+	}
+}
+
+// RegisterRoutes registers all routes for user data exports and erasures
+func (h *GDPRHandler) RegisterRoutes(app fiber.Router) {
+	v1 := app.Group("/v1")
+
+	// Protected user routes (requires client key and user authentication)
+	exports := v1.Group("/exports", h.clientMiddleware.ValidateKey(), h.authMiddleware.Authenticate())
+	exports.Post("/", h.RequestExport)             // Export the current user's data
+	exports.Get("/", h.GetExports)                 // List the current user's exports
+	exports.Get("/:id", h.GetExport)               // Poll an export
+	exports.Get("/:id/download", h.DownloadExport) // Download a completed export
+
+	// Admin protected routes
+	adminJobs := v1.Group("/admin/data-jobs", h.keyMiddleware.ValidateKey())
+	adminJobs.Post("/", h.CreateJob)
+	adminJobs.Get("/", h.GetJobs)
+	adminJobs.Get("/:id", h.GetJob)
+	adminJobs.Get("/:id/download", h.DownloadJob)
+}
+
+// RequestExport godoc
+// @Summary Request a data export
+// @Description Queues an export of the current user's profile, chatroom memberships and chat messages as JSON Lines. A pending or running export is returned instead of queueing another one
+// @Tags exports
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Success 202 {object} http.GeneralResponse{data=entity.DataJob}
+// @Failure 401 {object} http.ErrorResponse
+// @Router /v1/exports [post]
+func (h *GDPRHandler) RequestExport(c *fiber.Ctx) error {
+	user := c.Locals("user").(*entity.User)
+
+	params := gdpr.RequestJobParams{
+		UserID:      user.ID,
+		Type:        entity.DataJobTypeExport,
+		RequestedBy: user.ID,
+	}
+
+	job, err := h.gdprService.RequestJob(c.Context(), params)
+	if err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusAccepted).JSON(http.GeneralResponse{
+		Status:  fiber.StatusAccepted,
+		Message: "Export requested successfully",
+		Data:    job,
+	})
+}
+
+// GetExports godoc
+// @Summary Get data exports
+// @Description Retrieves the current user's exports, newest first
+// @Tags exports
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Param page query int false "Page number"
+// @Param limit query int false "Items per page"
+// @Success 200 {object} http.GeneralResponse{data=http.PaginatedResponse{result=[]entity.DataJob}}
+// @Failure 401 {object} http.ErrorResponse
+// @Router /v1/exports [get]
+func (h *GDPRHandler) GetExports(c *fiber.Ctx) error {
+	user := c.Locals("user").(*entity.User)
+	jobType := entity.DataJobTypeExport
+
+	filter := repository.DataJobFilter{
+		UserID: user.ID,
+		Type:   &jobType,
+	}
+
+	return h.listJobs(c, filter, "Exports fetched successfully")
+}
+
+// GetExport godoc
+// @Summary Get a data export
+// @Description Retrieves one of the current user's exports, poll it until its status is completed or failed
+// @Tags exports
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Param id path string true "Export ID"
+// @Success 200 {object} http.GeneralResponse{data=entity.DataJob}
+// @Failure 401,404 {object} http.ErrorResponse
+// @Router /v1/exports/{id} [get]
+func (h *GDPRHandler) GetExport(c *fiber.Ctx) error {
+	user := c.Locals("user").(*entity.User)
+
+	job, err := h.gdprService.GetJob(c.Context(), c.Params("id"))
+	if err != nil {
+		return err
+	}
+	if job.UserID != user.ID || job.Type != entity.DataJobTypeExport {
+		return exception.NotFound("Export")
+	}
+
+	return c.JSON(http.GeneralResponse{
+		Status:  fiber.StatusOK,
+		Message: "Export retrieved successfully",
+		Data:    job,
+	})
+}
+
+// DownloadExport godoc
+// @Summary Download a data export
+// @Description Streams the JSON Lines file of one of the current user's completed exports
+// @Tags exports
+// @Produce application/x-ndjson
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Param id path string true "Export ID"
+// @Success 200 {file} file
+// @Failure 401,404,409,410 {object} http.ErrorResponse
+// @Router /v1/exports/{id}/download [get]
+func (h *GDPRHandler) DownloadExport(c *fiber.Ctx) error {
+	user := c.Locals("user").(*entity.User)
+
+	job, err := h.gdprService.GetJob(c.Context(), c.Params("id"))
+	if err != nil {
+		return err
+	}
+	if job.UserID != user.ID {
+		return exception.NotFound("Export")
+	}
+
+	return h.download(c, job.ID)
+}
+
+// CreateJob godoc
+// @Summary Request a data export or erasure
+// @Description Queues an export of a user's data, or an erasure anonymizing the user and tombstoning or deleting their messages following their client's erasure policy. A pending or running job of the same type is returned instead of queueing another one
+// @Tags data-jobs
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param job body dto.CreateDataJobRequest true "Job details"
+// @Success 202 {object} http.GeneralResponse{data=entity.DataJob}
+// @Failure 400,401,404 {object} http.ErrorResponse
+// @Router /v1/admin/data-jobs [post]
+func (h *GDPRHandler) CreateJob(c *fiber.Ctx) error {
+	var req dto.CreateDataJobRequest
+	if err := c.BodyParser(&req); err != nil {
+		return exception.BadRequest("Invalid request body")
+	}
+	if req.UserID == "" {
+		return exception.BadRequest("User ID is required")
+	}
+
+	params := gdpr.RequestJobParams{
+		UserID:      req.UserID,
+		Type:        entity.DataJobType(req.Type),
+		RequestedBy: gdpr.RequestedByAdmin,
+	}
+
+	job, err := h.gdprService.RequestJob(c.Context(), params)
+	if err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusAccepted).JSON(http.GeneralResponse{
+		Status:  fiber.StatusAccepted,
+		Message: "Data job requested successfully",
+		Data:    job,
+	})
+}
+
+// GetJobs godoc
+// @Summary Get data jobs
+// @Description Retrieves exports and erasures with filtering and pagination, newest first
+// @Tags data-jobs
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param clientId query string false "Filter by client ID"
+// @Param userId query string false "Filter by user ID"
+// @Param type query string false "Filter by type (export, erasure)"
+// @Param status query string false "Filter by status (pending, running, completed, failed, expired)"
+// @Param page query int false "Page number"
+// @Param limit query int false "Items per page"
+// @Success 200 {object} http.GeneralResponse{data=http.PaginatedResponse{result=[]entity.DataJob}}
+// @Failure 401 {object} http.ErrorResponse
+// @Router /v1/admin/data-jobs [get]
+func (h *GDPRHandler) GetJobs(c *fiber.Ctx) error {
+	filter := repository.DataJobFilter{
+		ClientID: c.Query("clientId"),
+		UserID:   c.Query("userId"),
+	}
+	if jobType := c.Query("type"); jobType != "" {
+		dataJobType := entity.DataJobType(jobType)
+		filter.Type = &dataJobType
+	}
+	if status := c.Query("status"); status != "" {
+		dataJobStatus := entity.DataJobStatus(status)
+		filter.Status = &dataJobStatus
+	}
+
+	return h.listJobs(c, filter, "Data jobs fetched successfully")
+}
+
+// GetJob godoc
+// @Summary Get a data job
+// @Description Retrieves an export or erasure, poll it until its status is completed or failed
+// @Tags data-jobs
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path string true "Data job ID"
+// @Success 200 {object} http.GeneralResponse{data=entity.DataJob}
+// @Failure 401,404 {object} http.ErrorResponse
+// @Router /v1/admin/data-jobs/{id} [get]
+func (h *GDPRHandler) GetJob(c *fiber.Ctx) error {
+	job, err := h.gdprService.GetJob(c.Context(), c.Params("id"))
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.GeneralResponse{
+		Status:  fiber.StatusOK,
+		Message: "Data job retrieved successfully",
+		Data:    job,
+	})
+}
+
+// DownloadJob godoc
+// @Summary Download a data export
+// @Description Streams the JSON Lines file of a completed export
+// @Tags data-jobs
+// @Produce application/x-ndjson
+// @Security ApiKeyAuth
+// @Param id path string true "Data job ID"
+// @Success 200 {file} file
+// @Failure 401,404,409,410 {object} http.ErrorResponse
+// @Router /v1/admin/data-jobs/{id}/download [get]
+func (h *GDPRHandler) DownloadJob(c *fiber.Ctx) error {
+	return h.download(c, c.Params("id"))
+}
+
+// listJobs responds with a page of data jobs
+func (h *GDPRHandler) listJobs(c *fiber.Ctx, filter repository.DataJobFilter, message string) error {
+	page, _ := strconv.Atoi(c.Query("page", "1"))
+	limit, _ := strconv.Atoi(c.Query("limit", "10"))
+
+	pag := pagination.Pagination{
+		Page:  page,
+		Limit: limit,
+	}
+
+	jobs, total, err := h.gdprService.GetJobs(c.Context(), filter, pag)
+	if err != nil {
+		return err
+	}
+
+	metadata := pagination.Metadata{
+		Pagination: pag,
+		Total:      total,
+		Count:      len(jobs),
+		HasPrev:    page > 1,
+		HasNext:    len(jobs) > 0 && int64(page*limit) < total,
+	}
+
+	return c.JSON(http.GeneralResponse{
+		Status:  fiber.StatusOK,
+		Message: message,
+		Data: map[string]interface{}{
+			"metadata": metadata,
+			"result":   jobs,
+		},
+	})
+}
+
+// download streams the file of a completed export
+func (h *GDPRHandler) download(c *fiber.Ctx, id string) error {
+	job, reader, err := h.gdprService.OpenExport(c.Context(), id)
+	if err != nil {
+		return err
+	}
+
+	c.Set(fiber.HeaderContentType, "application/x-ndjson")
+	c.Set(fiber.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", fmt.Sprintf("export-%s.jsonl", job.ID)))
+	c.Set(fiber.HeaderXContentTypeOptions, "nosniff")
+
+	// The stream is closed by fasthttp once the response has been written
+	return c.SendStream(reader, int(job.Size))
+}
@@ -0,0 +1,162 @@
+package handler
+
+import (
+	"app/pkg/chat/domain/entity"
+	"app/pkg/chat/domain/repository"
+	"app/pkg/chat/service/moderation"
+	"app/pkg/chat/transport/http/dto"
+	"app/pkg/exception"
+	"app/pkg/middleware"
+	"app/pkg/types/http"
+	"app/pkg/types/pagination"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type ModerationHandler struct {
+	moderationService moderation.ModerationService
+	keyMiddleware     *middleware.KeyMiddleware
+}
+
+func NewModerationHandler(moderationService moderation.ModerationService, keyMiddleware *middleware.KeyMiddleware) *ModerationHandler {
+	return &ModerationHandler{
+		moderationService: moderationService,
+		keyMiddleware:     keyMiddleware,
+	}
+}
+
+// RegisterRoutes registers all routes for moderation management
+func (h *ModerationHandler) RegisterRoutes(app fiber.Router) {
+	v1 := app.Group("/v1")
+
+	// Admin protected routes
+	adminModeration := v1.Group("/admin/moderation", h.keyMiddleware.ValidateKey())
+	adminModeration.Get("/decisions", h.GetDecisions)
+	adminModeration.Get("/chatrooms/:id", h.GetSettings)
+	adminModeration.Put("/chatrooms/:id", h.UpdateSettings)
+}
+
+// GetDecisions godoc
+// @Summary Get moderation decisions
+// @Description Retrieves masked and rejected messages, newest first
+// @Tags moderation
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param chatroomId query string false "Filter by chatroom ID"
+// @Param senderId query string false "Filter by sender ID"
+// @Param clientId query string false "Filter by client ID"
+// @Param action query string false "Filter by action (mask, reject)"
+// @Param page query int false "Page number"
+// @Param limit query int false "Items per page"
+// @Success 200 {object} http.GeneralResponse{data=http.PaginatedResponse{result=[]entity.ModerationDecision}}
+// @Failure 401 {object} http.ErrorResponse
+// @Router /v1/admin/moderation/decisions [get]
+func (h *ModerationHandler) GetDecisions(c *fiber.Ctx) error {
+	page, _ := strconv.Atoi(c.Query("page", "1"))
+	limit, _ := strconv.Atoi(c.Query("limit", "10"))
+
+	pag := pagination.Pagination{
+		Page:  page,
+		Limit: limit,
+	}
+
+	filter := repository.ModerationDecisionFilter{
+		ChatroomID: c.Query("chatroomId"),
+		SenderID:   c.Query("senderId"),
+		ClientID:   c.Query("clientId"),
+	}
+	if action := c.Query("action"); action != "" {
+		moderationAction := entity.ModerationAction(action)
+		filter.Action = &moderationAction
+	}
+
+	decisions, total, err := h.moderationService.GetDecisions(c.Context(), filter, pag)
+	if err != nil {
+		return err
+	}
+
+	metadata := pagination.Metadata{
+		Pagination: pag,
+		Total:      total,
+		Count:      len(decisions),
+		HasPrev:    page > 1,
+		HasNext:    len(decisions) > 0 && int64(page*limit) < total,
+	}
+
+	return c.JSON(http.GeneralResponse{
+		Status:  fiber.StatusOK,
+		Message: "Moderation decisions fetched successfully",
+		Data: map[string]interface{}{
+			"metadata": metadata,
+			"result":   decisions,
+		},
+	})
+}
+
+// GetSettings godoc
+// @Summary Get chatroom moderation settings
+// @Description Retrieves a chatroom's moderation settings, defaults are returned if none are stored
+// @Tags moderation
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path string true "Chatroom ID"
+// @Success 200 {object} http.GeneralResponse{data=entity.ModerationSettings}
+// @Failure 401 {object} http.ErrorResponse
+// @Router /v1/admin/moderation/chatrooms/{id} [get]
+func (h *ModerationHandler) GetSettings(c *fiber.Ctx) error {
+	settings, err := h.moderationService.GetSettings(c.Context(), c.Params("id"))
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.GeneralResponse{
+		Status:  fiber.StatusOK,
+		Message: "Moderation settings retrieved successfully",
+		Data:    settings,
+	})
+}
+
+// UpdateSettings godoc
+// @Summary Update chatroom moderation settings
+// @Description Replaces a chatroom's moderation settings, which add to the service wide word and domain lists
+// @Tags moderation
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path string true "Chatroom ID"
+// @Param settings body dto.UpdateModerationSettingsRequest true "Moderation settings"
+// @Success 200 {object} http.GeneralResponse{data=entity.ModerationSettings}
+// @Failure 400,401,404 {object} http.ErrorResponse
+// @Router /v1/admin/moderation/chatrooms/{id} [put]
+func (h *ModerationHandler) UpdateSettings(c *fiber.Ctx) error {
+	var req dto.UpdateModerationSettingsRequest
+	if err := c.BodyParser(&req); err != nil {
+		return exception.BadRequest("Invalid request body")
+	}
+
+	params := moderation.UpdateSettingsParams{
+		ChatroomID:     c.Params("id"),
+		Disabled:       req.Disabled,
+		BlockedWords:   req.BlockedWords,
+		WordAction:     entity.ModerationAction(req.WordAction),
+		AllowedDomains: req.AllowedDomains,
+		BlockedDomains: req.BlockedDomains,
+		LinkAction:     entity.ModerationAction(req.LinkAction),
+		RepeatLimit:    req.RepeatLimit,
+		RepeatWindow:   req.RepeatWindow,
+	}
+
+	settings, err := h.moderationService.UpdateSettings(c.Context(), params)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.GeneralResponse{
+		Status:  fiber.StatusOK,
+		Message: "Moderation settings updated successfully",
+		Data:    settings,
+	})
+}
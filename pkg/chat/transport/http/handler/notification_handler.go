@@ -0,0 +1,199 @@
+package handler
+
+import (
+	"app/pkg/chat/domain/entity"
+	"app/pkg/chat/service/notification"
+	"app/pkg/chat/transport/http/dto"
+	chatMiddleware "app/pkg/chat/transport/http/middleware"
+	"app/pkg/exception"
+	"app/pkg/middleware"
+	"app/pkg/types/http"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type NotificationHandler struct {
+	notificationService notification.NotificationService
+	keyMiddleware       *middleware.KeyMiddleware
+	clientMiddleware    *chatMiddleware.ClientMiddleware
+	authMiddleware      *chatMiddleware.AuthMiddleware
+}
+
+func NewNotificationHandler(notificationService notification.NotificationService, keyMiddleware *middleware.KeyMiddleware, clientMiddleware *chatMiddleware.ClientMiddleware, authMiddleware *chatMiddleware.AuthMiddleware) *NotificationHandler {
+	return &NotificationHandler{
+		notificationService: notificationService,
+		keyMiddleware:       keyMiddleware,
+		clientMiddleware:    clientMiddleware,
+		authMiddleware:      authMiddleware,
+	}
+}
+
+// RegisterRoutes registers all routes for notification preferences
+func (h *NotificationHandler) RegisterRoutes(app fiber.Router) {
+	v1 := app.Group("/v1")
+
+	// Protected notification routes (requires client key and user authentication)
+	notifications := v1.Group("/notifications", h.clientMiddleware.ValidateKey(), h.authMiddleware.Authenticate())
+	notifications.Get("/preferences", h.GetPreference)    // Get current user's notification preferences
+	notifications.Put("/preferences", h.UpdatePreference) // Replace current user's notification preferences
+	notifications.Post("/telegram", h.CreateTelegramLink) // Issue a code linking a Telegram chat
+	notifications.Delete("/telegram", h.UnlinkTelegram)   // Unlink the current user's Telegram chat
+
+	// Admin protected routes, called by the telegram service
+	admin := v1.Group("/admin/notifications", h.keyMiddleware.ValidateKey())
+	admin.Post("/telegram", h.LinkTelegram)
+}
+
+// GetPreference godoc
+// @Summary Get notification preferences
+// @Description Retrieves the current user's preferences for notifications about messages sent while offline
+// @Tags notifications
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Success 200 {object} http.GeneralResponse{data=entity.NotificationPreference}
+// @Failure 401 {object} http.ErrorResponse
+// @Router /v1/notifications/preferences [get]
+func (h *NotificationHandler) GetPreference(c *fiber.Ctx) error {
+	user := c.Locals("user").(*entity.User)
+
+	preference, err := h.notificationService.GetPreference(c.Context(), user.ID)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.GeneralResponse{
+		Status:  fiber.StatusOK,
+		Message: "Notification preferences retrieved successfully",
+		Data:    preference,
+	})
+}
+
+// UpdatePreference godoc
+// @Summary Update notification preferences
+// @Description Replaces the current user's notification preferences, muting all notifications, chatrooms or channels. The linked Telegram chat is kept
+// @Tags notifications
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Param preferences body dto.UpdateNotificationPreferenceRequest true "Notification preferences"
+// @Success 200 {object} http.GeneralResponse{data=entity.NotificationPreference}
+// @Failure 400 {object} http.ErrorResponse
+// @Failure 401 {object} http.ErrorResponse
+// @Router /v1/notifications/preferences [put]
+func (h *NotificationHandler) UpdatePreference(c *fiber.Ctx) error {
+	user := c.Locals("user").(*entity.User)
+
+	var req dto.UpdateNotificationPreferenceRequest
+	if err := c.BodyParser(&req); err != nil {
+		return exception.BadRequest("Invalid request body")
+	}
+
+	channels := make([]entity.NotificationChannel, 0, len(req.DisabledChannels))
+	for _, channel := range req.DisabledChannels {
+		channels = append(channels, entity.NotificationChannel(channel))
+	}
+
+	params := notification.UpdatePreferenceParams{
+		UserID:           user.ID,
+		Muted:            req.Muted,
+		MutedChatrooms:   req.MutedChatrooms,
+		DisabledChannels: channels,
+	}
+
+	preference, err := h.notificationService.UpdatePreference(c.Context(), params)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.GeneralResponse{
+		Status:  fiber.StatusOK,
+		Message: "Notification preferences updated successfully",
+		Data:    preference,
+	})
+}
+
+// CreateTelegramLink godoc
+// @Summary Create a Telegram link code
+// @Description Issues a one-time code linking a Telegram chat to the current user's notifications. The user sends it to the notification bot as /start <code> within 10 minutes, replacing any chat linked before
+// @Tags notifications
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Success 201 {object} http.GeneralResponse{data=entity.TelegramLink}
+// @Failure 401,503 {object} http.ErrorResponse
+// @Router /v1/notifications/telegram [post]
+func (h *NotificationHandler) CreateTelegramLink(c *fiber.Ctx) error {
+	user := c.Locals("user").(*entity.User)
+
+	link, err := h.notificationService.CreateTelegramLink(c.Context(), user.ID)
+	if err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(http.GeneralResponse{
+		Status:  fiber.StatusCreated,
+		Message: "Telegram link code created successfully",
+		Data:    link,
+	})
+}
+
+// UnlinkTelegram godoc
+// @Summary Unlink Telegram
+// @Description Stops sending the current user's notifications to their Telegram chat
+// @Tags notifications
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Success 200 {object} http.GeneralResponse
+// @Failure 401 {object} http.ErrorResponse
+// @Router /v1/notifications/telegram [delete]
+func (h *NotificationHandler) UnlinkTelegram(c *fiber.Ctx) error {
+	user := c.Locals("user").(*entity.User)
+
+	if err := h.notificationService.UnlinkTelegram(c.Context(), user.ID); err != nil {
+		return err
+	}
+
+	return c.JSON(http.GeneralResponse{
+		Status:  fiber.StatusOK,
+		Message: "Telegram unlinked successfully",
+	})
+}
+
+// LinkTelegram godoc
+// @Summary Link a Telegram chat
+// @Description Links the Telegram chat that sent a link code to the notification bot to the user the code was issued to. Called by the telegram service
+// @Tags notifications
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param link body dto.LinkTelegramRequest true "Link code received by the bot"
+// @Success 200 {object} http.GeneralResponse
+// @Failure 400,401,403,404,503 {object} http.ErrorResponse
+// @Router /v1/admin/notifications/telegram [post]
+func (h *NotificationHandler) LinkTelegram(c *fiber.Ctx) error {
+	var req dto.LinkTelegramRequest
+	if err := c.BodyParser(&req); err != nil {
+		return exception.BadRequest("Invalid request body")
+	}
+
+	params := notification.LinkTelegramParams{
+		BotID:  req.BotID,
+		ChatID: req.ChatID,
+		Code:   req.Code,
+	}
+
+	if err := h.notificationService.LinkTelegram(c.Context(), params); err != nil {
+		return err
+	}
+
+	return c.JSON(http.GeneralResponse{
+		Status:  fiber.StatusOK,
+		Message: "Telegram linked successfully",
+	})
+}
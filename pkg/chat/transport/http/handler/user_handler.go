@@ -2,6 +2,9 @@ package handler
 
 import (
 	"app/pkg/chat/domain/entity"
+	"app/pkg/chat/domain/repository"
+	"app/pkg/chat/service/presence"
+	"app/pkg/chat/service/privacy"
 	"app/pkg/chat/service/user"
 	"app/pkg/chat/transport/http/dto"
 	"app/pkg/chat/transport/http/middleware"
@@ -9,19 +12,24 @@ import (
 	"app/pkg/types/http"
 	"app/pkg/types/pagination"
 	"strconv"
+	"strings"
 
 	"github.com/gofiber/fiber/v2"
 )
 
 type UserHandler struct {
 	userService      user.UserService
+	privacyService   privacy.PrivacyService
+	presenceService  presence.PresenceService
 	clientMiddleware *middleware.ClientMiddleware
 	authMiddleware   *middleware.AuthMiddleware
 }
 
-func NewUserHandler(userService user.UserService, clientMiddleware *middleware.ClientMiddleware, authMiddleware *middleware.AuthMiddleware) *UserHandler {
+func NewUserHandler(userService user.UserService, privacyService privacy.PrivacyService, presenceService presence.PresenceService, clientMiddleware *middleware.ClientMiddleware, authMiddleware *middleware.AuthMiddleware) *UserHandler {
 	return &UserHandler{
 		userService:      userService,
+		privacyService:   privacyService,
+		presenceService:  presenceService,
 		clientMiddleware: clientMiddleware,
 		authMiddleware:   authMiddleware,
 	}
@@ -33,7 +41,13 @@ func (h *UserHandler) RegisterRoutes(app fiber.Router) {
 
 	// Protected user routes (requires client key and user authentication)
 	users := v1.Group("/users", h.clientMiddleware.ValidateKey(), h.authMiddleware.Authenticate())
-	users.Get("/me", h.GetCurrentUser) // Get current user
+	users.Get("/me", h.GetCurrentUser)                // Get current user
+	users.Get("/me/privacy", h.GetPrivacy)            // Get current user's block list and direct message privacy
+	users.Put("/me/privacy", h.UpdatePrivacy)         // Set who can send direct messages to the current user
+	users.Post("/me/blocks", h.BlockUser)             // Block a user
+	users.Delete("/me/blocks/:userId", h.UnblockUser) // Unblock a user
+	users.Put("/me/presence", h.SetPresenceStatus)    // Set the current user's custom status
+	users.Get("/presence", h.GetPresence)             // Get the presence of users sharing a chatroom with the current user
 
 	// Admin protected routes
 	adminUsers := v1.Group("/admin/users", h.clientMiddleware.ValidateKey(), h.authMiddleware.Authenticate())
@@ -58,6 +72,7 @@ func (h *UserHandler) RegisterRoutes(app fiber.Router) {
 // @Failure 401 {object} http.ErrorResponse
 // @Router /v1/users [get]
 func (h *UserHandler) GetUsers(c *fiber.Ctx) error {
+	client := c.Locals("client").(*entity.Client)
 	page, _ := strconv.Atoi(c.Query("page", "1"))
 	limit, _ := strconv.Atoi(c.Query("limit", "10"))
 
@@ -66,7 +81,11 @@ func (h *UserHandler) GetUsers(c *fiber.Ctx) error {
 		Limit: limit,
 	}
 
-	users, total, err := h.userService.GetAll(c.Context(), pag)
+	filter := repository.UserFilter{
+		ClientID: client.ID,
+	}
+
+	users, total, err := h.userService.GetAll(c.Context(), filter, pag)
 	if err != nil {
 		return err
 	}
@@ -103,11 +122,13 @@ func (h *UserHandler) GetUsers(c *fiber.Ctx) error {
 // @Router /v1/users/{id} [get]
 func (h *UserHandler) GetUser(c *fiber.Ctx) error {
 	id := c.Params("id")
+	client := c.Locals("client").(*entity.Client)
+
 	user, err := h.userService.Get(c.Context(), id)
 	if err != nil {
 		return err
 	}
-	if user == nil {
+	if user == nil || user.ClientID != client.ID {
 		return exception.NotFound("User")
 	}
 
@@ -136,6 +157,213 @@ func (h *UserHandler) GetCurrentUser(c *fiber.Ctx) error {
 	})
 }
 
+// GetPrivacy godoc
+// @Summary Get privacy settings
+// @Description Retrieves the current user's block list and who can send them direct messages
+// @Tags users
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Success 200 {object} http.GeneralResponse{data=entity.UserPrivacy}
+// @Failure 401 {object} http.ErrorResponse
+// @Router /v1/users/me/privacy [get]
+func (h *UserHandler) GetPrivacy(c *fiber.Ctx) error {
+	user := c.Locals("user").(*entity.User)
+
+	settings, err := h.privacyService.GetSettings(c.Context(), user.ID)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.GeneralResponse{
+		Status:  fiber.StatusOK,
+		Message: "Privacy settings retrieved successfully",
+		Data:    settings,
+	})
+}
+
+// UpdatePrivacy godoc
+// @Summary Update privacy settings
+// @Description Sets who can send direct messages to the current user: everyone, users sharing a group chatroom or nobody
+// @Tags users
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Param privacy body dto.UpdatePrivacyRequest true "Privacy settings"
+// @Success 200 {object} http.GeneralResponse{data=entity.UserPrivacy}
+// @Failure 400,401 {object} http.ErrorResponse
+// @Router /v1/users/me/privacy [put]
+func (h *UserHandler) UpdatePrivacy(c *fiber.Ctx) error {
+	user := c.Locals("user").(*entity.User)
+
+	var req dto.UpdatePrivacyRequest
+	if err := c.BodyParser(&req); err != nil {
+		return exception.BadRequest("Invalid request body")
+	}
+
+	params := privacy.UpdateSettingsParams{
+		UserID:    user.ID,
+		DMPrivacy: entity.DMPrivacy(req.DMPrivacy),
+	}
+
+	settings, err := h.privacyService.UpdateSettings(c.Context(), params)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.GeneralResponse{
+		Status:  fiber.StatusOK,
+		Message: "Privacy settings updated successfully",
+		Data:    settings,
+	})
+}
+
+// BlockUser godoc
+// @Summary Block a user
+// @Description Stops a user's messages from reaching the current user and from starting direct conversations with them
+// @Tags users
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Param block body dto.BlockUserRequest true "User to block"
+// @Success 200 {object} http.GeneralResponse{data=entity.UserPrivacy}
+// @Failure 400,401 {object} http.ErrorResponse
+// @Router /v1/users/me/blocks [post]
+func (h *UserHandler) BlockUser(c *fiber.Ctx) error {
+	user := c.Locals("user").(*entity.User)
+
+	var req dto.BlockUserRequest
+	if err := c.BodyParser(&req); err != nil {
+		return exception.BadRequest("Invalid request body")
+	}
+
+	params := privacy.BlockParams{
+		UserID:        user.ID,
+		BlockedUserID: req.UserID,
+	}
+
+	settings, err := h.privacyService.BlockUser(c.Context(), params)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.GeneralResponse{
+		Status:  fiber.StatusOK,
+		Message: "User blocked successfully",
+		Data:    settings,
+	})
+}
+
+// UnblockUser godoc
+// @Summary Unblock a user
+// @Description Removes a user from the current user's block list
+// @Tags users
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Param userId path string true "Blocked user ID"
+// @Success 200 {object} http.GeneralResponse{data=entity.UserPrivacy}
+// @Failure 401 {object} http.ErrorResponse
+// @Router /v1/users/me/blocks/{userId} [delete]
+func (h *UserHandler) UnblockUser(c *fiber.Ctx) error {
+	user := c.Locals("user").(*entity.User)
+
+	params := privacy.BlockParams{
+		UserID:        user.ID,
+		BlockedUserID: c.Params("userId"),
+	}
+
+	settings, err := h.privacyService.UnblockUser(c.Context(), params)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.GeneralResponse{
+		Status:  fiber.StatusOK,
+		Message: "User unblocked successfully",
+		Data:    settings,
+	})
+}
+
+// SetPresenceStatus godoc
+// @Summary Set presence status
+// @Description Sets the custom status shown to users sharing a chatroom with the current user while they're online, online clears it
+// @Tags users
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Param status body dto.SetPresenceStatusRequest true "Custom status"
+// @Success 200 {object} http.GeneralResponse{data=entity.Presence}
+// @Failure 400,401 {object} http.ErrorResponse
+// @Router /v1/users/me/presence [put]
+func (h *UserHandler) SetPresenceStatus(c *fiber.Ctx) error {
+	user := c.Locals("user").(*entity.User)
+
+	var req dto.SetPresenceStatusRequest
+	if err := c.BodyParser(&req); err != nil {
+		return exception.BadRequest("Invalid request body")
+	}
+
+	params := presence.SetStatusParams{
+		UserID: user.ID,
+		Status: entity.PresenceStatus(req.Status),
+		Text:   req.Text,
+	}
+
+	result, err := h.presenceService.SetStatus(c.Context(), params)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.GeneralResponse{
+		Status:  fiber.StatusOK,
+		Message: "Presence status updated successfully",
+		Data:    result,
+	})
+}
+
+// GetPresence godoc
+// @Summary Get presence
+// @Description Retrieves whether users are online, their custom status, session count and when they were last seen. Users not sharing a chatroom with the current user are left out
+// @Tags users
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Param ids query string true "Comma separated user IDs, at most 100"
+// @Success 200 {object} http.GeneralResponse{data=[]entity.Presence}
+// @Failure 400,401 {object} http.ErrorResponse
+// @Router /v1/users/presence [get]
+func (h *UserHandler) GetPresence(c *fiber.Ctx) error {
+	user := c.Locals("user").(*entity.User)
+
+	var userIDs []string
+	for _, id := range strings.Split(c.Query("ids"), ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			userIDs = append(userIDs, id)
+		}
+	}
+	if len(userIDs) == 0 {
+		return exception.BadRequest("User IDs are required")
+	}
+
+	presences, err := h.presenceService.GetPresence(c.Context(), user.ID, userIDs)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.GeneralResponse{
+		Status:  fiber.StatusOK,
+		Message: "Presence retrieved successfully",
+		Data:    presences,
+	})
+}
+
 // CreateUser godoc
 // @Summary Create a new user
 // @Description Creates a new user with the provided details
@@ -149,12 +377,15 @@ func (h *UserHandler) GetCurrentUser(c *fiber.Ctx) error {
 // @Failure 400 {object} http.ErrorResponse
 // @Router /v1/admin/users [post]
 func (h *UserHandler) CreateUser(c *fiber.Ctx) error {
+	client := c.Locals("client").(*entity.Client)
+
 	var req dto.CreateUserRequest
 	if err := c.BodyParser(&req); err != nil {
 		return exception.BadRequest("Invalid request body")
 	}
 
 	user := &entity.User{
+		ClientID: client.ID,
 		UserID:   req.UserID,
 		Name:     req.Name,
 		Username: req.Username,
@@ -189,6 +420,7 @@ func (h *UserHandler) CreateUser(c *fiber.Ctx) error {
 func (h *UserHandler) UpdateUser(c *fiber.Ctx) error {
 	var userID string
 	currentUser := c.Locals("user").(*entity.User)
+	client := c.Locals("client").(*entity.Client)
 
 	// Check if updating current user or admin updating another user
 	if c.Path() == "/v1/users/me" {
@@ -202,7 +434,7 @@ func (h *UserHandler) UpdateUser(c *fiber.Ctx) error {
 	if err != nil {
 		return err
 	}
-	if user == nil {
+	if user == nil || user.ClientID != client.ID {
 		return exception.NotFound("User")
 	}
 
@@ -242,13 +474,14 @@ func (h *UserHandler) UpdateUser(c *fiber.Ctx) error {
 // @Router /v1/admin/users/{id} [delete]
 func (h *UserHandler) DeleteUser(c *fiber.Ctx) error {
 	id := c.Params("id")
+	client := c.Locals("client").(*entity.Client)
 
 	// Check if user exists
 	user, err := h.userService.Get(c.Context(), id)
 	if err != nil {
 		return err
 	}
-	if user == nil {
+	if user == nil || user.ClientID != client.ID {
 		return exception.NotFound("User")
 	}
 
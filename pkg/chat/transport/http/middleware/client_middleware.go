@@ -2,6 +2,7 @@
 package middleware
 
 import (
+	"app/pkg/chat/domain/entity"
 	"app/pkg/chat/service/client"
 
 	"github.com/gofiber/fiber/v2"
@@ -20,11 +21,18 @@ func NewClientMiddleware(clientService client.ClientService) *ClientMiddleware {
 }
 
 // ValidateClientKey middleware validates the X-Client-Key header and stores the client in context
+// Reading requests need a key with the read scope, all others a key with the write scope
 func (m *ClientMiddleware) ValidateKey() fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		clientKey := c.Get("X-Client-Key")
 
-		client, err := m.clientService.ValidateKey(c.Context(), clientKey)
+		scope := entity.ClientKeyScopeWrite
+		switch c.Method() {
+		case fiber.MethodGet, fiber.MethodHead, fiber.MethodOptions:
+			scope = entity.ClientKeyScopeRead
+		}
+
+		client, err := m.clientService.ValidateKey(c.Context(), clientKey, scope)
 		if err != nil {
 			return err
 		}
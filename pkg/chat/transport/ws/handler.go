@@ -5,7 +5,7 @@ import (
 	"app/pkg/chat/service/chat"
 	"app/pkg/chat/service/chatroom"
 	"app/pkg/chat/service/client"
-	"app/pkg/database/redis"
+	"app/pkg/chat/service/receipt"
 	"app/pkg/exception"
 	"context"
 	"encoding/json"
@@ -15,26 +15,32 @@ import (
 	"github.com/gofiber/websocket/v2"
 )
 
+// maxReplayBatch is the maximum number of events replayed per resume request
+const maxReplayBatch = 100
+
 // Handler handles WebSocket connections and events
 type Handler struct {
 	hub             *Hub
 	clientService   client.ClientService
 	chatService     chat.ChatService
 	chatroomService chatroom.ChatroomService
+	receiptService  receipt.ReceiptService
 }
 
-// NewHandler creates a new WebSocket handler
+// NewHandler creates a new WebSocket handler backed by the given hub
 func NewHandler(
-	redisClient *redis.Client,
+	hub *Hub,
 	clientService client.ClientService,
 	chatService chat.ChatService,
 	chatroomService chatroom.ChatroomService,
+	receiptService receipt.ReceiptService,
 ) *Handler {
 	return &Handler{
-		hub:             NewHub(redisClient, chatService, chatroomService),
+		hub:             hub,
 		clientService:   clientService,
 		chatService:     chatService,
 		chatroomService: chatroomService,
+		receiptService:  receiptService,
 	}
 }
 
@@ -69,8 +75,8 @@ func (h *Handler) authenticateConnection() fiber.Handler {
 			return exception.Http(401, "Auth token is required")
 		}
 
-		// Validate client key
-		client, err := h.clientService.ValidateKey(c.Context(), clientKey)
+		// Validate client key, it needs the websocket scope
+		client, err := h.clientService.ValidateKey(c.Context(), clientKey, entity.ClientKeyScopeWebSocket)
 		if err != nil {
 			return err
 		}
@@ -93,46 +99,83 @@ func (h *Handler) authenticateConnection() fiber.Handler {
 func (h *Handler) handleConnection(c *websocket.Conn) {
 	// Get authenticated data from context
 	user := c.Locals("user").(*entity.User)
+	clientData := c.Locals("client").(*entity.Client)
 
 	// Create new connection
 	conn := &Connection{
 		Socket: c,
 		User:   user,
+		Client: clientData,
 	}
 
 	// Create new client
-	client := NewClient(conn)
+	client := NewClient(conn, h.hub.options.SendQueueSize)
+
+	// Load the client's chatrooms and block list before registering it, so the hub's loop doesn't wait on the database
+	h.hub.loadClient(client)
 
 	// Register client with hub
 	h.hub.register <- client
 
-	// Start client message handlers
+	// The connection is released as soon as this handler returns,
+	// so read on this goroutine and wait for the write pump to stop using the socket
 	go h.writePump(client)
-	go h.readPump(client)
+	h.readPump(client)
+	<-client.done
 }
 
-// writePump pumps messages from the hub to the WebSocket connection
+// writePump pumps messages from the hub to the WebSocket connection and pings the client
+// It stops once the hub closes the send queue or a write fails, closing the socket
 func (h *Handler) writePump(client *Client) {
-	for message := range client.Send {
-		if err := client.Conn.Socket.WriteMessage(websocket.TextMessage, message); err != nil {
-			return
+	socket := client.Conn.Socket
+	ticker := time.NewTicker(h.hub.options.PingInterval)
+	defer func() {
+		ticker.Stop()
+		// Closing the socket ends the read pump, which unregisters the client
+		socket.Close()
+		close(client.done)
+	}()
+
+	for {
+		select {
+		case message, ok := <-client.Send:
+			socket.SetWriteDeadline(time.Now().Add(h.hub.options.WriteTimeout))
+			if !ok {
+				socket.WriteMessage(websocket.CloseMessage, []byte{})
+				return
+			}
+			if err := socket.WriteMessage(websocket.TextMessage, message); err != nil {
+				return
+			}
+		case <-ticker.C:
+			socket.SetWriteDeadline(time.Now().Add(h.hub.options.WriteTimeout))
+			if err := socket.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
 		}
 	}
-	client.Conn.Socket.WriteMessage(websocket.CloseMessage, []byte{})
 }
 
 // readPump pumps messages from the WebSocket connection to the hub
+// Clients that send nothing, not even a pong, within the pong timeout are considered dead
 func (h *Handler) readPump(client *Client) {
 	defer func() {
 		h.hub.unregister <- client
-		client.Conn.Socket.Close()
 	}()
 
+	socket := client.Conn.Socket
+	socket.SetReadLimit(h.hub.options.MaxMessageSize)
+	socket.SetReadDeadline(time.Now().Add(h.hub.options.PongTimeout))
+	socket.SetPongHandler(func(string) error {
+		return socket.SetReadDeadline(time.Now().Add(h.hub.options.PongTimeout))
+	})
+
 	for {
-		_, message, err := client.Conn.Socket.ReadMessage()
+		_, message, err := socket.ReadMessage()
 		if err != nil {
 			break
 		}
+		socket.SetReadDeadline(time.Now().Add(h.hub.options.PongTimeout))
 
 		// Parse the event
 		var event Event
@@ -146,12 +189,40 @@ func (h *Handler) readPump(client *Client) {
 
 		// Handle different event types
 		switch event.Type {
+		case EventTypeSubscribe:
+			h.handleSubscribe(client, &event)
+			continue
+		case EventTypeUnsubscribe:
+			h.handleUnsubscribe(client, &event)
+			continue
 		case EventTypeMessage:
+			// The message is broadcast by the chat service once stored
 			h.handleChatMessage(client, &event)
+			continue
+		case EventTypeResume:
+			if err := h.handleResume(client, &event); err != nil {
+				sendError(client, err)
+			}
+			continue
+		case EventTypeReaction:
+			// The reaction is broadcast by the chat service once stored
+			if err := h.handleReaction(client, &event); err != nil {
+				sendError(client, err)
+			}
+			continue
 		case EventTypeTypingStart, EventTypeTypingStop:
-			h.handleTypingIndicator(client, &event)
+			if err := h.handleTypingIndicator(client, &event); err != nil {
+				sendError(client, err)
+				continue
+			}
 		case EventTypeMessageRead:
-			h.handleMessageRead(client, &event)
+			if err := h.handleMessageRead(client, &event); err != nil {
+				sendError(client, err)
+				continue
+			}
+		default:
+			// Ignore server-only events sent by clients
+			continue
 		}
 
 		// Broadcast the event
@@ -161,48 +232,240 @@ func (h *Handler) readPump(client *Client) {
 	}
 }
 
-// handleChatMessage processes chat message events
+// handleChatMessage stores a chat message and acknowledges it to the sender
 func (h *Handler) handleChatMessage(client *Client, event *Event) {
 	var payload MessagePayload
 	if err := mapPayload(event.Payload, &payload); err != nil {
+		sendError(client, exception.BadRequest("Invalid message payload"))
 		return
 	}
 
 	// Create chat message using service
 	params := chat.SendMessageParams{
+		ChatroomID:      event.ChatroomID,
+		SenderID:        client.Conn.User.ID,
+		ClientID:        client.Conn.Client.ID,
+		Message:         payload.Message,
+		ReplyTo:         payload.ReplyTo,
+		Metadata:        payload.Metadata,
+		Attachments:     payload.Attachments,
+		ClientMessageID: payload.ClientMessageID,
+	}
+
+	msg, err := h.chatService.SendMessage(context.Background(), params)
+	if err != nil {
+		payload := errorPayload(err)
+		payload.ClientMessageID = params.ClientMessageID
+		client.SendEvent(EventTypeError, payload)
+		return
+	}
+
+	// Retried sends are acknowledged again so the client can stop retrying
+	client.send(Event{
+		Type:       EventTypeAck,
+		ChatroomID: msg.Chatroom,
+		UserID:     client.Conn.User.ID,
+		Sequence:   msg.Sequence,
+		Payload: AckPayload{
+			ClientMessageID: params.ClientMessageID,
+			MessageID:       msg.ID,
+			Sequence:        msg.Sequence,
+		},
+		Timestamp: TimeNow(),
+	})
+}
+
+// handleResume replays the chatroom's changes since the client's last seen sequence
+// A batch is limited to half of the send queue so the replay can't overflow it
+func (h *Handler) handleResume(client *Client, event *Event) error {
+	if event.ChatroomID == "" {
+		return exception.BadRequest("Chatroom ID is required")
+	}
+
+	var payload ResumePayload
+	if err := mapPayload(event.Payload, &payload); err != nil {
+		return exception.BadRequest("Invalid resume payload")
+	}
+
+	params := chat.GetUpdatesParams{
 		ChatroomID: event.ChatroomID,
-		SenderID:   client.Conn.User.ID,
-		Message:    payload.Message,
+		UserID:     client.Conn.User.ID,
+		Sequence:   payload.Sequence,
 	}
 
-	if msg, err := h.chatService.SendMessage(context.Background(), params); err == nil {
-		event.Payload = msg
+	limit := max(min(maxReplayBatch, h.hub.options.SendQueueSize/2), 1)
+	chats, hasMore, err := h.chatService.GetUpdates(context.Background(), params, limit)
+	if err != nil {
+		return err
+	}
+
+	last := payload.Sequence
+	for _, chat := range chats {
+		if h.hub.isBlocked(client, chat.Sender) {
+			last = chat.UpdatedSequence
+			continue
+		}
+		if err := client.send(replayEvent(chat, payload.Sequence)); err != nil {
+			// The queue is full, the client resumes again from the last event it received
+			hasMore = true
+			break
+		}
+		last = chat.UpdatedSequence
 	}
+
+	return client.send(Event{
+		Type:       EventTypeResume,
+		ChatroomID: event.ChatroomID,
+		Sequence:   last,
+		Payload: ResumePayload{
+			Sequence: last,
+			HasMore:  hasMore,
+		},
+		Timestamp: TimeNow(),
+	})
+}
+
+// replayEvent describes a message's latest state as the event a client that saw up to the given sequence missed
+// Messages created since are replayed as new messages, older ones as deleted or edited, which also covers reactions
+func replayEvent(chat *entity.Chat, since int64) Event {
+	event := Event{
+		Type:       EventTypeMessageEdited,
+		ChatroomID: chat.Chatroom,
+		Sequence:   chat.UpdatedSequence,
+		Payload:    chat,
+		Timestamp:  TimeNow(),
+	}
+
+	switch {
+	case chat.Sequence > since:
+		event.Type = EventTypeMessage
+		if chat.ReplyTo != nil {
+			event.Type = EventTypeMessageReply
+		}
+		event.UserID = chat.Sender
+	case chat.DeletedTimestamp != nil:
+		event.Type = EventTypeMessageDeleted
+		if chat.DeletedBy != nil {
+			event.UserID = *chat.DeletedBy
+		}
+	}
+
+	return event
+}
+
+// handleReaction toggles a reaction on a message
+func (h *Handler) handleReaction(client *Client, event *Event) error {
+	var payload ReactionPayload
+	if err := mapPayload(event.Payload, &payload); err != nil {
+		return exception.BadRequest("Invalid reaction payload")
+	}
+
+	params := chat.ToggleReactionParams{
+		ChatID: payload.MessageID,
+		UserID: client.Conn.User.ID,
+		Emoji:  payload.Emoji,
+	}
+
+	_, _, err := h.chatService.ToggleReaction(context.Background(), params)
+	return err
+}
+
+// handleSubscribe subscribes the client to a chatroom the user participates in
+func (h *Handler) handleSubscribe(client *Client, event *Event) {
+	if event.ChatroomID == "" {
+		client.SendEvent(EventTypeError, ErrorPayload{Code: 400, Message: "Chatroom ID is required"})
+		return
+	}
+
+	ok, err := h.chatroomService.IsParticipant(context.Background(), event.ChatroomID, client.Conn.User.ID)
+	if err != nil {
+		client.SendEvent(EventTypeError, ErrorPayload{Code: 500, Message: "Failed to subscribe to chatroom"})
+		return
+	}
+	if !ok {
+		client.SendEvent(EventTypeError, ErrorPayload{Code: 403, Message: "User is not a participant in the chatroom"})
+		return
+	}
+
+	h.hub.subscribeClient(client, event.ChatroomID)
+	client.SendEvent(EventTypeSubscribe, SubscriptionPayload{ChatroomID: event.ChatroomID})
+}
+
+// handleUnsubscribe unsubscribes the client from a chatroom
+func (h *Handler) handleUnsubscribe(client *Client, event *Event) {
+	if event.ChatroomID == "" {
+		client.SendEvent(EventTypeError, ErrorPayload{Code: 400, Message: "Chatroom ID is required"})
+		return
+	}
+
+	h.hub.unsubscribeClient(client, event.ChatroomID)
+	client.SendEvent(EventTypeUnsubscribe, SubscriptionPayload{ChatroomID: event.ChatroomID})
 }
 
 // handleTypingIndicator processes typing indicator events
-func (h *Handler) handleTypingIndicator(client *Client, event *Event) {
+// Only chatrooms the client is subscribed to can be typed in, an event without one would reach every client
+func (h *Handler) handleTypingIndicator(client *Client, event *Event) error {
 	var payload TypingPayload
 	if err := mapPayload(event.Payload, &payload); err != nil {
-		return
+		return exception.BadRequest("Invalid typing payload")
+	}
+
+	if event.ChatroomID == "" {
+		return exception.BadRequest("Chatroom ID is required")
+	}
+	if !h.hub.isSubscribed(client, event.ChatroomID) {
+		return exception.Forbidden()
 	}
 
 	// Update payload with user info
 	payload.UserID = client.Conn.User.ID
 	payload.ChatroomID = event.ChatroomID
 	event.Payload = payload
+
+	return nil
 }
 
-// handleMessageRead processes message read events
-func (h *Handler) handleMessageRead(client *Client, event *Event) {
+// handleMessageRead persists the read position before the event is broadcast
+func (h *Handler) handleMessageRead(client *Client, event *Event) error {
 	var payload ReadPayload
 	if err := mapPayload(event.Payload, &payload); err != nil {
-		return
+		return exception.BadRequest("Invalid read payload")
+	}
+
+	if payload.ChatroomID == "" {
+		payload.ChatroomID = event.ChatroomID
+	}
+
+	params := receipt.MarkAsReadParams{
+		ChatroomID: payload.ChatroomID,
+		UserID:     client.Conn.User.ID,
+		MessageID:  payload.MessageID,
+	}
+
+	if err := h.receiptService.MarkAsRead(context.Background(), params); err != nil {
+		return err
 	}
 
 	// Update payload with user info
 	payload.UserID = client.Conn.User.ID
+	event.ChatroomID = payload.ChatroomID
 	event.Payload = payload
+	return nil
+}
+
+// sendError sends an error event to the client
+func sendError(client *Client, err error) {
+	client.SendEvent(EventTypeError, errorPayload(err))
+}
+
+// errorPayload describes an error, preserving the HTTP error code when available
+func errorPayload(err error) ErrorPayload {
+	payload := ErrorPayload{Code: 500, Message: err.Error()}
+	if httpErr, ok := err.(exception.HttpError); ok {
+		payload.Code = httpErr.Code
+	}
+
+	return payload
 }
 
 // mapPayload helper function to map interface{} to a specific type
@@ -1,28 +1,54 @@
 package ws
 
 import (
+	"app/pkg/chat/domain/repository"
 	"app/pkg/chat/service/chat"
 	"app/pkg/chat/service/chatroom"
+	"app/pkg/chat/service/presence"
+	"app/pkg/chat/service/privacy"
 	"app/pkg/database/redis"
+	"app/pkg/types/pagination"
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"strings"
 	"sync"
 	"time"
 )
 
 const (
 	// Redis key prefixes
-	connectedUsersKey = "ws:connected_users"
-	userSocketKey     = "ws:user_socket:%s" // Format with user ID
-	socketUserKey     = "ws:socket_user:%s" // Format with socket ID
+	nodesKey        = "ws:nodes"
+	nodeAliveKey    = "ws:node:%s"          // Format with node ID
+	nodeSessionsKey = "ws:node_sessions:%s" // Format with node ID, holds <user ID>:<session ID> of the node's clients
+
+	// Redis pub/sub channels
+	chatroomChannelPrefix  = "ws:chatroom:"
+	chatroomChannelPattern = chatroomChannelPrefix + "*"
+	broadcastChannel       = "ws:broadcast"
+	membershipChannel      = "ws:membership"
+	privacyChannel         = "ws:privacy"
+	presenceChannel        = "ws:presence"
 
 	// Redis expiration times
-	socketExpiration = 24 * time.Hour
+	nodeExpiration = 30 * time.Second
+
+	// Number of chatrooms fetched per page when subscribing a new client
+	chatroomPageSize = 100
+
+	// Interval at which a node refreshes its liveness key and sweeps dead nodes
+	nodeHeartbeatInterval = 10 * time.Second
 )
 
-// Hub maintains the set of active clients and broadcasts messages
+// Hub maintains the set of active clients and broadcasts messages.
+// Events are fanned out through Redis pub/sub so that every node running a hub
+// relays them to its own local clients.
 type Hub struct {
+	// Unique identifier of this node, used for presence bookkeeping
+	nodeID string
+
 	// Registered clients
 	clients map[*Client]bool
 
@@ -44,13 +70,23 @@ type Hub struct {
 	// Chatroom service for managing rooms
 	chatroomService chatroom.ChatroomService
 
+	// Privacy service for the block lists of connected users
+	privacyService privacy.PrivacyService
+
+	// Presence service tracking the sessions of connected users
+	presenceService presence.PresenceService
+
+	// Heartbeat and backpressure settings of the connections
+	options Options
+
 	// Mutex for thread-safe operations
 	mu sync.RWMutex
 }
 
-// NewHub creates a new Hub instance
-func NewHub(redisClient *redis.Client, chatService chat.ChatService, chatroomService chatroom.ChatroomService) *Hub {
+// NewHub creates a new Hub instance, unset options fall back to DefaultOptions
+func NewHub(redisClient *redis.Client, chatService chat.ChatService, chatroomService chatroom.ChatroomService, privacyService privacy.PrivacyService, presenceService presence.PresenceService, options Options) *Hub {
 	return &Hub{
+		nodeID:          newNodeID(),
 		clients:         make(map[*Client]bool),
 		broadcast:       make(chan []byte),
 		register:        make(chan *Client),
@@ -58,11 +94,33 @@ func NewHub(redisClient *redis.Client, chatService chat.ChatService, chatroomSer
 		redisClient:     redisClient,
 		chatService:     chatService,
 		chatroomService: chatroomService,
+		privacyService:  privacyService,
+		presenceService: presenceService,
+		options:         options.withDefaults(),
 	}
 }
 
+// newNodeID generates a random identifier for this hub node
+func newNodeID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%d", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
+
+// newSessionID generates a random identifier for a client's session
+func newSessionID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
 // Run starts the hub's main event loop
 func (h *Hub) Run() {
+	go h.subscribe()
+	go h.heartbeat()
+
 	for {
 		select {
 		case client := <-h.register:
@@ -77,41 +135,40 @@ func (h *Hub) Run() {
 	}
 }
 
-// handleRegister processes a new client registration
+// handleRegister processes a new client registration, the client's chatrooms and block list are loaded beforehand
 func (h *Hub) handleRegister(client *Client) {
 	h.mu.Lock()
 	h.clients[client] = true
 	h.mu.Unlock()
 
-	// Store user connection in Redis
+	// Store the session in Redis, the user's first session announces them online
 	if err := h.storeUserConnection(client); err != nil {
 		fmt.Printf("Error storing user connection: %v\n", err)
-		return
 	}
-
-	// Notify other clients about the new user
-	h.broadcastUserStatus(client, true)
 }
 
 // handleUnregister processes a client disconnection
+// It's the only place a client is cleaned up, closing its send queue stops the write pump which closes the socket
 func (h *Hub) handleUnregister(client *Client) {
 	h.mu.Lock()
-	if _, ok := h.clients[client]; ok {
-		delete(h.clients, client)
-		close(client.Send)
-	}
+	_, ok := h.clients[client]
+	delete(h.clients, client)
 	h.mu.Unlock()
 
-	// Remove user connection from Redis
+	// Slow consumers are unregistered by the hub and again once their read pump exits
+	if !ok {
+		return
+	}
+
+	client.closeSend()
+
+	// Remove the session from Redis, the user's last session announces them offline
 	if err := h.removeUserConnection(client); err != nil {
 		fmt.Printf("Error removing user connection: %v\n", err)
 	}
-
-	// Notify other clients about the user leaving
-	h.broadcastUserStatus(client, false)
 }
 
-// handleBroadcast processes and broadcasts a message to relevant clients
+// handleBroadcast publishes an event to Redis so every node can relay it to its clients
 func (h *Hub) handleBroadcast(message []byte) {
 	var event Event
 	if err := json.Unmarshal(message, &event); err != nil {
@@ -119,137 +176,413 @@ func (h *Hub) handleBroadcast(message []byte) {
 		return
 	}
 
-	// If the message is for a specific chatroom, only send to clients in that room
-	if event.ChatroomID != "" {
-		h.broadcastToChatroom(event.ChatroomID, message)
+	h.publish(event.ChatroomID, message)
+}
+
+// publish sends a message to the chatroom channel, or the broadcast channel if no chatroom is given
+func (h *Hub) publish(chatroomID string, message []byte) {
+	channel := broadcastChannel
+	if chatroomID != "" {
+		channel = chatroomChannelPrefix + chatroomID
+	}
+
+	if err := h.redisClient.Publish(context.Background(), channel, message); err != nil {
+		fmt.Printf("Error publishing event: %v\n", err)
+
+		// Deliver locally so clients on this node still receive the event
+		h.relay(channel, message)
+	}
+}
+
+// subscribe listens on the Redis channels and relays events to local clients
+func (h *Hub) subscribe() {
+	pubsub, err := h.redisClient.PSubscribe(context.Background(), chatroomChannelPattern, broadcastChannel, membershipChannel, privacyChannel, presenceChannel)
+	if err != nil {
+		fmt.Printf("Error subscribing to hub channels: %v\n", err)
+		return
+	}
+	defer pubsub.Close()
+
+	for msg := range pubsub.Channel() {
+		h.relay(msg.Channel, []byte(msg.Payload))
+	}
+}
+
+// relay delivers a message received on a Redis channel to the local clients
+func (h *Hub) relay(channel string, message []byte) {
+	if channel == membershipChannel {
+		h.handleMembership(message)
+		return
+	}
+	if channel == privacyChannel {
+		h.handleBlock(message)
+		return
+	}
+	if channel == presenceChannel {
+		h.handlePresence(message)
+		return
+	}
+
+	if chatroomID, ok := strings.CutPrefix(channel, chatroomChannelPrefix); ok {
+		h.broadcastToChatroom(chatroomID, message)
 		return
 	}
 
-	// Otherwise, broadcast to all clients
 	h.broadcastToAll(message)
 }
 
-// broadcastToChatroom sends a message to all clients in a specific chatroom
-func (h *Hub) broadcastToChatroom(chatroomID string, message []byte) {
-	h.mu.RLock()
-	defer h.mu.RUnlock()
+// loadClient subscribes a client that is not yet registered to the user's chatrooms and loads their block list
+// It queries the database, so it runs on the connection's goroutine instead of the hub's loop, which would stall every other client
+func (h *Hub) loadClient(client *Client) {
+	// Subscribe the client to every chatroom the user belongs to
+	if err := h.subscribeToChatrooms(client); err != nil {
+		fmt.Printf("Error subscribing client to chatrooms: %v\n", err)
+	}
 
-	for client := range h.clients {
-		if client.Chatrooms[chatroomID] {
-			select {
-			case client.Send <- message:
-			default:
-				close(client.Send)
-				delete(h.clients, client)
-			}
+	// Load the users whose messages the client doesn't receive
+	if privacy, err := h.privacyService.GetSettings(context.Background(), client.Conn.User.ID); err != nil {
+		fmt.Printf("Error loading client block list: %v\n", err)
+	} else {
+		for _, userID := range privacy.BlockedUsers {
+			client.Blocked[userID] = true
 		}
 	}
 }
 
-// broadcastToAll sends a message to all connected clients
-func (h *Hub) broadcastToAll(message []byte) {
-	h.mu.RLock()
-	defer h.mu.RUnlock()
+// subscribeToChatrooms subscribes a client that is not yet registered to all of the user's chatrooms
+func (h *Hub) subscribeToChatrooms(client *Client) error {
+	filter := repository.ChatroomFilter{
+		ClientID:      client.Conn.Client.ID,
+		ParticipantID: client.Conn.User.ID,
+	}
+
+	for page := 1; ; page++ {
+		pag := pagination.Pagination{
+			Page:  page,
+			Limit: chatroomPageSize,
+		}
+
+		chatrooms, total, err := h.chatroomService.GetChatrooms(context.Background(), filter, pag)
+		if err != nil {
+			return err
+		}
+
+		for _, chatroom := range chatrooms {
+			client.Chatrooms[chatroom.ID] = true
+		}
+
+		if len(chatrooms) == 0 || int64(page*chatroomPageSize) >= total {
+			return nil
+		}
+	}
+}
+
+// subscribeClient subscribes a client to a chatroom
+func (h *Hub) subscribeClient(client *Client, chatroomID string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	client.Chatrooms[chatroomID] = true
+}
+
+// unsubscribeClient unsubscribes a client from a chatroom
+func (h *Hub) unsubscribeClient(client *Client, chatroomID string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	delete(client.Chatrooms, chatroomID)
+}
+
+// handleMembership updates the subscriptions of local clients after a membership change
+func (h *Hub) handleMembership(message []byte) {
+	var payload MembershipPayload
+	if err := json.Unmarshal(message, &payload); err != nil {
+		fmt.Printf("Error unmarshaling membership change: %v\n", err)
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
 
 	for client := range h.clients {
-		select {
-		case client.Send <- message:
-		default:
-			close(client.Send)
-			delete(h.clients, client)
+		if client.Conn.User.ID != payload.UserID {
+			continue
+		}
+		if payload.Joined {
+			client.Chatrooms[payload.ChatroomID] = true
+		} else {
+			delete(client.Chatrooms, payload.ChatroomID)
 		}
 	}
 }
 
-// broadcastUserStatus notifies clients about a user's connection status
-func (h *Hub) broadcastUserStatus(client *Client, isConnected bool) {
-	eventType := EventTypeConnect
-	if !isConnected {
-		eventType = EventTypeDisconnect
+// handleBlock updates the block lists of local clients after a user blocked or unblocked someone
+func (h *Hub) handleBlock(message []byte) {
+	var payload BlockPayload
+	if err := json.Unmarshal(message, &payload); err != nil {
+		fmt.Printf("Error unmarshaling block change: %v\n", err)
+		return
 	}
 
-	event := Event{
-		Type:      eventType,
-		UserID:    client.Conn.User.ID,
-		Timestamp: time.Now().UnixMilli(),
-		Payload: map[string]interface{}{
-			"user": client.Conn.User,
-		},
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for client := range h.clients {
+		if client.Conn.User.ID != payload.UserID {
+			continue
+		}
+		if payload.Blocked {
+			client.Blocked[payload.BlockedUserID] = true
+		} else {
+			delete(client.Blocked, payload.BlockedUserID)
+		}
 	}
+}
 
-	data, err := json.Marshal(event)
+// handlePresence delivers a presence change to the local clients subscribed to one of the user's chatrooms
+// Clients who blocked the user don't receive it
+func (h *Hub) handlePresence(message []byte) {
+	var payload PresencePayload
+	if err := json.Unmarshal(message, &payload); err != nil {
+		fmt.Printf("Error unmarshaling presence change: %v\n", err)
+		return
+	}
+	if payload.Presence == nil {
+		return
+	}
+
+	userID := payload.Presence.UserID
+	data, err := json.Marshal(Event{
+		Type:      EventTypePresence,
+		UserID:    userID,
+		Payload:   payload.Presence,
+		Timestamp: TimeNow(),
+	})
 	if err != nil {
-		fmt.Printf("Error marshaling user status event: %v\n", err)
+		fmt.Printf("Error marshaling presence event: %v\n", err)
 		return
 	}
 
-	h.broadcastToAll(data)
+	h.mu.RLock()
+	var slow []*Client
+	for client := range h.clients {
+		if client.Blocked[userID] || !isSubscribedToAny(client, payload.ChatroomIDs) {
+			continue
+		}
+		if !client.trySend(data) {
+			slow = append(slow, client)
+		}
+	}
+	h.mu.RUnlock()
+
+	h.handleSlowConsumers(slow)
 }
 
-// storeUserConnection stores user connection details in Redis
-func (h *Hub) storeUserConnection(client *Client) error {
-	ctx := context.Background()
-	userID := client.Conn.User.ID
-	socketID := client.Conn.Socket.LocalAddr().String()
+// isSubscribedToAny checks if the client is subscribed to one of the chatrooms, the hub must be locked
+func isSubscribedToAny(client *Client, chatroomIDs []string) bool {
+	for _, chatroomID := range chatroomIDs {
+		if client.Chatrooms[chatroomID] {
+			return true
+		}
+	}
+	return false
+}
+
+// isSubscribed checks if the client is subscribed to the chatroom
+func (h *Hub) isSubscribed(client *Client, chatroomID string) bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	return client.Chatrooms[chatroomID]
+}
+
+// isBlocked checks if the client blocked the given user
+func (h *Hub) isBlocked(client *Client, userID string) bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
 
-	// Store user's online status
-	if err := h.redisClient.SAdd(ctx, connectedUsersKey, userID); err != nil {
-		return fmt.Errorf("error storing user online status: %v", err)
+	return client.Blocked[userID]
+}
+
+// blockableSender returns the user behind an event that isn't delivered to clients who blocked them
+// Events about chatroom administration are always delivered
+func blockableSender(message []byte) string {
+	var event struct {
+		Type   EventType `json:"type"`
+		UserID string    `json:"userId"`
+	}
+	if err := json.Unmarshal(message, &event); err != nil {
+		return ""
 	}
 
-	// Store user-socket mapping
-	if err := h.redisClient.Set(ctx, fmt.Sprintf(userSocketKey, userID), socketID, socketExpiration); err != nil {
-		return fmt.Errorf("error storing user-socket mapping: %v", err)
+	switch event.Type {
+	case EventTypeMessage, EventTypeMessageReply, EventTypeMessageEdited, EventTypeReaction, EventTypeTypingStart, EventTypeTypingStop:
+		return event.UserID
+	}
+	return ""
+}
+
+// broadcastToChatroom sends a message to all clients in a specific chatroom, except those who blocked its sender
+func (h *Hub) broadcastToChatroom(chatroomID string, message []byte) {
+	sender := blockableSender(message)
+
+	h.mu.RLock()
+	var slow []*Client
+	for client := range h.clients {
+		if !client.Chatrooms[chatroomID] || (sender != "" && client.Blocked[sender]) {
+			continue
+		}
+		if !client.trySend(message) {
+			slow = append(slow, client)
+		}
 	}
+	h.mu.RUnlock()
+
+	h.handleSlowConsumers(slow)
+}
 
-	// Store socket-user mapping
-	if err := h.redisClient.Set(ctx, fmt.Sprintf(socketUserKey, socketID), userID, socketExpiration); err != nil {
-		return fmt.Errorf("error storing socket-user mapping: %v", err)
+// broadcastToAll sends a message to all connected clients
+func (h *Hub) broadcastToAll(message []byte) {
+	h.mu.RLock()
+	var slow []*Client
+	for client := range h.clients {
+		if !client.trySend(message) {
+			slow = append(slow, client)
+		}
 	}
+	h.mu.RUnlock()
 
-	return nil
+	h.handleSlowConsumers(slow)
 }
 
-// removeUserConnection removes user connection details from Redis
-func (h *Hub) removeUserConnection(client *Client) error {
+// handleSlowConsumers applies the slow consumer policy to clients whose send queue was full
+// Under the drop policy the message is simply lost for them
+func (h *Hub) handleSlowConsumers(clients []*Client) {
+	if h.options.SlowConsumerPolicy != SlowConsumerDisconnect {
+		return
+	}
+
+	for _, client := range clients {
+		// Broadcasts can run on the hub's own loop, so the unregister request mustn't block it
+		go func(client *Client) {
+			h.unregister <- client
+		}(client)
+	}
+}
+
+// storeUserConnection records the client's session in the user's presence
+func (h *Hub) storeUserConnection(client *Client) error {
 	ctx := context.Background()
-	userID := client.Conn.User.ID
-	socketID := client.Conn.Socket.LocalAddr().String()
 
-	// Remove user's online status
-	if err := h.redisClient.SRem(ctx, connectedUsersKey, userID); err != nil {
-		return fmt.Errorf("error removing user online status: %v", err)
+	// Track the session on this node so it can be cleaned up if the node dies
+	if err := h.redisClient.SAdd(ctx, fmt.Sprintf(nodeSessionsKey, h.nodeID), nodeSession(client)); err != nil {
+		return fmt.Errorf("error storing node session: %v", err)
 	}
 
-	// Remove user-socket mapping
-	if err := h.redisClient.Del(ctx, fmt.Sprintf(userSocketKey, userID)); err != nil {
-		return fmt.Errorf("error removing user-socket mapping: %v", err)
+	return h.presenceService.Connect(ctx, client.Conn.User.ID, client.SessionID)
+}
+
+// removeUserConnection removes the client's session from the user's presence
+// The user stays online while any other session, on this node or another, still serves them
+func (h *Hub) removeUserConnection(client *Client) error {
+	ctx := context.Background()
+
+	if err := h.presenceService.Disconnect(ctx, client.Conn.User.ID, client.SessionID); err != nil {
+		return fmt.Errorf("error removing session: %v", err)
 	}
 
-	// Remove socket-user mapping
-	if err := h.redisClient.Del(ctx, fmt.Sprintf(socketUserKey, socketID)); err != nil {
-		return fmt.Errorf("error removing socket-user mapping: %v", err)
+	if err := h.redisClient.SRem(ctx, fmt.Sprintf(nodeSessionsKey, h.nodeID), nodeSession(client)); err != nil {
+		return fmt.Errorf("error removing node session: %v", err)
 	}
 
 	return nil
 }
 
-// IsUserConnected checks if a user is currently connected
-func (h *Hub) IsUserConnected(userID string) (bool, error) {
-	return h.redisClient.SIsMember(context.Background(), connectedUsersKey, userID)
+// nodeSession returns how a client's session is tracked by its node
+func nodeSession(client *Client) string {
+	return client.Conn.User.ID + ":" + client.SessionID
 }
 
-// GetConnectedUsers returns a list of all connected user IDs
-func (h *Hub) GetConnectedUsers() ([]string, error) {
-	return h.redisClient.SMembers(context.Background(), connectedUsersKey)
+// parseNodeSession splits a session tracked by a node into its user and session ID
+// Session IDs are hex, so the last colon separates them even if the user ID holds one
+func parseNodeSession(member string) (string, string, bool) {
+	i := strings.LastIndex(member, ":")
+	if i < 0 {
+		return "", "", false
+	}
+	return member[:i], member[i+1:], true
 }
 
-// GetUserSocket returns the socket ID for a connected user
-func (h *Hub) GetUserSocket(userID string) (string, error) {
-	return h.redisClient.Get(context.Background(), fmt.Sprintf(userSocketKey, userID))
+// heartbeat periodically refreshes this node's liveness key and cleans up after dead nodes
+func (h *Hub) heartbeat() {
+	ticker := time.NewTicker(nodeHeartbeatInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := h.refreshNode(); err != nil {
+			fmt.Printf("Error refreshing hub node: %v\n", err)
+		}
+		if err := h.sweepDeadNodes(); err != nil {
+			fmt.Printf("Error sweeping dead hub nodes: %v\n", err)
+		}
+		<-ticker.C
+	}
+}
+
+// refreshNode registers this node and extends its liveness key
+func (h *Hub) refreshNode() error {
+	ctx := context.Background()
+
+	if err := h.redisClient.SAdd(ctx, nodesKey, h.nodeID); err != nil {
+		return err
+	}
+
+	return h.redisClient.Set(ctx, fmt.Sprintf(nodeAliveKey, h.nodeID), "1", nodeExpiration)
 }
 
-// GetSocketUser returns the user ID associated with a socket
-func (h *Hub) GetSocketUser(socketID string) (string, error) {
-	return h.redisClient.Get(context.Background(), fmt.Sprintf(socketUserKey, socketID))
+// sweepDeadNodes ends the sessions of nodes whose liveness key has expired
+func (h *Hub) sweepDeadNodes() error {
+	ctx := context.Background()
+
+	nodes, err := h.redisClient.SMembers(ctx, nodesKey)
+	if err != nil {
+		return err
+	}
+
+	for _, node := range nodes {
+		if node == h.nodeID {
+			continue
+		}
+		alive, err := h.redisClient.Exists(ctx, fmt.Sprintf(nodeAliveKey, node))
+		if err != nil {
+			return err
+		}
+		if alive {
+			continue
+		}
+
+		sessions, err := h.redisClient.SMembers(ctx, fmt.Sprintf(nodeSessionsKey, node))
+		if err != nil {
+			return err
+		}
+
+		for _, session := range sessions {
+			userID, sessionID, ok := parseNodeSession(session)
+			if !ok {
+				continue
+			}
+			if err := h.presenceService.Disconnect(ctx, userID, sessionID); err != nil {
+				return err
+			}
+		}
+
+		if err := h.redisClient.Del(ctx, fmt.Sprintf(nodeSessionsKey, node)); err != nil {
+			return err
+		}
+		if err := h.redisClient.SRem(ctx, nodesKey, node); err != nil {
+			return err
+		}
+	}
+
+	return nil
 }
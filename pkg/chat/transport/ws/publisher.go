@@ -0,0 +1,216 @@
+package ws
+
+import (
+	"app/pkg/chat/domain/entity"
+	"app/pkg/database/redis"
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// Publisher publishes chatroom events to every hub node through Redis
+type Publisher struct {
+	redisClient *redis.Client
+}
+
+// NewPublisher creates a new Publisher instance
+func NewPublisher(redisClient *redis.Client) *Publisher {
+	return &Publisher{
+		redisClient: redisClient,
+	}
+}
+
+// ParticipantAdded subscribes the user's connections to the chatroom and announces the join
+func (p *Publisher) ParticipantAdded(ctx context.Context, chatroomID string, userID string) {
+	// Subscribe first so the joining user also receives the join event
+	p.publishMembership(ctx, MembershipPayload{
+		ChatroomID: chatroomID,
+		UserID:     userID,
+		Joined:     true,
+	})
+
+	p.publishEvent(ctx, Event{
+		Type:       EventTypeUserJoin,
+		ChatroomID: chatroomID,
+		UserID:     userID,
+		Timestamp:  TimeNow(),
+	})
+}
+
+// ParticipantRemoved announces the leave and unsubscribes the user's connections from the chatroom
+func (p *Publisher) ParticipantRemoved(ctx context.Context, chatroomID string, userID string) {
+	// Announce first so the leaving user also receives the leave event
+	p.publishEvent(ctx, Event{
+		Type:       EventTypeUserLeave,
+		ChatroomID: chatroomID,
+		UserID:     userID,
+		Timestamp:  TimeNow(),
+	})
+
+	p.publishMembership(ctx, MembershipPayload{
+		ChatroomID: chatroomID,
+		UserID:     userID,
+		Joined:     false,
+	})
+}
+
+// ChatroomUpdated announces the chatroom's updated properties
+func (p *Publisher) ChatroomUpdated(ctx context.Context, chatroom *entity.Chatroom, actorID string) {
+	p.publishEvent(ctx, Event{
+		Type:       EventTypeChatroomMeta,
+		ChatroomID: chatroom.ID,
+		UserID:     actorID,
+		Payload:    chatroom,
+		Timestamp:  TimeNow(),
+	})
+}
+
+// RoleUpdated announces a participant's new role to the chatroom
+func (p *Publisher) RoleUpdated(ctx context.Context, chatroomID string, participant entity.ChatroomParticipant, actorID string) {
+	p.publishParticipant(ctx, EventTypeRoleUpdated, chatroomID, participant, actorID)
+}
+
+// ParticipantMuted announces a muted participant to the chatroom
+func (p *Publisher) ParticipantMuted(ctx context.Context, chatroomID string, participant entity.ChatroomParticipant, actorID string) {
+	p.publishParticipant(ctx, EventTypeUserMuted, chatroomID, participant, actorID)
+}
+
+// ParticipantUnmuted announces an unmuted participant to the chatroom
+func (p *Publisher) ParticipantUnmuted(ctx context.Context, chatroomID string, participant entity.ChatroomParticipant, actorID string) {
+	p.publishParticipant(ctx, EventTypeUserUnmuted, chatroomID, participant, actorID)
+}
+
+// MessageSent announces a new message to the chatroom
+func (p *Publisher) MessageSent(ctx context.Context, chat *entity.Chat) {
+	eventType := EventTypeMessage
+	if chat.ReplyTo != nil {
+		eventType = EventTypeMessageReply
+	}
+
+	p.publishEvent(ctx, Event{
+		Type:       eventType,
+		ChatroomID: chat.Chatroom,
+		UserID:     chat.Sender,
+		Sequence:   chat.Sequence,
+		Payload:    chat,
+		Timestamp:  TimeNow(),
+	})
+}
+
+// MessageEdited announces an edited message to the chatroom
+func (p *Publisher) MessageEdited(ctx context.Context, chat *entity.Chat, userID string) {
+	p.publishEvent(ctx, Event{
+		Type:       EventTypeMessageEdited,
+		ChatroomID: chat.Chatroom,
+		UserID:     userID,
+		Sequence:   chat.UpdatedSequence,
+		Payload:    chat,
+		Timestamp:  TimeNow(),
+	})
+}
+
+// MessageDeleted announces a deleted message to the chatroom
+func (p *Publisher) MessageDeleted(ctx context.Context, chat *entity.Chat, userID string) {
+	p.publishEvent(ctx, Event{
+		Type:       EventTypeMessageDeleted,
+		ChatroomID: chat.Chatroom,
+		UserID:     userID,
+		Sequence:   chat.UpdatedSequence,
+		Payload:    chat,
+		Timestamp:  TimeNow(),
+	})
+}
+
+// ReactionToggled announces an added or removed reaction to the chatroom
+func (p *Publisher) ReactionToggled(ctx context.Context, chat *entity.Chat, reaction entity.ChatReaction, added bool) {
+	p.publishEvent(ctx, Event{
+		Type:       EventTypeReaction,
+		ChatroomID: chat.Chatroom,
+		UserID:     reaction.User,
+		Sequence:   chat.UpdatedSequence,
+		Payload: ReactionPayload{
+			MessageID: chat.ID,
+			Emoji:     reaction.Emoji,
+			UserID:    reaction.User,
+			Added:     added,
+			Reactions: chat.Reactions,
+		},
+		Timestamp: TimeNow(),
+	})
+}
+
+// publishParticipant publishes a participant change event to its chatroom channel
+func (p *Publisher) publishParticipant(ctx context.Context, eventType EventType, chatroomID string, participant entity.ChatroomParticipant, actorID string) {
+	p.publishEvent(ctx, Event{
+		Type:       eventType,
+		ChatroomID: chatroomID,
+		UserID:     participant.User,
+		Payload: ParticipantPayload{
+			ChatroomID:          chatroomID,
+			UserID:              participant.User,
+			Role:                participant.Role,
+			MutedUntilTimestamp: participant.MutedUntilTimestamp,
+			ActorID:             actorID,
+		},
+		Timestamp: TimeNow(),
+	})
+}
+
+// BlockUpdated updates which messages the user's connections receive after they blocked or unblocked a user
+func (p *Publisher) BlockUpdated(ctx context.Context, userID string, blockedUserID string, blocked bool) {
+	data, err := json.Marshal(BlockPayload{
+		UserID:        userID,
+		BlockedUserID: blockedUserID,
+		Blocked:       blocked,
+	})
+	if err != nil {
+		fmt.Printf("Error marshaling block change: %v\n", err)
+		return
+	}
+
+	if err := p.redisClient.Publish(ctx, privacyChannel, data); err != nil {
+		fmt.Printf("Error publishing block change: %v\n", err)
+	}
+}
+
+// PresenceChanged sends the user's presence to the users sharing one of the chatrooms with them
+func (p *Publisher) PresenceChanged(ctx context.Context, presence *entity.Presence, chatroomIDs []string) {
+	data, err := json.Marshal(PresencePayload{
+		Presence:    presence,
+		ChatroomIDs: chatroomIDs,
+	})
+	if err != nil {
+		fmt.Printf("Error marshaling presence change: %v\n", err)
+		return
+	}
+
+	if err := p.redisClient.Publish(ctx, presenceChannel, data); err != nil {
+		fmt.Printf("Error publishing presence change: %v\n", err)
+	}
+}
+
+// publishEvent publishes an event to its chatroom channel
+func (p *Publisher) publishEvent(ctx context.Context, event Event) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		fmt.Printf("Error marshaling event: %v\n", err)
+		return
+	}
+
+	if err := p.redisClient.Publish(ctx, chatroomChannelPrefix+event.ChatroomID, data); err != nil {
+		fmt.Printf("Error publishing event: %v\n", err)
+	}
+}
+
+// publishMembership publishes a membership change to every hub node
+func (p *Publisher) publishMembership(ctx context.Context, payload MembershipPayload) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		fmt.Printf("Error marshaling membership change: %v\n", err)
+		return
+	}
+
+	if err := p.redisClient.Publish(ctx, membershipChannel, data); err != nil {
+		fmt.Printf("Error publishing membership change: %v\n", err)
+	}
+}
@@ -3,6 +3,9 @@ package ws
 import (
 	"app/pkg/chat/domain/entity"
 	"encoding/json"
+	"errors"
+	"sync"
+	"time"
 
 	"github.com/gofiber/websocket/v2"
 )
@@ -11,29 +14,100 @@ import (
 type EventType string
 
 const (
-	// Connection events
-	EventTypeConnect    EventType = "connect"
-	EventTypeDisconnect EventType = "disconnect"
+	// Presence events, only sent to users sharing a chatroom with the user
+	EventTypePresence EventType = "presence"
 
 	// Chat events
-	EventTypeMessage      EventType = "message"
-	EventTypeMessageRead  EventType = "message_read"
-	EventTypeTypingStart  EventType = "typing_start"
-	EventTypeTypingStop   EventType = "typing_stop"
-	EventTypeUserJoin     EventType = "user_join"
-	EventTypeUserLeave    EventType = "user_leave"
-	EventTypeUserMuted    EventType = "user_muted"
-	EventTypeUserUnmuted  EventType = "user_unmuted"
-	EventTypeRoleUpdated  EventType = "role_updated"
-	EventTypeChatroomMeta EventType = "chatroom_meta"
-	EventTypeError        EventType = "error"
+	EventTypeMessage        EventType = "message"
+	EventTypeMessageRead    EventType = "message_read"
+	EventTypeMessageEdited  EventType = "message_edited"
+	EventTypeMessageDeleted EventType = "message_deleted"
+	EventTypeMessageReply   EventType = "message_reply"
+	EventTypeReaction       EventType = "message_reaction"
+	EventTypeTypingStart    EventType = "typing_start"
+	EventTypeTypingStop     EventType = "typing_stop"
+	EventTypeUserJoin       EventType = "user_join"
+	EventTypeUserLeave      EventType = "user_leave"
+	EventTypeUserMuted      EventType = "user_muted"
+	EventTypeUserUnmuted    EventType = "user_unmuted"
+	EventTypeRoleUpdated    EventType = "role_updated"
+	EventTypeChatroomMeta   EventType = "chatroom_meta"
+	EventTypeError          EventType = "error"
+	EventTypeAck            EventType = "ack" // Confirms a message sent by the client was stored
+
+	// Delivery events
+	EventTypeResume EventType = "resume" // Requests, and confirms, a replay of a chatroom's changes since a sequence
+
+	// Subscription events
+	EventTypeSubscribe   EventType = "subscribe"
+	EventTypeUnsubscribe EventType = "unsubscribe"
 )
 
+// SlowConsumerPolicy decides what happens to a client whose send queue is full
+type SlowConsumerPolicy string
+
+const (
+	SlowConsumerDrop       SlowConsumerPolicy = "drop"       // Messages that don't fit in the queue are dropped
+	SlowConsumerDisconnect SlowConsumerPolicy = "disconnect" // The client is disconnected
+)
+
+// errSendQueueFull is returned when an event doesn't fit in a client's send queue
+var errSendQueueFull = errors.New("send queue is full")
+
+// Options configures the heartbeat and backpressure of WebSocket connections
+type Options struct {
+	PingInterval       time.Duration      // How often clients are pinged
+	PongTimeout        time.Duration      // How long a client can stay silent before it's considered dead, must exceed PingInterval
+	WriteTimeout       time.Duration      // Time limit for writing a single message
+	MaxMessageSize     int64              // Largest message accepted from a client in bytes
+	SendQueueSize      int                // Messages buffered per client before the slow consumer policy applies
+	SlowConsumerPolicy SlowConsumerPolicy // What to do with clients that can't keep up
+}
+
+// DefaultOptions returns the options used for any unset value
+func DefaultOptions() Options {
+	return Options{
+		PingInterval:       30 * time.Second,
+		PongTimeout:        60 * time.Second,
+		WriteTimeout:       10 * time.Second,
+		MaxMessageSize:     64 * 1024,
+		SendQueueSize:      256,
+		SlowConsumerPolicy: SlowConsumerDisconnect,
+	}
+}
+
+// withDefaults fills unset or invalid options with their defaults
+func (o Options) withDefaults() Options {
+	defaults := DefaultOptions()
+	if o.PingInterval <= 0 {
+		o.PingInterval = defaults.PingInterval
+	}
+	if o.PongTimeout <= o.PingInterval {
+		// A pong can't arrive before its ping is sent
+		o.PongTimeout = 2 * o.PingInterval
+	}
+	if o.WriteTimeout <= 0 {
+		o.WriteTimeout = defaults.WriteTimeout
+	}
+	if o.MaxMessageSize <= 0 {
+		o.MaxMessageSize = defaults.MaxMessageSize
+	}
+	if o.SendQueueSize <= 0 {
+		o.SendQueueSize = defaults.SendQueueSize
+	}
+	if o.SlowConsumerPolicy != SlowConsumerDrop && o.SlowConsumerPolicy != SlowConsumerDisconnect {
+		o.SlowConsumerPolicy = defaults.SlowConsumerPolicy
+	}
+	return o
+}
+
 // Event represents a WebSocket event
+// Events about stored messages carry the chatroom sequence of the change, which only ever increases within a chatroom
 type Event struct {
 	Type       EventType   `json:"type"`
 	ChatroomID string      `json:"chatroomId,omitempty"`
 	UserID     string      `json:"userId,omitempty"`
+	Sequence   int64       `json:"sequence,omitempty"`
 	Payload    interface{} `json:"payload,omitempty"`
 	Timestamp  int64       `json:"timestamp"`
 }
@@ -42,22 +116,57 @@ type Event struct {
 type Connection struct {
 	Socket *websocket.Conn
 	User   *entity.User
+	Client *entity.Client
 }
 
 // Client represents a connected WebSocket client
+// The send queue is only closed by the hub when the client is unregistered
 type Client struct {
+	SessionID string // Identifies the connection in the user's presence, a user has one session per connection
 	Conn      *Connection
 	Send      chan []byte
 	Chatrooms map[string]bool // Map of chatroom IDs the client is subscribed to
+	Blocked   map[string]bool // Map of user IDs whose messages aren't delivered to the client
+
+	mu     sync.Mutex    // Guards closing the send queue
+	closed bool          // Whether the send queue is closed
+	done   chan struct{} // Closed once the write pump stops using the socket
 }
 
 // MessagePayload represents a chat message event payload
 type MessagePayload struct {
-	ID       string                 `json:"id,omitempty"`
-	Message  string                 `json:"message"`
-	Type     string                 `json:"type"`
-	ReplyTo  string                 `json:"replyTo,omitempty"`
-	Metadata map[string]interface{} `json:"metadata,omitempty"`
+	ID          string                 `json:"id,omitempty"`
+	Message     string                 `json:"message"`
+	Type        string                 `json:"type"`
+	ReplyTo     string                 `json:"replyTo,omitempty"`
+	Metadata    map[string]interface{} `json:"metadata,omitempty"`
+	Attachments []string               `json:"attachments,omitempty"` // IDs returned by the attachment upload endpoint
+	// Client generated ID echoed in the ack, resending a message with the same ID doesn't store it twice
+	ClientMessageID string `json:"clientMessageId,omitempty"`
+}
+
+// AckPayload represents the acknowledgement of a message sent by the client
+type AckPayload struct {
+	ClientMessageID string `json:"clientMessageId,omitempty"`
+	MessageID       string `json:"messageId"`
+	Sequence        int64  `json:"sequence"`
+}
+
+// ResumePayload represents a replay request from the last sequence the client has seen in a chatroom
+// The server answers with the replayed events followed by a resume event holding the last replayed sequence,
+// and whether the client should resume again from it to receive the rest
+type ResumePayload struct {
+	Sequence int64 `json:"sequence"`
+	HasMore  bool  `json:"hasMore,omitempty"`
+}
+
+// ReactionPayload represents a message reaction event payload
+type ReactionPayload struct {
+	MessageID string                `json:"messageId"`
+	Emoji     string                `json:"emoji"`
+	UserID    string                `json:"userId"`
+	Added     bool                  `json:"added"` // true if the reaction was added, false if it was removed
+	Reactions []entity.ChatReaction `json:"reactions,omitempty"`
 }
 
 // TypingPayload represents a typing indicator event payload
@@ -74,40 +183,107 @@ type ReadPayload struct {
 	UserID     string `json:"userId"`
 }
 
+// SubscriptionPayload represents a subscribe or unsubscribe acknowledgement payload
+type SubscriptionPayload struct {
+	ChatroomID string `json:"chatroomId"`
+}
+
+// MembershipPayload represents a change in a user's chatroom membership shared between hub nodes
+type MembershipPayload struct {
+	ChatroomID string `json:"chatroomId"`
+	UserID     string `json:"userId"`
+	Joined     bool   `json:"joined"`
+}
+
+// BlockPayload represents a change in a user's block list shared between hub nodes
+type BlockPayload struct {
+	UserID        string `json:"userId"`
+	BlockedUserID string `json:"blockedUserId"`
+	Blocked       bool   `json:"blocked"`
+}
+
+// PresencePayload represents a user's presence change shared between hub nodes
+// Nodes relay it to their clients subscribed to one of the chatrooms
+type PresencePayload struct {
+	Presence    *entity.Presence `json:"presence"`
+	ChatroomIDs []string         `json:"chatroomIds"`
+}
+
+// ParticipantPayload represents a change to a participant's role or mute state
+type ParticipantPayload struct {
+	ChatroomID          string                 `json:"chatroomId"`
+	UserID              string                 `json:"userId"`
+	Role                entity.ParticipantRole `json:"role"`
+	MutedUntilTimestamp *int64                 `json:"mutedUntilTimestamp,omitempty"`
+	ActorID             string                 `json:"actorId"` // User who made the change
+}
+
 // ErrorPayload represents an error event payload
 type ErrorPayload struct {
-	Code    int    `json:"code"`
-	Message string `json:"message"`
+	Code            int    `json:"code"`
+	Message         string `json:"message"`
+	ClientMessageID string `json:"clientMessageId,omitempty"` // Set when sending a message failed
 }
 
-// NewClient creates a new WebSocket client
-func NewClient(conn *Connection) *Client {
+// NewClient creates a new WebSocket client buffering up to queueSize outgoing messages
+func NewClient(conn *Connection, queueSize int) *Client {
 	return &Client{
+		SessionID: newSessionID(),
 		Conn:      conn,
-		Send:      make(chan []byte, 256),
+		Send:      make(chan []byte, queueSize),
 		Chatrooms: make(map[string]bool),
+		Blocked:   make(map[string]bool),
+		done:      make(chan struct{}),
 	}
 }
 
 // SendEvent sends an event to the client
 func (c *Client) SendEvent(eventType EventType, payload interface{}) error {
-	event := Event{
+	return c.send(Event{
 		Type:      eventType,
 		Payload:   payload,
 		Timestamp: TimeNow(),
-	}
+	})
+}
 
+// send queues an event for the client
+func (c *Client) send(event Event) error {
 	data, err := json.Marshal(event)
 	if err != nil {
 		return err
 	}
 
-	c.Send <- data
+	if !c.trySend(data) {
+		return errSendQueueFull
+	}
 	return nil
 }
 
-// Close closes the client's WebSocket connection and channels
-func (c *Client) Close() {
-	close(c.Send)
-	c.Conn.Socket.Close()
+// trySend queues a message without blocking, reporting false if the queue is full
+// Messages for a client whose queue is already closed are discarded
+func (c *Client) trySend(message []byte) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return true
+	}
+
+	select {
+	case c.Send <- message:
+		return true
+	default:
+		return false
+	}
+}
+
+// closeSend closes the send queue, which stops the write pump and closes the socket
+func (c *Client) closeSend() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.closed {
+		c.closed = true
+		close(c.Send)
+	}
 }
@@ -4,11 +4,15 @@ import (
 	"app/pkg/database"
 	"context"
 	"fmt"
+	"net"
 	"time"
 
 	"github.com/redis/go-redis/v9"
 )
 
+// Nil is returned when a key doesn't exist or a blocking pop times out
+const Nil = redis.Nil
+
 type Client struct {
 	client *redis.Client
 	cfg    *database.DatabaseConfig
@@ -19,9 +23,12 @@ func NewClient(cfg database.DatabaseConfig) *Client {
 }
 
 func (c *Client) Connect() error {
+	// Addr is a plain host and port, credentials are passed on their own
 	c.client = redis.NewClient(&redis.Options{
-		Addr: fmt.Sprintf("redis://%s:%s@%s:%s", c.cfg.Username, c.cfg.Password, c.cfg.Host, c.cfg.Port),
-		DB:   0,
+		Addr:     net.JoinHostPort(c.cfg.Host, c.cfg.Port),
+		Username: c.cfg.Username,
+		Password: c.cfg.Password,
+		DB:       0,
 	})
 	return nil
 }
@@ -64,6 +71,34 @@ func (c *Client) Del(ctx context.Context, keys ...string) error {
 	return c.client.Del(ctx, keys...).Err()
 }
 
+// Exists checks if a key exists in Redis
+func (c *Client) Exists(ctx context.Context, key string) (bool, error) {
+	if c.client == nil {
+		return false, fmt.Errorf("redis connection not established")
+	}
+	n, err := c.client.Exists(ctx, key).Result()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+// Incr increments the integer value of a key by one and returns the new value
+func (c *Client) Incr(ctx context.Context, key string) (int64, error) {
+	if c.client == nil {
+		return 0, fmt.Errorf("redis connection not established")
+	}
+	return c.client.Incr(ctx, key).Result()
+}
+
+// Expire sets an expiration on an existing key
+func (c *Client) Expire(ctx context.Context, key string, expiration time.Duration) error {
+	if c.client == nil {
+		return fmt.Errorf("redis connection not established")
+	}
+	return c.client.Expire(ctx, key, expiration).Err()
+}
+
 // SAdd adds one or more members to a Redis set
 func (c *Client) SAdd(ctx context.Context, key string, members ...interface{}) error {
 	if c.client == nil {
@@ -96,6 +131,67 @@ func (c *Client) SIsMember(ctx context.Context, key string, member interface{})
 	return c.client.SIsMember(ctx, key, member).Result()
 }
 
+// LPush prepends one or more values to a Redis list
+func (c *Client) LPush(ctx context.Context, key string, values ...interface{}) error {
+	if c.client == nil {
+		return fmt.Errorf("redis connection not established")
+	}
+	return c.client.LPush(ctx, key, values...).Err()
+}
+
+// BRPop removes and returns the last value of the first non-empty list, blocking up to the timeout
+// It returns Nil when the timeout expires without a value
+func (c *Client) BRPop(ctx context.Context, timeout time.Duration, keys ...string) (string, error) {
+	if c.client == nil {
+		return "", fmt.Errorf("redis connection not established")
+	}
+	result, err := c.client.BRPop(ctx, timeout, keys...).Result()
+	if err != nil {
+		return "", err
+	}
+	return result[1], nil
+}
+
+// LTrim trims a Redis list to the given range
+func (c *Client) LTrim(ctx context.Context, key string, start, stop int64) error {
+	if c.client == nil {
+		return fmt.Errorf("redis connection not established")
+	}
+	return c.client.LTrim(ctx, key, start, stop).Err()
+}
+
+// ZAdd adds a member to a Redis sorted set with the given score
+func (c *Client) ZAdd(ctx context.Context, key string, score float64, member interface{}) error {
+	if c.client == nil {
+		return fmt.Errorf("redis connection not established")
+	}
+	return c.client.ZAdd(ctx, key, redis.Z{Score: score, Member: member}).Err()
+}
+
+// ZRangeByScore returns up to count members of a Redis sorted set with scores between min and max
+func (c *Client) ZRangeByScore(ctx context.Context, key string, min, max string, count int64) ([]string, error) {
+	if c.client == nil {
+		return nil, fmt.Errorf("redis connection not established")
+	}
+	return c.client.ZRangeByScore(ctx, key, &redis.ZRangeBy{Min: min, Max: max, Count: count}).Result()
+}
+
+// ZRem removes one or more members from a Redis sorted set and returns how many were removed
+func (c *Client) ZRem(ctx context.Context, key string, members ...interface{}) (int64, error) {
+	if c.client == nil {
+		return 0, fmt.Errorf("redis connection not established")
+	}
+	return c.client.ZRem(ctx, key, members...).Result()
+}
+
+// Eval runs a Lua script atomically with the given keys and arguments
+func (c *Client) Eval(ctx context.Context, script string, keys []string, args ...interface{}) (interface{}, error) {
+	if c.client == nil {
+		return nil, fmt.Errorf("redis connection not established")
+	}
+	return c.client.Eval(ctx, script, keys, args...).Result()
+}
+
 // Publish sends a message to a Redis channel
 func (c *Client) Publish(ctx context.Context, channel string, message interface{}) error {
 	if c.client == nil {
@@ -17,6 +17,13 @@ type PaymentConfig struct {
 	Currency      string `yaml:"currency" env:"PAYMENT_CURRENCY"`
 }
 
+// ChatConfig holds the chat service whose users link their Telegram chats through the bots
+// Linking is disabled unless the chat service URL and API key are set
+type ChatConfig struct {
+	URL    string `yaml:"url" env:"URL"`         // Chat service API root, e.g. http://chat:8080/api
+	APIKey string `yaml:"api_key" env:"API_KEY"` // Admin API key of the chat service
+}
+
 // TelegramConfig holds telegram service specific configuration
 type TelegramConfig struct {
 	Server  fiber.ServerConfig      `yaml:"server" env-prefix:"SERVER_"`
@@ -24,6 +31,7 @@ type TelegramConfig struct {
 	Redis   database.DatabaseConfig `yaml:"redis" env-prefix:"REDIS_"`
 	App     AppConfig               `yaml:"app" env-prefix:"APP_"`
 	Payment PaymentConfig           `yaml:"payment" env-prefix:"PAYMENT_"`
+	Chat    ChatConfig              `yaml:"chat" env-prefix:"CHAT_"`
 }
 
 // Load loads telegram service configuration
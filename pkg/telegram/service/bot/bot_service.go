@@ -10,15 +10,29 @@ import (
 	"gopkg.in/telebot.v4"
 )
 
+// StartHandler answers a /start command received by the bot of a client
+type StartHandler func(clientID string, c telebot.Context) error
+
 // BotService handles multiple bot instances
 type BotService struct {
-	bots  map[string]*telebot.Bot
-	mutex sync.RWMutex
+	bots    map[string]*telebot.Bot
+	onStart StartHandler
+	mutex   sync.RWMutex
 }
 
 // NewBotService creates a new instance of BotService
 func NewBotService() *BotService {
-	return &BotService{}
+	return &BotService{
+		bots: make(map[string]*telebot.Bot),
+	}
+}
+
+// OnStart sets the handler of /start commands, bots started afterwards use it
+func (m *BotService) OnStart(handler StartHandler) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.onStart = handler
 }
 
 // StartBot initializes and starts a new bot instance
@@ -52,6 +66,13 @@ func (m *BotService) StartBot(ctx context.Context, client *entity.Client) error
 		return fmt.Errorf("failed to create bot: %w", err)
 	}
 
+	if m.onStart != nil {
+		onStart := m.onStart
+		bot.Handle("/start", func(c telebot.Context) error {
+			return onStart(client.ID, c)
+		})
+	}
+
 	go bot.Start()
 
 	// Store the bot instance
@@ -122,9 +143,7 @@ func (m *BotService) GetAllBots() map[string]*telebot.Bot {
 
 // SendMessage sends a text message to a specified chat
 func (s *BotService) SendMessage(ctx context.Context, clientID string, chatID int64, text string) error {
-	s.mutex.RLock()
-	defer s.mutex.RUnlock()
-
+	// GetBot takes the read lock itself, locking here too could deadlock behind a waiting writer
 	bot, err := s.GetBot(clientID)
 	if err != nil {
 		return fmt.Errorf("failed to get bot: %w", err)
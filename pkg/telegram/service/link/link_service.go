@@ -0,0 +1,100 @@
+package link
+
+import (
+	"app/pkg/exception"
+	"app/pkg/telegram/domain/entity"
+	"app/pkg/telegram/service/user"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"gopkg.in/telebot.v4"
+)
+
+type linkService struct {
+	chatURL     string
+	apiKey      string
+	userService user.UserService
+	httpClient  *http.Client
+}
+
+// NewLinkService creates a new instance of LinkService
+// The chatURL is the chat service API root and apiKey its admin API key
+func NewLinkService(chatURL string, apiKey string, userService user.UserService) LinkService {
+	return &linkService{
+		chatURL:     strings.TrimRight(chatURL, "/"),
+		apiKey:      apiKey,
+		userService: userService,
+		httpClient: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+	}
+}
+
+// Link reports a link code a Telegram user sent to a bot to the chat service
+func (s *linkService) Link(ctx context.Context, clientID string, sender *telebot.User, code string) error {
+	if err := s.register(ctx, clientID, sender); err != nil {
+		return err
+	}
+
+	body, err := json.Marshal(map[string]interface{}{
+		"botId":  clientID,
+		"chatId": sender.ID, // A private chat's ID is its user's Telegram ID
+		"code":   code,
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.chatURL+"/v1/admin/notifications/telegram", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-API-Key", s.apiKey)
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return nil
+	case resp.StatusCode == http.StatusNotFound:
+		return ErrInvalidCode
+	default:
+		return fmt.Errorf("chat service responded with status %d", resp.StatusCode)
+	}
+}
+
+// register stores the Telegram user as a user of the bot's client unless they already are
+func (s *linkService) register(ctx context.Context, clientID string, sender *telebot.User) error {
+	existing, err := s.userService.GetByTelegramID(ctx, sender.ID)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		// Bots only message users of their own client
+		if existing.ClientID != clientID {
+			return exception.Http(http.StatusConflict, "Telegram user belongs to another bot")
+		}
+		return nil
+	}
+
+	return s.userService.Create(ctx, &entity.User{
+		ClientID:     clientID,
+		TelegramID:   sender.ID,
+		Username:     sender.Username,
+		FirstName:    sender.FirstName,
+		LastName:     sender.LastName,
+		LanguageCode: sender.LanguageCode,
+		IsBot:        sender.IsBot,
+		IsPremium:    sender.IsPremium,
+	})
+}
@@ -0,0 +1,18 @@
+package link
+
+import (
+	"context"
+	"errors"
+
+	"gopkg.in/telebot.v4"
+)
+
+// ErrInvalidCode is returned when the chat service doesn't know a link code, it expired or was already used
+var ErrInvalidCode = errors.New("invalid link code")
+
+// LinkService defines the interface for linking Telegram chats to chat service users
+type LinkService interface {
+	// Link reports a link code a Telegram user sent to a bot to the chat service
+	// The user is registered with the bot's client first, so the bot can send them notifications
+	Link(ctx context.Context, clientID string, sender *telebot.User, code string) error
+}
@@ -0,0 +1,47 @@
+package bot
+
+import (
+	"app/pkg/telegram/service/link"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"gopkg.in/telebot.v4"
+)
+
+// Time limit for linking a chat, Telegram resends updates that aren't answered in time
+const linkTimeout = 15 * time.Second
+
+// LinkHandler answers /start commands, linking the sender's chat when the command carries a chat service link code
+type LinkHandler struct {
+	linkService link.LinkService
+}
+
+// NewLinkHandler creates a new instance of LinkHandler
+func NewLinkHandler(linkService link.LinkService) *LinkHandler {
+	return &LinkHandler{
+		linkService: linkService,
+	}
+}
+
+// HandleStart links the sender's private chat to the chat service user the code of the command was issued to
+func (h *LinkHandler) HandleStart(clientID string, c telebot.Context) error {
+	code := c.Message().Payload
+	if code == "" || c.Chat().Type != telebot.ChatPrivate {
+		return c.Send("👋 Hello! Send /start followed by the link code from the chat app to receive its notifications here.")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), linkTimeout)
+	defer cancel()
+
+	if err := h.linkService.Link(ctx, clientID, c.Sender(), code); err != nil {
+		if errors.Is(err, link.ErrInvalidCode) {
+			return c.Send("This link code is invalid or expired, create a new one in the chat app.")
+		}
+		fmt.Printf("Error linking Telegram chat %d: %v\n", c.Chat().ID, err)
+		return c.Send("Your chat couldn't be linked, please try again later.")
+	}
+
+	return c.Send("✅ Notifications from the chat app will be sent here.")
+}
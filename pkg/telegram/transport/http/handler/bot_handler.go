@@ -2,8 +2,10 @@ package handler
 
 import (
 	"app/pkg/exception"
+	"app/pkg/middleware"
 	"app/pkg/telegram/service/bot"
 	"app/pkg/telegram/service/client"
+	"app/pkg/telegram/service/user"
 	"app/pkg/types/http"
 
 	"github.com/gofiber/fiber/v2"
@@ -12,13 +14,17 @@ import (
 type BotHandler struct {
 	botService    *bot.BotService
 	clientService client.ClientService
+	userService   user.UserService
+	keyMiddleware *middleware.KeyMiddleware
 }
 
 // NewBotHandler creates a new instance of BotHandler
-func NewBotHandler(botService *bot.BotService, clientService client.ClientService) *BotHandler {
+func NewBotHandler(botService *bot.BotService, clientService client.ClientService, userService user.UserService, keyMiddleware *middleware.KeyMiddleware) *BotHandler {
 	return &BotHandler{
 		botService:    botService,
 		clientService: clientService,
+		userService:   userService,
+		keyMiddleware: keyMiddleware,
 	}
 }
 
@@ -31,6 +37,7 @@ func (h *BotHandler) RegisterRoutes(app fiber.Router) {
 	botGroup.Post("/stop-all", h.StopAllBots)
 	botGroup.Get("/status/:clientId", h.GetBotStatus)
 	botGroup.Get("/status", h.GetAllBotsStatus)
+	botGroup.Post("/:clientId/messages", h.keyMiddleware.ValidateKey(), h.SendMessage)
 }
 
 // StartBot godoc
@@ -179,3 +186,59 @@ func (h *BotHandler) GetAllBotsStatus(c *fiber.Ctx) error {
 		Data:    status,
 	})
 }
+
+// SendMessage godoc
+// @Summary Send a message
+// @Description Send a text message through a running bot to the private chat of a Telegram user of the bot's client
+// @Tags bots
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param clientId path string true "Client ID"
+// @Param message body object true "Chat ID and text"
+// @Success 200 {object} http.GeneralResponse
+// @Failure 400 {object} http.ErrorResponse
+// @Failure 401 {object} http.ErrorResponse
+// @Failure 404 {object} http.ErrorResponse
+// @Router /v1/bots/{clientId}/messages [post]
+func (h *BotHandler) SendMessage(c *fiber.Ctx) error {
+	clientID := c.Params("clientId")
+
+	var req struct {
+		ChatID int64  `json:"chatId"`
+		Text   string `json:"text"`
+	}
+
+	if err := c.BodyParser(&req); err != nil {
+		return exception.BadRequest("Invalid request body")
+	}
+	if req.ChatID == 0 || req.Text == "" {
+		return exception.BadRequest("Chat ID and text are required")
+	}
+
+	if _, err := h.botService.GetBot(clientID); err != nil {
+		return exception.NotFound("Bot")
+	}
+
+	// A private chat's ID is its user's Telegram ID, only users of the bot's client can be messaged
+	recipient, err := h.userService.GetByTelegramID(c.Context(), req.ChatID)
+	if err != nil {
+		return err
+	}
+	if recipient == nil || recipient.ClientID != clientID {
+		return exception.BadRequest("Chat isn't linked to the client")
+	}
+
+	if err := h.botService.SendMessage(c.Context(), clientID, req.ChatID, req.Text); err != nil {
+		return exception.InternalError(err.Error())
+	}
+
+	return c.JSON(http.GeneralResponse{
+		Status:  fiber.StatusOK,
+		Message: "Message sent successfully",
+		Data: fiber.Map{
+			"clientId": clientID,
+			"chatId":   req.ChatID,
+		},
+	})
+}
@@ -0,0 +1,55 @@
+package pagination
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Cursor represents keyset pagination parameters
+type Cursor struct {
+	Before string `query:"before" json:"before,omitempty"` // Return items older than this cursor
+	After  string `query:"after" json:"after,omitempty"`   // Return items newer than this cursor
+	Limit  int    `query:"limit" json:"limit"`
+}
+
+// CursorMetadata describes a page returned by keyset pagination
+type CursorMetadata struct {
+	Limit   int    `json:"limit"`
+	Count   int    `json:"count"`
+	Before  string `json:"before,omitempty"` // Cursor to fetch the next older page
+	After   string `json:"after,omitempty"`  // Cursor to fetch the next newer page
+	HasMore bool   `json:"hasMore"`          // More items exist in the requested direction
+}
+
+// CursorPaginatedResult represents a page of items returned by keyset pagination
+type CursorPaginatedResult[T any] struct {
+	Metadata CursorMetadata `json:"metadata"`
+	Result   []*T           `json:"result"`
+}
+
+// EncodeCursor encodes a timestamp and ID pair into an opaque cursor
+func EncodeCursor(timestamp int64, id string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf("%d:%s", timestamp, id)))
+}
+
+// DecodeCursor decodes an opaque cursor into its timestamp and ID pair
+func DecodeCursor(cursor string) (int64, string, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, "", fmt.Errorf("invalid cursor: %v", err)
+	}
+
+	timestamp, id, ok := strings.Cut(string(data), ":")
+	if !ok || id == "" {
+		return 0, "", fmt.Errorf("invalid cursor format")
+	}
+
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return 0, "", fmt.Errorf("invalid cursor timestamp: %v", err)
+	}
+
+	return ts, id, nil
+}
@@ -0,0 +1,63 @@
+package pagination
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	tests := []struct {
+		timestamp int64
+		id        string
+	}{
+		{1718000000, "6650a1b2c3d4e5f607182930"},
+		{0, "first"},
+		{-1, "negative"},
+		{1718000000, "client:user:42"}, // IDs may contain the separator
+		{1718000000, "ünïcode/+="},
+	}
+
+	for _, tt := range tests {
+		cursor := EncodeCursor(tt.timestamp, tt.id)
+		if strings.ContainsAny(cursor, "+/=") {
+			t.Errorf("EncodeCursor(%d, %q) = %q, want URL safe", tt.timestamp, tt.id, cursor)
+		}
+
+		timestamp, id, err := DecodeCursor(cursor)
+		if err != nil {
+			t.Errorf("DecodeCursor(%q): %v", cursor, err)
+			continue
+		}
+		if timestamp != tt.timestamp || id != tt.id {
+			t.Errorf("DecodeCursor(EncodeCursor(%d, %q)) = %d, %q", tt.timestamp, tt.id, timestamp, id)
+		}
+	}
+}
+
+func TestDecodeCursorRejectsInvalidCursors(t *testing.T) {
+	encode := func(s string) string {
+		return base64.RawURLEncoding.EncodeToString([]byte(s))
+	}
+
+	tests := []struct {
+		name   string
+		cursor string
+	}{
+		{"not base64", "not a cursor!"},
+		{"padded base64", base64.URLEncoding.EncodeToString([]byte("1718000000:abc"))},
+		{"no separator", encode("1718000000")},
+		{"no id", encode("1718000000:")},
+		{"no timestamp", encode(":abc")},
+		{"text timestamp", encode("yesterday:abc")},
+		{"overflowing timestamp", encode("99999999999999999999:abc")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if timestamp, id, err := DecodeCursor(tt.cursor); err == nil {
+				t.Errorf("DecodeCursor(%q) = %d, %q, want an error", tt.cursor, timestamp, id)
+			}
+		})
+	}
+}